@@ -0,0 +1,84 @@
+// Package albumio imports and exports albums in bulk as CSV, NDJSON and XLSX
+package albumio
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Format is a bulk import/export file format
+type Format string
+
+// Supported formats
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+	FormatXLSX   Format = "xlsx"
+)
+
+// Mode controls how an import handles invalid rows
+type Mode string
+
+// Supported import modes
+const (
+	// ModeAtomic imports every row in one transaction, or none if any row fails
+	ModeAtomic Mode = "atomic"
+	// ModeBestEffort imports every valid row and reports the rest
+	ModeBestEffort Mode = "best-effort"
+)
+
+// columns is the header written on export and understood on import
+var columns = []string{"id", "title", "artist", "price", "tax", "version"}
+
+// ParseFormat converts a format name or file extension into a Format
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(s, ".")) {
+	case "csv":
+		return FormatCSV, nil
+	case "ndjson", "jsonl":
+		return FormatNDJSON, nil
+	case "xlsx":
+		return FormatXLSX, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q", s)
+	}
+}
+
+// ParseMode converts a mode name into a Mode. An empty name selects ModeAtomic.
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(s) {
+	case "", "atomic":
+		return ModeAtomic, nil
+	case "best-effort", "best_effort":
+		return ModeBestEffort, nil
+	default:
+		return "", fmt.Errorf("unsupported import mode %q", s)
+	}
+}
+
+// FormatFromContentType maps a request Content-Type to an import Format
+func FormatFromContentType(contentType string) (Format, error) {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return FormatCSV, nil
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/json-seq":
+		return FormatNDJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported content type %q", contentType)
+	}
+}
+
+// ContentType returns the MIME type used when exporting in format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/octet-stream"
+	}
+}
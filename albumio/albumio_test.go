@@ -0,0 +1,228 @@
+package albumio
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"io"
+	"strings"
+	"testing"
+)
+
+func newTestService() (*Service, *repository.MockAlbumRepository) {
+	albums := repository.NewMockAlbumRepository()
+	return NewService(albums, repository.NewMockUnitOfWork(albums)), albums
+}
+
+func TestService_Import(t *testing.T) {
+	csvInput := "\xEF\xBB\xBFTitle,Artist,Price,Tax\n" +
+		"Blue Train,John Coltrane,19.99,0.1\n" +
+		",Missing Title,10,0.1\n" +
+		"Kind of Blue,Miles Davis,abc,0.1\n" +
+		"Hammerhead,THE OFFSPRING,25.05,0.1\n" +
+		"Waltz for Debby,Bill Evans,15,\n"
+
+	ndjsonInput := `{"title":"Blue Train","artist":"John Coltrane","price":19.99,"tax":0.1}
+{"title":"Blue Train","artist":"John Coltrane","price":19.99,"tax":0.1}
+
+not json
+{"title":"Waltz for Debby","artist":"Bill Evans","price":15}
+`
+
+	tests := []struct {
+		name         string
+		input        string
+		format       Format
+		mode         Mode
+		wantImported int
+		wantFailed   int
+		wantRows     []int
+		wantAlbums   int
+	}{
+		{"CSV best effort", csvInput, FormatCSV, ModeBestEffort, 2, 3, []int{3, 4, 5}, 5},
+		{"CSV atomic", csvInput, FormatCSV, ModeAtomic, 0, 2, []int{3, 4}, 3},
+		{"NDJSON best effort", ndjsonInput, FormatNDJSON, ModeBestEffort, 2, 2, []int{2, 4}, 5},
+		{"NDJSON atomic", ndjsonInput, FormatNDJSON, ModeAtomic, 0, 2, []int{2, 4}, 3},
+		{"Valid atomic", "title,artist,price\nA,B,1\nC,D,2\n", FormatCSV, ModeAtomic, 2, 0, nil, 5},
+		{"Differing case", "title,artist,price\nBlue Train,John Coltrane,1\nBLUE TRAIN,john coltrane,2\n", FormatCSV, ModeAtomic, 2, 0, nil, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, albums := newTestService()
+			ctx := context.Background()
+
+			result, err := svc.Import(ctx, strings.NewReader(tt.input), tt.format, tt.mode)
+			if err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+			if result.Imported != tt.wantImported || result.Failed != tt.wantFailed {
+				t.Errorf("Expected %d imported and %d failed, got %+v", tt.wantImported, tt.wantFailed, result)
+			}
+			if len(result.Errors) != len(tt.wantRows) {
+				t.Fatalf("Expected errors for rows %v, got %+v", tt.wantRows, result.Errors)
+			}
+			for i, row := range tt.wantRows {
+				if result.Errors[i].Row != row {
+					t.Errorf("Expected error for row %d, got %+v", row, result.Errors[i])
+				}
+			}
+
+			all, _ := albums.FindAll(ctx)
+			if len(all) != tt.wantAlbums {
+				t.Errorf("Expected %d albums after import, got %d", tt.wantAlbums, len(all))
+			}
+		})
+	}
+}
+
+func TestService_ImportRejectsBadHeader(t *testing.T) {
+	svc, _ := newTestService()
+
+	_, err := svc.Import(context.Background(), strings.NewReader("name,price\nA,1\n"), FormatCSV, ModeAtomic)
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) {
+		t.Errorf("Expected InputError for CSV without title column, got %v", err)
+	}
+}
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"Hammerhead", "Hammerhead"},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1", "'+1"},
+		{"-2", "'-2"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"'=already quoted", "''=already quoted"},
+		{"'quoted", "'quoted"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := escapeFormula(tt.value); got != tt.expected {
+			t.Errorf("escapeFormula(%q): expected %q, got %q", tt.value, tt.expected, got)
+		}
+		if got := unescapeFormula(escapeFormula(tt.value)); got != tt.value {
+			t.Errorf("Expected %q to round trip, got %q", tt.value, got)
+		}
+	}
+}
+
+func TestService_ExportImportFormulas(t *testing.T) {
+	svc, albums := newTestService()
+	ctx := context.Background()
+	albums.Create(ctx, &models.Album{Title: "=1+1", Artist: "@artist", Price: 1, Tax: 0.1})
+
+	var buf bytes.Buffer
+	if err := svc.Export(ctx, &buf, FormatCSV); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), ",'=1+1,'@artist,") {
+		t.Fatalf("Expected formula cells to be escaped, got %q", buf.String())
+	}
+
+	// The seeded rows already exist in the target and are skipped
+	target, imported := newTestService()
+	result, err := target.Import(ctx, &buf, FormatCSV, ModeBestEffort)
+	if err != nil || result.Imported != 1 {
+		t.Fatalf("Expected 1 imported album, got %+v, %v", result, err)
+	}
+	all, _ := imported.FindAll(ctx)
+	if last := all[len(all)-1]; last.Title != "=1+1" || last.Artist != "@artist" {
+		t.Errorf("Expected escaped cells to be restored on import, got %+v", last)
+	}
+}
+
+func TestService_Export(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+
+	t.Run("CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := svc.Export(ctx, &buf, FormatCSV); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 4 {
+			t.Fatalf("Expected header and 3 rows, got %q", buf.String())
+		}
+		if lines[0] != "id,title,artist,price,tax,version" {
+			t.Errorf("Unexpected header %q", lines[0])
+		}
+		if lines[1] != "1,Hammerhead,THE OFFSPRING,25.05,0.1,1" {
+			t.Errorf("Unexpected row %q", lines[1])
+		}
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := svc.Export(ctx, &buf, FormatNDJSON); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		dec := json.NewDecoder(&buf)
+		count := 0
+		for {
+			var album map[string]interface{}
+			if err := dec.Decode(&album); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("Invalid NDJSON: %v", err)
+			}
+			count++
+		}
+		if count != 3 {
+			t.Errorf("Expected 3 albums, got %d", count)
+		}
+	})
+
+	t.Run("XLSX", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := svc.Export(ctx, &buf, FormatXLSX); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("Export is not a zip archive: %v", err)
+		}
+
+		var sheet []byte
+		for _, f := range zr.File {
+			if f.Name == "xl/worksheets/sheet1.xml" {
+				rc, _ := f.Open()
+				sheet, _ = io.ReadAll(rc)
+				rc.Close()
+			}
+		}
+		if !bytes.Contains(sheet, []byte("Miho Komatsu")) || !bytes.Contains(sheet, []byte(`<row r="4">`)) {
+			t.Errorf("Unexpected worksheet %s", sheet)
+		}
+	})
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Format
+		wantErr bool
+	}{
+		{"csv", FormatCSV, false},
+		{".jsonl", FormatNDJSON, false},
+		{"XLSX", FormatXLSX, false},
+		{"xml", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.input)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseFormat(%q) = %q, %v", tt.input, got, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package albumio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang-gin/models"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxLineSize is the longest NDJSON line accepted on import
+const maxLineSize = 1 << 20
+
+// utf8BOM is stripped from the start of imported files, since spreadsheet
+// applications commonly add it to exported CSV
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// rowError reports a row that could not be parsed. Reading can continue
+// with the next row.
+type rowError struct {
+	row int
+	err error
+}
+
+func (e *rowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.row, e.err)
+}
+
+// rowReader reads albums one row at a time
+type rowReader interface {
+	// next returns the next album and its row number. It returns a *rowError
+	// for a malformed row and io.EOF after the last row.
+	next() (int, models.Album, error)
+}
+
+// newRowReader creates a rowReader for format
+func newRowReader(r io.Reader, format Format) (rowReader, error) {
+	br := bufio.NewReader(r)
+	if prefix, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(prefix, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+
+	switch format {
+	case FormatCSV:
+		return newCSVReader(br)
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(br)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+		return &ndjsonReader{scanner: scanner}, nil
+	default:
+		return nil, fmt.Errorf("import from %s is not supported", format)
+	}
+}
+
+// csvReader reads albums from CSV with a header row
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("csv: missing header row")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("csv: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"title", "artist", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv: missing %q column", required)
+		}
+	}
+
+	return &csvReader{r: cr, columns: columns}, nil
+}
+
+func (c *csvReader) next() (int, models.Album, error) {
+	record, err := c.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return parseErr.StartLine, models.Album{}, &rowError{row: parseErr.StartLine, err: parseErr.Err}
+		}
+		return 0, models.Album{}, err
+	}
+	row, _ := c.r.FieldPos(0)
+
+	field := func(name string) string {
+		if i, ok := c.columns[name]; ok && i < len(record) {
+			return unescapeFormula(strings.TrimSpace(record[i]))
+		}
+		return ""
+	}
+
+	album := models.Album{Title: field("title"), Artist: field("artist")}
+	if album.Price, err = parseNumber("price", field("price"), 64); err != nil {
+		return row, models.Album{}, &rowError{row: row, err: err}
+	}
+	tax, err := parseNumber("tax", field("tax"), 32)
+	if err != nil {
+		return row, models.Album{}, &rowError{row: row, err: err}
+	}
+	album.Tax = float32(tax)
+	return row, album, nil
+}
+
+// parseNumber parses an optional numeric CSV field
+func parseNumber(name, value string, bitSize int) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(value, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+	return f, nil
+}
+
+// ndjsonReader reads albums from newline-delimited JSON objects
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// ndjsonRecord is the subset of album fields accepted on import
+type ndjsonRecord struct {
+	Title  string  `json:"title"`
+	Artist string  `json:"artist"`
+	Price  float64 `json:"price"`
+	Tax    float32 `json:"tax"`
+}
+
+func (n *ndjsonReader) next() (int, models.Album, error) {
+	for n.scanner.Scan() {
+		n.line++
+		line := bytes.TrimSpace(n.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var record ndjsonRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return n.line, models.Album{}, &rowError{row: n.line, err: fmt.Errorf("invalid JSON: %v", err)}
+		}
+		return n.line, models.Album{
+			Title:  strings.TrimSpace(record.Title),
+			Artist: strings.TrimSpace(record.Artist),
+			Price:  record.Price,
+			Tax:    record.Tax,
+		}, nil
+	}
+
+	if err := n.scanner.Err(); err != nil {
+		return 0, models.Album{}, fmt.Errorf("ndjson: line %d: %w", n.line+1, err)
+	}
+	return 0, models.Album{}, io.EOF
+}
@@ -0,0 +1,162 @@
+package albumio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-gin/repository"
+	"io"
+
+	"gorm.io/gorm"
+)
+
+// maxReportedErrors caps the row errors included in a Result
+const maxReportedErrors = 1000
+
+// errRollback aborts the import transaction after a failed row
+var errRollback = errors.New("albumio: rollback")
+
+// InputError reports an import file that cannot be read at all, as opposed
+// to a database failure
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// RowError describes why a single row was not imported
+type RowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// Result summarizes an import
+type Result struct {
+	Mode     Mode       `json:"mode"`
+	Total    int        `json:"total"`
+	Imported int        `json:"imported"`
+	Failed   int        `json:"failed"`
+	Errors   []RowError `json:"errors"`
+}
+
+// addError records a failed row
+func (r *Result) addError(row int, err error) {
+	r.Failed++
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, RowError{Row: row, Error: err.Error()})
+	}
+}
+
+// Service imports and exports albums
+type Service struct {
+	albums repository.AlbumRepository
+	uow    repository.UnitOfWork
+}
+
+// NewService creates a new Service. The unit of work is used for atomic imports.
+func NewService(albums repository.AlbumRepository, uow repository.UnitOfWork) *Service {
+	return &Service{albums: albums, uow: uow}
+}
+
+// Import reads albums from r and creates them. Invalid rows are reported in
+// the result; an error is only returned when the input cannot be read (an
+// *InputError) or the database fails. In ModeAtomic nothing is imported if
+// any row fails.
+func (s *Service) Import(ctx context.Context, r io.Reader, format Format, mode Mode) (*Result, error) {
+	rows, err := newRowReader(r, format)
+	if err != nil {
+		return nil, &InputError{Err: err}
+	}
+
+	result := &Result{Mode: mode, Errors: []RowError{}}
+	if mode == ModeBestEffort {
+		return result, s.importRows(ctx, rows, s.albums, result, false)
+	}
+
+	err = s.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		if err := s.importRows(ctx, rows, repos.Albums, result, true); err != nil {
+			return err
+		}
+		if result.Failed > 0 {
+			return errRollback
+		}
+		return nil
+	})
+	if errors.Is(err, errRollback) {
+		result.Imported = 0
+		return result, nil
+	}
+	return result, err
+}
+
+// importRows creates every valid row with albums. When stopOnFailure is set
+// no further albums are created after the first failed row, but the
+// remaining rows are still validated so that all errors are reported.
+func (s *Service) importRows(ctx context.Context, rows rowReader, albums repository.AlbumRepository, result *Result, stopOnFailure bool) error {
+	seen := make(map[string]int)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		row, album, err := rows.next()
+		if err == io.EOF {
+			return nil
+		}
+		result.Total++
+
+		var rowErr *rowError
+		if errors.As(err, &rowErr) {
+			result.addError(rowErr.row, rowErr.err)
+			continue
+		}
+		if err != nil {
+			return &InputError{Err: err}
+		}
+
+		if err := album.Validate(); err != nil {
+			result.addError(row, err)
+			continue
+		}
+
+		// Compared like the unique index on title and artist, which is case
+		// sensitive
+		key := album.Title + "\x00" + album.Artist
+		if first, ok := seen[key]; ok {
+			result.addError(row, fmt.Errorf("duplicate of row %d", first))
+			continue
+		}
+		seen[key] = row
+
+		if stopOnFailure && result.Failed > 0 {
+			continue
+		}
+
+		if err := albums.Create(ctx, &album); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				result.addError(row, errors.New("album already exists"))
+				continue
+			}
+			return err
+		}
+		result.Imported++
+	}
+}
+
+// Export streams every album to w in format
+func (s *Service) Export(ctx context.Context, w io.Writer, format Format) error {
+	rows, err := newRowWriter(w, format)
+	if err != nil {
+		return err
+	}
+	if err := s.albums.Stream(ctx, rows.write); err != nil {
+		return err
+	}
+	return rows.close()
+}
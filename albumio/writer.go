@@ -0,0 +1,202 @@
+package albumio
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"golang-gin/models"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// rowWriter writes albums one row at a time
+type rowWriter interface {
+	write(album *models.Album) error
+	// close flushes buffered output. It does not close the underlying writer.
+	close() error
+}
+
+// newRowWriter creates a rowWriter for format and writes any header
+func newRowWriter(w io.Writer, format Format) (rowWriter, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w: cw}, nil
+	case FormatNDJSON:
+		bw := bufio.NewWriter(w)
+		return &ndjsonWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, fmt.Errorf("export to %s is not supported", format)
+	}
+}
+
+// fields returns the exported column values of album
+func fields(album *models.Album) []string {
+	return []string{
+		strconv.FormatUint(uint64(album.ID), 10),
+		album.Title,
+		album.Artist,
+		strconv.FormatFloat(album.Price, 'f', -1, 64),
+		strconv.FormatFloat(float64(album.Tax), 'f', -1, 32),
+		strconv.FormatUint(uint64(album.Version), 10),
+	}
+}
+
+// csvWriter writes albums as CSV with a header row
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) write(album *models.Album) error {
+	values := fields(album)
+	for i, value := range values {
+		values[i] = escapeFormula(value)
+	}
+	return c.w.Write(values)
+}
+
+// formulaPrefixes are the leading characters that make a spreadsheet treat
+// a CSV cell as a formula
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeFormula prefixes cells that a spreadsheet would evaluate as a
+// formula with a single quote. Cells that only look escaped get a second
+// quote, so unescapeFormula restores every value exactly.
+func escapeFormula(value string) string {
+	if rest := strings.TrimLeft(value, "'"); rest != "" && strings.ContainsRune(formulaPrefixes, rune(rest[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// unescapeFormula reverses escapeFormula
+func unescapeFormula(value string) string {
+	if strings.HasPrefix(value, "'") && escapeFormula(value[1:]) == value {
+		return value[1:]
+	}
+	return value
+}
+
+func (c *csvWriter) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ndjsonWriter writes albums as newline-delimited JSON objects
+type ndjsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) write(album *models.Album) error {
+	return n.enc.Encode(album)
+}
+
+func (n *ndjsonWriter) close() error {
+	return n.w.Flush()
+}
+
+// Static parts of the generated XLSX package
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="albums" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxSheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetFooter = `</sheetData></worksheet>`
+)
+
+// xlsxWriter writes a single-sheet XLSX workbook. The worksheet is streamed
+// into the zip archive row by row, so memory use does not grow with the
+// number of albums.
+type xlsxWriter struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	row   int
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxWriter{zw: zw, sheet: bufio.NewWriter(f)}
+	x.sheet.WriteString(xlsxSheetHeader)
+
+	header := make([]interface{}, len(columns))
+	for i, name := range columns {
+		header[i] = name
+	}
+	if err := x.writeRow(header); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxWriter) write(album *models.Album) error {
+	return x.writeRow([]interface{}{album.ID, album.Title, album.Artist, album.Price, album.Tax, album.Version})
+}
+
+// writeRow writes one worksheet row. Strings become inline string cells and
+// numbers numeric cells, so spreadsheet formulas work on prices.
+func (x *xlsxWriter) writeRow(values []interface{}) error {
+	x.row++
+	fmt.Fprintf(x.sheet, `<row r="%d">`, x.row)
+	for i, value := range values {
+		ref := fmt.Sprintf("%c%d", 'A'+i, x.row)
+		switch v := value.(type) {
+		case string:
+			fmt.Fprintf(x.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			if err := xml.EscapeText(x.sheet, []byte(v)); err != nil {
+				return err
+			}
+			x.sheet.WriteString(`</t></is></c>`)
+		case float64:
+			fmt.Fprintf(x.sheet, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+		case float32:
+			fmt.Fprintf(x.sheet, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(float64(v), 'f', -1, 32))
+		default:
+			fmt.Fprintf(x.sheet, `<c r="%s"><v>%d</v></c>`, ref, v)
+		}
+	}
+	_, err := x.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (x *xlsxWriter) close() error {
+	x.sheet.WriteString(xlsxSheetFooter)
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"context"
+	"golang-gin/models"
+)
+
+// Source identifies the entry point that triggered a write
+type Source string
+
+// Known write sources
+const (
+	SourceREST   Source = "rest"
+	SourceGRPC   Source = "grpc"
+	SourceCLI    Source = "cli"
+	SourceSystem Source = "system"
+)
+
+// AnonymousActor is recorded when a write carries no actor
+const AnonymousActor = "anonymous"
+
+// Metadata describes who triggered a write and how
+type Metadata struct {
+	Actor string
+	// ActorVerified is set when Actor is an authenticated identity (the
+	// admin token, the CLI user or a job) rather than a value supplied by
+	// the client
+	ActorVerified bool
+	RequestID     string
+	Source        Source
+}
+
+type contextKey struct{}
+
+// WithMetadata returns a copy of ctx carrying the given audit metadata
+func WithMetadata(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, contextKey{}, md)
+}
+
+// FromContext returns the audit metadata carried by ctx, with an anonymous
+// actor and the system source filled in when missing
+func FromContext(ctx context.Context) Metadata {
+	md, _ := ctx.Value(contextKey{}).(Metadata)
+	if md.Actor == "" {
+		md.Actor = AnonymousActor
+	}
+	if md.Source == "" {
+		md.Source = SourceSystem
+	}
+	return md
+}
+
+// auditedFields lists the album fields tracked in audit diffs
+var auditedFields = []struct {
+	name string
+	get  func(*models.Album) interface{}
+}{
+	{"title", func(a *models.Album) interface{} { return a.Title }},
+	{"artist", func(a *models.Album) interface{} { return a.Artist }},
+	{"price", func(a *models.Album) interface{} { return a.Price }},
+	{"tax", func(a *models.Album) interface{} { return a.Tax }},
+	{"owner_id", func(a *models.Album) interface{} { return optionalID(a.OwnerID) }},
+	{"product_id", func(a *models.Album) interface{} { return optionalID(a.ProductID) }},
+}
+
+// optionalID returns the value of an optional ID, or nil if it is not set
+func optionalID(id *uint) interface{} {
+	if id == nil {
+		return nil
+	}
+	return *id
+}
+
+// Diff returns the fields that differ between two versions of an album.
+// A nil before (create) or after (purge) reports every field as changed.
+func Diff(before, after *models.Album) map[string]models.FieldChange {
+	changes := map[string]models.FieldChange{}
+	for _, field := range auditedFields {
+		var from, to interface{}
+		if before != nil {
+			from = field.get(before)
+		}
+		if after != nil {
+			to = field.get(after)
+		}
+
+		if before != nil && after != nil && from == to {
+			continue
+		}
+		changes[field.name] = models.FieldChange{From: from, To: to}
+	}
+	return changes
+}
@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"context"
+	"golang-gin/models"
+	"testing"
+)
+
+func TestFromContext_Defaults(t *testing.T) {
+	md := FromContext(context.Background())
+	if md.Actor != AnonymousActor {
+		t.Errorf("Expected actor %s, got %s", AnonymousActor, md.Actor)
+	}
+	if md.Source != SourceSystem {
+		t.Errorf("Expected source %s, got %s", SourceSystem, md.Source)
+	}
+
+	ctx := WithMetadata(context.Background(), Metadata{Actor: "alice", RequestID: "r1", Source: SourceGRPC})
+	md = FromContext(ctx)
+	if md.Actor != "alice" || md.RequestID != "r1" || md.Source != SourceGRPC {
+		t.Errorf("Unexpected metadata: %+v", md)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := &models.Album{Title: "A", Artist: "X", Price: 10, Tax: 0.1}
+	after := &models.Album{Title: "A", Artist: "X", Price: 12, Tax: 0.08}
+
+	changes := Diff(before, after)
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %+v", changes)
+	}
+	if changes["price"].From != 10.0 || changes["price"].To != 12.0 {
+		t.Errorf("Unexpected price change: %+v", changes["price"])
+	}
+	if _, ok := changes["title"]; ok {
+		t.Error("Expected unchanged title to be omitted")
+	}
+
+	if created := Diff(nil, after); len(created) != 6 || created["title"].From != nil {
+		t.Errorf("Expected all fields on create, got %+v", created)
+	}
+	if deleted := Diff(before, nil); len(deleted) != 6 || deleted["title"].To != nil {
+		t.Errorf("Expected all fields on delete, got %+v", deleted)
+	}
+
+	owner := uint(7)
+	owned := *before
+	owned.OwnerID = &owner
+	if changes := Diff(before, &owned); len(changes) != 1 || changes["owner_id"].From != nil || changes["owner_id"].To != uint(7) {
+		t.Errorf("Expected owner change, got %+v", changes)
+	}
+	sameOwner := uint(7)
+	if changes := Diff(&owned, &models.Album{Title: "A", Artist: "X", Price: 10, Tax: 0.1, OwnerID: &sameOwner}); len(changes) != 0 {
+		t.Errorf("Expected equal owners to be unchanged, got %+v", changes)
+	}
+}
@@ -0,0 +1,31 @@
+// Package backoff computes retry delays shared by the clients, consumers and
+// background jobs
+package backoff
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Exponential returns the delay before the given attempt, starting at base
+// for the first attempt and doubling on every further attempt up to max. It
+// never overflows, however large attempt is.
+func Exponential(attempt int, base, max time.Duration) time.Duration {
+	if base <= 0 || max <= 0 {
+		return 0
+	}
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	return min(delay, max)
+}
+
+// Jitter spreads d over [d/2, d] so that callers failing together do not
+// retry together
+func Jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
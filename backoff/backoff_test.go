@@ -0,0 +1,44 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponential(t *testing.T) {
+	tests := []struct {
+		attempt  int
+		base     time.Duration
+		max      time.Duration
+		expected time.Duration
+	}{
+		{1, time.Second, 5 * time.Minute, time.Second},
+		{2, time.Second, 5 * time.Minute, 2 * time.Second},
+		{5, time.Second, 5 * time.Minute, 16 * time.Second},
+		{30, time.Second, 5 * time.Minute, 5 * time.Minute},
+		{1000, time.Second, 24 * time.Hour, 24 * time.Hour},
+		{0, time.Second, time.Minute, time.Second},
+		{1, time.Minute, time.Second, time.Second},
+		{3, 0, time.Second, 0},
+		{3, time.Second, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := Exponential(tt.attempt, tt.base, tt.max); got != tt.expected {
+			t.Errorf("Expected %v for attempt %d (%v..%v), got %v", tt.expected, tt.attempt, tt.base, tt.max, got)
+		}
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for _, d := range []time.Duration{time.Millisecond, time.Second, time.Hour} {
+		for i := 0; i < 20; i++ {
+			if got := Jitter(d); got < d/2 || got > d {
+				t.Errorf("Expected jitter of %v within [%v, %v], got %v", d, d/2, d, got)
+			}
+		}
+	}
+	if got := Jitter(0); got != 0 {
+		t.Errorf("Expected no jitter for a zero delay, got %v", got)
+	}
+}
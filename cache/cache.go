@@ -0,0 +1,22 @@
+// Package cache provides the stores behind the read-through caches
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Store is a byte oriented cache with per-entry expiry. Implementations must
+// be safe for concurrent use. The in-memory LRU is used by default; a shared
+// store such as Redis or Memcached can be plugged in to share entries between
+// instances.
+type Store interface {
+	// Get returns the value stored under key and whether it was found.
+	// Callers must not modify the returned slice.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key. A ttl <= 0 keeps the entry until it is
+	// evicted or deleted.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the given keys. Missing keys are ignored.
+	Delete(ctx context.Context, keys ...string) error
+}
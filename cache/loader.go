@@ -0,0 +1,245 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Stats reports how lookups of a Loader were served
+type Stats struct {
+	Hits          int64 `json:"hits"`
+	NegativeHits  int64 `json:"negative_hits"`
+	Misses        int64 `json:"misses"`
+	SharedLoads   int64 `json:"shared_loads"`
+	Invalidations int64 `json:"invalidations"`
+	Errors        int64 `json:"errors"`
+}
+
+// LoaderOption configures a Loader
+type LoaderOption func(*loaderConfig)
+
+// loaderConfig holds the settings applied by LoaderOption
+type loaderConfig struct {
+	ttl         time.Duration
+	staleTTL    time.Duration
+	negativeTTL time.Duration
+	stale       func(error) bool
+	now         func() time.Time
+}
+
+// WithTTL sets how long a loaded value is served before it is loaded again
+func WithTTL(ttl time.Duration) LoaderOption {
+	return func(c *loaderConfig) {
+		c.ttl = ttl
+	}
+}
+
+// WithNegativeTTL sets how long keys reported as not found are cached. Zero
+// disables negative caching.
+func WithNegativeTTL(ttl time.Duration) LoaderOption {
+	return func(c *loaderConfig) {
+		c.negativeTTL = ttl
+	}
+}
+
+// WithStaleTTL serves an expired value for up to ttl after it expires when
+// a reload fails with an error for which stale reports true, e.g. while the
+// backing service is unavailable
+func WithStaleTTL(ttl time.Duration, stale func(error) bool) LoaderOption {
+	return func(c *loaderConfig) {
+		c.staleTTL = ttl
+		c.stale = stale
+	}
+}
+
+// WithClock replaces time.Now, e.g. in tests
+func WithClock(now func() time.Time) LoaderOption {
+	return func(c *loaderConfig) {
+		c.now = now
+	}
+}
+
+// entry is the stored form of a lookup. A nil Value records a key that was
+// not found.
+type entry[V any] struct {
+	Value     *V        `json:"value,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Loader is a read-through cache of values of type V kept in a Store.
+// Concurrent misses for the same key share one load, and an invalidation
+// during a load keeps the loaded value out of the store. It is safe for
+// concurrent use.
+type Loader[V any] struct {
+	store    Store
+	name     string
+	notFound error
+	cfg      loaderConfig
+	group    singleflight.Group
+
+	// epoch is bumped on every invalidation so a load that started before a
+	// write does not put the old value back into the store
+	epoch atomic.Uint64
+
+	hits, negativeHits, misses, sharedLoads, invalidations, errors atomic.Int64
+}
+
+// NewLoader creates a Loader backed by store. name is used in log messages.
+// Loads failing with notFound are cached as negative entries, and cached
+// negative entries are returned as notFound. Values are cached for a minute
+// by default; negative entries for 10 seconds.
+func NewLoader[V any](store Store, name string, notFound error, opts ...LoaderOption) *Loader[V] {
+	l := &Loader[V]{
+		store:    store,
+		name:     name,
+		notFound: notFound,
+		cfg: loaderConfig{
+			ttl:         time.Minute,
+			negativeTTL: 10 * time.Second,
+			now:         time.Now,
+		},
+	}
+	for _, opt := range opts {
+		opt(&l.cfg)
+	}
+	return l
+}
+
+// Get returns the value stored under key, calling load on a miss or after
+// the value expired. Each caller gets its own copy of the value.
+func (l *Loader[V]) Get(ctx context.Context, key string, load func(ctx context.Context) (*V, error)) (*V, error) {
+	cached := l.lookup(ctx, key)
+	if cached != nil {
+		if cached.Value == nil {
+			l.negativeHits.Add(1)
+			return nil, l.notFound
+		}
+		if l.cfg.now().Sub(cached.FetchedAt) < l.cfg.ttl {
+			l.hits.Add(1)
+			return cached.Value, nil
+		}
+	}
+	l.misses.Add(1)
+
+	// The load is shared by every caller waiting on the key, so it must not
+	// be cancelled when the first caller goes away
+	ch := l.group.DoChan(key, func() (interface{}, error) {
+		return l.load(context.WithoutCancel(ctx), key, load)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Shared {
+			l.sharedLoads.Add(1)
+		}
+		if res.Err != nil {
+			if cached != nil && l.cfg.stale != nil && l.cfg.stale(res.Err) &&
+				l.cfg.now().Sub(cached.FetchedAt) < l.cfg.ttl+l.cfg.staleTTL {
+				log.Printf("Serving stale %s %s: %v", l.name, key, res.Err)
+				return cached.Value, nil
+			}
+			return nil, res.Err
+		}
+		value := *res.Val.(*V)
+		return &value, nil
+	}
+}
+
+// lookup reads key from the store and returns nil on a miss or store error
+func (l *Loader[V]) lookup(ctx context.Context, key string) *entry[V] {
+	data, found, err := l.store.Get(ctx, key)
+	if err != nil {
+		l.errors.Add(1)
+		log.Printf("%s cache get %s failed: %v", l.name, key, err)
+		return nil
+	}
+	if !found {
+		return nil
+	}
+
+	cached := &entry[V]{}
+	if err := json.Unmarshal(data, cached); err != nil {
+		l.errors.Add(1)
+		log.Printf("%s cache entry %s is invalid: %v", l.name, key, err)
+		return nil
+	}
+	return cached
+}
+
+// load calls load and caches the result
+func (l *Loader[V]) load(ctx context.Context, key string, load func(ctx context.Context) (*V, error)) (*V, error) {
+	epoch := l.epoch.Load()
+
+	value, err := load(ctx)
+	switch {
+	case err == nil:
+		l.set(ctx, key, entry[V]{Value: value, FetchedAt: l.cfg.now()}, l.cfg.ttl+l.cfg.staleTTL, epoch)
+	case errors.Is(err, l.notFound) && l.cfg.negativeTTL > 0:
+		l.set(ctx, key, entry[V]{FetchedAt: l.cfg.now()}, l.cfg.negativeTTL, epoch)
+	}
+	return value, err
+}
+
+// set stores a loaded entry unless an invalidation happened during the load
+func (l *Loader[V]) set(ctx context.Context, key string, e entry[V], ttl time.Duration, epoch uint64) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		l.errors.Add(1)
+		return
+	}
+	if l.epoch.Load() != epoch {
+		return
+	}
+	if err := l.store.Set(ctx, key, data, ttl); err != nil {
+		l.errors.Add(1)
+		log.Printf("%s cache set %s failed: %v", l.name, key, err)
+		return
+	}
+
+	// An invalidation between the check above and Set may have deleted the
+	// key before the loaded value was written, so remove it again
+	if l.epoch.Load() != epoch {
+		if err := l.store.Delete(ctx, key); err != nil {
+			l.errors.Add(1)
+			log.Printf("%s cache delete %s failed: %v", l.name, key, err)
+		}
+	}
+}
+
+// Invalidate removes the given keys from the store
+func (l *Loader[V]) Invalidate(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	l.epoch.Add(1)
+	for _, key := range keys {
+		l.group.Forget(key)
+	}
+
+	l.invalidations.Add(int64(len(keys)))
+	if err := l.store.Delete(ctx, keys...); err != nil {
+		l.errors.Add(1)
+		log.Printf("%s cache invalidation of %v failed: %v", l.name, keys, err)
+	}
+}
+
+// Stats returns the cache counters
+func (l *Loader[V]) Stats() Stats {
+	return Stats{
+		Hits:          l.hits.Load(),
+		NegativeHits:  l.negativeHits.Load(),
+		Misses:        l.misses.Load(),
+		SharedLoads:   l.sharedLoads.Load(),
+		Invalidations: l.invalidations.Load(),
+		Errors:        l.errors.Load(),
+	}
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTestNotFound = errors.New("not found")
+
+type testValue struct {
+	Name string
+}
+
+// hookStore calls beforeSet before every write to the wrapped store
+type hookStore struct {
+	Store
+	beforeSet func()
+}
+
+func (s *hookStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if s.beforeSet != nil {
+		s.beforeSet()
+	}
+	return s.Store.Set(ctx, key, value, ttl)
+}
+
+func TestLoader_Get(t *testing.T) {
+	ctx := context.Background()
+	l := NewLoader[testValue](NewLRU(10), "Test", errTestNotFound)
+
+	loads := 0
+	load := func(ctx context.Context) (*testValue, error) {
+		loads++
+		return &testValue{Name: "a"}, nil
+	}
+	for i := 0; i < 3; i++ {
+		if v, err := l.Get(ctx, "k", load); err != nil || v.Name != "a" {
+			t.Fatalf("Expected value a, got %+v (%v)", v, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("Expected 1 load, got %d", loads)
+	}
+
+	missing := func(ctx context.Context) (*testValue, error) {
+		loads++
+		return nil, errTestNotFound
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := l.Get(ctx, "missing", missing); !errors.Is(err, errTestNotFound) {
+			t.Errorf("Expected errTestNotFound, got %v", err)
+		}
+	}
+	if loads != 2 {
+		t.Errorf("Expected the negative entry to be cached, got %d loads", loads)
+	}
+	if stats := l.Stats(); stats.Hits != 2 || stats.NegativeHits != 1 || stats.Misses != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestLoader_InvalidateDuringSet(t *testing.T) {
+	ctx := context.Background()
+	store := &hookStore{Store: NewLRU(10)}
+	l := NewLoader[testValue](store, "Test", errTestNotFound)
+
+	// The invalidation lands after the epoch check but before the value is
+	// written
+	store.beforeSet = func() {
+		store.beforeSet = nil
+		l.Invalidate(ctx, "k")
+	}
+	l.Get(ctx, "k", func(ctx context.Context) (*testValue, error) {
+		return &testValue{Name: "stale"}, nil
+	})
+
+	v, err := l.Get(ctx, "k", func(ctx context.Context) (*testValue, error) {
+		return &testValue{Name: "fresh"}, nil
+	})
+	if err != nil || v.Name != "fresh" {
+		t.Errorf("Expected the stale value to be dropped, got %+v (%v)", v, err)
+	}
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRU_Eviction(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "b", []byte("2"), 0)
+	// Reading "a" makes "b" the least recently used entry
+	c.Get(ctx, "a")
+	c.Set(ctx, "c", []byte("3"), 0)
+
+	tests := []struct {
+		key   string
+		found bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			_, found, err := c.Get(ctx, tt.key)
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			if found != tt.found {
+				t.Errorf("Expected found=%v, got %v", tt.found, found)
+			}
+		})
+	}
+
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", c.Len())
+	}
+}
+
+func TestLRU_TTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "short", []byte("1"), time.Minute)
+	c.Set(ctx, "forever", []byte("2"), 0)
+
+	now = now.Add(time.Minute)
+
+	if _, found, _ := c.Get(ctx, "short"); found {
+		t.Error("Expected expired entry to be dropped")
+	}
+	if value, found, _ := c.Get(ctx, "forever"); !found || string(value) != "2" {
+		t.Errorf("Expected entry without ttl to be kept, got %q", value)
+	}
+	if c.Len() != 1 {
+		t.Errorf("Expected 1 entry after expiry, got %d", c.Len())
+	}
+}
+
+func TestLRU_SetAndDelete(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "a", []byte("2"), 0)
+	if value, _, _ := c.Get(ctx, "a"); string(value) != "2" {
+		t.Errorf("Expected overwritten value, got %q", value)
+	}
+
+	c.Set(ctx, "b", []byte("3"), 0)
+	if err := c.Delete(ctx, "a", "b", "missing"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if c.Len() != 0 {
+		t.Errorf("Expected empty cache, got %d entries", c.Len())
+	}
+}
@@ -0,0 +1,150 @@
+// Package cli implements the command-line subcommands of the server binary
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"golang-gin/albumio"
+	"golang-gin/audit"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrUsage is returned when the command line is invalid
+var ErrUsage = errors.New("invalid usage")
+
+const usage = `Usage:
+  albums import [-mode atomic|best-effort] [-format csv|ndjson] <file|->
+  albums export [-format csv|ndjson|xlsx] [-o file]
+`
+
+// App runs CLI subcommands
+type App struct {
+	Albums *albumio.Service
+	Actor  string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Run executes the subcommand given by args, e.g. ["albums", "export"]
+func (a *App) Run(ctx context.Context, args []string) error {
+	ctx = audit.WithMetadata(ctx, audit.Metadata{Actor: a.Actor, ActorVerified: true, Source: audit.SourceCLI})
+
+	if len(args) < 2 || args[0] != "albums" {
+		fmt.Fprint(a.Stderr, usage)
+		return ErrUsage
+	}
+
+	switch args[1] {
+	case "import":
+		return a.importAlbums(ctx, args[2:])
+	case "export":
+		return a.exportAlbums(ctx, args[2:])
+	default:
+		fmt.Fprint(a.Stderr, usage)
+		return ErrUsage
+	}
+}
+
+// importAlbums implements "albums import"
+func (a *App) importAlbums(ctx context.Context, args []string) error {
+	fs := a.flagSet("albums import")
+	modeName := fs.String("mode", string(albumio.ModeAtomic), "atomic or best-effort")
+	formatName := fs.String("format", "", "csv or ndjson (default: from file extension)")
+	if err := fs.Parse(args); err != nil {
+		return ErrUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprint(a.Stderr, usage)
+		return ErrUsage
+	}
+	path := fs.Arg(0)
+
+	mode, err := albumio.ParseMode(*modeName)
+	if err != nil {
+		return err
+	}
+	format, err := resolveFormat(*formatName, path, albumio.FormatCSV)
+	if err != nil {
+		return err
+	}
+
+	in := a.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	result, err := a.Albums.Import(ctx, in, format, mode)
+	if err != nil {
+		return err
+	}
+
+	for _, rowErr := range result.Errors {
+		fmt.Fprintf(a.Stderr, "row %d: %s\n", rowErr.Row, rowErr.Error)
+	}
+	fmt.Fprintf(a.Stdout, "%d rows read, %d imported, %d failed (%s)\n",
+		result.Total, result.Imported, result.Failed, result.Mode)
+	if result.Failed > 0 {
+		return fmt.Errorf("%d rows failed", result.Failed)
+	}
+	return nil
+}
+
+// exportAlbums implements "albums export"
+func (a *App) exportAlbums(ctx context.Context, args []string) (err error) {
+	fs := a.flagSet("albums export")
+	formatName := fs.String("format", "", "csv, ndjson or xlsx (default: from -o extension, else csv)")
+	output := fs.String("o", "-", "output file")
+	if err := fs.Parse(args); err != nil {
+		return ErrUsage
+	}
+
+	format, err := resolveFormat(*formatName, *output, albumio.FormatCSV)
+	if err != nil {
+		return err
+	}
+
+	out := a.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		out = f
+	}
+
+	return a.Albums.Export(ctx, out, format)
+}
+
+// flagSet creates a flag set that reports errors to Stderr
+func (a *App) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	return fs
+}
+
+// resolveFormat picks the explicit format, else the one implied by the file
+// extension, else fallback
+func resolveFormat(name, path string, fallback albumio.Format) (albumio.Format, error) {
+	if name != "" {
+		return albumio.ParseFormat(name)
+	}
+	if ext := filepath.Ext(path); ext != "" {
+		return albumio.ParseFormat(ext)
+	}
+	return fallback, nil
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"golang-gin/albumio"
+	"golang-gin/repository"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestApp(stdin string) (*App, *bytes.Buffer, *repository.MockUnitOfWork) {
+	albums := repository.NewMockAlbumRepository()
+	uow := repository.NewMockUnitOfWork(albums)
+	stdout := &bytes.Buffer{}
+	return &App{
+		Albums: albumio.NewService(albums, uow),
+		Actor:  "tester",
+		Stdin:  strings.NewReader(stdin),
+		Stdout: stdout,
+		Stderr: &bytes.Buffer{},
+	}, stdout, uow
+}
+
+func TestApp_Import(t *testing.T) {
+	app, stdout, uow := newTestApp("title,artist,price\nBlue Train,John Coltrane,19.99\n")
+
+	if err := app.Run(context.Background(), []string{"albums", "import", "-"}); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "1 imported") {
+		t.Errorf("Unexpected output %q", stdout.String())
+	}
+
+	albums, _ := uow.Albums.FindAll(context.Background())
+	if len(albums) != 4 {
+		t.Errorf("Expected 4 albums, got %d", len(albums))
+	}
+}
+
+func TestApp_ImportReportsFailedRows(t *testing.T) {
+	app, _, _ := newTestApp("{\"title\":\"\",\"artist\":\"X\",\"price\":1}\n")
+
+	err := app.Run(context.Background(), []string{"albums", "import", "-mode", "best-effort", "-format", "ndjson", "-"})
+	if err == nil {
+		t.Error("Expected error when rows fail")
+	}
+}
+
+func TestApp_Export(t *testing.T) {
+	app, _, _ := newTestApp("")
+	path := filepath.Join(t.TempDir(), "albums.ndjson")
+
+	if err := app.Run(context.Background(), []string{"albums", "export", "-o", path}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines != 3 {
+		t.Errorf("Expected 3 NDJSON lines, got %d", lines)
+	}
+}
+
+func TestApp_Usage(t *testing.T) {
+	app, _, _ := newTestApp("")
+
+	for _, args := range [][]string{nil, {"albums"}, {"albums", "delete"}, {"users", "export"}} {
+		if err := app.Run(context.Background(), args); !errors.Is(err, ErrUsage) {
+			t.Errorf("Run(%v): expected ErrUsage, got %v", args, err)
+		}
+	}
+}
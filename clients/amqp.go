@@ -0,0 +1,78 @@
+package clients
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Dialer opens a connection to the broker at url
+type Dialer func(url string) (Connection, error)
+
+// Connection is the AMQP connection used by RabbitMQClient
+type Connection interface {
+	Channel() (Channel, error)
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	Close() error
+}
+
+// Channel is the subset of AMQP channel operations used by RabbitMQClient
+// and the consumers built on it
+type Channel interface {
+	Confirm(noWait bool) error
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Cancel(consumer string, noWait bool) error
+	// Publish publishes msg on a channel in confirm mode and returns the
+	// pending publisher confirm
+	Publish(ctx context.Context, exchange, key string, mandatory bool, msg amqp.Publishing) (Confirmation, error)
+	NotifyReturn(receiver chan amqp.Return) chan amqp.Return
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	Close() error
+}
+
+// Confirmation is a pending publisher confirm
+type Confirmation interface {
+	// WaitContext blocks until the broker acks or nacks the message
+	WaitContext(ctx context.Context) (bool, error)
+}
+
+// DialAMQP connects to a RabbitMQ broker
+func DialAMQP(url string) (Connection, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	return amqpConnection{conn}, nil
+}
+
+// amqpConnection adapts *amqp.Connection to Connection
+type amqpConnection struct {
+	*amqp.Connection
+}
+
+// Channel opens a new channel
+func (c amqpConnection) Channel() (Channel, error) {
+	ch, err := c.Connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return amqpChannel{ch}, nil
+}
+
+// amqpChannel adapts *amqp.Channel to Channel
+type amqpChannel struct {
+	*amqp.Channel
+}
+
+// Publish publishes msg and returns its deferred confirmation
+func (c amqpChannel) Publish(ctx context.Context, exchange, key string, mandatory bool, msg amqp.Publishing) (Confirmation, error) {
+	confirmation, err := c.PublishWithDeferredConfirmWithContext(ctx, exchange, key, mandatory, false, msg)
+	if err != nil {
+		return nil, err
+	}
+	return confirmation, nil
+}
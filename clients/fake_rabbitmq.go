@@ -0,0 +1,939 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// FakeBroker is an in-process AMQP broker for tests. It implements the
+// default, direct, fanout and topic exchanges, durable queues, bindings,
+// publisher confirms, mandatory returns, prefetch, acks, nacks, message TTLs
+// and dead-lettering. Time only moves when Advance is called, so TTL based
+// retries are deterministic.
+//
+//	broker := clients.NewFakeBroker()
+//	client, err := clients.NewRabbitMQClient("amqp://fake", clients.WithDialer(broker.Dial))
+type FakeBroker struct {
+	mu        sync.Mutex
+	cond      *sync.Cond // signalled whenever a consumer may have work
+	now       time.Time
+	exchanges map[string]*fakeExchange
+	queues    map[string]*fakeQueue
+	conns     map[*fakeConnection]struct{}
+	down      bool
+	nack      bool
+	nextName  int
+}
+
+// fakeExchange is a declared exchange and its bindings
+type fakeExchange struct {
+	name     string
+	kind     string
+	bindings []fakeBinding
+}
+
+// fakeBinding binds a queue to an exchange
+type fakeBinding struct {
+	queue string
+	key   string
+}
+
+// fakeQueue is a declared queue holding ready messages
+type fakeQueue struct {
+	name      string
+	args      amqp.Table
+	messages  []fakeMessage
+	consumers int
+}
+
+// fakeMessage is a message stored in a queue
+type fakeMessage struct {
+	exchange    string
+	routingKey  string
+	publishing  amqp.Publishing
+	redelivered bool
+	expiresAt   time.Time // zero if the message does not expire
+}
+
+// NewFakeBroker creates an empty broker with the default exchange declared
+func NewFakeBroker() *FakeBroker {
+	b := &FakeBroker{
+		now:       time.Unix(0, 0).UTC(),
+		exchanges: map[string]*fakeExchange{"": {kind: "direct"}},
+		queues:    map[string]*fakeQueue{},
+		conns:     map[*fakeConnection]struct{}{},
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// Dial opens a connection to the broker. It implements Dialer.
+func (b *FakeBroker) Dial(url string) (Connection, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.down {
+		return nil, fmt.Errorf("dial %s: connection refused", url)
+	}
+	conn := &fakeConnection{broker: b}
+	b.conns[conn] = struct{}{}
+	return conn, nil
+}
+
+// Disconnect force-closes every open connection, as a broker restart or
+// network failure would. Queued messages are kept.
+func (b *FakeBroker) Disconnect() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for conn := range b.conns {
+		conn.closeLocked(&amqp.Error{Code: amqp.ConnectionForced, Reason: "CONNECTION_FORCED - broker forced connection closure", Server: true})
+	}
+}
+
+// Reset closes every connection and deletes all exchanges, queues and
+// messages, like a broker that restarted without its definitions
+func (b *FakeBroker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for conn := range b.conns {
+		conn.closeLocked(&amqp.Error{Code: amqp.ConnectionForced, Reason: "CONNECTION_FORCED - broker forced connection closure", Server: true})
+	}
+	b.exchanges = map[string]*fakeExchange{"": {kind: "direct"}}
+	b.queues = map[string]*fakeQueue{}
+}
+
+// SetDown makes new dials fail while down is set
+func (b *FakeBroker) SetDown(down bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.down = down
+}
+
+// SetNackPublishes makes the broker drop and nack every published message
+// while nack is set
+func (b *FakeBroker) SetNackPublishes(nack bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nack = nack
+}
+
+// Advance moves the broker clock forward and expires messages whose TTL has
+// passed, dead-lettering them where configured
+func (b *FakeBroker) Advance(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.now = b.now.Add(d)
+	for _, q := range b.queues {
+		b.expireLocked(q)
+	}
+	b.cond.Broadcast()
+}
+
+// QueueLen returns the number of ready messages in a queue
+func (b *FakeBroker) QueueLen(queue string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if q, ok := b.queues[queue]; ok {
+		return len(q.messages)
+	}
+	return 0
+}
+
+// Messages returns copies of the ready messages in a queue without
+// consuming them
+func (b *FakeBroker) Messages(queue string) []amqp.Delivery {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[queue]
+	if !ok {
+		return nil
+	}
+	deliveries := make([]amqp.Delivery, len(q.messages))
+	for i, m := range q.messages {
+		deliveries[i] = m.delivery(nil, "", 0)
+	}
+	return deliveries
+}
+
+// delivery converts the message to an AMQP delivery
+func (m fakeMessage) delivery(ack amqp.Acknowledger, consumerTag string, tag uint64) amqp.Delivery {
+	p := m.publishing
+	return amqp.Delivery{
+		Acknowledger:    ack,
+		Headers:         p.Headers,
+		ContentType:     p.ContentType,
+		ContentEncoding: p.ContentEncoding,
+		DeliveryMode:    p.DeliveryMode,
+		Priority:        p.Priority,
+		CorrelationId:   p.CorrelationId,
+		ReplyTo:         p.ReplyTo,
+		Expiration:      p.Expiration,
+		MessageId:       p.MessageId,
+		Timestamp:       p.Timestamp,
+		Type:            p.Type,
+		UserId:          p.UserId,
+		AppId:           p.AppId,
+		ConsumerTag:     consumerTag,
+		DeliveryTag:     tag,
+		Redelivered:     m.redelivered,
+		Exchange:        m.exchange,
+		RoutingKey:      m.routingKey,
+		Body:            p.Body,
+	}
+}
+
+// routeLocked delivers a publishing to every queue bound to the exchange
+// for key and reports whether any queue matched
+func (b *FakeBroker) routeLocked(exchange, key string, p amqp.Publishing) (bool, *amqp.Error) {
+	ex, ok := b.exchanges[exchange]
+	if !ok {
+		return false, &amqp.Error{Code: amqp.NotFound, Reason: fmt.Sprintf("NOT_FOUND - no exchange '%s'", exchange), Server: true}
+	}
+
+	var targets []string
+	if exchange == "" {
+		if _, ok := b.queues[key]; ok {
+			targets = append(targets, key)
+		}
+	} else {
+		seen := map[string]bool{}
+		for _, binding := range ex.bindings {
+			if seen[binding.queue] || !ex.matches(binding.key, key) {
+				continue
+			}
+			seen[binding.queue] = true
+			targets = append(targets, binding.queue)
+		}
+	}
+
+	for _, name := range targets {
+		q := b.queues[name]
+		m := fakeMessage{exchange: exchange, routingKey: key, publishing: p}
+		if ttl, ok := q.ttl(p); ok {
+			m.expiresAt = b.now.Add(ttl)
+		}
+		q.messages = append(q.messages, m)
+		b.expireLocked(q)
+	}
+	if len(targets) > 0 {
+		b.cond.Broadcast()
+	}
+	return len(targets) > 0, nil
+}
+
+// matches reports whether a binding key matches a routing key
+func (ex *fakeExchange) matches(bindingKey, routingKey string) bool {
+	switch ex.kind {
+	case "fanout":
+		return true
+	case "topic":
+		return topicMatch(strings.Split(bindingKey, "."), strings.Split(routingKey, "."))
+	default:
+		return bindingKey == routingKey
+	}
+}
+
+// topicMatch matches routing key words against a topic pattern, where *
+// matches one word and # matches zero or more
+func topicMatch(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if topicMatch(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && topicMatch(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && pattern[0] == words[0] && topicMatch(pattern[1:], words[1:])
+	}
+}
+
+// ttl returns the lifetime of a message in the queue, the lower of the
+// queue's x-message-ttl and the message expiration
+func (q *fakeQueue) ttl(p amqp.Publishing) (time.Duration, bool) {
+	ttl, ok := time.Duration(0), false
+	if ms, found := intArg(q.args["x-message-ttl"]); found {
+		ttl, ok = time.Duration(ms)*time.Millisecond, true
+	}
+	if ms, err := strconv.ParseInt(p.Expiration, 10, 64); err == nil {
+		if d := time.Duration(ms) * time.Millisecond; !ok || d < ttl {
+			ttl, ok = d, true
+		}
+	}
+	return ttl, ok
+}
+
+// intArg converts a numeric queue argument
+func intArg(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	}
+	return 0, false
+}
+
+// expireLocked dead-letters the expired messages at the head of a queue.
+// Like RabbitMQ, only the head is checked, so a message with a long TTL
+// holds back the ones behind it.
+func (b *FakeBroker) expireLocked(q *fakeQueue) {
+	for len(q.messages) > 0 {
+		m := q.messages[0]
+		if m.expiresAt.IsZero() || m.expiresAt.After(b.now) {
+			return
+		}
+		q.messages = q.messages[1:]
+		b.deadLetterLocked(q, m, "expired")
+	}
+}
+
+// deadLetterLocked republishes a message to the queue's dead letter
+// exchange, recording the death in the x-death header. Messages of queues
+// without a dead letter exchange are dropped.
+func (b *FakeBroker) deadLetterLocked(q *fakeQueue, m fakeMessage, reason string) {
+	dlx, ok := q.args["x-dead-letter-exchange"].(string)
+	if !ok {
+		return
+	}
+	key := m.routingKey
+	if k, ok := q.args["x-dead-letter-routing-key"].(string); ok {
+		key = k
+	}
+
+	p := m.publishing
+	p.Expiration = ""
+	p.Headers = amqp.Table{}
+	for k, v := range m.publishing.Headers {
+		p.Headers[k] = v
+	}
+	p.Headers["x-death"] = recordDeath(m.publishing.Headers["x-death"], q.name, reason, m.exchange, m.routingKey, b.now)
+
+	b.routeLocked(dlx, key, p)
+}
+
+// recordDeath adds a death to an x-death header, counting repeated deaths
+// in the same queue for the same reason
+func recordDeath(header interface{}, queue, reason, exchange, routingKey string, at time.Time) []interface{} {
+	deaths, _ := header.([]interface{})
+	for i, d := range deaths {
+		death, ok := d.(amqp.Table)
+		if !ok || death["queue"] != queue || death["reason"] != reason {
+			continue
+		}
+		updated := amqp.Table{}
+		for k, v := range death {
+			updated[k] = v
+		}
+		count, _ := intArg(death["count"])
+		updated["count"] = count + 1
+		updated["time"] = at
+
+		// The most recent death comes first
+		result := []interface{}{updated}
+		result = append(result, deaths[:i]...)
+		return append(result, deaths[i+1:]...)
+	}
+
+	death := amqp.Table{
+		"queue":        queue,
+		"reason":       reason,
+		"exchange":     exchange,
+		"routing-keys": []interface{}{routingKey},
+		"count":        int64(1),
+		"time":         at,
+	}
+	return append([]interface{}{death}, deaths...)
+}
+
+// fakeConnection is a connection to a FakeBroker
+type fakeConnection struct {
+	broker    *FakeBroker
+	channels  []*fakeChannel
+	listeners []chan *amqp.Error
+	closed    bool
+}
+
+// Channel opens a channel on the connection
+func (c *fakeConnection) Channel() (Channel, error) {
+	b := c.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c.closed {
+		return nil, amqp.ErrClosed
+	}
+	ch := &fakeChannel{conn: c, consumers: map[string]*fakeConsumer{}, unacked: map[uint64]*fakeUnacked{}}
+	c.channels = append(c.channels, ch)
+	return ch, nil
+}
+
+// NotifyClose registers a listener for the connection closing
+func (c *fakeConnection) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+
+	if c.closed {
+		close(receiver)
+		return receiver
+	}
+	c.listeners = append(c.listeners, receiver)
+	return receiver
+}
+
+// Close closes the connection and its channels
+func (c *fakeConnection) Close() error {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+
+	if c.closed {
+		return amqp.ErrClosed
+	}
+	c.closeLocked(nil)
+	return nil
+}
+
+// closeLocked closes the connection, notifying listeners with reason
+func (c *fakeConnection) closeLocked(reason *amqp.Error) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	delete(c.broker.conns, c)
+
+	for _, ch := range c.channels {
+		ch.closeLocked(reason)
+	}
+	notifyClosed(c.listeners, reason)
+}
+
+// notifyClosed sends reason to close listeners and closes them
+func notifyClosed(listeners []chan *amqp.Error, reason *amqp.Error) {
+	for _, l := range listeners {
+		if reason != nil {
+			select {
+			case l <- reason:
+			default:
+			}
+		}
+		close(l)
+	}
+}
+
+// fakeChannel is a channel on a fakeConnection
+type fakeChannel struct {
+	conn            *fakeConnection
+	confirm         bool
+	prefetch        int
+	globalPrefetch  int
+	nextTag         uint64
+	consumers       map[string]*fakeConsumer
+	unacked         map[uint64]*fakeUnacked
+	returnListeners []chan amqp.Return
+	closeListeners  []chan *amqp.Error
+	closed          bool
+}
+
+// fakeUnacked is a delivered message waiting for an ack
+type fakeUnacked struct {
+	queue    *fakeQueue
+	message  fakeMessage
+	consumer *fakeConsumer
+}
+
+// fakeConsumer is an active basic.consume subscription
+type fakeConsumer struct {
+	tag        string
+	queue      *fakeQueue
+	channel    *fakeChannel
+	autoAck    bool
+	prefetch   int
+	unacked    int
+	deliveries chan amqp.Delivery
+	done       chan struct{}
+	cancelled  bool
+}
+
+// broker returns the broker the channel belongs to
+func (ch *fakeChannel) broker() *FakeBroker {
+	return ch.conn.broker
+}
+
+// failLocked closes the channel with a channel-level error, as the broker
+// does on protocol errors
+func (ch *fakeChannel) failLocked(code int, format string, args ...interface{}) error {
+	err := &amqp.Error{Code: code, Reason: fmt.Sprintf(format, args...), Server: true}
+	ch.closeLocked(err)
+	return err
+}
+
+// Confirm puts the channel into confirm mode
+func (ch *fakeChannel) Confirm(noWait bool) error {
+	b := ch.broker()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch.closed {
+		return amqp.ErrClosed
+	}
+	ch.confirm = true
+	return nil
+}
+
+// ExchangeDeclare declares an exchange. Redeclaring it with another kind
+// fails with PRECONDITION_FAILED.
+func (ch *fakeChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	b := ch.broker()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch.closed {
+		return amqp.ErrClosed
+	}
+	switch kind {
+	case "direct", "fanout", "topic":
+	default:
+		return ch.failLocked(amqp.NotImplemented, "NOT_IMPLEMENTED - exchange type '%s' is not supported by the fake broker", kind)
+	}
+	if ex, ok := b.exchanges[name]; ok {
+		if ex.kind != kind {
+			return ch.failLocked(amqp.PreconditionFailed, "PRECONDITION_FAILED - inequivalent arg 'type' for exchange '%s'", name)
+		}
+		return nil
+	}
+	b.exchanges[name] = &fakeExchange{name: name, kind: kind}
+	return nil
+}
+
+// QueueDeclare declares a queue. Redeclaring it with other arguments fails
+// with PRECONDITION_FAILED.
+func (ch *fakeChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	b := ch.broker()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch.closed {
+		return amqp.Queue{}, amqp.ErrClosed
+	}
+	if name == "" {
+		b.nextName++
+		name = fmt.Sprintf("amq.gen-%d", b.nextName)
+	}
+	if args == nil {
+		args = amqp.Table{}
+	}
+
+	q, ok := b.queues[name]
+	if !ok {
+		q = &fakeQueue{name: name, args: args}
+		b.queues[name] = q
+	} else if !reflect.DeepEqual(q.args, args) {
+		return amqp.Queue{}, ch.failLocked(amqp.PreconditionFailed, "PRECONDITION_FAILED - inequivalent args for queue '%s'", name)
+	}
+	return amqp.Queue{Name: name, Messages: len(q.messages), Consumers: q.consumers}, nil
+}
+
+// QueueBind binds a queue to an exchange
+func (ch *fakeChannel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	b := ch.broker()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch.closed {
+		return amqp.ErrClosed
+	}
+	if _, ok := b.queues[name]; !ok {
+		return ch.failLocked(amqp.NotFound, "NOT_FOUND - no queue '%s'", name)
+	}
+	ex, ok := b.exchanges[exchange]
+	if !ok || exchange == "" {
+		return ch.failLocked(amqp.NotFound, "NOT_FOUND - no exchange '%s'", exchange)
+	}
+	for _, binding := range ex.bindings {
+		if binding.queue == name && binding.key == key {
+			return nil
+		}
+	}
+	ex.bindings = append(ex.bindings, fakeBinding{queue: name, key: key})
+	return nil
+}
+
+// Qos limits the number of unacked deliveries per consumer, or per channel
+// when global is set. It applies to consumers started afterwards.
+func (ch *fakeChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	b := ch.broker()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch.closed {
+		return amqp.ErrClosed
+	}
+	if global {
+		ch.globalPrefetch = prefetchCount
+	} else {
+		ch.prefetch = prefetchCount
+	}
+	b.cond.Broadcast()
+	return nil
+}
+
+// Consume starts delivering messages from a queue
+func (ch *fakeChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	b := ch.broker()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch.closed {
+		return nil, amqp.ErrClosed
+	}
+	q, ok := b.queues[queue]
+	if !ok {
+		return nil, ch.failLocked(amqp.NotFound, "NOT_FOUND - no queue '%s'", queue)
+	}
+	if consumer == "" {
+		b.nextName++
+		consumer = fmt.Sprintf("amq.ctag-%d", b.nextName)
+	}
+	if _, ok := ch.consumers[consumer]; ok {
+		return nil, ch.failLocked(amqp.NotAllowed, "NOT_ALLOWED - attempt to reuse consumer tag '%s'", consumer)
+	}
+
+	c := &fakeConsumer{
+		tag:        consumer,
+		queue:      q,
+		channel:    ch,
+		autoAck:    autoAck,
+		prefetch:   ch.prefetch,
+		deliveries: make(chan amqp.Delivery),
+		done:       make(chan struct{}),
+	}
+	ch.consumers[consumer] = c
+	q.consumers++
+	go b.pump(c)
+	return c.deliveries, nil
+}
+
+// pump delivers messages to a consumer until it is cancelled
+func (b *FakeBroker) pump(c *fakeConsumer) {
+	defer close(c.deliveries)
+
+	for {
+		b.mu.Lock()
+		var d amqp.Delivery
+		for {
+			if c.cancelled {
+				b.mu.Unlock()
+				return
+			}
+			var ok bool
+			if d, ok = b.nextLocked(c); ok {
+				break
+			}
+			b.cond.Wait()
+		}
+		b.mu.Unlock()
+
+		select {
+		case c.deliveries <- d:
+			if c.autoAck {
+				b.mu.Lock()
+				c.channel.settleLocked(d.DeliveryTag, false)
+				b.mu.Unlock()
+			}
+		case <-c.done:
+			// The consumer went away before taking the message
+			b.mu.Lock()
+			c.channel.settleLocked(d.DeliveryTag, true)
+			b.mu.Unlock()
+			return
+		}
+	}
+}
+
+// nextLocked takes the next message for a consumer if its prefetch allows
+func (b *FakeBroker) nextLocked(c *fakeConsumer) (amqp.Delivery, bool) {
+	ch, q := c.channel, c.queue
+	if c.prefetch > 0 && c.unacked >= c.prefetch {
+		return amqp.Delivery{}, false
+	}
+	if ch.globalPrefetch > 0 && len(ch.unacked) >= ch.globalPrefetch {
+		return amqp.Delivery{}, false
+	}
+
+	b.expireLocked(q)
+	if len(q.messages) == 0 {
+		return amqp.Delivery{}, false
+	}
+	m := q.messages[0]
+	q.messages = q.messages[1:]
+
+	ch.nextTag++
+	u := &fakeUnacked{queue: q, message: m}
+	// Auto-acked messages count against no prefetch limit and stay tracked
+	// only until they are handed over
+	if !c.autoAck {
+		u.consumer = c
+		c.unacked++
+	}
+	ch.unacked[ch.nextTag] = u
+	return m.delivery(ch, c.tag, ch.nextTag), true
+}
+
+// settleLocked removes an unacked message, requeueing it at the head of its
+// queue if requeue is set
+func (ch *fakeChannel) settleLocked(tag uint64, requeue bool) {
+	u, ok := ch.unacked[tag]
+	if !ok {
+		return
+	}
+	delete(ch.unacked, tag)
+	if u.consumer != nil {
+		u.consumer.unacked--
+	}
+	if requeue {
+		m := u.message
+		m.redelivered = true
+		u.queue.messages = append([]fakeMessage{m}, u.queue.messages...)
+	}
+	ch.broker().cond.Broadcast()
+}
+
+// tagsLocked returns the unacked delivery tags settled by an ack or nack
+func (ch *fakeChannel) tagsLocked(tag uint64, multiple bool) ([]uint64, error) {
+	if !multiple {
+		if _, ok := ch.unacked[tag]; !ok {
+			return nil, ch.failLocked(amqp.PreconditionFailed, "PRECONDITION_FAILED - unknown delivery tag %d", tag)
+		}
+		return []uint64{tag}, nil
+	}
+
+	var tags []uint64
+	for t := range ch.unacked {
+		if t <= tag || tag == 0 {
+			tags = append(tags, t)
+		}
+	}
+	return tags, nil
+}
+
+// Ack acknowledges deliveries. It implements amqp.Acknowledger.
+func (ch *fakeChannel) Ack(tag uint64, multiple bool) error {
+	b := ch.broker()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch.closed {
+		return amqp.ErrClosed
+	}
+	tags, err := ch.tagsLocked(tag, multiple)
+	if err != nil {
+		return err
+	}
+	for _, t := range tags {
+		ch.settleLocked(t, false)
+	}
+	return nil
+}
+
+// Nack rejects deliveries, requeueing or dead-lettering them. It implements
+// amqp.Acknowledger.
+func (ch *fakeChannel) Nack(tag uint64, multiple, requeue bool) error {
+	b := ch.broker()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch.closed {
+		return amqp.ErrClosed
+	}
+	tags, err := ch.tagsLocked(tag, multiple)
+	if err != nil {
+		return err
+	}
+	for _, t := range tags {
+		u := ch.unacked[t]
+		ch.settleLocked(t, requeue)
+		if !requeue {
+			b.deadLetterLocked(u.queue, u.message, "rejected")
+		}
+	}
+	return nil
+}
+
+// Reject rejects a single delivery. It implements amqp.Acknowledger.
+func (ch *fakeChannel) Reject(tag uint64, requeue bool) error {
+	return ch.Nack(tag, false, requeue)
+}
+
+// Cancel stops a consumer. Its delivery channel is closed; messages it has
+// not acked yet stay unacked until they are settled or the channel closes.
+func (ch *fakeChannel) Cancel(consumer string, noWait bool) error {
+	b := ch.broker()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch.closed {
+		return amqp.ErrClosed
+	}
+	c, ok := ch.consumers[consumer]
+	if !ok {
+		return nil
+	}
+	ch.cancelLocked(c)
+	return nil
+}
+
+// cancelLocked stops a consumer's pump
+func (ch *fakeChannel) cancelLocked(c *fakeConsumer) {
+	if c.cancelled {
+		return
+	}
+	c.cancelled = true
+	close(c.done)
+	c.queue.consumers--
+	delete(ch.consumers, c.tag)
+	ch.broker().cond.Broadcast()
+}
+
+// Publish routes a message and returns its confirmation. Mandatory messages
+// that match no queue are returned before the confirmation, as RabbitMQ
+// does.
+func (ch *fakeChannel) Publish(ctx context.Context, exchange, key string, mandatory bool, msg amqp.Publishing) (Confirmation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b := ch.broker()
+	b.mu.Lock()
+	if ch.closed {
+		b.mu.Unlock()
+		return nil, amqp.ErrClosed
+	}
+
+	if b.nack {
+		b.mu.Unlock()
+		return fakeConfirmation(false), nil
+	}
+
+	routed, amqpErr := b.routeLocked(exchange, key, msg)
+	if amqpErr != nil {
+		ch.closeLocked(amqpErr)
+		b.mu.Unlock()
+		return nil, amqpErr
+	}
+
+	// Returns are sent before the confirmation. The fake drops returns when
+	// a listener's buffer is full instead of blocking the broker.
+	if mandatory && !routed {
+		ret := amqp.Return{
+			ReplyCode:     amqp.NoRoute,
+			ReplyText:     "NO_ROUTE",
+			Exchange:      exchange,
+			RoutingKey:    key,
+			ContentType:   msg.ContentType,
+			Headers:       msg.Headers,
+			DeliveryMode:  msg.DeliveryMode,
+			CorrelationId: msg.CorrelationId,
+			MessageId:     msg.MessageId,
+			Timestamp:     msg.Timestamp,
+			Type:          msg.Type,
+			Body:          msg.Body,
+		}
+		for _, l := range ch.returnListeners {
+			select {
+			case l <- ret:
+			default:
+			}
+		}
+	}
+	b.mu.Unlock()
+	return fakeConfirmation(true), nil
+}
+
+// NotifyReturn registers a listener for returned mandatory messages
+func (ch *fakeChannel) NotifyReturn(receiver chan amqp.Return) chan amqp.Return {
+	b := ch.broker()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch.closed {
+		close(receiver)
+		return receiver
+	}
+	ch.returnListeners = append(ch.returnListeners, receiver)
+	return receiver
+}
+
+// NotifyClose registers a listener for the channel closing
+func (ch *fakeChannel) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	b := ch.broker()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch.closed {
+		close(receiver)
+		return receiver
+	}
+	ch.closeListeners = append(ch.closeListeners, receiver)
+	return receiver
+}
+
+// Close closes the channel, requeueing its unacked messages
+func (ch *fakeChannel) Close() error {
+	b := ch.broker()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ch.closed {
+		return amqp.ErrClosed
+	}
+	ch.closeLocked(nil)
+	return nil
+}
+
+// closeLocked cancels the channel's consumers, requeues its unacked messages
+// and notifies listeners with reason
+func (ch *fakeChannel) closeLocked(reason *amqp.Error) {
+	if ch.closed {
+		return
+	}
+	for _, c := range ch.consumers {
+		ch.cancelLocked(c)
+	}
+	for tag := range ch.unacked {
+		ch.settleLocked(tag, true)
+	}
+	ch.closed = true
+
+	for _, l := range ch.returnListeners {
+		close(l)
+	}
+	notifyClosed(ch.closeListeners, reason)
+}
+
+// fakeConfirmation is a publisher confirm that has already arrived
+type fakeConfirmation bool
+
+// WaitContext returns whether the message was acked
+func (c fakeConfirmation) WaitContext(ctx context.Context) (bool, error) {
+	return bool(c), nil
+}
@@ -0,0 +1,159 @@
+package clients
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestTopicMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		key      string
+		expected bool
+	}{
+		{"album.created", "album.created", true},
+		{"album.*", "album.created", true},
+		{"album.*", "album.created.v2", false},
+		{"album.#", "album", true},
+		{"album.#", "album.created.v2", true},
+		{"#", "anything.at.all", true},
+		{"*.created", "artist.created", true},
+		{"#.created", "album.track.created", true},
+		{"album.created", "album.updated", false},
+	}
+
+	for _, tt := range tests {
+		got := topicMatch(strings.Split(tt.pattern, "."), strings.Split(tt.key, "."))
+		if got != tt.expected {
+			t.Errorf("Expected topicMatch(%q, %q) to be %v, got %v", tt.pattern, tt.key, tt.expected, got)
+		}
+	}
+}
+
+// openFakeChannel opens a channel on broker and declares queues
+func openFakeChannel(t *testing.T, broker *FakeBroker, queues ...QueueSpec) Channel {
+	t.Helper()
+	conn, err := broker.Dial("amqp://fake")
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	ch, err := conn.Channel()
+	if err != nil {
+		t.Fatalf("Channel failed: %v", err)
+	}
+	if err := (Topology{Queues: queues}).declare(ch); err != nil {
+		t.Fatalf("Declare failed: %v", err)
+	}
+	return ch
+}
+
+// receive waits for the next delivery
+func receive(t *testing.T, deliveries <-chan amqp.Delivery) amqp.Delivery {
+	t.Helper()
+	select {
+	case d, ok := <-deliveries:
+		if !ok {
+			t.Fatal("Delivery channel closed")
+		}
+		return d
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a delivery")
+	}
+	return amqp.Delivery{}
+}
+
+func TestFakeBroker_DeadLettering(t *testing.T) {
+	broker := NewFakeBroker()
+	ch := openFakeChannel(t, broker,
+		QueueSpec{Name: "work", Args: map[string]interface{}{
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": "work.dead",
+		}},
+		QueueSpec{Name: "work.dead"},
+		QueueSpec{Name: "work.delay", Args: map[string]interface{}{
+			"x-message-ttl":             int64(1000),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": "work",
+		}},
+	)
+	ctx := context.Background()
+
+	// Delayed messages move to the work queue once their TTL has passed
+	ch.Publish(ctx, "", "work.delay", false, amqp.Publishing{MessageId: "m1"})
+	broker.Advance(999 * time.Millisecond)
+	if broker.QueueLen("work") != 0 {
+		t.Fatalf("Expected message to be delayed, got %d in work", broker.QueueLen("work"))
+	}
+	broker.Advance(time.Millisecond)
+	if broker.QueueLen("work") != 1 {
+		t.Fatalf("Expected expired message in work, got %d", broker.QueueLen("work"))
+	}
+
+	// Rejected messages go to the dead letter queue
+	deliveries, err := ch.Consume("work", "", false, false, false, false, nil)
+	if err != nil {
+		t.Fatalf("Consume failed: %v", err)
+	}
+	d := receive(t, deliveries)
+	if err := d.Nack(false, false); err != nil {
+		t.Fatalf("Nack failed: %v", err)
+	}
+
+	dead := broker.Messages("work.dead")
+	if len(dead) != 1 || dead[0].MessageId != "m1" {
+		t.Fatalf("Expected m1 to be dead-lettered, got %+v", dead)
+	}
+	deaths, _ := dead[0].Headers["x-death"].([]interface{})
+	if len(deaths) != 2 {
+		t.Fatalf("Expected 2 x-death entries, got %v", dead[0].Headers["x-death"])
+	}
+	if reason := deaths[0].(amqp.Table)["reason"]; reason != "rejected" {
+		t.Errorf("Expected latest death reason rejected, got %v", reason)
+	}
+}
+
+func TestFakeBroker_PrefetchAndRequeue(t *testing.T) {
+	broker := NewFakeBroker()
+	ch := openFakeChannel(t, broker, QueueSpec{Name: "work"})
+	ctx := context.Background()
+
+	for _, id := range []string{"m1", "m2", "m3"} {
+		ch.Publish(ctx, "", "work", false, amqp.Publishing{MessageId: id})
+	}
+
+	if err := ch.Qos(1, 0, false); err != nil {
+		t.Fatalf("Qos failed: %v", err)
+	}
+	deliveries, err := ch.Consume("work", "c1", false, false, false, false, nil)
+	if err != nil {
+		t.Fatalf("Consume failed: %v", err)
+	}
+
+	first := receive(t, deliveries)
+	select {
+	case d := <-deliveries:
+		t.Fatalf("Expected prefetch to hold back %s", d.MessageId)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if err := first.Ack(false); err != nil {
+		t.Fatalf("Ack failed: %v", err)
+	}
+	second := receive(t, deliveries)
+	if second.MessageId != "m2" {
+		t.Fatalf("Expected m2, got %s", second.MessageId)
+	}
+
+	// Closing the channel requeues the unacked message
+	ch.Close()
+	messages := broker.Messages("work")
+	if len(messages) != 2 || messages[0].MessageId != "m2" || !messages[0].Redelivered {
+		t.Errorf("Expected m2 to be requeued as redelivered, got %+v", messages)
+	}
+}
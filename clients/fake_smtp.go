@@ -0,0 +1,473 @@
+package clients
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeMail is a message received by FakeSMTPServer
+type FakeMail struct {
+	From string
+	To   []string
+	Data []byte // line endings are converted to LF
+	// TLS reports whether the session was encrypted
+	TLS bool
+	// Username is the authenticated user, if any
+	Username string
+}
+
+// FakeSMTPOption configures a FakeSMTPServer
+type FakeSMTPOption func(*FakeSMTPServer)
+
+// WithFakeSMTPStartTLS makes the server offer STARTTLS
+func WithFakeSMTPStartTLS() FakeSMTPOption {
+	return func(s *FakeSMTPServer) {
+		s.startTLS = true
+	}
+}
+
+// WithFakeSMTPImplicitTLS makes the server accept TLS connections only
+func WithFakeSMTPImplicitTLS() FakeSMTPOption {
+	return func(s *FakeSMTPServer) {
+		s.implicitTLS = true
+	}
+}
+
+// WithFakeSMTPAuth requires authentication with the given credentials and
+// offers mechanisms (default: PLAIN, LOGIN and CRAM-MD5)
+func WithFakeSMTPAuth(username, password string, mechanisms ...string) FakeSMTPOption {
+	return func(s *FakeSMTPServer) {
+		s.username = username
+		s.password = password
+		s.mechanisms = mechanisms
+		if len(mechanisms) == 0 {
+			s.mechanisms = []string{MailAuthPlain, MailAuthLogin, MailAuthCRAMMD5}
+		}
+	}
+}
+
+// FakeSMTPServer is an in-process SMTP server for tests. It listens on a
+// random local port, supports STARTTLS or implicit TLS with a self-signed
+// certificate, PLAIN, LOGIN and CRAM-MD5 auth, and records every message it
+// accepts. Recipients can be rejected and replies delayed to test error
+// handling and timeouts.
+//
+//	server, _ := clients.NewFakeSMTPServer(clients.WithFakeSMTPStartTLS())
+//	defer server.Close()
+//	client := clients.NewMailClient(server.Host(), server.Port(), "", "", "noreply@example.com",
+//		clients.WithMailTLSConfig(&tls.Config{RootCAs: server.CertPool()}))
+type FakeSMTPServer struct {
+	listener    net.Listener
+	tlsConfig   *tls.Config
+	certPool    *x509.CertPool
+	startTLS    bool
+	implicitTLS bool
+	username    string
+	password    string
+	mechanisms  []string
+
+	mu          sync.Mutex
+	messages    []FakeMail
+	connections int
+	rejects     map[string]string
+	delay       time.Duration
+	conns       map[net.Conn]struct{}
+	closed      bool
+
+	wg sync.WaitGroup
+}
+
+// NewFakeSMTPServer starts a server on 127.0.0.1
+func NewFakeSMTPServer(opts ...FakeSMTPOption) (*FakeSMTPServer, error) {
+	s := &FakeSMTPServer{
+		rejects: map[string]string{},
+		conns:   map[net.Conn]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	cert, pool, err := newFakeCertificate()
+	if err != nil {
+		return nil, err
+	}
+	s.tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	s.certPool = pool
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	if s.implicitTLS {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+	s.listener = listener
+
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// newFakeCertificate creates a self-signed certificate for 127.0.0.1 and
+// localhost
+func newFakeCertificate() (tls.Certificate, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake smtp server"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool, nil
+}
+
+// Host returns the host the server listens on
+func (s *FakeSMTPServer) Host() string {
+	host, _, _ := net.SplitHostPort(s.listener.Addr().String())
+	return host
+}
+
+// Port returns the port the server listens on
+func (s *FakeSMTPServer) Port() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+// CertPool returns a pool trusting the server's certificate
+func (s *FakeSMTPServer) CertPool() *x509.CertPool {
+	return s.certPool
+}
+
+// Messages returns the messages received so far
+func (s *FakeSMTPServer) Messages() []FakeMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]FakeMail(nil), s.messages...)
+}
+
+// Connections returns the number of connections accepted so far
+func (s *FakeSMTPServer) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+// Reject makes RCPT TO for address fail with reply, e.g.
+// "550 5.1.1 No such user" or "451 4.3.0 Try again later". An empty reply
+// accepts the address again.
+func (s *FakeSMTPServer) Reject(address, reply string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	address = strings.ToLower(address)
+	if reply == "" {
+		delete(s.rejects, address)
+		return
+	}
+	s.rejects[address] = reply
+}
+
+// SetDelay delays every reply by d
+func (s *FakeSMTPServer) SetDelay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = d
+}
+
+// DropConnections closes all open connections, as a server restart would
+func (s *FakeSMTPServer) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// Close stops the server and closes all connections
+func (s *FakeSMTPServer) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+// serve accepts connections until the listener is closed
+func (s *FakeSMTPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.connections++
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				conn.Close()
+			}()
+			s.handle(conn)
+		}()
+	}
+}
+
+// fakeSMTPSession is the state of one SMTP connection
+type fakeSMTPSession struct {
+	server   *FakeSMTPServer
+	conn     net.Conn
+	text     *textproto.Conn
+	tls      bool
+	username string
+	mail     *FakeMail
+}
+
+// reply writes a response line after the configured delay
+func (ss *fakeSMTPSession) reply(format string, args ...interface{}) error {
+	ss.server.mu.Lock()
+	delay := ss.server.delay
+	ss.server.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return ss.text.PrintfLine(format, args...)
+}
+
+// handle runs an SMTP session
+func (s *FakeSMTPServer) handle(conn net.Conn) {
+	ss := &fakeSMTPSession{server: s, conn: conn, text: textproto.NewConn(conn), tls: s.implicitTLS}
+	if err := ss.reply("220 fake ESMTP ready"); err != nil {
+		return
+	}
+
+	for {
+		line, err := ss.text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		if !ss.command(strings.ToUpper(verb), arg) {
+			return
+		}
+	}
+}
+
+// command handles one command and reports whether the session continues
+func (ss *fakeSMTPSession) command(verb, arg string) bool {
+	s := ss.server
+	switch verb {
+	case "HELO":
+		return ss.reply("250 fake") == nil
+	case "EHLO":
+		lines := []string{"fake", "8BITMIME", "SIZE 10485760"}
+		if s.startTLS && !ss.tls {
+			lines = append(lines, "STARTTLS")
+		}
+		if s.username != "" {
+			lines = append(lines, "AUTH "+strings.Join(s.mechanisms, " "))
+		}
+		for i, l := range lines {
+			sep := "-"
+			if i == len(lines)-1 {
+				sep = " "
+			}
+			if ss.reply("250%s%s", sep, l) != nil {
+				return false
+			}
+		}
+		return true
+	case "STARTTLS":
+		if !s.startTLS || ss.tls {
+			return ss.reply("502 5.5.1 STARTTLS not available") == nil
+		}
+		if ss.reply("220 2.0.0 Ready to start TLS") != nil {
+			return false
+		}
+		tlsConn := tls.Server(ss.conn, s.tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			return false
+		}
+		ss.conn, ss.text, ss.tls = tlsConn, textproto.NewConn(tlsConn), true
+		ss.mail, ss.username = nil, ""
+		return true
+	case "AUTH":
+		return ss.auth(arg)
+	case "MAIL":
+		if s.username != "" && ss.username == "" {
+			return ss.reply("530 5.7.0 Authentication required") == nil
+		}
+		ss.mail = &FakeMail{From: extractPath(arg, "FROM:"), TLS: ss.tls, Username: ss.username}
+		return ss.reply("250 2.1.0 OK") == nil
+	case "RCPT":
+		if ss.mail == nil {
+			return ss.reply("503 5.5.1 MAIL first") == nil
+		}
+		rcpt := extractPath(arg, "TO:")
+		s.mu.Lock()
+		rejection, rejected := s.rejects[strings.ToLower(rcpt)]
+		s.mu.Unlock()
+		if rejected {
+			return ss.reply("%s", rejection) == nil
+		}
+		ss.mail.To = append(ss.mail.To, rcpt)
+		return ss.reply("250 2.1.5 OK") == nil
+	case "DATA":
+		if ss.mail == nil || len(ss.mail.To) == 0 {
+			return ss.reply("503 5.5.1 RCPT first") == nil
+		}
+		if ss.reply("354 Start mail input; end with <CRLF>.<CRLF>") != nil {
+			return false
+		}
+		data, err := ss.text.ReadDotBytes()
+		if err != nil {
+			return false
+		}
+		mail := *ss.mail
+		mail.Data = data
+		ss.mail = nil
+
+		s.mu.Lock()
+		s.messages = append(s.messages, mail)
+		s.mu.Unlock()
+		return ss.reply("250 2.0.0 OK queued") == nil
+	case "RSET":
+		ss.mail = nil
+		return ss.reply("250 2.0.0 OK") == nil
+	case "NOOP":
+		return ss.reply("250 2.0.0 OK") == nil
+	case "QUIT":
+		ss.reply("221 2.0.0 Bye")
+		return false
+	}
+	return ss.reply("502 5.5.2 Command not recognized") == nil
+}
+
+// auth handles the AUTH command
+func (ss *fakeSMTPSession) auth(arg string) bool {
+	s := ss.server
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	mechanism = strings.ToUpper(mechanism)
+	if s.username == "" || !containsString(s.mechanisms, mechanism) {
+		return ss.reply("504 5.5.4 Unrecognized authentication type") == nil
+	}
+
+	// prompt sends a base64 challenge and returns the decoded answer
+	prompt := func(challenge string) (string, bool) {
+		if ss.reply("334 %s", base64.StdEncoding.EncodeToString([]byte(challenge))) != nil {
+			return "", false
+		}
+		line, err := ss.text.ReadLine()
+		if err != nil {
+			return "", false
+		}
+		decoded, err := base64.StdEncoding.DecodeString(line)
+		return string(decoded), err == nil
+	}
+
+	var username string
+	var ok bool
+	switch mechanism {
+	case MailAuthPlain:
+		var response string
+		if initial != "" {
+			decoded, err := base64.StdEncoding.DecodeString(initial)
+			response, ok = string(decoded), err == nil
+		} else if response, ok = prompt(""); !ok {
+			return false
+		}
+		parts := strings.Split(response, "\x00")
+		ok = ok && len(parts) == 3 && parts[1] == s.username && parts[2] == s.password
+		username = s.username
+	case MailAuthLogin:
+		user, uok := prompt("Username:")
+		if !uok {
+			return false
+		}
+		password, pok := prompt("Password:")
+		if !pok {
+			return false
+		}
+		ok = user == s.username && password == s.password
+		username = user
+	case MailAuthCRAMMD5:
+		challenge := fmt.Sprintf("<%d.%d@fake>", time.Now().UnixNano(), s.Connections())
+		response, rok := prompt(challenge)
+		if !rok {
+			return false
+		}
+		mac := hmac.New(md5.New, []byte(s.password))
+		mac.Write([]byte(challenge))
+		ok = response == s.username+" "+hex.EncodeToString(mac.Sum(nil))
+		username = s.username
+	}
+
+	if !ok {
+		return ss.reply("535 5.7.8 Authentication credentials invalid") == nil
+	}
+	ss.username = username
+	return ss.reply("235 2.7.0 Authentication successful") == nil
+}
+
+// extractPath returns the address of a MAIL FROM or RCPT TO argument
+func extractPath(arg, prefix string) string {
+	arg = strings.TrimSpace(arg)
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	path, _, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	return strings.Trim(path, "<>")
+}
@@ -2,73 +2,545 @@ package clients
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"golang-gin/backoff"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
-// HTTPClient wraps http.Client for external API calls
+var (
+	// ErrCircuitOpen is returned without sending the request while the
+	// circuit breaker of the host is open
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+	// ErrResponseTooLarge is returned when a response body exceeds the
+	// response size limit
+	ErrResponseTooLarge = errors.New("response body too large")
+)
+
+// HTTPError is returned for responses with a non-2xx status code
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s %s: unexpected status code: %d, body: %s", e.Method, e.URL, e.StatusCode, string(e.Body))
+}
+
+// Temporary reports whether the request may succeed when retried later
+func (e *HTTPError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout ||
+		(e.StatusCode >= 500 && e.StatusCode != http.StatusNotImplemented)
+}
+
+// HTTPStatus returns the status code of an *HTTPError in err's chain, or 0
+func HTTPStatus(err error) int {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode
+	}
+	return 0
+}
+
+// RetryPolicy configures how failed requests are retried. Transport errors
+// and temporary status codes (408, 429, 5xx except 501) are retried for
+// idempotent methods; 429 is also retried for POST and PATCH as the server
+// did not process the request.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first; 1 disables
+	// retries
+	MaxAttempts int
+	// MinBackoff and MaxBackoff bound the jittered exponential backoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxRetryAfter caps the wait requested by a Retry-After header; longer
+	// waits are not retried
+	MaxRetryAfter time.Duration
+	// RetryNonIdempotent retries POST and PATCH like idempotent methods. Only
+	// enable it for endpoints that deduplicate requests.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy is the retry policy of new HTTP clients
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:   3,
+	MinBackoff:    100 * time.Millisecond,
+	MaxBackoff:    2 * time.Second,
+	MaxRetryAfter: 30 * time.Second,
+}
+
+// NoRetry disables retries
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// backoff returns the jittered delay before the given retry, doubling from
+// MinBackoff up to MaxBackoff. The jitter spreads the delay over [d/2, d) so
+// that clients failing together do not retry together.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	return backoff.Jitter(backoff.Exponential(retry, p.MinBackoff, p.MaxBackoff))
+}
+
+// CircuitBreakerConfig configures the per-host circuit breaker. After
+// FailureThreshold consecutive failures (transport errors and 5xx responses)
+// the circuit opens and requests fail with ErrCircuitOpen. After OpenTimeout
+// one trial request is let through: its success closes the circuit, its
+// failure opens it again.
+type CircuitBreakerConfig struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+}
+
+// DefaultCircuitBreaker is the circuit breaker configuration of new HTTP
+// clients
+var DefaultCircuitBreaker = CircuitBreakerConfig{
+	FailureThreshold: 5,
+	OpenTimeout:      30 * time.Second,
+}
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all requests
+	CircuitOpen
+	// CircuitHalfOpen lets one trial request through
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker tracks the failures of one host
+type circuitBreaker struct {
+	mu       sync.Mutex
+	cfg      CircuitBreakerConfig
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	trial    bool // a trial request is in flight
+}
+
+// allow reports whether a request may be sent
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if now.Sub(b.openedAt) < b.cfg.OpenTimeout {
+			return false
+		}
+		b.state = CircuitHalfOpen
+		b.trial = true
+		return true
+	case CircuitHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record records the outcome of a request
+func (b *circuitBreaker) record(now time.Time, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if !failed {
+		b.state = CircuitClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.cfg.FailureThreshold {
+		b.state = CircuitOpen
+		b.openedAt = now
+	}
+}
+
+// abort ends a request without recording an outcome
+func (b *circuitBreaker) abort() {
+	b.mu.Lock()
+	b.trial = false
+	b.mu.Unlock()
+}
+
+// current returns the state of the breaker
+func (b *circuitBreaker) current(now time.Time) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && now.Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// HTTPOption configures an HTTPClient
+type HTTPOption func(*HTTPClient)
+
+// WithHTTPTimeout sets the timeout of a single attempt (default: 10s). The
+// context passed to a request bounds all attempts together.
+func WithHTTPTimeout(d time.Duration) HTTPOption {
+	return func(c *HTTPClient) {
+		c.client.Timeout = d
+	}
+}
+
+// WithHTTPTransport sets the transport used to send requests
+func WithHTTPTransport(rt http.RoundTripper) HTTPOption {
+	return func(c *HTTPClient) {
+		c.client.Transport = rt
+	}
+}
+
+// WithRetryPolicy sets the retry policy (default: DefaultRetryPolicy)
+func WithRetryPolicy(policy RetryPolicy) HTTPOption {
+	return func(c *HTTPClient) {
+		if policy.MaxAttempts < 1 {
+			policy.MaxAttempts = 1
+		}
+		c.retry = policy
+	}
+}
+
+// WithCircuitBreaker configures the per-host circuit breaker (default:
+// DefaultCircuitBreaker). A FailureThreshold of 0 disables it.
+func WithCircuitBreaker(cfg CircuitBreakerConfig) HTTPOption {
+	return func(c *HTTPClient) {
+		c.breaker = cfg
+	}
+}
+
+// WithMaxResponseBytes sets the default response size limit (default: 10
+// MiB). 0 disables the limit.
+func WithMaxResponseBytes(n int64) HTTPOption {
+	return func(c *HTTPClient) {
+		c.maxResponseBytes = n
+	}
+}
+
+// WithRequestHook adds a hook called before every attempt
+func WithRequestHook(hook RequestHook) HTTPOption {
+	return func(c *HTTPClient) {
+		c.requestHooks = append(c.requestHooks, hook)
+	}
+}
+
+// WithResponseHook adds a hook called after every attempt
+func WithResponseHook(hook ResponseHook) HTTPOption {
+	return func(c *HTTPClient) {
+		c.responseHooks = append(c.responseHooks, hook)
+	}
+}
+
+// HTTPClient wraps http.Client for external API calls. Requests are retried
+// according to the retry policy and guarded by a circuit breaker per host.
 type HTTPClient struct {
-	client  *http.Client
-	baseURL string
+	client           *http.Client
+	baseURL          string
+	retry            RetryPolicy
+	breaker          CircuitBreakerConfig
+	maxResponseBytes int64
+	requestHooks     []RequestHook
+	responseHooks    []ResponseHook
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+
+	now func() time.Time
 }
 
 // NewHTTPClient creates a new HTTP client
-func NewHTTPClient(baseURL string) *HTTPClient {
-	return &HTTPClient{
+func NewHTTPClient(baseURL string, opts ...HTTPOption) *HTTPClient {
+	c := &HTTPClient{
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		baseURL: baseURL,
+		baseURL:          baseURL,
+		retry:            DefaultRetryPolicy,
+		breaker:          DefaultCircuitBreaker,
+		maxResponseBytes: 10 << 20,
+		breakers:         map[string]*circuitBreaker{},
+		now:              time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
 // Get performs a GET request
 func (c *HTTPClient) Get(endpoint string) ([]byte, error) {
-	url := c.baseURL + endpoint
-	resp, err := c.client.Get(url)
+	return c.GetContext(context.Background(), endpoint)
+}
+
+// Post performs a POST request with data encoded as JSON
+func (c *HTTPClient) Post(endpoint string, data interface{}) ([]byte, error) {
+	return c.PostContext(context.Background(), endpoint, data)
+}
+
+// GetContext performs a GET request
+func (c *HTTPClient) GetContext(ctx context.Context, endpoint string, opts ...RequestOption) ([]byte, error) {
+	return c.Do(ctx, http.MethodGet, endpoint, nil, opts...)
+}
+
+// PostContext performs a POST request with data encoded as JSON
+func (c *HTTPClient) PostContext(ctx context.Context, endpoint string, data interface{}, opts ...RequestOption) ([]byte, error) {
+	return c.Do(ctx, http.MethodPost, endpoint, data, opts...)
+}
+
+// PutContext performs a PUT request with data encoded as JSON
+func (c *HTTPClient) PutContext(ctx context.Context, endpoint string, data interface{}, opts ...RequestOption) ([]byte, error) {
+	return c.Do(ctx, http.MethodPut, endpoint, data, opts...)
+}
+
+// PatchContext performs a PATCH request with data encoded as JSON
+func (c *HTTPClient) PatchContext(ctx context.Context, endpoint string, data interface{}, opts ...RequestOption) ([]byte, error) {
+	return c.Do(ctx, http.MethodPatch, endpoint, data, opts...)
+}
+
+// DeleteContext performs a DELETE request
+func (c *HTTPClient) DeleteContext(ctx context.Context, endpoint string, opts ...RequestOption) ([]byte, error) {
+	return c.Do(ctx, http.MethodDelete, endpoint, nil, opts...)
+}
+
+// Do sends a request to baseURL+endpoint and returns the body of a 2xx
+// response. A non-nil data is encoded as JSON. Other status codes are
+// returned as *HTTPError.
+func (c *HTTPClient) Do(ctx context.Context, method, endpoint string, data interface{}, opts ...RequestOption) ([]byte, error) {
+	cfg := c.requestConfig(opts)
+
+	var payload []byte
+	if data != nil {
+		var err error
+		if payload, err = json.Marshal(data); err != nil {
+			return nil, fmt.Errorf("failed to marshal data: %w", err)
+		}
+	}
+
+	target, err := cfg.url(c.baseURL + endpoint)
 	if err != nil {
-		return nil, fmt.Errorf("GET request failed: %w", err)
+		return nil, fmt.Errorf("invalid %s request: %w", method, err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	for attempt := 1; ; attempt++ {
+		body, err := c.attempt(ctx, method, target, payload, cfg)
+		if err == nil {
+			return body, nil
+		}
+
+		delay, retry := c.retryDelay(ctx, method, attempt, err)
+		if !retry {
+			return nil, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%s %s: %w (last error: %v)", method, target, ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends a request once, guarded by the circuit breaker of the host
+func (c *HTTPClient) attempt(ctx context.Context, method, target string, payload []byte, cfg *requestConfig) ([]byte, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, &noRetryError{fmt.Errorf("invalid %s request: %w", method, err)}
 	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	cfg.apply(req)
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+	for _, hook := range c.requestHooks {
+		if err := hook(req); err != nil {
+			return nil, &noRetryError{fmt.Errorf("%s %s: request hook failed: %w", method, target, err)}
+		}
 	}
 
-	return body, nil
-}
+	breaker := c.circuitBreaker(req.URL.Host)
+	if breaker != nil && !breaker.allow(c.now()) {
+		return nil, &noRetryError{fmt.Errorf("%s %s: %w", method, target, ErrCircuitOpen)}
+	}
 
-// Post performs a POST request
-func (c *HTTPClient) Post(endpoint string, data interface{}) ([]byte, error) {
-	url := c.baseURL + endpoint
+	start := time.Now()
+	resp, body, err := c.send(req, cfg.maxResponseBytes)
+	for _, hook := range c.responseHooks {
+		hook(req, resp, err, time.Since(start))
+	}
 
-	jsonData, err := json.Marshal(data)
+	if breaker != nil {
+		switch {
+		case err != nil && ctx.Err() != nil:
+			// Cancellation by the caller says nothing about the host
+			breaker.abort()
+		case errors.Is(err, ErrResponseTooLarge):
+			breaker.record(c.now(), false)
+		default:
+			breaker.record(c.now(), err != nil || resp.StatusCode >= 500)
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal data: %w", err)
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &HTTPError{
+			Method:     method,
+			URL:        target,
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+			Body:       body,
+		}
 	}
+	return body, nil
+}
 
-	resp, err := c.client.Post(url, "application/json", bytes.NewBuffer(jsonData))
+// send sends req and reads at most limit bytes of the response body. The
+// response is returned with its body read and closed.
+func (c *HTTPClient) send(req *http.Request, limit int64) (*http.Response, []byte, error) {
+	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("POST request failed: %w", err)
+		return nil, nil, fmt.Errorf("%s request failed: %w", req.Method, err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	reader := io.Reader(resp.Body)
+	if limit > 0 {
+		reader = io.LimitReader(resp.Body, limit+1)
+	}
+	body, err := io.ReadAll(reader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return resp, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if limit > 0 && int64(len(body)) > limit {
+		return resp, nil, &noRetryError{fmt.Errorf("%s %s: %w (limit %d bytes)", req.Method, req.URL, ErrResponseTooLarge, limit)}
 	}
+	return resp, body, nil
+}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+// noRetryError marks errors that retrying cannot fix
+type noRetryError struct {
+	err error
+}
+
+func (e *noRetryError) Error() string { return e.err.Error() }
+func (e *noRetryError) Unwrap() error { return e.err }
+
+// retryDelay decides whether a failed attempt is retried and after how long
+func (c *HTTPClient) retryDelay(ctx context.Context, method string, attempt int, err error) (time.Duration, bool) {
+	var noRetry *noRetryError
+	if attempt >= c.retry.MaxAttempts || ctx.Err() != nil || errors.As(err, &noRetry) {
+		return 0, false
 	}
+	idempotent := c.retry.RetryNonIdempotent || isIdempotent(method)
 
-	return body, nil
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		// Transport error: the request may or may not have been processed
+		return c.retry.backoff(attempt), idempotent
+	}
+	if !httpErr.Temporary() || (!idempotent && httpErr.StatusCode != http.StatusTooManyRequests) {
+		return 0, false
+	}
+
+	if wait, ok := parseRetryAfter(httpErr.Header.Get("Retry-After"), c.now()); ok {
+		if c.retry.MaxRetryAfter > 0 && wait > c.retry.MaxRetryAfter {
+			return 0, false
+		}
+		return wait, true
+	}
+	return c.retry.backoff(attempt), true
+}
+
+// circuitBreaker returns the breaker of host, or nil if disabled
+func (c *HTTPClient) circuitBreaker(host string) *circuitBreaker {
+	if c.breaker.FailureThreshold <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.breakers[host]
+	if !ok {
+		b = &circuitBreaker{cfg: c.breaker}
+		c.breakers[host] = b
+	}
+	return b
+}
+
+// CircuitState returns the state of the circuit breaker of host (host[:port])
+func (c *HTTPClient) CircuitState(host string) CircuitState {
+	c.mu.Lock()
+	b, ok := c.breakers[host]
+	c.mu.Unlock()
+	if !ok {
+		return CircuitClosed
+	}
+	return b.current(c.now())
+}
+
+// isIdempotent reports whether repeating a request has the same effect as
+// sending it once
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP
+// date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := at.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
 }
@@ -0,0 +1,199 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RequestHook is called before every attempt of a request. It may modify the
+// request, e.g. to add tracing headers; an error aborts the request.
+type RequestHook func(req *http.Request) error
+
+// ResponseHook is called after every attempt of a request with the response,
+// whose body has already been read, or the error if no response was read
+type ResponseHook func(req *http.Request, resp *http.Response, err error, elapsed time.Duration)
+
+// LogResponses returns a ResponseHook that logs every attempt
+func LogResponses() ResponseHook {
+	return func(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+		if err != nil {
+			log.Printf("🌐 %s %s failed after %v: %v", req.Method, req.URL.Redacted(), elapsed, err)
+			return
+		}
+		log.Printf("🌐 %s %s %d (%v)", req.Method, req.URL.Redacted(), resp.StatusCode, elapsed)
+	}
+}
+
+// Decoder decodes a response body into v
+type Decoder func(body io.Reader, v interface{}) error
+
+// JSONDecoder decodes JSON bodies. It is the default decoder of GetJSON and
+// the other typed helpers.
+func JSONDecoder(body io.Reader, v interface{}) error {
+	return json.NewDecoder(body).Decode(v)
+}
+
+// RequestOption configures a single request
+type RequestOption func(*requestConfig)
+
+// requestConfig holds the options of a request
+type requestConfig struct {
+	header           http.Header
+	query            url.Values
+	username         string
+	password         string
+	basicAuth        bool
+	decoder          Decoder
+	maxResponseBytes int64
+}
+
+// WithHeader sets a request header
+func WithHeader(key, value string) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.header.Set(key, value)
+	}
+}
+
+// WithQuery adds a query parameter to the endpoint
+func WithQuery(key, value string) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.query.Add(key, value)
+	}
+}
+
+// WithQueryValues adds query parameters to the endpoint
+func WithQueryValues(values url.Values) RequestOption {
+	return func(cfg *requestConfig) {
+		for key, list := range values {
+			for _, value := range list {
+				cfg.query.Add(key, value)
+			}
+		}
+	}
+}
+
+// WithBearerToken authenticates the request with a bearer token
+func WithBearerToken(token string) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// WithBasicAuth authenticates the request with HTTP basic auth
+func WithBasicAuth(username, password string) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.username, cfg.password, cfg.basicAuth = username, password, true
+	}
+}
+
+// WithDecoder sets the decoder of the typed helpers (default: JSONDecoder)
+func WithDecoder(decoder Decoder) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.decoder = decoder
+	}
+}
+
+// WithResponseLimit limits the response body of the request to n bytes,
+// overriding the client's limit. 0 disables the limit.
+func WithResponseLimit(n int64) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.maxResponseBytes = n
+	}
+}
+
+// requestConfig applies opts to the client's defaults
+func (c *HTTPClient) requestConfig(opts []RequestOption) *requestConfig {
+	cfg := &requestConfig{
+		header:           http.Header{},
+		query:            url.Values{},
+		decoder:          JSONDecoder,
+		maxResponseBytes: c.maxResponseBytes,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// url adds the query parameters to target
+func (cfg *requestConfig) url(target string) (string, error) {
+	if len(cfg.query) == 0 {
+		return target, nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	for key, list := range cfg.query {
+		for _, value := range list {
+			query.Add(key, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// apply sets the headers and credentials on req
+func (cfg *requestConfig) apply(req *http.Request) {
+	for key, values := range cfg.header {
+		req.Header[key] = values
+	}
+	if cfg.basicAuth {
+		req.SetBasicAuth(cfg.username, cfg.password)
+	}
+}
+
+// GetJSON performs a GET request and decodes the response into T
+func GetJSON[T any](ctx context.Context, c *HTTPClient, endpoint string, opts ...RequestOption) (T, error) {
+	return decode[T](ctx, c, http.MethodGet, endpoint, nil, opts)
+}
+
+// PostJSON performs a POST request with body encoded as JSON and decodes
+// the response into Resp
+func PostJSON[Req, Resp any](ctx context.Context, c *HTTPClient, endpoint string, body Req, opts ...RequestOption) (Resp, error) {
+	return decode[Resp](ctx, c, http.MethodPost, endpoint, body, opts)
+}
+
+// PutJSON performs a PUT request with body encoded as JSON and decodes the
+// response into Resp
+func PutJSON[Req, Resp any](ctx context.Context, c *HTTPClient, endpoint string, body Req, opts ...RequestOption) (Resp, error) {
+	return decode[Resp](ctx, c, http.MethodPut, endpoint, body, opts)
+}
+
+// PatchJSON performs a PATCH request with body encoded as JSON and decodes
+// the response into Resp
+func PatchJSON[Req, Resp any](ctx context.Context, c *HTTPClient, endpoint string, body Req, opts ...RequestOption) (Resp, error) {
+	return decode[Resp](ctx, c, http.MethodPatch, endpoint, body, opts)
+}
+
+// DeleteJSON performs a DELETE request and decodes the response into T
+func DeleteJSON[T any](ctx context.Context, c *HTTPClient, endpoint string, opts ...RequestOption) (T, error) {
+	return decode[T](ctx, c, http.MethodDelete, endpoint, nil, opts)
+}
+
+// decode sends a request and decodes the response with the configured
+// decoder
+func decode[T any](ctx context.Context, c *HTTPClient, method, endpoint string, data interface{}, opts []RequestOption) (T, error) {
+	var result T
+	raw, err := c.Do(ctx, method, endpoint, data, opts...)
+	if err != nil {
+		return result, err
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return result, nil
+	}
+
+	decoder := c.requestConfig(opts).decoder
+	if err := decoder(bytes.NewReader(raw), &result); err != nil {
+		return result, fmt.Errorf("failed to decode %s %s response: %w", method, endpoint, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,171 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testUser is the user resource of the echo server
+type testUser struct {
+	ID    int    `json:"id" xml:"id"`
+	Name  string `json:"name" xml:"name"`
+	Query string `json:"query,omitempty" xml:"-"`
+	Auth  string `json:"auth,omitempty" xml:"-"`
+}
+
+// newUserServer returns a server that echoes request details as a testUser
+func newUserServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case http.MethodPost:
+			var user testUser
+			json.NewDecoder(r.Body).Decode(&user)
+			user.ID = 999
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(user)
+			return
+		}
+
+		user := testUser{ID: 1, Name: "Alice", Query: r.URL.RawQuery, Auth: r.Header.Get("Authorization")}
+		if r.Header.Get("Accept") == "application/xml" {
+			xml.NewEncoder(w).Encode(user)
+			return
+		}
+		json.NewEncoder(w).Encode(user)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGetJSON(t *testing.T) {
+	server := newUserServer(t)
+	client := NewHTTPClient(server.URL)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		endpoint  string
+		opts      []RequestOption
+		wantQuery string
+		wantAuth  string
+	}{
+		{"Plain", "/users/1", nil, "", ""},
+		{"Query", "/users/1?fields=name", []RequestOption{WithQuery("include", "a b"), WithQueryValues(url.Values{"tag": {"x", "y"}})},
+			"fields=name&include=a+b&tag=x&tag=y", ""},
+		{"Bearer", "/users/1", []RequestOption{WithBearerToken("secret")}, "", "Bearer secret"},
+		{"Basic", "/users/1", []RequestOption{WithBasicAuth("user", "pass")}, "", "Basic dXNlcjpwYXNz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := GetJSON[testUser](ctx, client, tt.endpoint, tt.opts...)
+			if err != nil {
+				t.Fatalf("GetJSON failed: %v", err)
+			}
+			if user.ID != 1 || user.Name != "Alice" || user.Query != tt.wantQuery || user.Auth != tt.wantAuth {
+				t.Errorf("Unexpected user %+v", user)
+			}
+		})
+	}
+
+	t.Run("Custom decoder", func(t *testing.T) {
+		xmlDecoder := func(body io.Reader, v interface{}) error { return xml.NewDecoder(body).Decode(v) }
+		user, err := GetJSON[testUser](ctx, client, "/users/1",
+			WithHeader("Accept", "application/xml"), WithDecoder(xmlDecoder))
+		if err != nil || user.Name != "Alice" {
+			t.Errorf("Expected XML decoded user, got %+v (%v)", user, err)
+		}
+	})
+
+	t.Run("Decode error", func(t *testing.T) {
+		if _, err := GetJSON[[]testUser](ctx, client, "/users/1"); err == nil || !strings.Contains(err.Error(), "failed to decode") {
+			t.Errorf("Expected decode error, got %v", err)
+		}
+	})
+}
+
+func TestPostJSON(t *testing.T) {
+	server := newUserServer(t)
+	client := NewHTTPClient(server.URL)
+	ctx := context.Background()
+
+	created, err := PostJSON[testUser, testUser](ctx, client, "/users", testUser{Name: "Bob"})
+	if err != nil || created.ID != 999 || created.Name != "Bob" {
+		t.Errorf("Expected created user, got %+v (%v)", created, err)
+	}
+
+	// A 204 decodes into the zero value
+	deleted, err := DeleteJSON[*testUser](ctx, client, "/users/999")
+	if err != nil || deleted != nil {
+		t.Errorf("Expected no content, got %+v (%v)", deleted, err)
+	}
+}
+
+func TestHTTPClient_ResponseLimit(t *testing.T) {
+	server := newStatusServer(t, 200)
+	client := NewHTTPClient(server.URL, WithMaxResponseBytes(5), WithRetryPolicy(fastRetry))
+
+	if _, err := client.Get("/"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Expected ErrResponseTooLarge, got %v", err)
+	}
+	if server.Requests() != 1 {
+		t.Errorf("Expected oversized response not to be retried, got %d requests", server.Requests())
+	}
+
+	if _, err := client.GetContext(context.Background(), "/", WithResponseLimit(0)); err != nil {
+		t.Errorf("Expected request without limit to succeed, got %v", err)
+	}
+}
+
+func TestHTTPClient_Hooks(t *testing.T) {
+	server := newStatusServer(t, 503, 200)
+
+	var mu sync.Mutex
+	var statuses []int
+	var traced []string
+	client := NewHTTPClient(server.URL,
+		WithRetryPolicy(fastRetry),
+		WithRequestHook(func(req *http.Request) error {
+			req.Header.Set("X-Trace-ID", "trace-1")
+			return nil
+		}),
+		WithResponseHook(func(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			traced = append(traced, req.Header.Get("X-Trace-ID"))
+			if err == nil {
+				statuses = append(statuses, resp.StatusCode)
+			}
+		}),
+	)
+
+	if _, err := client.Get("/"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0] != 503 || statuses[1] != 200 {
+		t.Errorf("Expected the response hook to see every attempt, got %v", statuses)
+	}
+	if len(traced) != 2 || traced[0] != "trace-1" {
+		t.Errorf("Expected the request hook to run before every attempt, got %v", traced)
+	}
+
+	errHook := errors.New("no credentials")
+	failing := NewHTTPClient(server.URL, WithRequestHook(func(req *http.Request) error { return errHook }))
+	if _, err := failing.Get("/"); !errors.Is(err, errHook) || server.Requests() != 2 {
+		t.Errorf("Expected hook error without a request, got %v after %d requests", err, server.Requests())
+	}
+}
@@ -1,8 +1,17 @@
 package clients
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // TestHTTPClient_Get tests HTTP GET requests to mock server
@@ -56,3 +65,264 @@ func TestHTTPClient_Post(t *testing.T) {
 
 	t.Logf("Created user: %+v", user)
 }
+
+// statusServer responds with the given status codes in order, repeating the
+// last one, and counts the requests it received
+type statusServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header
+	requests int
+}
+
+func newStatusServer(t *testing.T, statuses ...int) *statusServer {
+	t.Helper()
+	s := &statusServer{statuses: statuses, header: http.Header{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		status := s.statuses[min(s.requests, len(s.statuses)-1)]
+		s.requests++
+		for name, values := range s.header {
+			w.Header()[name] = values
+		}
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"status":%d}`, status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Requests returns the number of requests received
+func (s *statusServer) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// fastRetry retries quickly so that tests do not wait
+var fastRetry = RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+// noBreaker disables the circuit breaker
+var noBreaker = CircuitBreakerConfig{}
+
+func TestHTTPClient_Methods(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(map[string]string{
+			"method":       r.Method,
+			"path":         r.URL.Path,
+			"content_type": r.Header.Get("Content-Type"),
+			"body":         string(body),
+		})
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL)
+	ctx := context.Background()
+	data := map[string]string{"name": "Alice"}
+
+	tests := []struct {
+		method string
+		call   func() ([]byte, error)
+		body   string
+	}{
+		{"GET", func() ([]byte, error) { return client.GetContext(ctx, "/users") }, ""},
+		{"POST", func() ([]byte, error) { return client.PostContext(ctx, "/users", data) }, `{"name":"Alice"}`},
+		{"PUT", func() ([]byte, error) { return client.PutContext(ctx, "/users", data) }, `{"name":"Alice"}`},
+		{"PATCH", func() ([]byte, error) { return client.PatchContext(ctx, "/users", data) }, `{"name":"Alice"}`},
+		{"DELETE", func() ([]byte, error) { return client.DeleteContext(ctx, "/users") }, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			raw, err := tt.call()
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			var got map[string]string
+			if err := json.Unmarshal(raw, &got); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if got["method"] != tt.method || got["path"] != "/users" || got["body"] != tt.body {
+				t.Errorf("Unexpected request %+v", got)
+			}
+			if tt.body != "" && got["content_type"] != "application/json" {
+				t.Errorf("Expected JSON content type, got %q", got["content_type"])
+			}
+		})
+	}
+}
+
+func TestHTTPClient_Retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		statuses     []int
+		policy       RetryPolicy
+		wantRequests int
+		wantStatus   int // 0 for success
+	}{
+		{"GET recovers", "GET", []int{503, 502, 200}, fastRetry, 3, 0},
+		{"GET gives up", "GET", []int{500}, fastRetry, 3, 500},
+		{"Client error", "GET", []int{404}, fastRetry, 1, 404},
+		{"Not implemented", "GET", []int{501}, fastRetry, 1, 501},
+		{"PUT is idempotent", "PUT", []int{504, 200}, fastRetry, 2, 0},
+		{"POST is not retried", "POST", []int{503, 200}, fastRetry, 1, 503},
+		{"POST is retried on 429", "POST", []int{429, 200}, fastRetry, 2, 0},
+		{"POST opted in", "POST", []int{503, 200}, RetryPolicy{MaxAttempts: 2, RetryNonIdempotent: true}, 2, 0},
+		{"No retry", "GET", []int{503, 200}, NoRetry, 1, 503},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStatusServer(t, tt.statuses...)
+			client := NewHTTPClient(server.URL, WithRetryPolicy(tt.policy), WithCircuitBreaker(noBreaker))
+
+			_, err := client.Do(context.Background(), tt.method, "/", nil)
+			if got := HTTPStatus(err); got != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d (%v)", tt.wantStatus, got, err)
+			}
+			if server.Requests() != tt.wantRequests {
+				t.Errorf("Expected %d requests, got %d", tt.wantRequests, server.Requests())
+			}
+		})
+	}
+}
+
+func TestHTTPClient_RetryAfter(t *testing.T) {
+	t.Run("Honored", func(t *testing.T) {
+		server := newStatusServer(t, 503, 200)
+		server.header.Set("Retry-After", "1")
+		client := NewHTTPClient(server.URL, WithRetryPolicy(fastRetry))
+
+		start := time.Now()
+		if _, err := client.Get("/"); err != nil {
+			t.Fatalf("Expected retry to succeed, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+			t.Errorf("Expected to wait for Retry-After, waited %v", elapsed)
+		}
+	})
+
+	t.Run("Too long", func(t *testing.T) {
+		server := newStatusServer(t, 429, 200)
+		server.header.Set("Retry-After", "120")
+		client := NewHTTPClient(server.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, MaxRetryAfter: time.Second}))
+
+		_, err := client.Get("/")
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != 429 || httpErr.Header.Get("Retry-After") != "120" {
+			t.Errorf("Expected 429 error with Retry-After, got %v", err)
+		}
+		if !strings.Contains(string(httpErr.Body), "429") || server.Requests() != 1 {
+			t.Errorf("Expected 1 request and the error body, got %d and %q", server.Requests(), httpErr.Body)
+		}
+	})
+
+	t.Run("Canceled while waiting", func(t *testing.T) {
+		server := newStatusServer(t, 503, 200)
+		server.header.Set("Retry-After", "10")
+		client := NewHTTPClient(server.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 3}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := client.GetContext(ctx, "/"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected DeadlineExceeded, got %v", err)
+		}
+	})
+}
+
+func TestHTTPClient_CircuitBreaker(t *testing.T) {
+	server := newStatusServer(t, 500, 500, 500, 200)
+	client := NewHTTPClient(server.URL, WithRetryPolicy(NoRetry),
+		WithCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute}))
+	now := time.Now()
+	client.now = func() time.Time { return now }
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get("/"); HTTPStatus(err) != 500 {
+			t.Fatalf("Expected 500, got %v", err)
+		}
+	}
+	if state := client.CircuitState(host); state != CircuitOpen {
+		t.Fatalf("Expected open circuit, got %v", state)
+	}
+	if _, err := client.Get("/"); !errors.Is(err, ErrCircuitOpen) || server.Requests() != 2 {
+		t.Fatalf("Expected ErrCircuitOpen without a request, got %v after %d requests", err, server.Requests())
+	}
+
+	// A failed trial request opens the circuit again
+	now = now.Add(time.Minute)
+	if state := client.CircuitState(host); state != CircuitHalfOpen {
+		t.Errorf("Expected half-open circuit, got %v", state)
+	}
+	if _, err := client.Get("/"); HTTPStatus(err) != 500 {
+		t.Fatalf("Expected trial request to fail with 500, got %v", err)
+	}
+	if _, err := client.Get("/"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen after failed trial, got %v", err)
+	}
+
+	// A successful trial request closes it
+	now = now.Add(time.Minute)
+	if _, err := client.Get("/"); err != nil {
+		t.Fatalf("Expected trial request to succeed, got %v", err)
+	}
+	if state := client.CircuitState(host); state != CircuitClosed {
+		t.Errorf("Expected closed circuit, got %v", state)
+	}
+
+	// Other hosts have their own breaker
+	if state := client.CircuitState("other.example.com"); state != CircuitClosed {
+		t.Errorf("Expected closed circuit for another host, got %v", state)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, false},
+		{"Thu, 01 Jan 2026 00:00:30 GMT", 30 * time.Second, true},
+		{"Wed, 31 Dec 2025 23:59:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q): Expected %v %v, got %v %v", tt.value, tt.expected, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{10, time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := policy.backoff(tt.retry); got < tt.max/2 || got > tt.max {
+				t.Errorf("Expected backoff for retry %d within [%v, %v], got %v", tt.retry, tt.max/2, tt.max, got)
+			}
+		}
+	}
+}
@@ -1,46 +1,528 @@
 package clients
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"golang-gin/email"
+	"net"
 	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
 )
 
-// MailClient wraps SMTP client for sending emails
+var (
+	// ErrStartTLSUnsupported is returned when STARTTLS is required but the
+	// server does not offer it
+	ErrStartTLSUnsupported = errors.New("SMTP server does not support STARTTLS")
+	// ErrAuthUnsupported is returned when the server offers none of the
+	// allowed auth mechanisms
+	ErrAuthUnsupported = errors.New("SMTP server does not support the auth mechanism")
+	// ErrMailClientClosed is returned when the mail client has been closed
+	ErrMailClientClosed = errors.New("mail client is closed")
+)
+
+// MailTLSMode selects how the connection to the SMTP server is secured
+type MailTLSMode int
+
+const (
+	// MailTLSOpportunistic upgrades with STARTTLS when the server offers it
+	MailTLSOpportunistic MailTLSMode = iota
+	// MailTLSNone never uses TLS
+	MailTLSNone
+	// MailTLSStartTLS requires STARTTLS
+	MailTLSStartTLS
+	// MailTLSImplicit connects over TLS (SMTPS, usually port 465)
+	MailTLSImplicit
+)
+
+// ParseMailTLSMode parses a TLS mode name: "" or "opportunistic", "none",
+// "starttls" or "tls"
+func ParseMailTLSMode(s string) (MailTLSMode, error) {
+	switch strings.ToLower(s) {
+	case "", "opportunistic":
+		return MailTLSOpportunistic, nil
+	case "none":
+		return MailTLSNone, nil
+	case "starttls":
+		return MailTLSStartTLS, nil
+	case "tls", "implicit":
+		return MailTLSImplicit, nil
+	}
+	return 0, fmt.Errorf("unknown SMTP TLS mode %q", s)
+}
+
+// SMTP auth mechanisms
+const (
+	// MailAuthAuto picks a mechanism the server offers, or none without a
+	// username
+	MailAuthAuto    = ""
+	MailAuthNone    = "NONE"
+	MailAuthPlain   = "PLAIN"
+	MailAuthLogin   = "LOGIN"
+	MailAuthCRAMMD5 = "CRAM-MD5"
+)
+
+// MailOption configures a MailClient
+type MailOption func(*MailClient)
+
+// WithMailTLS sets the TLS mode (default: MailTLSOpportunistic)
+func WithMailTLS(mode MailTLSMode) MailOption {
+	return func(c *MailClient) {
+		c.tlsMode = mode
+	}
+}
+
+// WithMailTLSConfig sets the TLS configuration, e.g. RootCAs for a private
+// CA. ServerName defaults to the SMTP host.
+func WithMailTLSConfig(config *tls.Config) MailOption {
+	return func(c *MailClient) {
+		c.tlsConfig = config
+	}
+}
+
+// WithMailAuth sets the auth mechanism (default: MailAuthAuto)
+func WithMailAuth(mechanism string) MailOption {
+	return func(c *MailClient) {
+		c.auth = strings.ToUpper(mechanism)
+	}
+}
+
+// WithMailPool sets how many connections may be open at once and how long
+// idle connections are kept for reuse. A size of 0 opens a new connection
+// per message and does not limit concurrent sends.
+func WithMailPool(size int, idleTimeout time.Duration) MailOption {
+	return func(c *MailClient) {
+		c.poolSize = size
+		c.idleTimeout = idleTimeout
+	}
+}
+
+// WithMailTimeout bounds each message, including connecting, when the
+// context has no earlier deadline
+func WithMailTimeout(timeout time.Duration) MailOption {
+	return func(c *MailClient) {
+		c.timeout = timeout
+	}
+}
+
+// MailClient sends email over SMTP. Connections are pooled and reused while
+// they stay idle for less than the idle timeout. It is safe for concurrent
+// use.
 type MailClient struct {
-	host     string
-	port     string
-	username string
-	password string
-	from     string
+	host        string
+	port        string
+	username    string
+	password    string
+	from        string
+	tlsMode     MailTLSMode
+	tlsConfig   *tls.Config
+	auth        string
+	poolSize    int
+	idleTimeout time.Duration
+	timeout     time.Duration
+
+	slots  chan struct{} // limits open connections, nil without a pool
+	mu     sync.Mutex    // guards idle and closed
+	idle   []*mailConn
+	closed bool
+}
+
+// mailConn is an open SMTP session
+type mailConn struct {
+	conn     net.Conn
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+// close ends the session, politely if possible
+func (mc *mailConn) close() {
+	mc.conn.SetDeadline(time.Now().Add(time.Second))
+	if err := mc.client.Quit(); err != nil {
+		mc.client.Close()
+	}
 }
 
 // NewMailClient creates a new mail client
-func NewMailClient(host, port, username, password, from string) *MailClient {
-	return &MailClient{
-		host:     host,
-		port:     port,
-		username: username,
-		password: password,
-		from:     from,
+func NewMailClient(host, port, username, password, from string, opts ...MailOption) *MailClient {
+	c := &MailClient{
+		host:        host,
+		port:        port,
+		username:    username,
+		password:    password,
+		from:        from,
+		poolSize:    2,
+		idleTimeout: 30 * time.Second,
+		timeout:     30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.poolSize > 0 {
+		c.slots = make(chan struct{}, c.poolSize)
 	}
+	return c
 }
 
-// SendMail sends an email
+// SendMail sends a plain text email
 func (c *MailClient) SendMail(to []string, subject, body string) error {
-	message := []byte(fmt.Sprintf("From: %s\r\n"+
-		"To: %s\r\n"+
-		"Subject: %s\r\n"+
-		"\r\n"+
-		"%s\r\n", c.from, to[0], subject, body))
+	return c.Send(&email.Message{To: to, Subject: subject, Text: body})
+}
 
-	addr := fmt.Sprintf("%s:%s", c.host, c.port)
+// Send sends a message. The client's sender address is used if the message
+// has no From address.
+func (c *MailClient) Send(msg *email.Message) error {
+	return c.SendContext(context.Background(), msg)
+}
 
-	// For MailHog, no authentication needed
-	if c.host == "mailhog" || c.host == "localhost" {
-		return smtp.SendMail(addr, nil, c.from, to, message)
+// SendContext sends a message, giving up when ctx is done or the client's
+// timeout expires. SMTP errors are returned as *textproto.Error, so callers
+// can tell permanent (5xx) from transient (4xx) failures.
+func (c *MailClient) SendContext(ctx context.Context, msg *email.Message) error {
+	if msg.From == "" {
+		m := *msg
+		m.From = c.from
+		msg = &m
 	}
 
-	// For real SMTP servers with authentication
-	auth := smtp.PlainAuth("", c.username, c.password, c.host)
-	return smtp.SendMail(addr, auth, c.from, to, message)
+	sender, err := msg.Sender()
+	if err != nil {
+		return err
+	}
+	recipients, err := msg.Recipients()
+	if err != nil {
+		return err
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	if c.slots != nil {
+		select {
+		case c.slots <- struct{}{}:
+			defer func() { <-c.slots }()
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for SMTP connection: %w", ctx.Err())
+		}
+	}
+
+	mc, err := c.conn(ctx)
+	if err != nil {
+		return err
+	}
+	return c.deliver(ctx, mc, sender, recipients, data)
+}
+
+// deliver runs one mail transaction and returns the connection to the pool
+func (c *MailClient) deliver(ctx context.Context, mc *mailConn, sender string, recipients []string, data []byte) error {
+	// Expire the deadline on cancellation to abort blocking reads and writes
+	stop := context.AfterFunc(ctx, func() {
+		mc.conn.SetDeadline(time.Unix(1, 0))
+	})
+	deadline, _ := ctx.Deadline()
+	mc.conn.SetDeadline(deadline)
+
+	err := transaction(mc.client, sender, recipients, data)
+	if !stop() {
+		// The deadline was expired by the cancellation; the session is unusable
+		mc.client.Close()
+		if err != nil {
+			return fmt.Errorf("failed to send mail: %w", ctx.Err())
+		}
+		return nil
+	}
+	if err != nil {
+		mc.close()
+		return fmt.Errorf("failed to send mail: %w", &transactionError{contextError(ctx, err)})
+	}
+
+	mc.conn.SetDeadline(time.Time{})
+	c.release(mc)
+	return nil
+}
+
+// transactionError marks errors of the mail transaction itself, as opposed
+// to errors setting up the session
+type transactionError struct {
+	err error
+}
+
+func (e *transactionError) Error() string { return e.err.Error() }
+func (e *transactionError) Unwrap() error { return e.err }
+
+// IsPermanentMailError reports whether sending a message failed in a way
+// that retrying cannot fix: the message is invalid or the server rejected
+// it with a 5xx reply. Connection, TLS and auth failures are not permanent
+// as they are not caused by the message.
+func IsPermanentMailError(err error) bool {
+	if errors.Is(err, email.ErrNoSender) || errors.Is(err, email.ErrNoRecipients) || errors.Is(err, email.ErrInvalidAddress) {
+		return true
+	}
+	var te *transactionError
+	var reply *textproto.Error
+	return errors.As(err, &te) && errors.As(te.err, &reply) && reply.Code >= 500
+}
+
+// transaction sends one message on an SMTP session
+func transaction(client *smtp.Client, sender string, recipients []string, data []byte) error {
+	if err := client.Mail(sender); err != nil {
+		return err
+	}
+	for _, rcpt := range recipients {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// conn returns an idle connection that still responds, or dials a new one
+func (c *MailClient) conn(ctx context.Context) (*mailConn, error) {
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return nil, ErrMailClientClosed
+		}
+		if len(c.idle) == 0 {
+			c.mu.Unlock()
+			return c.dial(ctx)
+		}
+		mc := c.idle[len(c.idle)-1]
+		c.idle = c.idle[:len(c.idle)-1]
+		c.mu.Unlock()
+
+		if time.Since(mc.lastUsed) > c.idleTimeout {
+			mc.close()
+			continue
+		}
+		// The server may have dropped the connection while it was idle
+		mc.conn.SetDeadline(time.Now().Add(5 * time.Second))
+		if err := mc.client.Noop(); err != nil {
+			mc.client.Close()
+			continue
+		}
+		return mc, nil
+	}
+}
+
+// release puts a connection back into the pool
+func (c *MailClient) release(mc *mailConn) {
+	mc.lastUsed = time.Now()
+
+	c.mu.Lock()
+	if !c.closed && c.poolSize > 0 && len(c.idle) < c.poolSize {
+		c.idle = append(c.idle, mc)
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+	mc.close()
+}
+
+// Close closes idle connections. Messages being sent are not interrupted.
+func (c *MailClient) Close() error {
+	c.mu.Lock()
+	idle := c.idle
+	c.idle = nil
+	c.closed = true
+	c.mu.Unlock()
+
+	for _, mc := range idle {
+		mc.close()
+	}
+	return nil
+}
+
+// dial connects, secures and authenticates a new SMTP session
+func (c *MailClient) dial(ctx context.Context) (*mailConn, error) {
+	addr := net.JoinHostPort(c.host, c.port)
+
+	var conn net.Conn
+	var err error
+	if c.tlsMode == MailTLSImplicit {
+		dialer := &tls.Dialer{Config: c.clientTLSConfig()}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SMTP server %s: %w", addr, err)
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SMTP greeting from %s failed: %w", addr, contextError(ctx, err))
+	}
+	if err := c.handshake(client); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("SMTP handshake with %s failed: %w", addr, contextError(ctx, err))
+	}
+	return &mailConn{conn: conn, client: client}, nil
+}
+
+// handshake upgrades to TLS and authenticates as configured
+func (c *MailClient) handshake(client *smtp.Client) error {
+	if err := client.Hello("localhost"); err != nil {
+		return err
+	}
+
+	if c.tlsMode == MailTLSOpportunistic || c.tlsMode == MailTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(c.clientTLSConfig()); err != nil {
+				return err
+			}
+		} else if c.tlsMode == MailTLSStartTLS {
+			return ErrStartTLSUnsupported
+		}
+	}
+
+	auth, err := c.smtpAuth(client)
+	if err != nil || auth == nil {
+		return err
+	}
+	return client.Auth(auth)
+}
+
+// smtpAuth returns the auth to use for a session, or nil for none
+func (c *MailClient) smtpAuth(client *smtp.Client) (smtp.Auth, error) {
+	if c.auth == MailAuthNone || (c.auth == MailAuthAuto && c.username == "") {
+		return nil, nil
+	}
+
+	ok, offered := client.Extension("AUTH")
+	if !ok {
+		if c.auth == MailAuthAuto {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: %s (server offers no AUTH)", ErrAuthUnsupported, c.auth)
+	}
+	mechanisms := strings.Fields(strings.ToUpper(offered))
+
+	candidates := []string{c.auth}
+	if c.auth == MailAuthAuto {
+		// Without TLS only CRAM-MD5 keeps the password off the wire
+		_, secure := client.TLSConnectionState()
+		candidates = []string{MailAuthPlain, MailAuthLogin, MailAuthCRAMMD5}
+		if !secure {
+			candidates = []string{MailAuthCRAMMD5, MailAuthPlain, MailAuthLogin}
+		}
+	}
+
+	for _, candidate := range candidates {
+		if !containsString(mechanisms, candidate) {
+			continue
+		}
+		switch candidate {
+		case MailAuthPlain:
+			return smtp.PlainAuth("", c.username, c.password, c.host), nil
+		case MailAuthLogin:
+			return &loginAuth{username: c.username, password: c.password, host: c.host}, nil
+		case MailAuthCRAMMD5:
+			return smtp.CRAMMD5Auth(c.username, c.password), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: want %s, server offers %s", ErrAuthUnsupported, strings.Join(candidates, "/"), offered)
+}
+
+// clientTLSConfig returns the TLS configuration for the SMTP host
+func (c *MailClient) clientTLSConfig() *tls.Config {
+	config := &tls.Config{}
+	if c.tlsConfig != nil {
+		config = c.tlsConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = c.host
+	}
+	return config
+}
+
+// contextError returns the context's error for I/O errors caused by its
+// deadline or cancellation
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	// The connection deadline can fire just before the context notices
+	var netErr net.Error
+	if deadline, ok := ctx.Deadline(); ok && errors.As(err, &netErr) && netErr.Timeout() && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// loginAuth implements the LOGIN auth mechanism. Like smtp.PlainAuth it
+// only sends credentials over TLS or to localhost.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+// Start begins LOGIN authentication
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+// Next answers the username and password prompts
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("unexpected LOGIN prompt %q", fromServer)
+}
+
+// isLocalhost reports whether name is the local host
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
 }
@@ -1,27 +1,286 @@
 package clients
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"golang-gin/email"
+	netmail "net/mail"
+	"net/textproto"
+	"sync"
 	"testing"
+	"time"
 )
 
-// TestMailClient_SendMail tests sending mail to MailHog
-func TestMailClient_SendMail(t *testing.T) {
-	// このテストはdocker-composeでMailHogが起動している必要があります
-	// docker-compose up -d mailhog
-	// Web UI: http://localhost:17008
+// newFakeSMTPServer starts a server and stops it when the test ends
+func newFakeSMTPServer(t *testing.T, opts ...FakeSMTPOption) *FakeSMTPServer {
+	t.Helper()
+	server, err := NewFakeSMTPServer(opts...)
+	if err != nil {
+		t.Fatalf("Failed to start fake SMTP server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
 
-	client := NewMailClient("localhost", "17007", "", "", "noreply@golang-gin.test")
+// newTestMailClient connects a client to server, trusting its certificate
+func newTestMailClient(t *testing.T, server *FakeSMTPServer, username, password string, opts ...MailOption) *MailClient {
+	t.Helper()
+	opts = append([]MailOption{WithMailTLSConfig(&tls.Config{RootCAs: server.CertPool()})}, opts...)
+	client := NewMailClient(server.Host(), server.Port(), username, password, "noreply@golang-gin.test", opts...)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
 
-	to := []string{"test@example.com"}
-	subject := "Test Email from golang-gin"
-	body := "This is a test email sent from the golang-gin project.\n\nCheck MailHog UI at http://localhost:17008"
+func TestMailClient_Send(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverOpts []FakeSMTPOption
+		clientOpts []MailOption
+		username   string
+		wantTLS    bool
+		wantUser   string
+	}{
+		{"Plain", nil, nil, "", false, ""},
+		{"Opportunistic STARTTLS", []FakeSMTPOption{WithFakeSMTPStartTLS()}, nil, "", true, ""},
+		{"Required STARTTLS", []FakeSMTPOption{WithFakeSMTPStartTLS()}, []MailOption{WithMailTLS(MailTLSStartTLS)}, "", true, ""},
+		{"Implicit TLS", []FakeSMTPOption{WithFakeSMTPImplicitTLS()}, []MailOption{WithMailTLS(MailTLSImplicit)}, "", true, ""},
+		{"TLS disabled", []FakeSMTPOption{WithFakeSMTPStartTLS()}, []MailOption{WithMailTLS(MailTLSNone)}, "", false, ""},
+		{"Auto auth over TLS", []FakeSMTPOption{WithFakeSMTPStartTLS(), WithFakeSMTPAuth("user", "secret")}, nil, "user", true, "user"},
+		{"PLAIN", []FakeSMTPOption{WithFakeSMTPAuth("user", "secret")}, []MailOption{WithMailAuth(MailAuthPlain)}, "user", false, "user"},
+		{"LOGIN", []FakeSMTPOption{WithFakeSMTPImplicitTLS(), WithFakeSMTPAuth("user", "secret")},
+			[]MailOption{WithMailTLS(MailTLSImplicit), WithMailAuth(MailAuthLogin)}, "user", true, "user"},
+		{"CRAM-MD5", []FakeSMTPOption{WithFakeSMTPAuth("user", "secret", MailAuthCRAMMD5)}, []MailOption{WithMailAuth("cram-md5")}, "user", false, "user"},
+	}
 
-	err := client.SendMail(to, subject, body)
-	if err != nil {
-		t.Skipf("MailHog not available: %v", err)
-		return
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeSMTPServer(t, tt.serverOpts...)
+			client := newTestMailClient(t, server, tt.username, "secret", tt.clientOpts...)
+
+			err := client.Send(&email.Message{
+				To:      []string{"山田 太郎 <yamada@example.com>"},
+				Bcc:     []string{"audit@example.com"},
+				Subject: "テスト",
+				Text:    "本文",
+			})
+			if err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+
+			messages := server.Messages()
+			if len(messages) != 1 {
+				t.Fatalf("Expected 1 message, got %d", len(messages))
+			}
+			got := messages[0]
+			if got.From != "noreply@golang-gin.test" || len(got.To) != 2 || got.To[1] != "audit@example.com" {
+				t.Errorf("Unexpected envelope: from %s to %v", got.From, got.To)
+			}
+			if got.TLS != tt.wantTLS || got.Username != tt.wantUser {
+				t.Errorf("Expected TLS=%v user=%q, got TLS=%v user=%q", tt.wantTLS, tt.wantUser, got.TLS, got.Username)
+			}
+			if _, err := netmail.ReadMessage(bytes.NewReader(got.Data)); err != nil {
+				t.Errorf("Received message does not parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestMailClient_SendErrors(t *testing.T) {
+	msg := &email.Message{To: []string{"to@example.com"}, Text: "body"}
+
+	t.Run("STARTTLS unsupported", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		client := newTestMailClient(t, server, "", "", WithMailTLS(MailTLSStartTLS))
+		if err := client.Send(msg); !errors.Is(err, ErrStartTLSUnsupported) {
+			t.Errorf("Expected ErrStartTLSUnsupported, got %v", err)
+		}
+	})
+
+	t.Run("Untrusted certificate", func(t *testing.T) {
+		server := newFakeSMTPServer(t, WithFakeSMTPStartTLS())
+		client := NewMailClient(server.Host(), server.Port(), "", "", "noreply@golang-gin.test")
+		defer client.Close()
+		if err := client.Send(msg); err == nil || len(server.Messages()) != 0 {
+			t.Errorf("Expected certificate verification to fail, got %v", err)
+		}
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		server := newFakeSMTPServer(t, WithFakeSMTPAuth("user", "secret"))
+		client := newTestMailClient(t, server, "user", "wrong", WithMailAuth(MailAuthPlain))
+		var smtpErr *textproto.Error
+		err := client.Send(msg)
+		if !errors.As(err, &smtpErr) || smtpErr.Code != 535 {
+			t.Errorf("Expected 535 error, got %v", err)
+		}
+		if IsPermanentMailError(err) {
+			t.Error("Expected auth failure not to be permanent")
+		}
+	})
+
+	t.Run("Mechanism unsupported", func(t *testing.T) {
+		server := newFakeSMTPServer(t, WithFakeSMTPAuth("user", "secret", MailAuthPlain))
+		client := newTestMailClient(t, server, "user", "secret", WithMailAuth(MailAuthCRAMMD5))
+		if err := client.Send(msg); !errors.Is(err, ErrAuthUnsupported) {
+			t.Errorf("Expected ErrAuthUnsupported, got %v", err)
+		}
+	})
+
+	t.Run("Rejected recipient", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		server.Reject("to@example.com", "550 5.1.1 No such user")
+		client := newTestMailClient(t, server, "", "")
+		var smtpErr *textproto.Error
+		err := client.Send(msg)
+		if !errors.As(err, &smtpErr) || smtpErr.Code != 550 {
+			t.Errorf("Expected 550 error, got %v", err)
+		}
+		if !IsPermanentMailError(err) {
+			t.Error("Expected 550 rejection to be permanent")
+		}
+
+		server.Reject("to@example.com", "451 4.3.0 Try again later")
+		if err := client.Send(msg); err == nil || IsPermanentMailError(err) {
+			t.Errorf("Expected transient 451 error, got %v", err)
+		}
+
+		// The failed session is discarded and the next message succeeds
+		server.Reject("to@example.com", "")
+		if err := client.Send(msg); err != nil {
+			t.Errorf("Expected send to succeed after rejection, got %v", err)
+		}
+	})
+}
+
+func TestIsPermanentMailError(t *testing.T) {
+	tests := []struct {
+		name      string
+		msg       *email.Message
+		permanent bool
+	}{
+		{"No recipients", &email.Message{Text: "body"}, true},
+		{"Invalid address", &email.Message{To: []string{"not an address"}, Text: "body"}, true},
+		{"Valid", &email.Message{To: []string{"to@example.com"}, Text: "body"}, false},
+	}
+
+	server := newFakeSMTPServer(t)
+	client := newTestMailClient(t, server, "", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPermanentMailError(client.Send(tt.msg)); got != tt.permanent {
+				t.Errorf("Expected permanent %v, got %v", tt.permanent, got)
+			}
+		})
+	}
+	if IsPermanentMailError(context.DeadlineExceeded) {
+		t.Error("Expected timeouts not to be permanent")
+	}
+}
+
+func TestMailClient_Pool(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	client := newTestMailClient(t, server, "", "")
+	msg := &email.Message{To: []string{"to@example.com"}, Text: "body"}
+
+	for i := 0; i < 3; i++ {
+		if err := client.Send(msg); err != nil {
+			t.Fatalf("Send %d failed: %v", i, err)
+		}
+	}
+	if server.Connections() != 1 {
+		t.Errorf("Expected 1 pooled connection, got %d", server.Connections())
+	}
+
+	// A dropped idle connection is detected and replaced
+	server.DropConnections()
+	if err := client.Send(msg); err != nil {
+		t.Fatalf("Send after dropped connection failed: %v", err)
+	}
+	if server.Connections() != 2 || len(server.Messages()) != 4 {
+		t.Errorf("Expected 2 connections and 4 messages, got %d and %d", server.Connections(), len(server.Messages()))
+	}
+
+	noPool := newTestMailClient(t, server, "", "", WithMailPool(0, 0))
+	noPool.Send(msg)
+	noPool.Send(msg)
+	if server.Connections() != 4 {
+		t.Errorf("Expected a connection per message without pooling, got %d", server.Connections())
+	}
+}
+
+// TestMailClient_PoolConcurrency tests that the pool size limits concurrent
+// sends, and that sends without a pool run in parallel
+func TestMailClient_PoolConcurrency(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	msg := &email.Message{To: []string{"to@example.com"}, Text: "body"}
+
+	// Measure one send, connection setup included
+	server.SetDelay(20 * time.Millisecond)
+	start := time.Now()
+	if err := newTestMailClient(t, server, "", "", WithMailPool(0, 0)).Send(msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	single := time.Since(start)
+
+	sendAll := func(client *MailClient, n int) time.Duration {
+		start := time.Now()
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := client.Send(msg); err != nil {
+					t.Errorf("Send failed: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		return time.Since(start)
 	}
 
-	t.Log("Successfully sent email to MailHog")
-	t.Log("Check http://localhost:17008 to see the email")
+	if elapsed := sendAll(newTestMailClient(t, server, "", "", WithMailPool(0, 0)), 4); elapsed > 2*single {
+		t.Errorf("Expected 4 sends without a pool to run in parallel in about %v, took %v", single, elapsed)
+	}
+	if elapsed := sendAll(newTestMailClient(t, server, "", "", WithMailPool(1, time.Minute)), 3); elapsed < single*3/2 {
+		t.Errorf("Expected a pool of 1 to serialize 3 sends, took %v (one send %v)", elapsed, single)
+	}
+}
+
+func TestMailClient_Timeout(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	msg := &email.Message{To: []string{"to@example.com"}, Text: "body"}
+
+	client := newTestMailClient(t, server, "", "", WithMailTimeout(50*time.Millisecond))
+	server.SetDelay(100 * time.Millisecond)
+	if err := client.Send(msg); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if err := newTestMailClient(t, server, "", "").SendContext(ctx, msg); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestParseMailTLSMode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected MailTLSMode
+	}{
+		{"", MailTLSOpportunistic},
+		{"none", MailTLSNone},
+		{"STARTTLS", MailTLSStartTLS},
+		{"tls", MailTLSImplicit},
+	}
+	for _, tt := range tests {
+		if got, err := ParseMailTLSMode(tt.input); err != nil || got != tt.expected {
+			t.Errorf("Expected %v for %q, got %v (%v)", tt.expected, tt.input, got, err)
+		}
+	}
+	if _, err := ParseMailTLSMode("ssl3"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
 }
@@ -2,77 +2,513 @@ package clients
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"golang-gin/backoff"
+	"log"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
-// RabbitMQClient wraps RabbitMQ connection
+var (
+	// ErrPublishNacked is returned when the broker rejects a published
+	// message or the connection is lost before it is confirmed
+	ErrPublishNacked = errors.New("message was nacked by the broker")
+	// ErrUnroutable is returned when a mandatory message matched no queue
+	ErrUnroutable = errors.New("message was returned as unroutable")
+	// ErrClientClosed is returned when the client has been closed
+	ErrClientClosed = errors.New("rabbitmq client is closed")
+)
+
+// ExchangeSpec declares a durable exchange
+type ExchangeSpec struct {
+	Name string
+	Kind string // direct, fanout, topic or headers
+	Args map[string]interface{}
+}
+
+// QueueSpec declares a durable queue
+type QueueSpec struct {
+	Name string
+	Args map[string]interface{}
+}
+
+// BindingSpec binds a queue to an exchange with a routing key
+type BindingSpec struct {
+	Queue      string
+	Exchange   string
+	RoutingKey string
+	Args       map[string]interface{}
+}
+
+// Topology is the set of exchanges, queues and bindings the client declares
+// on connect and again after every reconnect
+type Topology struct {
+	Exchanges []ExchangeSpec
+	Queues    []QueueSpec
+	Bindings  []BindingSpec
+}
+
+// declare declares the topology on ch
+func (t Topology) declare(ch Channel) error {
+	for _, ex := range t.Exchanges {
+		if err := ch.ExchangeDeclare(ex.Name, ex.Kind, true, false, false, false, amqp.Table(ex.Args)); err != nil {
+			return fmt.Errorf("failed to declare exchange %s: %w", ex.Name, err)
+		}
+	}
+	for _, q := range t.Queues {
+		if _, err := ch.QueueDeclare(q.Name, true, false, false, false, amqp.Table(q.Args)); err != nil {
+			return fmt.Errorf("failed to declare queue %s: %w", q.Name, err)
+		}
+	}
+	for _, b := range t.Bindings {
+		if err := ch.QueueBind(b.Queue, b.RoutingKey, b.Exchange, false, amqp.Table(b.Args)); err != nil {
+			return fmt.Errorf("failed to bind queue %s to %s: %w", b.Queue, b.Exchange, err)
+		}
+	}
+	return nil
+}
+
+// merge adds the declarations of other to t. A spec for an exchange or
+// queue that t already declares replaces it and identical bindings are
+// kept once, so declaring the same topology repeatedly does not grow t.
+func (t Topology) merge(other Topology) Topology {
+	merged := Topology{
+		Exchanges: append([]ExchangeSpec{}, t.Exchanges...),
+		Queues:    append([]QueueSpec{}, t.Queues...),
+		Bindings:  append([]BindingSpec{}, t.Bindings...),
+	}
+
+exchanges:
+	for _, ex := range other.Exchanges {
+		for i := range merged.Exchanges {
+			if merged.Exchanges[i].Name == ex.Name {
+				merged.Exchanges[i] = ex
+				continue exchanges
+			}
+		}
+		merged.Exchanges = append(merged.Exchanges, ex)
+	}
+queues:
+	for _, q := range other.Queues {
+		for i := range merged.Queues {
+			if merged.Queues[i].Name == q.Name {
+				merged.Queues[i] = q
+				continue queues
+			}
+		}
+		merged.Queues = append(merged.Queues, q)
+	}
+bindings:
+	for _, b := range other.Bindings {
+		for i := range merged.Bindings {
+			if merged.Bindings[i].Queue == b.Queue && merged.Bindings[i].Exchange == b.Exchange && merged.Bindings[i].RoutingKey == b.RoutingKey {
+				merged.Bindings[i] = b
+				continue bindings
+			}
+		}
+		merged.Bindings = append(merged.Bindings, b)
+	}
+	return merged
+}
+
+// Message is a message published through RabbitMQClient
+type Message struct {
+	// ID is sent as the AMQP message-id. A random ID is generated if empty.
+	ID            string
+	Type          string
+	CorrelationID string
+	ContentType   string // defaults to application/json
+	Headers       map[string]interface{}
+	Body          []byte
+	Timestamp     time.Time // defaults to the time of publishing
+	// Transient messages are not persisted by the broker
+	Transient bool
+	// Mandatory messages fail with ErrUnroutable when no queue is bound
+	// for their routing key
+	Mandatory bool
+}
+
+// publishing converts m to an AMQP publishing
+func (m Message) publishing() amqp.Publishing {
+	p := amqp.Publishing{
+		MessageId:     m.ID,
+		Type:          m.Type,
+		CorrelationId: m.CorrelationID,
+		ContentType:   m.ContentType,
+		Headers:       amqp.Table(m.Headers),
+		Body:          m.Body,
+		Timestamp:     m.Timestamp,
+		DeliveryMode:  amqp.Persistent,
+	}
+	if p.ContentType == "" {
+		p.ContentType = "application/json"
+	}
+	if p.Timestamp.IsZero() {
+		p.Timestamp = time.Now().UTC()
+	}
+	if m.Transient {
+		p.DeliveryMode = amqp.Transient
+	}
+	return p
+}
+
+// RabbitMQOption configures a RabbitMQClient
+type RabbitMQOption func(*RabbitMQClient)
+
+// WithTopology declares topology on every connect
+func WithTopology(topology Topology) RabbitMQOption {
+	return func(c *RabbitMQClient) {
+		c.topology = c.topology.merge(topology)
+	}
+}
+
+// WithDialer replaces the AMQP dialer, e.g. with FakeBroker.Dial in tests
+func WithDialer(dial Dialer) RabbitMQOption {
+	return func(c *RabbitMQClient) {
+		c.dial = dial
+	}
+}
+
+// WithReconnectBackoff sets the delay before the first reconnect attempt and
+// the maximum delay it doubles up to
+func WithReconnectBackoff(min, max time.Duration) RabbitMQOption {
+	return func(c *RabbitMQClient) {
+		c.minBackoff = min
+		c.maxBackoff = max
+	}
+}
+
+// WithPublishTimeout bounds Publish calls whose context has no deadline,
+// including the time spent waiting for a reconnect
+func WithPublishTimeout(timeout time.Duration) RabbitMQOption {
+	return func(c *RabbitMQClient) {
+		c.publishTimeout = timeout
+	}
+}
+
+// RabbitMQClient is a RabbitMQ publisher that reconnects with exponential
+// backoff when the connection or channel is lost, redeclaring its topology.
+// Every publish waits for a publisher confirm. It is safe for concurrent use;
+// publishes are serialized so that returned messages can be attributed.
 type RabbitMQClient struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
+	url            string
+	dial           Dialer
+	topology       Topology
+	minBackoff     time.Duration
+	maxBackoff     time.Duration
+	publishTimeout time.Duration
+
+	mu      sync.Mutex // guards the fields below
+	conn    Connection
+	channel Channel
+	returns chan amqp.Return
+	ready   chan struct{} // closed while connected
+	closed  bool
+	done    chan struct{}
+
+	publishMu sync.Mutex
 }
 
-// NewRabbitMQClient creates a new RabbitMQ client
-func NewRabbitMQClient(url string) (*RabbitMQClient, error) {
-	conn, err := amqp.Dial(url)
+// NewRabbitMQClient connects to RabbitMQ and declares the configured
+// topology. If the broker cannot be reached, the client keeps retrying in the
+// background with the reconnect backoff, just as after a connection loss, and
+// callers wait for the connection. It only fails if url is invalid.
+func NewRabbitMQClient(url string, opts ...RabbitMQOption) (*RabbitMQClient, error) {
+	c := &RabbitMQClient{
+		url:            url,
+		dial:           DialAMQP,
+		minBackoff:     500 * time.Millisecond,
+		maxBackoff:     30 * time.Second,
+		publishTimeout: 5 * time.Second,
+		ready:          make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if _, err := amqp.ParseURI(url); err != nil {
+		return nil, fmt.Errorf("invalid RabbitMQ URL: %w", err)
+	}
+
+	if err := c.connect(); err != nil {
+		log.Printf("⚠️  %v; retrying in the background", err)
+		go c.reconnect()
+	}
+	return c, nil
+}
+
+// connect dials the broker, opens a confirming channel, declares the
+// topology and starts watching the connection
+func (c *RabbitMQClient) connect() error {
+	conn, err := c.dial(c.url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
 
 	ch, err := conn.Channel()
 	if err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to open channel: %w", err)
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	c.mu.Lock()
+	topology := c.topology
+	c.mu.Unlock()
+	if err := topology.declare(ch); err != nil {
+		conn.Close()
+		return err
 	}
 
-	return &RabbitMQClient{
-		conn:    conn,
-		channel: ch,
-	}, nil
+	// Returns are delivered synchronously before the matching confirm, so a
+	// buffered channel holds them by the time Publish sees the ack
+	returns := ch.NotifyReturn(make(chan amqp.Return, 16))
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		conn.Close()
+		return ErrClientClosed
+	}
+	c.conn, c.channel, c.returns = conn, ch, returns
+	close(c.ready)
+	c.mu.Unlock()
+
+	go c.watch(conn, ch, connClosed, chClosed)
+	return nil
+}
+
+// watch waits for the connection or channel to close and reconnects
+func (c *RabbitMQClient) watch(conn Connection, ch Channel, connClosed, chClosed chan *amqp.Error) {
+	var reason *amqp.Error
+	select {
+	case reason = <-connClosed:
+	case reason = <-chClosed:
+	case <-c.done:
+		return
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.detachLocked(ch)
+	c.mu.Unlock()
+
+	// A channel-level error leaves the connection open
+	conn.Close()
+
+	log.Printf("⚠️  RabbitMQ connection lost: %v; reconnecting", reason)
+	c.reconnect()
+}
+
+// reconnect retries connect with backoff until it succeeds or the client is
+// closed
+func (c *RabbitMQClient) reconnect() {
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-c.done:
+			return
+		case <-time.After(backoff.Exponential(attempt, c.minBackoff, c.maxBackoff)):
+		}
+
+		err := c.connect()
+		if err == nil {
+			log.Println("✅ Connected to RabbitMQ")
+			return
+		}
+		if errors.Is(err, ErrClientClosed) {
+			return
+		}
+		log.Printf("RabbitMQ connection attempt %d failed: %v", attempt, err)
+	}
 }
 
-// Close closes the RabbitMQ connection
+// detachLocked marks ch as unusable so that callers wait for the reconnect
+func (c *RabbitMQClient) detachLocked(ch Channel) {
+	if c.channel != nil && c.channel == ch {
+		c.channel = nil
+		c.ready = make(chan struct{})
+	}
+}
+
+// Close closes the RabbitMQ connection and stops reconnecting
 func (c *RabbitMQClient) Close() error {
-	if err := c.channel.Close(); err != nil {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	if err := conn.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
 		return err
 	}
-	return c.conn.Close()
+	return nil
 }
 
-// Publish publishes a message to a queue
-func (c *RabbitMQClient) Publish(queueName string, message []byte) error {
-	// Declare queue
-	q, err := c.channel.QueueDeclare(
-		queueName, // name
-		false,     // durable
-		false,     // delete when unused
-		false,     // exclusive
-		false,     // no-wait
-		nil,       // arguments
-	)
+// Declare declares additional topology now and, once that succeeded, after
+// every reconnect. The declaration runs on a channel of its own because a
+// failed one, e.g. PRECONDITION_FAILED for a queue that exists with other
+// arguments, closes the channel it ran on.
+func (c *RabbitMQClient) Declare(ctx context.Context, topology Topology) error {
+	ch, err := c.Channel(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to declare queue: %w", err)
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	err = c.channel.PublishWithContext(
-		ctx,
-		"",     // exchange
-		q.Name, // routing key
-		false,  // mandatory
-		false,  // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        message,
-		},
-	)
+		return err
+	}
+	defer ch.Close()
+
+	if err := topology.declare(ch); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.topology = c.topology.merge(topology)
+	c.mu.Unlock()
+	return nil
+}
+
+// currentChannel returns the open channel, waiting for a reconnect if needed
+func (c *RabbitMQClient) currentChannel(ctx context.Context) (Channel, chan amqp.Return, error) {
+	for {
+		c.mu.Lock()
+		closed, ready, ch, returns := c.closed, c.ready, c.channel, c.returns
+		c.mu.Unlock()
+
+		if closed {
+			return nil, nil, ErrClientClosed
+		}
+		if ch != nil {
+			return ch, returns, nil
+		}
+
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("waiting for RabbitMQ connection: %w", ctx.Err())
+		case <-c.done:
+			return nil, nil, ErrClientClosed
+		}
+	}
+}
+
+// Channel opens a new channel on the current connection, waiting for a
+// reconnect if needed. Consumers use their own channels so that prefetch
+// limits do not affect publishing.
+func (c *RabbitMQClient) Channel(ctx context.Context) (Channel, error) {
+	if _, _, err := c.currentChannel(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+
+	ch, err := conn.Channel()
 	if err != nil {
-		return fmt.Errorf("failed to publish message: %w", err)
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+	return ch, nil
+}
+
+// Publish publishes msg to exchange with routingKey and waits until the
+// broker confirms it. Mandatory messages that match no queue fail with
+// ErrUnroutable. While disconnected, Publish waits for the reconnect until
+// ctx or the publish timeout expires.
+func (c *RabbitMQClient) Publish(ctx context.Context, exchange, routingKey string, msg Message) error {
+	if _, ok := ctx.Deadline(); !ok && c.publishTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.publishTimeout)
+		defer cancel()
+	}
+
+	c.publishMu.Lock()
+	defer c.publishMu.Unlock()
+
+	if msg.ID == "" {
+		msg.ID = newMessageID()
+	}
+
+	var confirmation Confirmation
+	var returns chan amqp.Return
+	for confirmation == nil {
+		var ch Channel
+		var err error
+		ch, returns, err = c.currentChannel(ctx)
+		if err != nil {
+			return err
+		}
+		// Drop returns left over from publishes that timed out
+		drainReturns(returns, "")
+
+		confirmation, err = ch.Publish(ctx, exchange, routingKey, msg.Mandatory, msg.publishing())
+		if errors.Is(err, amqp.ErrClosed) {
+			// The connection dropped before watch noticed; nothing was sent
+			c.mu.Lock()
+			c.detachLocked(ch)
+			c.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to publish message: %w", err)
+		}
 	}
 
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to wait for publisher confirm: %w", err)
+	}
+	if !acked {
+		return ErrPublishNacked
+	}
+	if drainReturns(returns, msg.ID) {
+		return fmt.Errorf("%w: exchange %q, routing key %q", ErrUnroutable, exchange, routingKey)
+	}
 	return nil
 }
+
+// drainReturns empties the returns channel and reports whether it held a
+// return for messageID
+func drainReturns(returns chan amqp.Return, messageID string) bool {
+	found := false
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				return found
+			}
+			if messageID != "" && ret.MessageId == messageID {
+				found = true
+			}
+		default:
+			return found
+		}
+	}
+}
+
+// newMessageID returns a random 128-bit hex identifier
+func newMessageID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
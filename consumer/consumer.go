@@ -0,0 +1,426 @@
+// Package consumer runs RabbitMQ consumers with typed handlers, retries with
+// exponential backoff and a parking queue for poison messages
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang-gin/backoff"
+	"golang-gin/clients"
+	"log"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Headers used to carry retry state across the retry queues
+const (
+	HeaderRetryCount = "x-retry-count"
+	HeaderRoutingKey = "x-original-routing-key"
+	HeaderLastError  = "x-last-error"
+)
+
+// Delivery is a message received by a consumer
+type Delivery struct {
+	MessageID string
+	// RoutingKey is the key the message was originally published with, also
+	// after it has passed through a retry queue
+	RoutingKey  string
+	ContentType string
+	Headers     map[string]interface{}
+	Body        []byte
+	// Retries is the number of times the message has been retried
+	Retries     int
+	Redelivered bool
+}
+
+// Handler processes a delivery. Returning an error retries the message with
+// backoff, or parks it once the retries are used up or the error is
+// permanent.
+type Handler func(ctx context.Context, d Delivery) error
+
+// permanentError marks errors that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the message is parked without further retries
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Config configures a Consumer
+type Config struct {
+	// Queue is the name of the main queue. The retry queues are named
+	// <Queue>.retry.<n>.<delay in ms> and the parking queue <Queue>.parking.
+	Queue string
+	// Exchange is the topic exchange the queue is bound to for every routing
+	// key with a registered handler
+	Exchange string
+	// Prefetch is the number of unacknowledged messages delivered at once
+	Prefetch int
+	// Concurrency is the number of messages handled in parallel
+	Concurrency int
+	// MaxRetries is the number of retries before a message is parked. Zero
+	// or a negative value parks failed messages right away.
+	MaxRetries int
+	// RetryDelay is the delay before the first retry; it doubles with
+	// every further retry up to maxRetryDelay
+	RetryDelay time.Duration
+	// DrainTimeout is how long in-flight messages may take to finish on
+	// shutdown before their handlers are cancelled
+	DrainTimeout time.Duration
+}
+
+// publisher publishes messages to the broker
+type publisher interface {
+	Publish(ctx context.Context, exchange, routingKey string, msg clients.Message) error
+}
+
+// Consumer consumes one queue and dispatches messages by routing key
+type Consumer struct {
+	client   *clients.RabbitMQClient
+	cfg      Config
+	handlers map[string]Handler
+	publish  publisher
+}
+
+// New creates a new Consumer. Zero config values other than MaxRetries are
+// replaced by defaults: prefetch 10, concurrency 1, retries starting at 1s
+// and a 10s drain.
+func New(client *clients.RabbitMQClient, cfg Config) *Consumer {
+	if cfg.Prefetch <= 0 {
+		cfg.Prefetch = 10
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = time.Second
+	}
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = 10 * time.Second
+	}
+
+	c := &Consumer{
+		client:   client,
+		cfg:      cfg,
+		handlers: map[string]Handler{},
+	}
+	if client != nil {
+		c.publish = client
+	}
+	return c
+}
+
+// Handle registers h for messages published with routingKey. Handlers must
+// be registered before Run.
+func (c *Consumer) Handle(routingKey string, h Handler) {
+	c.handlers[routingKey] = h
+}
+
+// On registers a handler that receives the JSON body decoded into T.
+// Messages that cannot be decoded are parked.
+func On[T any](c *Consumer, routingKey string, fn func(ctx context.Context, msg T, d Delivery) error) {
+	c.Handle(routingKey, func(ctx context.Context, d Delivery) error {
+		var msg T
+		if err := json.Unmarshal(d.Body, &msg); err != nil {
+			return Permanent(fmt.Errorf("failed to decode %s message: %w", routingKey, err))
+		}
+		return fn(ctx, msg, d)
+	})
+}
+
+// retryQueue returns the name of the queue for the given retry. The name
+// includes the delay because the broker refuses to redeclare a queue with a
+// different TTL, so a changed RetryDelay declares new queues instead.
+func (c *Consumer) retryQueue(retry int) string {
+	return fmt.Sprintf("%s.retry.%d.%d", c.cfg.Queue, retry, c.retryDelay(retry).Milliseconds())
+}
+
+// parkingQueue returns the name of the queue for poison messages
+func (c *Consumer) parkingQueue() string {
+	return c.cfg.Queue + ".parking"
+}
+
+// maxRetryDelay caps the retry delay, well below the TTL limit of RabbitMQ
+const maxRetryDelay = 24 * time.Hour
+
+// retryDelay returns the delay before the given retry, doubling from
+// RetryDelay up to maxRetryDelay
+func (c *Consumer) retryDelay(retry int) time.Duration {
+	return backoff.Exponential(retry, c.cfg.RetryDelay, maxRetryDelay)
+}
+
+// Topology returns the queues and bindings the consumer needs. Each retry
+// has its own queue whose TTL sends messages back to the main queue through
+// the default exchange, so messages with different delays never block each
+// other. Messages rejected without requeueing go to the parking queue.
+func (c *Consumer) Topology() clients.Topology {
+	t := clients.Topology{
+		Queues: []clients.QueueSpec{
+			{Name: c.cfg.Queue, Args: map[string]interface{}{
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": c.parkingQueue(),
+			}},
+			{Name: c.parkingQueue()},
+		},
+	}
+
+	for retry := 1; retry <= c.cfg.MaxRetries; retry++ {
+		t.Queues = append(t.Queues, clients.QueueSpec{
+			Name: c.retryQueue(retry),
+			Args: map[string]interface{}{
+				"x-message-ttl":             c.retryDelay(retry).Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": c.cfg.Queue,
+			},
+		})
+	}
+
+	if c.cfg.Exchange != "" {
+		t.Exchanges = append(t.Exchanges, clients.ExchangeSpec{Name: c.cfg.Exchange, Kind: "topic"})
+		for routingKey := range c.handlers {
+			t.Bindings = append(t.Bindings, clients.BindingSpec{
+				Queue:      c.cfg.Queue,
+				Exchange:   c.cfg.Exchange,
+				RoutingKey: routingKey,
+			})
+		}
+	}
+	return t
+}
+
+// Run declares the topology and consumes until ctx is cancelled. It then
+// stops taking new messages and waits for in-flight ones to be handled
+// before returning. Until the broker is reachable Run waits for the client
+// to connect; failed declarations and lost connections are retried.
+func (c *Consumer) Run(ctx context.Context) error {
+	for {
+		err := c.client.Declare(ctx, c.Topology())
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("Consumer %s failed to declare its topology: %v; retrying", c.cfg.Queue, err)
+		if !sleep(ctx, resumeDelay) {
+			return nil
+		}
+	}
+
+	log.Printf("📥 Consumer started (queue: %s, prefetch: %d, concurrency: %d)", c.cfg.Queue, c.cfg.Prefetch, c.cfg.Concurrency)
+	for {
+		err := c.consume(ctx)
+		if ctx.Err() != nil {
+			log.Printf("📥 Consumer stopped (queue: %s)", c.cfg.Queue)
+			return nil
+		}
+		log.Printf("Consumer %s interrupted: %v; resuming", c.cfg.Queue, err)
+		if !sleep(ctx, resumeDelay) {
+			return nil
+		}
+	}
+}
+
+// resumeDelay is the pause before Run retries after an error
+const resumeDelay = time.Second
+
+// sleep waits for d and reports whether ctx is still active
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// consume opens a channel and handles deliveries until ctx is cancelled or
+// the channel is closed
+func (c *Consumer) consume(ctx context.Context) error {
+	ch, err := c.client.Channel(ctx)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	if err := ch.Qos(c.cfg.Prefetch, 0, false); err != nil {
+		return fmt.Errorf("failed to set prefetch: %w", err)
+	}
+
+	tag := "golang-gin." + c.cfg.Queue
+	deliveries, err := ch.Consume(c.cfg.Queue, tag, false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to consume %s: %w", c.cfg.Queue, err)
+	}
+
+	// Handlers outlive ctx so in-flight messages can finish during the drain
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range deliveries {
+				c.process(handlerCtx, d)
+			}
+		}()
+	}
+
+	workersDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(workersDone)
+	}()
+
+	select {
+	case <-workersDone:
+		return errors.New("delivery channel closed")
+	case <-ctx.Done():
+	}
+
+	// Stop new deliveries; prefetched messages that were not handled yet are
+	// requeued by the broker when the channel closes
+	if err := ch.Cancel(tag, false); err != nil {
+		log.Printf("Failed to cancel consumer %s: %v", tag, err)
+	}
+
+	select {
+	case <-workersDone:
+	case <-time.After(c.cfg.DrainTimeout):
+		log.Printf("Consumer %s drain timed out after %v, cancelling handlers", c.cfg.Queue, c.cfg.DrainTimeout)
+		cancelHandlers()
+		<-workersDone
+	}
+	return ctx.Err()
+}
+
+// process handles a single delivery and acknowledges it
+func (c *Consumer) process(ctx context.Context, d amqp.Delivery) {
+	delivery := newDelivery(d)
+
+	err := c.handle(ctx, delivery)
+	if err == nil {
+		if ackErr := d.Ack(false); ackErr != nil {
+			log.Printf("Failed to ack message %s: %v", delivery.MessageID, ackErr)
+		}
+		return
+	}
+
+	if IsPermanent(err) || delivery.Retries >= c.cfg.MaxRetries {
+		c.park(ctx, d, delivery, err)
+		return
+	}
+	c.retry(ctx, d, delivery, err)
+}
+
+// handle runs the handler registered for the delivery's routing key
+func (c *Consumer) handle(ctx context.Context, d Delivery) (err error) {
+	h, ok := c.handlers[d.RoutingKey]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler for routing key %q", d.RoutingKey))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return h(ctx, d)
+}
+
+// retry sends the message to the next retry queue
+func (c *Consumer) retry(ctx context.Context, d amqp.Delivery, delivery Delivery, cause error) {
+	next := delivery.Retries + 1
+	log.Printf("Message %s (%s) failed, retry %d/%d in %v: %v",
+		delivery.MessageID, delivery.RoutingKey, next, c.cfg.MaxRetries, c.retryDelay(next), cause)
+
+	if err := c.publish.Publish(ctx, "", c.retryQueue(next), republish(d, delivery, next, cause)); err != nil {
+		// Redeliver instead of losing the message
+		log.Printf("Failed to schedule retry of message %s: %v", delivery.MessageID, err)
+		d.Nack(false, true)
+		return
+	}
+	d.Ack(false)
+}
+
+// park moves the message to the parking queue
+func (c *Consumer) park(ctx context.Context, d amqp.Delivery, delivery Delivery, cause error) {
+	log.Printf("⚠️  Parking message %s (%s) after %d retries: %v",
+		delivery.MessageID, delivery.RoutingKey, delivery.Retries, cause)
+
+	if err := c.publish.Publish(ctx, "", c.parkingQueue(), republish(d, delivery, delivery.Retries, cause)); err != nil {
+		// Dead-lettering routes the message to the parking queue as well,
+		// only without the error headers
+		log.Printf("Failed to park message %s: %v", delivery.MessageID, err)
+		d.Nack(false, false)
+		return
+	}
+	d.Ack(false)
+}
+
+// republish copies a delivery into a message carrying the retry state
+func republish(d amqp.Delivery, delivery Delivery, retries int, cause error) clients.Message {
+	headers := map[string]interface{}{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[HeaderRetryCount] = int32(retries)
+	headers[HeaderRoutingKey] = delivery.RoutingKey
+	headers[HeaderLastError] = cause.Error()
+
+	return clients.Message{
+		ID:            d.MessageId,
+		Type:          d.Type,
+		CorrelationID: d.CorrelationId,
+		ContentType:   d.ContentType,
+		Headers:       headers,
+		Body:          d.Body,
+		Timestamp:     d.Timestamp,
+		Mandatory:     true,
+	}
+}
+
+// newDelivery converts an AMQP delivery, restoring the original routing key
+// and retry count of retried messages
+func newDelivery(d amqp.Delivery) Delivery {
+	delivery := Delivery{
+		MessageID:   d.MessageId,
+		RoutingKey:  d.RoutingKey,
+		ContentType: d.ContentType,
+		Headers:     d.Headers,
+		Body:        d.Body,
+		Redelivered: d.Redelivered,
+	}
+	if key, ok := d.Headers[HeaderRoutingKey].(string); ok && key != "" {
+		delivery.RoutingKey = key
+	}
+	switch n := d.Headers[HeaderRetryCount].(type) {
+	case int32:
+		delivery.Retries = int(n)
+	case int64:
+		delivery.Retries = int(n)
+	case int:
+		delivery.Retries = n
+	}
+	return delivery
+}
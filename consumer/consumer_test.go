@@ -0,0 +1,342 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"golang-gin/clients"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// fakeAcknowledger records how a delivery was settled
+type fakeAcknowledger struct {
+	acked, nacked, requeued bool
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.acked = true
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.nacked = true
+	a.requeued = requeue
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+// published is a message sent through fakePublisher
+type published struct {
+	routingKey string
+	msg        clients.Message
+}
+
+// fakePublisher records published messages and fails while err is set
+type fakePublisher struct {
+	messages []published
+	err      error
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, exchange, routingKey string, msg clients.Message) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.messages = append(p.messages, published{routingKey: routingKey, msg: msg})
+	return nil
+}
+
+type albumEvent struct {
+	Type string `json:"type"`
+}
+
+func newTestConsumer(handlerErr error) (*Consumer, *fakePublisher) {
+	c := New(nil, Config{Queue: "albums", Exchange: "events", MaxRetries: 2})
+	pub := &fakePublisher{}
+	c.publish = pub
+
+	On(c, "album.created", func(ctx context.Context, msg albumEvent, d Delivery) error {
+		if msg.Type != "album.created" {
+			return errors.New("unexpected payload")
+		}
+		return handlerErr
+	})
+	return c, pub
+}
+
+func TestConsumer_Process(t *testing.T) {
+	tests := []struct {
+		name       string
+		handlerErr error
+		routingKey string
+		body       string
+		headers    amqp.Table
+		publishErr error
+		wantQueue  string
+		wantRetry  int32
+		wantAck    bool
+		wantNack   bool
+		wantQueued bool
+	}{
+		{"Success", nil, "album.created", `{"type":"album.created"}`, nil, nil, "", 0, true, false, false},
+		{"Retry", errors.New("db down"), "album.created", `{"type":"album.created"}`, nil, nil, "albums.retry.1.1000", 1, true, false, false},
+		{"Second retry keeps original routing key", errors.New("db down"), "albums", `{"type":"album.created"}`,
+			amqp.Table{HeaderRetryCount: int32(1), HeaderRoutingKey: "album.created"}, nil, "albums.retry.2.2000", 2, true, false, false},
+		{"Retries exhausted", errors.New("db down"), "albums", `{"type":"album.created"}`,
+			amqp.Table{HeaderRetryCount: int32(2), HeaderRoutingKey: "album.created"}, nil, "albums.parking", 2, true, false, false},
+		{"Permanent error", Permanent(errors.New("invalid")), "album.created", `{"type":"album.created"}`, nil, nil, "albums.parking", 0, true, false, false},
+		{"Undecodable body", nil, "album.created", `not json`, nil, nil, "albums.parking", 0, true, false, false},
+		{"Unknown routing key", nil, "album.unknown", `{}`, nil, nil, "albums.parking", 0, true, false, false},
+		{"Retry publish fails", errors.New("db down"), "album.created", `{"type":"album.created"}`, nil, errors.New("broker down"), "", 0, false, true, true},
+		{"Park publish fails", Permanent(errors.New("invalid")), "album.created", `{"type":"album.created"}`, nil, errors.New("broker down"), "", 0, false, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, pub := newTestConsumer(tt.handlerErr)
+			pub.err = tt.publishErr
+			ack := &fakeAcknowledger{}
+
+			c.process(context.Background(), amqp.Delivery{
+				Acknowledger: ack,
+				MessageId:    "m1",
+				RoutingKey:   tt.routingKey,
+				Headers:      tt.headers,
+				Body:         []byte(tt.body),
+			})
+
+			if ack.acked != tt.wantAck || ack.nacked != tt.wantNack || ack.requeued != tt.wantQueued {
+				t.Errorf("Expected ack=%v nack=%v requeue=%v, got %+v", tt.wantAck, tt.wantNack, tt.wantQueued, ack)
+			}
+
+			if tt.wantQueue == "" {
+				if len(pub.messages) != 0 {
+					t.Errorf("Expected no republished messages, got %+v", pub.messages)
+				}
+				return
+			}
+			if len(pub.messages) != 1 {
+				t.Fatalf("Expected 1 republished message, got %d", len(pub.messages))
+			}
+			got := pub.messages[0]
+			if got.routingKey != tt.wantQueue {
+				t.Errorf("Expected message in %s, got %s", tt.wantQueue, got.routingKey)
+			}
+			if got.msg.ID != "m1" || got.msg.Headers[HeaderRetryCount] != tt.wantRetry {
+				t.Errorf("Expected message m1 with retry count %d, got %+v", tt.wantRetry, got.msg)
+			}
+			if key := got.msg.Headers[HeaderRoutingKey]; key != tt.routingKey && key != "album.created" {
+				t.Errorf("Expected original routing key header, got %v", key)
+			}
+		})
+	}
+}
+
+func TestConsumer_Topology(t *testing.T) {
+	c, _ := newTestConsumer(nil)
+	topology := c.Topology()
+
+	queues := map[string]map[string]interface{}{}
+	for _, q := range topology.Queues {
+		queues[q.Name] = q.Args
+	}
+
+	tests := []struct {
+		queue string
+		ttl   interface{}
+		dlrk  string
+	}{
+		{"albums", nil, "albums.parking"},
+		{"albums.retry.1.1000", time.Second.Milliseconds(), "albums"},
+		{"albums.retry.2.2000", (2 * time.Second).Milliseconds(), "albums"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.queue, func(t *testing.T) {
+			args, ok := queues[tt.queue]
+			if !ok {
+				t.Fatalf("Expected queue %s to be declared", tt.queue)
+			}
+			if args["x-message-ttl"] != tt.ttl || args["x-dead-letter-routing-key"] != tt.dlrk {
+				t.Errorf("Unexpected arguments for %s: %v", tt.queue, args)
+			}
+		})
+	}
+
+	if _, ok := queues["albums.parking"]; !ok {
+		t.Error("Expected parking queue to be declared")
+	}
+	if len(topology.Bindings) != 1 || topology.Bindings[0].RoutingKey != "album.created" {
+		t.Errorf("Expected binding for album.created, got %+v", topology.Bindings)
+	}
+}
+
+func TestConsumer_RetryDelay(t *testing.T) {
+	c := New(nil, Config{Queue: "albums", MaxRetries: 100, RetryDelay: time.Second})
+
+	tests := []struct {
+		retry    int
+		expected time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{10, 512 * time.Second},
+		{18, maxRetryDelay},
+		{100, maxRetryDelay},
+	}
+
+	for _, tt := range tests {
+		if got := c.retryDelay(tt.retry); got != tt.expected {
+			t.Errorf("retry %d: expected %v, got %v", tt.retry, tt.expected, got)
+		}
+	}
+}
+
+func TestNew_MaxRetries(t *testing.T) {
+	tests := []struct {
+		maxRetries int
+		expected   int
+	}{
+		{0, 0},
+		{-1, 0},
+		{3, 3},
+	}
+
+	for _, tt := range tests {
+		c := New(nil, Config{Queue: "albums", MaxRetries: tt.maxRetries})
+		if c.cfg.MaxRetries != tt.expected {
+			t.Errorf("MaxRetries %d: expected %d, got %d", tt.maxRetries, tt.expected, c.cfg.MaxRetries)
+		}
+		if len(c.Topology().Queues) != 2+tt.expected {
+			t.Errorf("MaxRetries %d: expected %d retry queues, got %d", tt.maxRetries, tt.expected, len(c.Topology().Queues)-2)
+		}
+	}
+}
+
+// waitFor polls cond until it holds or a second has passed
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestConsumer_Run runs a consumer against the fake broker and follows a
+// message through a retry and into the parking queue
+func TestConsumer_Run(t *testing.T) {
+	broker := clients.NewFakeBroker()
+	client, err := clients.NewRabbitMQClient("amqp://fake", clients.WithDialer(broker.Dial))
+	if err != nil {
+		t.Fatalf("Failed to connect to fake broker: %v", err)
+	}
+	defer client.Close()
+
+	c := New(client, Config{Queue: "albums", Exchange: "events", MaxRetries: 1, RetryDelay: time.Second})
+	var attempts atomic.Int32
+	On(c, "album.created", func(ctx context.Context, msg albumEvent, d Delivery) error {
+		if attempts.Add(1) == 1 {
+			return errors.New("db down")
+		}
+		return nil
+	})
+	On(c, "album.deleted", func(ctx context.Context, msg albumEvent, d Delivery) error {
+		return Permanent(errors.New("invalid"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// Declare up front so that publishing does not race with Run
+	if err := client.Declare(ctx, c.Topology()); err != nil {
+		t.Fatalf("Declare failed: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+
+	publish := func(routingKey string) {
+		t.Helper()
+		if err := client.Publish(ctx, "events", routingKey, clients.Message{ID: routingKey, Body: []byte(`{}`), Mandatory: true}); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+
+	// The first attempt fails and the message waits in the retry queue
+	publish("album.created")
+	waitFor(t, "retry", func() bool { return broker.QueueLen("albums.retry.1.1000") == 1 })
+	if attempts.Load() != 1 {
+		t.Fatalf("Expected 1 attempt before the retry delay, got %d", attempts.Load())
+	}
+
+	broker.Advance(time.Second)
+	waitFor(t, "second attempt", func() bool { return attempts.Load() == 2 })
+	waitFor(t, "ack", func() bool { return broker.QueueLen("albums") == 0 && broker.QueueLen("albums.retry.1.1000") == 0 })
+
+	// Permanent errors go straight to the parking queue
+	publish("album.deleted")
+	waitFor(t, "parking", func() bool { return broker.QueueLen("albums.parking") == 1 })
+	parked := broker.Messages("albums.parking")[0]
+	if parked.Headers[HeaderLastError] != "invalid" || parked.Headers[HeaderRoutingKey] != "album.deleted" {
+		t.Errorf("Expected error headers on parked message, got %v", parked.Headers)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected Run to stop cleanly, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run did not stop after cancel")
+	}
+}
+
+// TestConsumer_RunBeforeBrokerIsUp tests that a consumer started while the
+// broker is down declares its queues and consumes once it comes up
+func TestConsumer_RunBeforeBrokerIsUp(t *testing.T) {
+	broker := clients.NewFakeBroker()
+	broker.SetDown(true)
+	client, err := clients.NewRabbitMQClient("amqp://fake", clients.WithDialer(broker.Dial),
+		clients.WithReconnectBackoff(time.Millisecond, time.Millisecond),
+		clients.WithTopology(clients.Topology{Exchanges: []clients.ExchangeSpec{{Name: "events", Kind: "topic"}}}))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	c := New(client, Config{Queue: "albums", Exchange: "events"})
+	var handled atomic.Int32
+	On(c, "album.created", func(ctx context.Context, msg albumEvent, d Delivery) error {
+		handled.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+
+	time.Sleep(10 * time.Millisecond)
+	broker.SetDown(false)
+	// Publishes are unroutable until the consumer has bound its queue
+	waitFor(t, "queue declaration", func() bool {
+		return client.Publish(ctx, "events", "album.created", clients.Message{Body: []byte(`{}`), Mandatory: true}) == nil
+	})
+	waitFor(t, "handler", func() bool { return handled.Load() == 1 })
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected Run to stop cleanly, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run did not stop after cancel")
+	}
+}
@@ -7,41 +7,62 @@ import (
 	"time"
 
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
+// Supported storage drivers
+const (
+	DriverPostgres     = "postgres"
+	DriverSQLite       = "sqlite"
+	DriverSQLiteMemory = "sqlite-memory"
+)
+
 // Config holds database configuration
 type Config struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
+	Driver     string
+	Host       string
+	Port       string
+	User       string
+	Password   string
+	DBName     string
+	SQLitePath string
+
+	// LogLevel sets the SQL log level. The zero value logs every query.
+	LogLevel logger.LogLevel
 }
 
 // GetConfigFromEnv reads database configuration from environment variables
 func GetConfigFromEnv() *Config {
 	return &Config{
-		Host:     getEnv("POSTGRES_HOST", "localhost"),
-		Port:     getEnv("POSTGRES_PORT", "17004"),
-		User:     getEnv("POSTGRES_USER", "golang_gin_dev"),
-		Password: getEnv("POSTGRES_PASSWORD", "postgres_dev"),
-		DBName:   getEnv("POSTGRES_DB", "golang_gin_dev"),
+		Driver:     getEnv("DB_DRIVER", DriverPostgres),
+		Host:       getEnv("POSTGRES_HOST", "localhost"),
+		Port:       getEnv("POSTGRES_PORT", "17004"),
+		User:       getEnv("POSTGRES_USER", "golang_gin_dev"),
+		Password:   getEnv("POSTGRES_PASSWORD", "postgres_dev"),
+		DBName:     getEnv("POSTGRES_DB", "golang_gin_dev"),
+		SQLitePath: getEnv("SQLITE_PATH", "golang_gin_dev.db"),
 	}
 }
 
 // Connect establishes a database connection
 func Connect(cfg *Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=UTC",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName,
-	)
+	dialector, err := openDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	logLevel := cfg.LogLevel
+	if logLevel == 0 {
+		logLevel = logger.Info
+	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:         logger.Default.LogMode(logLevel),
+		TranslateError: true,
 		NowFunc: func() time.Time {
 			return time.Now().UTC()
 		},
@@ -56,15 +77,54 @@ func Connect(cfg *Config) (*gorm.DB, error) {
 	}
 
 	// Connection pool settings
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	switch cfg.Driver {
+	case DriverSQLite, DriverSQLiteMemory:
+		// SQLite allows a single writer, and every new connection to
+		// ":memory:" would open an empty database, so keep exactly one
+		// connection alive for the lifetime of the pool.
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+	default:
+		sqlDB.SetMaxIdleConns(10)
+		sqlDB.SetMaxOpenConns(100)
+		sqlDB.SetConnMaxLifetime(time.Hour)
+	}
 
 	DB = db
-	log.Println("✅ Database connection established")
+	log.Printf("✅ Database connection established (%s)", cfg.driverName())
 	return db, nil
 }
 
+// openDialector returns the GORM dialector for the configured driver
+func openDialector(cfg *Config) (gorm.Dialector, error) {
+	switch cfg.driverName() {
+	case DriverPostgres:
+		dsn := fmt.Sprintf(
+			"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=UTC",
+			cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName,
+		)
+		return postgres.Open(dsn), nil
+	case DriverSQLite:
+		if cfg.SQLitePath == "" {
+			return nil, fmt.Errorf("SQLITE_PATH is required for the %s driver", DriverSQLite)
+		}
+		return sqlite.Open(cfg.SQLitePath + "?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL"), nil
+	case DriverSQLiteMemory:
+		return sqlite.Open(":memory:?_foreign_keys=on"), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %q", cfg.Driver)
+	}
+}
+
+// driverName returns the configured driver, defaulting to postgres
+func (c *Config) driverName() string {
+	if c.Driver == "" {
+		return DriverPostgres
+	}
+	return c.Driver
+}
+
 // Close closes the database connection
 func Close() error {
 	if DB == nil {
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestConnect_SQLiteMemory(t *testing.T) {
+	db, err := Connect(&Config{Driver: DriverSQLiteMemory})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer Close()
+
+	var result int
+	if err := db.Raw("SELECT 1").Scan(&result).Error; err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if result != 1 {
+		t.Errorf("Expected 1, got %d", result)
+	}
+}
+
+func TestConnect_SQLiteFile(t *testing.T) {
+	path := t.TempDir() + "/test.db"
+
+	db, err := Connect(&Config{Driver: DriverSQLite, SQLitePath: path})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer Close()
+
+	if err := db.Exec("CREATE TABLE t (id INTEGER)").Error; err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+}
+
+func TestConnect_UnsupportedDriver(t *testing.T) {
+	if _, err := Connect(&Config{Driver: "oracle"}); err == nil {
+		t.Error("Expected error for unsupported driver")
+	}
+}
@@ -0,0 +1,439 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNoSender is returned when a message has no From address
+	ErrNoSender = errors.New("email has no sender")
+	// ErrNoRecipients is returned when a message has no To, Cc or Bcc
+	// addresses
+	ErrNoRecipients = errors.New("email has no recipients")
+	// ErrInvalidAddress is returned when an address cannot be parsed
+	ErrInvalidAddress = errors.New("invalid email address")
+	// ErrInvalidHeader is returned when an additional header has an invalid
+	// or reserved name, or a header value contains a line break
+	ErrInvalidHeader = errors.New("invalid email header")
+)
+
+// reservedHeaders are written from the Message fields and the MIME structure
+// and cannot be set through Headers
+var reservedHeaders = map[string]bool{
+	"From":                      true,
+	"Sender":                    true,
+	"To":                        true,
+	"Cc":                        true,
+	"Bcc":                       true,
+	"Reply-To":                  true,
+	"Subject":                   true,
+	"Date":                      true,
+	"Message-Id":                true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+	"Content-Disposition":       true,
+	"Content-Id":                true,
+}
+
+// Attachment is a file attached to a message. Attachments with a ContentID
+// are inline parts that the HTML body can reference as cid:<ContentID>.
+type Attachment struct {
+	Filename    string
+	ContentType string // detected from the file extension if empty
+	Data        []byte
+	ContentID   string
+}
+
+// inline reports whether the attachment is an inline part
+func (a Attachment) inline() bool {
+	return a.ContentID != ""
+}
+
+// Message is an email message. Addresses may include a display name, e.g.
+// "山田 太郎 <yamada@example.com>". Non-ASCII headers are RFC 2047 encoded.
+type Message struct {
+	From        string
+	To          []string
+	Cc          []string
+	Bcc         []string // envelope only, never written to the headers
+	ReplyTo     []string
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []Attachment
+	// Headers are additional header fields such as List-Unsubscribe. The
+	// headers written from the other fields are reserved.
+	Headers map[string]string
+	// Date defaults to the time the message is written
+	Date time.Time
+	// MessageID defaults to a random ID at the sender's domain
+	MessageID string
+}
+
+// Validate checks that the message has a sender, recipients, valid
+// addresses and headers that cannot inject further header fields
+func (m *Message) Validate() error {
+	if m.From == "" {
+		return ErrNoSender
+	}
+	if len(m.To)+len(m.Cc)+len(m.Bcc) == 0 {
+		return ErrNoRecipients
+	}
+	if _, err := m.Sender(); err != nil {
+		return err
+	}
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc, m.ReplyTo} {
+		if _, err := parseAddresses(list); err != nil {
+			return err
+		}
+	}
+	for name, value := range m.Headers {
+		if !validHeaderName(name) {
+			return fmt.Errorf("%w: invalid name %q", ErrInvalidHeader, name)
+		}
+		if reservedHeaders[textproto.CanonicalMIMEHeaderKey(name)] {
+			return fmt.Errorf("%w: %s is reserved", ErrInvalidHeader, name)
+		}
+		if hasLineBreak(value) {
+			return fmt.Errorf("%w: line break in %s", ErrInvalidHeader, name)
+		}
+	}
+	if hasLineBreak(m.MessageID) {
+		return fmt.Errorf("%w: line break in Message-ID", ErrInvalidHeader)
+	}
+	return nil
+}
+
+// Recipients returns the bare envelope addresses of all To, Cc and Bcc
+// recipients without duplicates
+func (m *Message) Recipients() ([]string, error) {
+	seen := map[string]bool{}
+	var recipients []string
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc} {
+		addresses, err := parseAddresses(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addresses {
+			key := strings.ToLower(a.Address)
+			if !seen[key] {
+				seen[key] = true
+				recipients = append(recipients, a.Address)
+			}
+		}
+	}
+	return recipients, nil
+}
+
+// Sender returns the bare envelope address of the sender
+func (m *Message) Sender() (string, error) {
+	from, err := netmail.ParseAddress(m.From)
+	if err != nil {
+		return "", fmt.Errorf("%w: From %q: %v", ErrInvalidAddress, m.From, err)
+	}
+	return from.Address, nil
+}
+
+// Bytes returns the message in RFC 5322 format
+func (m *Message) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteTo writes the message in RFC 5322 format. The body is a
+// multipart/alternative of the text and HTML parts, wrapped in
+// multipart/related for inline images and multipart/mixed for attachments
+// as needed.
+func (m *Message) WriteTo(w io.Writer) (int64, error) {
+	if err := m.Validate(); err != nil {
+		return 0, err
+	}
+
+	from, _ := netmail.ParseAddress(m.From)
+	date := m.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	messageID := m.MessageID
+	if messageID == "" {
+		messageID = NewMessageID(from.Address)
+	}
+
+	contentHeader, body, err := render(m.structure())
+	if err != nil {
+		return 0, err
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", from.String())
+	for _, field := range []struct {
+		name string
+		list []string
+	}{{"To", m.To}, {"Cc", m.Cc}, {"Reply-To", m.ReplyTo}} {
+		if len(field.list) == 0 {
+			continue
+		}
+		addresses, _ := parseAddresses(field.list)
+		writeHeader(&buf, field.name, formatAddresses(addresses))
+	}
+	writeHeader(&buf, "Subject", encodeHeader(m.Subject))
+	writeHeader(&buf, "Date", date.Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", "<"+strings.Trim(messageID, "<>")+">")
+	writeHeader(&buf, "MIME-Version", "1.0")
+	names := make([]string, 0, len(m.Headers))
+	for name := range m.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeHeader(&buf, textproto.CanonicalMIMEHeaderKey(name), encodeHeader(m.Headers[name]))
+	}
+	for _, name := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+		if value := contentHeader.Get(name); value != "" {
+			writeHeader(&buf, name, value)
+		}
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body)
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// part is a node of the MIME tree
+type part struct {
+	header   textproto.MIMEHeader
+	body     []byte
+	subtype  string // set for multipart nodes
+	children []part
+}
+
+// structure builds the MIME tree of the message
+func (m *Message) structure() part {
+	var inline, attached []Attachment
+	for _, a := range m.Attachments {
+		if a.inline() && m.HTML != "" {
+			inline = append(inline, a)
+		} else {
+			attached = append(attached, a)
+		}
+	}
+
+	var alternatives []part
+	if m.Text != "" || m.HTML == "" {
+		alternatives = append(alternatives, textPart("text/plain", m.Text))
+	}
+	if m.HTML != "" {
+		html := textPart("text/html", m.HTML)
+		if len(inline) > 0 {
+			related := part{subtype: "related", children: []part{html}}
+			for _, a := range inline {
+				related.children = append(related.children, attachmentPart(a))
+			}
+			html = related
+		}
+		alternatives = append(alternatives, html)
+	}
+
+	body := alternatives[0]
+	if len(alternatives) > 1 {
+		body = part{subtype: "alternative", children: alternatives}
+	}
+
+	if len(attached) == 0 {
+		return body
+	}
+	mixed := part{subtype: "mixed", children: []part{body}}
+	for _, a := range attached {
+		mixed.children = append(mixed.children, attachmentPart(a))
+	}
+	return mixed
+}
+
+// textPart returns a quoted-printable UTF-8 text part
+func textPart(contentType, text string) part {
+	var buf bytes.Buffer
+	qp := quotedprintable.NewWriter(&buf)
+	qp.Write([]byte(strings.ReplaceAll(text, "\r\n", "\n")))
+	qp.Close()
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType(contentType, map[string]string{"charset": "UTF-8"}))
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	return part{header: header, body: buf.Bytes()}
+}
+
+// attachmentPart returns a base64 encoded attachment or inline part
+func attachmentPart(a Attachment) part {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(extension(a.Filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	disposition := "attachment"
+	if a.inline() {
+		disposition = "inline"
+	}
+
+	header := textproto.MIMEHeader{}
+	params := map[string]string{}
+	if a.Filename != "" {
+		params["filename"] = a.Filename
+	}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, params))
+	header.Set("Content-Transfer-Encoding", "base64")
+	if a.inline() {
+		header.Set("Content-ID", "<"+strings.Trim(a.ContentID, "<>")+">")
+	}
+	return part{header: header, body: encodeBase64(a.Data)}
+}
+
+// render encodes a MIME tree and returns the content headers of its root
+func render(p part) (textproto.MIMEHeader, []byte, error) {
+	if p.subtype == "" {
+		return p.header, p.body, nil
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, child := range p.children {
+		header, body, err := render(child)
+		if err != nil {
+			return nil, nil, err
+		}
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := pw.Write(body); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType("multipart/"+p.subtype, map[string]string{"boundary": mw.Boundary()}))
+	return header, buf.Bytes(), nil
+}
+
+// encodeBase64 encodes data in lines of 76 characters
+func encodeBase64(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var buf bytes.Buffer
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76])
+		buf.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded)
+	return buf.Bytes()
+}
+
+// encodeHeader RFC 2047 encodes non-ASCII header values. Base64 is used as
+// it is much shorter than Q encoding for Japanese text.
+func encodeHeader(value string) string {
+	value = stripLineBreaks(value)
+	encoded := mime.BEncoding.Encode("UTF-8", value)
+	// Long values are split into several encoded words; fold between them
+	return strings.ReplaceAll(encoded, "?= =?", "?=\r\n =?")
+}
+
+// formatAddresses formats an address list, encoding display names
+func formatAddresses(addresses []*netmail.Address) string {
+	formatted := make([]string, len(addresses))
+	for i, a := range addresses {
+		formatted[i] = a.String()
+	}
+	return strings.Join(formatted, ",\r\n ")
+}
+
+// writeHeader writes a header field
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteString("\r\n")
+}
+
+// parseAddresses parses a list of addresses
+func parseAddresses(list []string) ([]*netmail.Address, error) {
+	addresses := make([]*netmail.Address, 0, len(list))
+	for _, s := range list {
+		a, err := netmail.ParseAddress(stripLineBreaks(s))
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidAddress, s, err)
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, nil
+}
+
+// stripLineBreaks removes CR and LF so that values cannot inject headers
+func stripLineBreaks(s string) string {
+	return strings.NewReplacer("\r", "", "\n", " ").Replace(s)
+}
+
+// hasLineBreak reports whether s contains CR or LF
+func hasLineBreak(s string) bool {
+	return strings.ContainsAny(s, "\r\n")
+}
+
+// validHeaderName reports whether name is a valid header field name
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r <= ' ' || r > '~' || r == ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// extension returns the file extension of name including the dot
+func extension(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return strings.ToLower(name[i:])
+	}
+	return ""
+}
+
+// NewMessageID returns a random Message-ID at the domain of address, which
+// may include a display name. Without an address localhost is used.
+func NewMessageID(address string) string {
+	if a, err := netmail.ParseAddress(address); err == nil {
+		address = a.Address
+	}
+	domain := "localhost"
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		domain = address[i+1:]
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b) + "@" + domain
+}
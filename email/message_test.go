@@ -0,0 +1,198 @@
+package email
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseMessage parses raw into its header and MIME tree, flattened as the
+// content types of all parts in order
+func parseMessage(t *testing.T, raw []byte) (*netmail.Message, []string, map[string][]byte) {
+	t.Helper()
+	msg, err := netmail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+
+	var types []string
+	bodies := map[string][]byte{}
+	var walk func(contentType, encoding string, body io.Reader)
+	walk = func(contentType, encoding string, body io.Reader) {
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			t.Fatalf("Invalid Content-Type %q: %v", contentType, err)
+		}
+		types = append(types, mediaType)
+		if strings.HasPrefix(mediaType, "multipart/") {
+			r := multipart.NewReader(body, params["boundary"])
+			for {
+				p, err := r.NextRawPart()
+				if err == io.EOF {
+					return
+				}
+				if err != nil {
+					t.Fatalf("Failed to read part: %v", err)
+				}
+				walk(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p)
+			}
+		}
+		if encoding == "quoted-printable" {
+			body = quotedprintable.NewReader(body)
+		}
+		data, _ := io.ReadAll(body)
+		bodies[mediaType] = data
+	}
+	walk(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	return msg, types, bodies
+}
+
+func TestMessage_Structure(t *testing.T) {
+	image := Attachment{Filename: "cover.png", Data: []byte("png"), ContentID: "cover"}
+	pdf := Attachment{Filename: "請求書.pdf", Data: []byte("pdf")}
+
+	tests := []struct {
+		name     string
+		msg      Message
+		expected []string
+	}{
+		{"Text only", Message{Text: "hello"}, []string{"text/plain"}},
+		{"HTML only", Message{HTML: "<p>hello</p>"}, []string{"text/html"}},
+		{"Alternative", Message{Text: "hello", HTML: "<p>hello</p>"},
+			[]string{"multipart/alternative", "text/plain", "text/html"}},
+		{"Inline image", Message{Text: "hello", HTML: `<img src="cid:cover">`, Attachments: []Attachment{image}},
+			[]string{"multipart/alternative", "text/plain", "multipart/related", "text/html", "image/png"}},
+		{"Attachment", Message{Text: "hello", Attachments: []Attachment{pdf}},
+			[]string{"multipart/mixed", "text/plain", "application/pdf"}},
+		{"Everything", Message{Text: "hello", HTML: `<img src="cid:cover">`, Attachments: []Attachment{image, pdf}},
+			[]string{"multipart/mixed", "multipart/alternative", "text/plain", "multipart/related", "text/html", "image/png", "application/pdf"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.msg.From = "noreply@example.com"
+			tt.msg.To = []string{"to@example.com"}
+			raw, err := tt.msg.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes failed: %v", err)
+			}
+
+			_, types, _ := parseMessage(t, raw)
+			if strings.Join(types, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected parts %v, got %v", tt.expected, types)
+			}
+		})
+	}
+}
+
+func TestMessage_Headers(t *testing.T) {
+	msg := Message{
+		From:    "アルバム管理 <noreply@example.com>",
+		To:      []string{"山田 太郎 <yamada@example.com>", "suzuki@example.com"},
+		Cc:      []string{"cc@example.com"},
+		Bcc:     []string{"hidden@example.com", "SUZUKI@example.com"},
+		ReplyTo: []string{"support@example.com"},
+		Subject: "【新着アルバム】ブルー・トレイン / ジョン・コルトレーン（リマスター版）が登録されました",
+		Text:    "本文です。\n二行目",
+		HTML:    "<p>本文です。</p>",
+		Headers: map[string]string{"List-Unsubscribe": "<mailto:unsubscribe@example.com>"},
+		Date:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	raw, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		if len(line) > 998 {
+			t.Errorf("Line exceeds 998 characters: %q", line)
+		}
+	}
+
+	parsed, _, bodies := parseMessage(t, raw)
+
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Expected subject %q, got %q (%v)", msg.Subject, subject, err)
+	}
+
+	to, err := parsed.Header.AddressList("To")
+	if err != nil || len(to) != 2 || to[0].Name != "山田 太郎" || to[1].Address != "suzuki@example.com" {
+		t.Errorf("Unexpected To header: %v (%v)", to, err)
+	}
+	if parsed.Header.Get("Bcc") != "" {
+		t.Error("Expected no Bcc header")
+	}
+	if got := parsed.Header.Get("Date"); got != "Tue, 02 Jan 2024 03:04:05 +0000" {
+		t.Errorf("Unexpected Date header %q", got)
+	}
+	if id := parsed.Header.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Unexpected Message-ID %q", id)
+	}
+	for _, name := range []string{"Cc", "Reply-To", "List-Unsubscribe", "MIME-Version"} {
+		if parsed.Header.Get(name) == "" {
+			t.Errorf("Expected %s header", name)
+		}
+	}
+
+	if text := string(bodies["text/plain"]); text != "本文です。\r\n二行目" {
+		t.Errorf("Unexpected text body %q", text)
+	}
+
+	recipients, _ := msg.Recipients()
+	if strings.Join(recipients, ",") != "yamada@example.com,suzuki@example.com,cc@example.com,hidden@example.com" {
+		t.Errorf("Unexpected recipients %v", recipients)
+	}
+}
+
+func TestMessage_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     Message
+		wantErr error
+	}{
+		{"Valid", Message{From: "a@example.com", Bcc: []string{"b@example.com"}}, nil},
+		{"No sender", Message{To: []string{"b@example.com"}}, ErrNoSender},
+		{"No recipients", Message{From: "a@example.com"}, ErrNoRecipients},
+		{"Invalid sender", Message{From: "not an address", To: []string{"b@example.com"}}, ErrInvalidAddress},
+		{"Invalid recipient", Message{From: "a@example.com", To: []string{"not an address"}}, ErrInvalidAddress},
+		{"Invalid header name", Message{From: "a@example.com", To: []string{"b@example.com"}, Headers: map[string]string{"Bad Header": "x"}}, ErrInvalidHeader},
+		{"Reserved header", Message{From: "a@example.com", To: []string{"b@example.com"}, Headers: map[string]string{"bcc": "c@example.com"}}, ErrInvalidHeader},
+		{"Reserved content header", Message{From: "a@example.com", To: []string{"b@example.com"}, Headers: map[string]string{"Content-Type": "text/html"}}, ErrInvalidHeader},
+		{"Line break in header", Message{From: "a@example.com", To: []string{"b@example.com"}, Headers: map[string]string{"X-Campaign": "a\r\nBcc: c@example.com"}}, ErrInvalidHeader},
+		{"Line break in Message-ID", Message{From: "a@example.com", To: []string{"b@example.com"}, MessageID: "id@example.com\nBcc: c@example.com"}, ErrInvalidHeader},
+		{"Additional header", Message{From: "a@example.com", To: []string{"b@example.com"}, Headers: map[string]string{"List-Unsubscribe": "<mailto:u@example.com>"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.msg.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestMessage_HeaderInjection(t *testing.T) {
+	msg := Message{
+		From:    "a@example.com",
+		To:      []string{"b@example.com"},
+		Subject: "hello\r\nBcc: victim@example.com",
+	}
+	raw, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	parsed, _, _ := parseMessage(t, raw)
+	if parsed.Header.Get("Bcc") != "" {
+		t.Error("Expected line breaks in the subject to be removed")
+	}
+}
@@ -0,0 +1,259 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// ErrTemplateNotFound is returned when no locale has the requested template
+var ErrTemplateNotFound = errors.New("email template not found")
+
+//go:embed templates
+var embedded embed.FS
+
+// Rendered is the output of a template
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string // empty if the template has no HTML version
+}
+
+// TemplateOption configures Templates
+type TemplateOption func(*Templates)
+
+// WithDefaultLocale sets the locale used when a template does not exist in
+// the requested locale (default: ja)
+func WithDefaultLocale(locale string) TemplateOption {
+	return func(t *Templates) {
+		t.defaultLocale = normalizeLocale(locale)
+	}
+}
+
+// WithLayout sets the layout the templates are rendered in (default: base)
+func WithLayout(name string) TemplateOption {
+	return func(t *Templates) {
+		t.layout = name
+	}
+}
+
+// Templates renders localized emails. The file system is laid out as
+//
+//	layouts/base.txt, layouts/base.html   layouts wrapping {{template "content" .}}
+//	<locale>/messages.json                strings for the t function
+//	<locale>/<name>.txt                   defines "subject" and "content"
+//	<locale>/<name>.html                  defines "content" (optional)
+//
+// Templates missing in a locale fall back to the language without region
+// (ja-JP → ja) and then to the default locale. The t function looks up
+// messages.json of the same chain, so layouts can be shared by all locales.
+type Templates struct {
+	defaultLocale string
+	layout        string
+	locales       map[string]map[string]*templateSet
+}
+
+// templateSet holds the parsed text and HTML versions of one template
+type templateSet struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// DefaultTemplates returns the templates bundled with the application
+func DefaultTemplates(opts ...TemplateOption) (*Templates, error) {
+	fsys, err := fs.Sub(embedded, "templates")
+	if err != nil {
+		return nil, err
+	}
+	return NewTemplates(fsys, opts...)
+}
+
+// NewTemplates parses all templates in fsys
+func NewTemplates(fsys fs.FS, opts ...TemplateOption) (*Templates, error) {
+	t := &Templates{
+		defaultLocale: "ja",
+		layout:        "base",
+		locales:       map[string]map[string]*templateSet{},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email templates: %w", err)
+	}
+
+	catalogs := map[string]map[string]string{}
+	var locales []string
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == "layouts" {
+			continue
+		}
+		locale := normalizeLocale(entry.Name())
+		catalog, err := loadCatalog(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		catalogs[locale] = catalog
+		locales = append(locales, entry.Name())
+	}
+
+	for _, dir := range locales {
+		locale := normalizeLocale(dir)
+		funcs := t.funcs(locale, catalogs)
+		sets, err := t.parseLocale(fsys, dir, funcs)
+		if err != nil {
+			return nil, err
+		}
+		t.locales[locale] = sets
+	}
+	return t, nil
+}
+
+// loadCatalog reads the messages.json of a locale, if any
+func loadCatalog(fsys fs.FS, dir string) (map[string]string, error) {
+	data, err := fs.ReadFile(fsys, path.Join(dir, "messages.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := map[string]string{}
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("invalid %s/messages.json: %w", dir, err)
+	}
+	return catalog, nil
+}
+
+// funcs returns the template functions bound to a locale
+func (t *Templates) funcs(locale string, catalogs map[string]map[string]string) map[string]interface{} {
+	chain := t.fallbacks(locale)
+	return map[string]interface{}{
+		"locale": func() string { return locale },
+		// t translates a key, formatting args into the message if given
+		"t": func(key string, args ...interface{}) string {
+			for _, l := range chain {
+				if msg, ok := catalogs[l][key]; ok {
+					if len(args) > 0 {
+						return fmt.Sprintf(msg, args...)
+					}
+					return msg
+				}
+			}
+			return key
+		},
+	}
+}
+
+// parseLocale parses every template of a locale together with the layouts
+func (t *Templates) parseLocale(fsys fs.FS, dir string, funcs map[string]interface{}) (map[string]*templateSet, error) {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	layoutText := path.Join("layouts", t.layout+".txt")
+	layoutHTML := path.Join("layouts", t.layout+".html")
+
+	sets := map[string]*templateSet{}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || path.Ext(name) != ".txt" {
+			continue
+		}
+		base := strings.TrimSuffix(name, ".txt")
+
+		text, err := texttemplate.New(path.Base(layoutText)).Funcs(funcs).ParseFS(fsys, layoutText, path.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s/%s: %w", dir, name, err)
+		}
+		if text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("email template %s/%s does not define a subject", dir, name)
+		}
+		set := &templateSet{text: text}
+
+		htmlFile := path.Join(dir, base+".html")
+		if _, err := fs.Stat(fsys, htmlFile); err == nil {
+			set.html, err = htmltemplate.New(path.Base(layoutHTML)).Funcs(funcs).ParseFS(fsys, layoutHTML, htmlFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse email template %s: %w", htmlFile, err)
+			}
+		}
+		sets[base] = set
+	}
+	return sets, nil
+}
+
+// fallbacks returns the locales tried for locale, most specific first
+func (t *Templates) fallbacks(locale string) []string {
+	locale = normalizeLocale(locale)
+	chain := []string{locale}
+	if i := strings.Index(locale, "-"); i > 0 {
+		chain = append(chain, locale[:i])
+	}
+	if chain[len(chain)-1] != t.defaultLocale {
+		chain = append(chain, t.defaultLocale)
+	}
+	return chain
+}
+
+// Render renders the named template in locale
+func (t *Templates) Render(name, locale string, data interface{}) (*Rendered, error) {
+	var set *templateSet
+	for _, l := range t.fallbacks(locale) {
+		if s, ok := t.locales[l][name]; ok {
+			set = s
+			break
+		}
+	}
+	if set == nil {
+		return nil, fmt.Errorf("%w: %s (%s)", ErrTemplateNotFound, name, locale)
+	}
+
+	var subject, text bytes.Buffer
+	if err := set.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render subject of %s: %w", name, err)
+	}
+	if err := set.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+
+	rendered := &Rendered{
+		// Subjects are a single header line
+		Subject: strings.TrimSpace(strings.ReplaceAll(subject.String(), "\n", " ")),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+	}
+	if set.html != nil {
+		var html bytes.Buffer
+		if err := set.html.Execute(&html, data); err != nil {
+			return nil, fmt.Errorf("failed to render HTML of %s: %w", name, err)
+		}
+		rendered.HTML = html.String()
+	}
+	return rendered, nil
+}
+
+// Message renders the named template into a message. The caller sets the
+// sender and recipients.
+func (t *Templates) Message(name, locale string, data interface{}) (*Message, error) {
+	rendered, err := t.Render(name, locale, data)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Subject: rendered.Subject, Text: rendered.Text, HTML: rendered.HTML}, nil
+}
+
+// normalizeLocale lowercases a locale and uses - as the separator
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
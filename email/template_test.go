@@ -0,0 +1,101 @@
+package email
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type testAlbum struct {
+	Title  string
+	Artist string
+	Price  float64
+}
+
+func TestTemplates_Render(t *testing.T) {
+	templates, err := DefaultTemplates()
+	if err != nil {
+		t.Fatalf("DefaultTemplates failed: %v", err)
+	}
+	data := map[string]interface{}{
+		"Album": testAlbum{Title: "Blue <Train>", Artist: "John Coltrane", Price: 56.99},
+		"URL":   "http://localhost:8080/albums/1",
+	}
+
+	tests := []struct {
+		locale  string
+		subject string
+		text    string
+		footer  string
+	}{
+		{"ja", "【新着アルバム】Blue <Train> / John Coltrane", "価格: $56.99", "自動送信"},
+		{"en", "New album: Blue <Train> by John Coltrane", "Price: $56.99", "sent automatically"},
+		{"en-US", "New album: Blue <Train> by John Coltrane", "Price: $56.99", "sent automatically"},
+		{"fr", "【新着アルバム】Blue <Train> / John Coltrane", "価格: $56.99", "自動送信"},
+		{"", "【新着アルバム】Blue <Train> / John Coltrane", "価格: $56.99", "自動送信"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			rendered, err := templates.Render("album_created", tt.locale, data)
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if rendered.Subject != tt.subject {
+				t.Errorf("Expected subject %q, got %q", tt.subject, rendered.Subject)
+			}
+			if !strings.Contains(rendered.Text, tt.text) || !strings.Contains(rendered.Text, tt.footer) {
+				t.Errorf("Unexpected text body %q", rendered.Text)
+			}
+			if !strings.Contains(rendered.HTML, "Blue &lt;Train&gt;") || !strings.Contains(rendered.HTML, tt.footer) {
+				t.Errorf("Expected escaped HTML with layout, got %q", rendered.HTML)
+			}
+		})
+	}
+
+	if _, err := templates.Render("unknown", "ja", data); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Expected ErrTemplateNotFound, got %v", err)
+	}
+}
+
+func TestTemplates_Fallback(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.txt":     {Data: []byte(`{{template "content" .}} - {{t "sign"}}`)},
+		"en/messages.json":     {Data: []byte(`{"sign": "Bye", "hello": "Hello %s"}`)},
+		"en/welcome.txt":       {Data: []byte(`{{define "subject"}}{{t "hello" .}}{{end}}{{define "content"}}{{t "hello" .}}{{end}}`)},
+		"en-gb/messages.json":  {Data: []byte(`{"hello": "Hiya %s"}`)},
+		"en-gb/welcome.txt":    {Data: []byte(`{{define "subject"}}{{t "hello" .}}{{end}}{{define "content"}}{{t "hello" .}}!{{end}}`)},
+		"en-gb/only_plain.txt": {Data: []byte(`{{define "subject"}}Plain{{end}}{{define "content"}}{{t "missing"}}{{end}}`)},
+	}
+
+	templates, err := NewTemplates(fsys, WithDefaultLocale("en"))
+	if err != nil {
+		t.Fatalf("NewTemplates failed: %v", err)
+	}
+
+	tests := []struct {
+		name, locale string
+		subject      string
+		text         string
+	}{
+		{"welcome", "en", "Hello Ann", "Hello Ann - Bye\n"},
+		{"welcome", "en_GB", "Hiya Ann", "Hiya Ann! - Bye\n"},
+		{"welcome", "ja", "Hello Ann", "Hello Ann - Bye\n"},
+		{"only_plain", "en-GB", "Plain", "missing - Bye\n"},
+	}
+
+	for _, tt := range tests {
+		rendered, err := templates.Render(tt.name, tt.locale, "Ann")
+		if err != nil {
+			t.Fatalf("Render %s/%s failed: %v", tt.locale, tt.name, err)
+		}
+		if rendered.Subject != tt.subject || rendered.Text != tt.text || rendered.HTML != "" {
+			t.Errorf("Expected %q/%q for %s/%s, got %+v", tt.subject, tt.text, tt.locale, tt.name, rendered)
+		}
+	}
+
+	if _, err := templates.Render("only_plain", "en", nil); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Expected only_plain to be missing in en, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+// Package events defines the domain events published for album changes
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"golang-gin/audit"
+	"golang-gin/models"
+	"time"
+)
+
+// SchemaVersion is the version of the AlbumEvent JSON schema. Fields may be
+// added within a version; renames and removals bump it.
+const SchemaVersion = 1
+
+// Album event types, also used as routing keys
+const (
+	AlbumCreated = "album.created"
+	AlbumUpdated = "album.updated"
+	AlbumDeleted = "album.deleted"
+)
+
+// AlbumData is the album state carried by an event
+type AlbumData struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	Artist    string    `json:"artist"`
+	Price     float64   `json:"price"`
+	Tax       float32   `json:"tax"`
+	Version   uint      `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AlbumEvent is the JSON envelope of an album event. Album holds the state
+// after the change, or the last state before it for deletes. Consumers should
+// ignore events with a lower album version than one already applied, since
+// events for the same album may arrive out of order after a retry.
+type AlbumEvent struct {
+	SchemaVersion int       `json:"schema_version"`
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	OccurredAt    time.Time `json:"occurred_at"`
+	Actor         string    `json:"actor,omitempty"`
+	ActorVerified bool      `json:"actor_verified,omitempty"`
+	RequestID     string    `json:"request_id,omitempty"`
+	Source        string    `json:"source,omitempty"`
+	Album         AlbumData `json:"album"`
+}
+
+// AlbumEventType returns the event type published for an audit action. A
+// restore is published as an update, so consumers should upsert on updates.
+// Purges are not published: only albums that are already deleted are purged.
+func AlbumEventType(action string) (string, bool) {
+	switch action {
+	case models.AuditActionCreate:
+		return AlbumCreated, true
+	case models.AuditActionUpdate, models.AuditActionRestore:
+		return AlbumUpdated, true
+	case models.AuditActionDelete:
+		return AlbumDeleted, true
+	default:
+		return "", false
+	}
+}
+
+// NewAlbumEvent creates an event of the given type for album
+func NewAlbumEvent(eventType string, album *models.Album, md audit.Metadata, occurredAt time.Time) *AlbumEvent {
+	return &AlbumEvent{
+		SchemaVersion: SchemaVersion,
+		ID:            newEventID(),
+		Type:          eventType,
+		OccurredAt:    occurredAt,
+		Actor:         md.Actor,
+		ActorVerified: md.ActorVerified,
+		RequestID:     md.RequestID,
+		Source:        string(md.Source),
+		Album: AlbumData{
+			ID:        album.ID,
+			Title:     album.Title,
+			Artist:    album.Artist,
+			Price:     album.Price,
+			Tax:       album.Tax,
+			Version:   album.Version,
+			UpdatedAt: album.UpdatedAt,
+		},
+	}
+}
+
+// newEventID returns a random 128-bit hex identifier
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
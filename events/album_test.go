@@ -0,0 +1,59 @@
+package events
+
+import (
+	"encoding/json"
+	"golang-gin/audit"
+	"golang-gin/models"
+	"testing"
+	"time"
+)
+
+func TestAlbumEventType(t *testing.T) {
+	tests := []struct {
+		action    string
+		eventType string
+		published bool
+	}{
+		{models.AuditActionCreate, AlbumCreated, true},
+		{models.AuditActionUpdate, AlbumUpdated, true},
+		{models.AuditActionRestore, AlbumUpdated, true},
+		{models.AuditActionDelete, AlbumDeleted, true},
+		{models.AuditActionPurge, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			eventType, published := AlbumEventType(tt.action)
+			if eventType != tt.eventType || published != tt.published {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tt.eventType, tt.published, eventType, published)
+			}
+		})
+	}
+}
+
+func TestNewAlbumEvent(t *testing.T) {
+	album := &models.Album{ID: 1, Title: "Hammerhead", Artist: "THE OFFSPRING", Price: 25.05, Tax: 0.1, Version: 2}
+	md := audit.Metadata{Actor: "alice", RequestID: "req-1", Source: audit.SourceREST}
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	event := NewAlbumEvent(AlbumUpdated, album, md, at)
+	if len(event.ID) != 32 {
+		t.Errorf("Expected 32 character event ID, got %q", event.ID)
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded["schema_version"] != float64(SchemaVersion) || decoded["type"] != AlbumUpdated || decoded["actor"] != "alice" {
+		t.Errorf("Unexpected event envelope: %s", data)
+	}
+	if albumData, ok := decoded["album"].(map[string]interface{}); !ok || albumData["version"] != float64(2) {
+		t.Errorf("Expected album version 2 in payload: %s", data)
+	}
+}
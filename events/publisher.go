@@ -0,0 +1,33 @@
+package events
+
+import (
+	"context"
+	"golang-gin/clients"
+	"golang-gin/models"
+)
+
+// RabbitMQPublisher publishes outbox messages to a RabbitMQ exchange
+type RabbitMQPublisher struct {
+	client   *clients.RabbitMQClient
+	exchange string
+}
+
+// NewRabbitMQPublisher creates a new RabbitMQPublisher for exchange
+func NewRabbitMQPublisher(client *clients.RabbitMQClient, exchange string) *RabbitMQPublisher {
+	return &RabbitMQPublisher{client: client, exchange: exchange}
+}
+
+// Publish publishes msg with its routing key and returns once the broker has
+// confirmed it. The event ID is used as the message ID so consumers can
+// deduplicate redeliveries. Events that no queue is bound for fail with
+// clients.ErrUnroutable and are retried by the relay.
+func (p *RabbitMQPublisher) Publish(ctx context.Context, msg *models.OutboxMessage) error {
+	return p.client.Publish(ctx, p.exchange, msg.RoutingKey, clients.Message{
+		ID:        msg.EventID,
+		Type:      msg.RoutingKey,
+		Timestamp: msg.CreatedAt,
+		Headers:   map[string]interface{}{"schema_version": int32(SchemaVersion)},
+		Body:      []byte(msg.Payload),
+		Mandatory: true,
+	})
+}
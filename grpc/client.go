@@ -64,12 +64,11 @@ func (c *Client) GetAlbumByID(id string) (*pb.Album, error) {
 }
 
 // CreateAlbum creates a new album via gRPC
-func (c *Client) CreateAlbum(id, title, artist string, price float64, tax float32) (*pb.Album, error) {
+func (c *Client) CreateAlbum(title, artist string, price float64, tax float32) (*pb.Album, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	resp, err := c.client.CreateAlbum(ctx, &pb.CreateAlbumRequest{
-		Id:     id,
 		Title:  title,
 		Artist: artist,
 		Price:  price,
@@ -82,6 +81,40 @@ func (c *Client) CreateAlbum(id, title, artist string, price float64, tax float3
 	return resp, nil
 }
 
+// UpdateAlbum updates an album via gRPC. Pass the etag from a previous read to
+// fail instead of overwriting concurrent changes, or "" to update unconditionally.
+func (c *Client) UpdateAlbum(id, title, artist string, price float64, tax float32, etag string) (*pb.Album, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := c.client.UpdateAlbum(ctx, &pb.UpdateAlbumRequest{
+		Id:     id,
+		Title:  title,
+		Artist: artist,
+		Price:  price,
+		Tax:    tax,
+		Etag:   etag,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update album: %w", err)
+	}
+
+	return resp, nil
+}
+
+// GetAlbumHistory retrieves the change history of an album via gRPC
+func (c *Client) GetAlbumHistory(id string) (*pb.GetAlbumHistoryResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := c.client.GetAlbumHistory(ctx, &pb.GetAlbumHistoryRequest{Id: id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get album history: %w", err)
+	}
+
+	return resp, nil
+}
+
 // Example demonstrates how to use the gRPC client
 func Example() {
 	client, err := NewClient("localhost:50051")
@@ -105,9 +138,16 @@ func Example() {
 	log.Printf("Album: %v", album)
 
 	// Create album
-	newAlbum, err := client.CreateAlbum("4", "New Album", "Artist", 29.99, 0.1)
+	newAlbum, err := client.CreateAlbum("New Album", "Artist", 29.99, 0.1)
 	if err != nil {
 		log.Fatalf("Failed to create album: %v", err)
 	}
 	log.Printf("Created album: %v", newAlbum)
+
+	// Update album, guarded by the etag returned on creation
+	updated, err := client.UpdateAlbum(newAlbum.Id, "New Album (Remastered)", "Artist", 31.99, 0.1, newAlbum.Etag)
+	if err != nil {
+		log.Fatalf("Failed to update album: %v", err)
+	}
+	log.Printf("Updated album: %v", updated)
 }
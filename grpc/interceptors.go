@@ -0,0 +1,32 @@
+package grpc
+
+import (
+	"context"
+	"golang-gin/audit"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// AuditUnaryInterceptor attaches audit metadata from the x-actor and
+// x-request-id request metadata to the handler context. The actor is not
+// authenticated and is recorded as unverified.
+func AuditUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md := audit.Metadata{Source: audit.SourceGRPC}
+		if incoming, ok := metadata.FromIncomingContext(ctx); ok {
+			md.Actor = firstValue(incoming, "x-actor")
+			md.RequestID = firstValue(incoming, "x-request-id")
+		}
+
+		return handler(audit.WithMetadata(ctx, md), req)
+	}
+}
+
+// firstValue returns the first value for key in md, or ""
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -2,42 +2,68 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"golang-gin/idempotency"
 	"golang-gin/models"
 	"golang-gin/repository"
+	"log"
+	"sort"
 	"strconv"
 
 	pb "golang-gin/grpc/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"gorm.io/gorm"
 )
 
 // Server implements the AlbumService gRPC server
 type Server struct {
 	pb.UnimplementedAlbumServiceServer
-	repo repository.AlbumRepository
+	repo        repository.AlbumRepository
+	audits      repository.AuditRepository
+	idempotency *idempotency.Service
+}
+
+// ServerOption configures a Server
+type ServerOption func(*Server)
+
+// WithAuditRepository enables GetAlbumHistory using the given audit trail
+func WithAuditRepository(audits repository.AuditRepository) ServerOption {
+	return func(s *Server) {
+		s.audits = audits
+	}
+}
+
+// WithIdempotency makes CreateAlbum requests with a request_id idempotent
+func WithIdempotency(svc *idempotency.Service) ServerOption {
+	return func(s *Server) {
+		s.idempotency = svc
+	}
 }
 
 // NewServer creates a new gRPC server instance
-func NewServer(repo repository.AlbumRepository) *Server {
-	return &Server{repo: repo}
+func NewServer(repo repository.AlbumRepository, opts ...ServerOption) *Server {
+	s := &Server{repo: repo}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // GetAlbums returns all albums
 func (s *Server) GetAlbums(ctx context.Context, req *pb.GetAlbumsRequest) (*pb.GetAlbumsResponse, error) {
-	albums, err := s.repo.FindAll()
+	albums, err := s.repo.FindAll(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	var pbAlbums []*pb.Album
 	for _, a := range albums {
-		pbAlbums = append(pbAlbums, &pb.Album{
-			Id:     fmt.Sprintf("%d", a.ID),
-			Title:  a.Title,
-			Artist: a.Artist,
-			Price:  a.Price,
-			Tax:    a.Tax,
-		})
+		pbAlbums = append(pbAlbums, toPBAlbum(&a))
 	}
 	return &pb.GetAlbumsResponse{Albums: pbAlbums}, nil
 }
@@ -49,7 +75,7 @@ func (s *Server) GetAlbumByID(ctx context.Context, req *pb.GetAlbumByIDRequest)
 		return nil, fmt.Errorf("invalid album ID: %w", err)
 	}
 
-	album, err := s.repo.FindByID(uint(id))
+	album, err := s.repo.FindByID(ctx, uint(id))
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -57,33 +83,215 @@ func (s *Server) GetAlbumByID(ctx context.Context, req *pb.GetAlbumByIDRequest)
 		return nil, err
 	}
 
-	return &pb.Album{
-		Id:     fmt.Sprintf("%d", album.ID),
-		Title:  album.Title,
-		Artist: album.Artist,
-		Price:  album.Price,
-		Tax:    album.Tax,
-	}, nil
+	return toPBAlbum(album), nil
 }
 
-// CreateAlbum creates a new album
+// CreateAlbum creates a new album. Requests that repeat a request_id return
+// the originally created album when idempotency is enabled.
 func (s *Server) CreateAlbum(ctx context.Context, req *pb.CreateAlbumRequest) (*pb.Album, error) {
+	if s.idempotency == nil || req.RequestId == "" {
+		return s.createAlbum(ctx, req)
+	}
+
+	const scope = "grpc:CreateAlbum"
+	fingerprintReq := proto.Clone(req).(*pb.CreateAlbumRequest)
+	fingerprintReq.RequestId = ""
+	payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(fingerprintReq)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	claim, replay, err := s.idempotency.Begin(ctx, scope, req.RequestId, idempotency.Fingerprint(payload))
+	switch {
+	case errors.Is(err, idempotency.ErrFingerprintMismatch):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, idempotency.ErrInFlight):
+		return nil, status.Error(codes.Aborted, err.Error())
+	case err != nil:
+		return nil, toStatusError(err)
+	case replay != nil:
+		album := &pb.Album{}
+		if err := protojson.Unmarshal(replay.Body, album); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		return album, nil
+	}
+
+	album, err := s.createAlbum(ctx, req)
+	if err != nil {
+		if releaseErr := s.idempotency.Release(ctx, claim); releaseErr != nil {
+			log.Printf("Failed to release idempotency key %q: %v", req.RequestId, releaseErr)
+		}
+		return nil, err
+	}
+
+	body, err := protojson.Marshal(album)
+	if err == nil {
+		err = s.idempotency.Complete(ctx, claim, idempotency.Response{
+			StatusCode:  int(codes.OK),
+			ContentType: "application/json",
+			Body:        body,
+		})
+	}
+	if err != nil {
+		log.Printf("Failed to store idempotent response for request_id %q: %v", req.RequestId, err)
+	}
+
+	return album, nil
+}
+
+// createAlbum creates a new album without idempotency checks
+func (s *Server) createAlbum(ctx context.Context, req *pb.CreateAlbumRequest) (*pb.Album, error) {
 	albumModel := &models.Album{
 		Title:  req.Title,
 		Artist: req.Artist,
 		Price:  req.Price,
 		Tax:    req.Tax,
 	}
+	if err := albumModel.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 
-	if err := s.repo.Create(albumModel); err != nil {
-		return nil, err
+	if err := s.repo.Create(ctx, albumModel); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return toPBAlbum(albumModel), nil
+}
+
+// UpdateAlbum updates an existing album. When req.Etag is set the update is
+// rejected with FailedPrecondition if the album has changed since it was read.
+func (s *Server) UpdateAlbum(ctx context.Context, req *pb.UpdateAlbumRequest) (*pb.Album, error) {
+	id, err := strconv.ParseUint(req.Id, 10, 32)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid album ID: %v", err)
+	}
+
+	album, err := s.repo.FindByID(ctx, uint(id))
+	if err != nil {
+		return nil, toStatusError(err)
 	}
 
+	if req.Etag != "" && req.Etag != album.ETag() {
+		return nil, status.Error(codes.FailedPrecondition, "album has been modified")
+	}
+
+	album.Title = req.Title
+	album.Artist = req.Artist
+	album.Price = req.Price
+	album.Tax = req.Tax
+	if err := album.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.repo.Update(ctx, album); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return toPBAlbum(album), nil
+}
+
+// GetAlbumHistory returns the change history of an album and, when as_of is
+// set, the album as it was at that time
+func (s *Server) GetAlbumHistory(ctx context.Context, req *pb.GetAlbumHistoryRequest) (*pb.GetAlbumHistoryResponse, error) {
+	if s.audits == nil {
+		return nil, status.Error(codes.Unimplemented, "album history is not enabled")
+	}
+
+	id, err := strconv.ParseUint(req.Id, 10, 32)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid album ID: %v", err)
+	}
+
+	entries, err := s.audits.FindByAlbumID(ctx, uint(id))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	if len(entries) == 0 {
+		return nil, status.Error(codes.NotFound, "album history not found")
+	}
+
+	resp := &pb.GetAlbumHistoryResponse{}
+	for _, entry := range entries {
+		resp.Entries = append(resp.Entries, toPBAuditEntry(&entry))
+	}
+
+	if req.AsOf != nil {
+		album, err := s.audits.FindAlbumAsOf(ctx, uint(id), req.AsOf.AsTime())
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, toStatusError(err)
+		}
+		if album != nil {
+			resp.Album = toPBAlbum(album)
+		}
+	}
+
+	return resp, nil
+}
+
+// toPBAlbum converts an album model to its protobuf representation
+func toPBAlbum(album *models.Album) *pb.Album {
 	return &pb.Album{
-		Id:     fmt.Sprintf("%d", albumModel.ID),
-		Title:  albumModel.Title,
-		Artist: albumModel.Artist,
-		Price:  albumModel.Price,
-		Tax:    albumModel.Tax,
-	}, nil
+		Id:     fmt.Sprintf("%d", album.ID),
+		Title:  album.Title,
+		Artist: album.Artist,
+		Price:  album.Price,
+		Tax:    album.Tax,
+		Etag:   album.ETag(),
+	}
+}
+
+// toPBAuditEntry converts an audit entry to its protobuf representation
+func toPBAuditEntry(entry *models.AlbumAudit) *pb.AlbumAuditEntry {
+	fields := make([]string, 0, len(entry.Changes))
+	for field := range entry.Changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	changes := make([]*pb.FieldChange, 0, len(fields))
+	for _, field := range fields {
+		change := entry.Changes[field]
+		changes = append(changes, &pb.FieldChange{
+			Field: field,
+			From:  formatValue(change.From),
+			To:    formatValue(change.To),
+		})
+	}
+
+	return &pb.AlbumAuditEntry{
+		Id:        fmt.Sprintf("%d", entry.ID),
+		Action:    entry.Action,
+		Actor:     entry.Actor,
+		RequestId: entry.RequestID,
+		Source:    entry.Source,
+		Changes:   changes,
+		CreatedAt: timestamppb.New(entry.CreatedAt),
+	}
+}
+
+// formatValue renders an audited field value, using "" for absent values
+func formatValue(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// toStatusError maps repository errors to gRPC status errors
+func toStatusError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Error(codes.NotFound, "album not found")
+	case errors.Is(err, repository.ErrVersionConflict):
+		return status.Error(codes.FailedPrecondition, "album has been modified")
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return status.Error(codes.AlreadyExists, "album already exists")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
 }
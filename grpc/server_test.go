@@ -2,10 +2,17 @@ package grpc
 
 import (
 	"context"
+	"golang-gin/idempotency"
+	"golang-gin/models"
 	"golang-gin/repository"
 	"testing"
+	"time"
 
 	pb "golang-gin/grpc/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestServer_GetAlbums(t *testing.T) {
@@ -103,4 +110,110 @@ func TestServer_CreateAlbum(t *testing.T) {
 	}
 
 	t.Logf("Created album: %+v", resp)
+
+	if _, err := server.CreateAlbum(ctx, req); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Expected AlreadyExists for a duplicate album, got %v", err)
+	}
+}
+
+func TestServer_GetAlbums_CanceledContext(t *testing.T) {
+	mockRepo := repository.NewMockAlbumRepository()
+	server := NewServer(mockRepo)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := server.GetAlbums(ctx, &pb.GetAlbumsRequest{}); err == nil {
+		t.Error("Expected error for canceled context")
+	}
+}
+
+func TestServer_UpdateAlbum(t *testing.T) {
+	mockRepo := repository.NewMockAlbumRepository()
+	server := NewServer(mockRepo)
+	ctx := context.Background()
+
+	current, err := server.GetAlbumByID(ctx, &pb.GetAlbumByIDRequest{Id: "1"})
+	if err != nil {
+		t.Fatalf("GetAlbumByID failed: %v", err)
+	}
+
+	req := &pb.UpdateAlbumRequest{Id: "1", Title: "Updated", Artist: "Artist", Price: 10, Tax: 0.1, Etag: current.Etag}
+	updated, err := server.UpdateAlbum(ctx, req)
+	if err != nil {
+		t.Fatalf("UpdateAlbum failed: %v", err)
+	}
+	if updated.Etag == current.Etag {
+		t.Error("Expected etag to change after update")
+	}
+
+	// Reusing the old etag must fail
+	if _, err := server.UpdateAlbum(ctx, req); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition, got %v", err)
+	}
+
+	if _, err := server.UpdateAlbum(ctx, &pb.UpdateAlbumRequest{Id: "999"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+}
+
+func TestServer_GetAlbumHistory(t *testing.T) {
+	audits := repository.NewMockAuditRepository()
+	server := NewServer(repository.NewMockAlbumRepository(), WithAuditRepository(audits))
+	ctx := context.Background()
+
+	if _, err := server.GetAlbumHistory(ctx, &pb.GetAlbumHistoryRequest{Id: "1"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound without history, got %v", err)
+	}
+
+	audits.Record(ctx, &models.AlbumAudit{
+		AlbumID: 1, Action: models.AuditActionUpdate, Actor: "alice", Source: "grpc",
+		Changes:  map[string]models.FieldChange{"price": {From: 10.0, To: 12.5}},
+		Snapshot: &models.Album{ID: 1, Title: "Hammerhead", Price: 12.5},
+	})
+
+	resp, err := server.GetAlbumHistory(ctx, &pb.GetAlbumHistoryRequest{Id: "1", AsOf: timestamppb.Now()})
+	if err != nil {
+		t.Fatalf("GetAlbumHistory failed: %v", err)
+	}
+	if len(resp.Entries) != 1 || resp.Entries[0].Changes[0].To != "12.5" {
+		t.Errorf("Unexpected history: %+v", resp.Entries)
+	}
+	if resp.Album == nil || resp.Album.Price != 12.5 {
+		t.Errorf("Expected reconstructed album, got %+v", resp.Album)
+	}
+
+	unconfigured := NewServer(repository.NewMockAlbumRepository())
+	if _, err := unconfigured.GetAlbumHistory(ctx, &pb.GetAlbumHistoryRequest{Id: "1"}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("Expected Unimplemented, got %v", err)
+	}
+}
+
+func TestServer_CreateAlbum_Idempotent(t *testing.T) {
+	mockRepo := repository.NewMockAlbumRepository()
+	svc := idempotency.NewService(repository.NewMockIdempotencyRepository(), time.Hour)
+	server := NewServer(mockRepo, WithIdempotency(svc))
+	ctx := context.Background()
+
+	req := &pb.CreateAlbumRequest{Title: "Retry", Artist: "Artist", Price: 10, Tax: 0.1, RequestId: "req-1"}
+	first, err := server.CreateAlbum(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateAlbum failed: %v", err)
+	}
+	second, err := server.CreateAlbum(ctx, req)
+	if err != nil {
+		t.Fatalf("Retried CreateAlbum failed: %v", err)
+	}
+	if second.Id != first.Id {
+		t.Errorf("Expected replayed album %s, got %s", first.Id, second.Id)
+	}
+
+	albums, _ := mockRepo.FindAll(ctx)
+	if len(albums) != 4 {
+		t.Errorf("Expected exactly one album to be created, got %d albums", len(albums))
+	}
+
+	changed := &pb.CreateAlbumRequest{Title: "Other", Artist: "Artist", Price: 10, Tax: 0.1, RequestId: "req-1"}
+	if _, err := server.CreateAlbum(ctx, changed); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for reused request_id, got %v", err)
+	}
 }
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"golang-gin/models"
+	"golang-gin/products"
 	"golang-gin/repository"
+	"golang-gin/users"
 	"net/http"
 	"strconv"
 
@@ -12,59 +15,325 @@ import (
 
 // AlbumHandler handles album-related requests
 type AlbumHandler struct {
-	repo repository.AlbumRepository
+	repo           repository.AlbumRepository
+	uow            repository.UnitOfWork
+	requireIfMatch bool
+	cacheControl   string
+	includes       map[string]AlbumInclude
+	users          users.UserService
+	products       products.ProductService
+	productRepo    repository.ProductRepository
+}
+
+// AlbumHandlerOption configures an AlbumHandler
+type AlbumHandlerOption func(*AlbumHandler)
+
+// WithIfMatchRequired makes writes without an If-Match header fail with 428
+func WithIfMatchRequired() AlbumHandlerOption {
+	return func(h *AlbumHandler) {
+		h.requireIfMatch = true
+	}
+}
+
+// WithUnitOfWork enables atomic batch requests
+func WithUnitOfWork(uow repository.UnitOfWork) AlbumHandlerOption {
+	return func(h *AlbumHandler) {
+		h.uow = uow
+	}
+}
+
+// WithCacheControl sets the Cache-Control header sent on album reads
+func WithCacheControl(value string) AlbumHandlerOption {
+	return func(h *AlbumHandler) {
+		h.cacheControl = value
+	}
 }
 
 // NewAlbumHandler creates a new AlbumHandler
-func NewAlbumHandler(repo repository.AlbumRepository) *AlbumHandler {
-	return &AlbumHandler{repo: repo}
+func NewAlbumHandler(repo repository.AlbumRepository, opts ...AlbumHandlerOption) *AlbumHandler {
+	h := &AlbumHandler{
+		repo:         repo,
+		cacheControl: DefaultCacheControl,
+		includes:     map[string]AlbumInclude{"tax_breakdown": TaxBreakdownInclude()},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
-// GetAlbums returns all albums
+// GetAlbums returns all albums. ?fields= and ?include= select the returned
+// fields and embedded sub-resources. If-Modified-Since is answered with 304
+// before the albums are loaded.
 func (h *AlbumHandler) GetAlbums(c *gin.Context) {
-	albums, err := h.repo.FindAll()
+	q, ok := h.parseAlbumQuery(c)
+	if !ok {
+		return
+	}
+
+	lastModified, err := h.repo.LastModified(c.Request.Context())
 	if err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch albums"})
+		writeResponse(c, http.StatusInternalServerError, gin.H{"error": "Failed to fetch albums"})
 		return
 	}
-	c.IndentedJSON(http.StatusOK, albums)
+	h.setCacheHeaders(c, lastModified)
+	if notModifiedSince(c, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	var opts []repository.FindOption
+	if q != nil {
+		opts = q.findOptions(h.includes)
+	}
+
+	albums, err := h.repo.FindAll(c.Request.Context(), opts...)
+	if err != nil {
+		writeResponse(c, http.StatusInternalServerError, gin.H{"error": "Failed to fetch albums"})
+		return
+	}
+
+	if q != nil {
+		writeResponse(c, http.StatusOK, h.albumResources(c.Request.Context(), albums, q))
+		return
+	}
+	writeResponse(c, http.StatusOK, albums)
 }
 
 // GetAlbumByID returns a specific album by ID
 func (h *AlbumHandler) GetAlbumByID(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+	id, ok := parseAlbumID(c)
+	if !ok {
 		return
 	}
 
-	album, err := h.repo.FindByID(uint(id))
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.IndentedJSON(http.StatusNotFound, gin.H{"message": "album not found"})
-			return
-		}
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch album"})
+	q, ok := h.parseAlbumQuery(c)
+	if !ok {
+		return
+	}
+
+	var opts []repository.FindOption
+	if q != nil {
+		opts = q.findOptions(h.includes)
+	}
+
+	album, ok := h.findAlbum(c, id, opts...)
+	if !ok {
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, album)
+	etag := representationETag(c, album)
+	c.Header("ETag", etag)
+	h.setCacheHeaders(c, album.UpdatedAt)
+	if inm := c.GetHeader("If-None-Match"); inm != "" && matchETag(inm, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	if notModifiedSince(c, album.UpdatedAt) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	if q != nil {
+		resources := h.albumResources(c.Request.Context(), []models.Album{*album}, q)
+		writeResponse(c, http.StatusOK, resources[0])
+		return
+	}
+	writeResponse(c, http.StatusOK, album)
 }
 
 // PostAlbums adds a new album
 func (h *AlbumHandler) PostAlbums(c *gin.Context) {
 	var newAlbum models.Album
 
-	if err := c.BindJSON(&newAlbum); err != nil {
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := bindBody(c, &newAlbum); err != nil {
+		writeBindError(c, err)
 		return
 	}
 
-	if err := h.repo.Create(&newAlbum); err != nil {
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create album"})
+	if err := newAlbum.Validate(); err != nil {
+		writeResponse(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.IndentedJSON(http.StatusCreated, newAlbum)
+	ctx := c.Request.Context()
+	product, err := h.checkLinks(ctx, &newAlbum, nil)
+	if err != nil {
+		status, message := linkStatus(err)
+		writeResponse(c, status, gin.H{"error": message})
+		return
+	}
+	h.defaultOwner(ctx, &newAlbum)
+
+	if err := h.repo.Create(ctx, &newAlbum); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			writeResponse(c, http.StatusConflict, gin.H{"error": "album already exists"})
+			return
+		}
+		writeResponse(c, http.StatusInternalServerError, gin.H{"error": "Failed to create album"})
+		return
+	}
+	h.storeProduct(ctx, product)
+
+	c.Header("ETag", representationETag(c, &newAlbum))
+	writeResponse(c, http.StatusCreated, newAlbum)
+}
+
+// UpdateAlbum replaces an existing album, honoring If-Match preconditions
+func (h *AlbumHandler) UpdateAlbum(c *gin.Context) {
+	id, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	var input models.Album
+	if err := bindBody(c, &input); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	if err := input.Validate(); err != nil {
+		writeResponse(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, ok := h.findAlbum(c, id)
+	if !ok || !h.checkIfMatch(c, current) {
+		return
+	}
+	product, err := h.checkLinks(c.Request.Context(), &input, current)
+	if err != nil {
+		status, message := linkStatus(err)
+		writeResponse(c, status, gin.H{"error": message})
+		return
+	}
+
+	applyInput(current, &input)
+
+	if err := h.repo.Update(c.Request.Context(), current); err != nil {
+		h.writeUpdateError(c, err)
+		return
+	}
+	h.storeProduct(c.Request.Context(), product)
+
+	c.Header("ETag", representationETag(c, current))
+	writeResponse(c, http.StatusOK, current)
+}
+
+// applyInput copies the fields written by PUT from input to current. An
+// owner_id or product_id that is absent from input keeps the current link.
+func applyInput(current, input *models.Album) {
+	current.Title = input.Title
+	current.Artist = input.Artist
+	current.Price = input.Price
+	current.Tax = input.Tax
+	if input.OwnerID != nil {
+		current.OwnerID = input.OwnerID
+	}
+	if input.ProductID != nil {
+		current.ProductID = input.ProductID
+	}
+}
+
+// DeleteAlbum soft deletes an album, honoring If-Match preconditions
+func (h *AlbumHandler) DeleteAlbum(c *gin.Context) {
+	id, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	current, ok := h.findAlbum(c, id)
+	if !ok || !h.checkIfMatch(c, current) {
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), current.ID, repository.IfVersion(current.Version)); err != nil {
+		h.writeUpdateError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// findAlbum loads an album and writes the error response if it fails
+func (h *AlbumHandler) findAlbum(c *gin.Context, id uint, opts ...repository.FindOption) (*models.Album, bool) {
+	album, err := h.repo.FindByID(c.Request.Context(), id, opts...)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeResponse(c, http.StatusNotFound, gin.H{"message": "album not found"})
+			return nil, false
+		}
+		writeResponse(c, http.StatusInternalServerError, gin.H{"error": "Failed to fetch album"})
+		return nil, false
+	}
+	return album, true
+}
+
+// checkIfMatch validates the If-Match header against the current album
+func (h *AlbumHandler) checkIfMatch(c *gin.Context, current *models.Album) bool {
+	status, message := h.preconditionStatus(c.GetHeader("If-Match"), current)
+	if status == 0 {
+		return true
+	}
+
+	if status == http.StatusPreconditionFailed {
+		c.Header("ETag", representationETag(c, current))
+	}
+	writeResponse(c, status, gin.H{"error": message})
+	return false
+}
+
+// preconditionStatus checks an If-Match value against the current album. It
+// returns 0 when the write may proceed, and the error status otherwise.
+func (h *AlbumHandler) preconditionStatus(ifMatch string, current *models.Album) (int, string) {
+	if ifMatch == "" {
+		if h.requireIfMatch {
+			return http.StatusPreconditionRequired, "If-Match header is required"
+		}
+		return 0, ""
+	}
+
+	if !matchIfMatch(ifMatch, current.Version) {
+		return http.StatusPreconditionFailed, "album has been modified"
+	}
+	return 0, ""
+}
+
+// writeUpdateError maps repository write errors to HTTP responses
+func (h *AlbumHandler) writeUpdateError(c *gin.Context, err error) {
+	status, message := writeErrorStatus(err, c.GetHeader("If-Match") != "")
+	if status == http.StatusNotFound {
+		writeResponse(c, status, gin.H{"message": message})
+		return
+	}
+	writeResponse(c, status, gin.H{"error": message})
+}
+
+// writeErrorStatus maps a repository write error to an HTTP status and
+// message. A version conflict is a failed precondition when the client sent
+// If-Match, and a plain conflict otherwise.
+func writeErrorStatus(err error, ifMatchSent bool) (int, string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound, "album not found"
+	case errors.Is(err, repository.ErrVersionConflict):
+		if ifMatchSent {
+			return http.StatusPreconditionFailed, "album has been modified"
+		}
+		return http.StatusConflict, "album has been modified"
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return http.StatusConflict, "album already exists"
+	default:
+		return http.StatusInternalServerError, "Failed to save album"
+	}
+}
+
+// parseAlbumID reads the :id path parameter and writes a 400 if it is invalid
+func parseAlbumID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		writeResponse(c, http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return 0, false
+	}
+	return uint(id), true
 }
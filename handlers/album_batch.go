@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxBatchOperations limits the number of operations in one batch request
+const maxBatchOperations = 100
+
+// Batch operation methods
+const (
+	batchCreate = "create"
+	batchUpdate = "update"
+	batchDelete = "delete"
+)
+
+// errBatchFailed rolls back an atomic batch after a failed operation
+var errBatchFailed = errors.New("batch operation failed")
+
+// batchRequest is the body of POST /albums:batch
+type batchRequest struct {
+	XMLName xml.Name `json:"-" xml:"batch"`
+
+	// Atomic runs all operations in one transaction that is rolled back if
+	// any of them fails. Otherwise every operation is applied on its own.
+	Atomic     bool             `json:"atomic" xml:"atomic"`
+	Operations []batchOperation `json:"operations" xml:"operation"`
+}
+
+// batchOperation is a single create, update or delete in a batch request
+type batchOperation struct {
+	Method  string        `json:"method" xml:"method"`
+	ID      uint          `json:"id,omitempty" xml:"id,omitempty"`
+	IfMatch string        `json:"if_match,omitempty" xml:"if_match,omitempty"`
+	Album   *models.Album `json:"album,omitempty" xml:"album,omitempty"`
+}
+
+// batchResult reports the outcome of one batch operation, using the status
+// code the equivalent single request would have returned
+type batchResult struct {
+	Status int           `json:"status" xml:"status"`
+	ETag   string        `json:"etag,omitempty" xml:"etag,omitempty"`
+	Album  *models.Album `json:"album,omitempty" xml:"album,omitempty"`
+	Error  string        `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// batchResponse is the response of POST /albums:batch
+type batchResponse struct {
+	XMLName xml.Name      `json:"-" xml:"batch"`
+	Atomic  bool          `json:"atomic" xml:"atomic"`
+	Results []batchResult `json:"results" xml:"result"`
+}
+
+// BatchAlbums applies several create, update and delete operations in one
+// request and reports a status for each of them
+func (h *AlbumHandler) BatchAlbums(c *gin.Context) {
+	var req batchRequest
+	if err := bindBody(c, &req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchOperations {
+		writeResponse(c, http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("a batch must contain between 1 and %d operations", maxBatchOperations),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp := batchResponse{Atomic: req.Atomic, Results: make([]batchResult, len(req.Operations))}
+
+	if !req.Atomic {
+		for i, op := range req.Operations {
+			p := h.prepareOperation(ctx, op)
+			resp.Results[i] = h.applyOperation(ctx, h.repo, p)
+			if resp.Results[i].Status < http.StatusBadRequest {
+				h.storeProduct(ctx, p.product)
+			}
+		}
+		writeResponse(c, http.StatusOK, resp)
+		return
+	}
+
+	if h.uow == nil {
+		writeResponse(c, http.StatusNotImplemented, gin.H{"error": "atomic batches are not supported"})
+		return
+	}
+
+	// Links are resolved against the users and products services before the
+	// transaction is opened, so that slow or retried calls never hold it.
+	// Preparing stops at the first failure, which the transaction then
+	// reports unless an earlier operation fails first.
+	prepared := make([]preparedOperation, 0, len(req.Operations))
+	for _, op := range req.Operations {
+		p := h.prepareOperation(ctx, op)
+		prepared = append(prepared, p)
+		if p.failure != nil {
+			break
+		}
+	}
+
+	failed := -1
+	err := h.uow.WithTx(ctx, func(repos repository.Repositories) error {
+		for i, op := range prepared {
+			resp.Results[i] = h.applyOperation(ctx, repos.Albums, op)
+			if resp.Results[i].Status >= http.StatusBadRequest {
+				failed = i
+				return errBatchFailed
+			}
+		}
+		return nil
+	})
+
+	switch {
+	case failed >= 0:
+		// Nothing was applied, so report every other operation as a failed
+		// dependency and answer with the status of the operation that failed
+		for i := range resp.Results {
+			if i != failed {
+				resp.Results[i] = batchResult{
+					Status: http.StatusFailedDependency,
+					Error:  fmt.Sprintf("not applied because operation %d failed", failed),
+				}
+			}
+		}
+		writeResponse(c, resp.Results[failed].Status, resp)
+	case err != nil:
+		writeResponse(c, http.StatusInternalServerError, gin.H{"error": "Failed to apply batch"})
+	default:
+		// Products are stored once the transaction has released its
+		// connection
+		for _, p := range prepared {
+			h.storeProduct(ctx, p.product)
+		}
+		writeResponse(c, http.StatusOK, resp)
+	}
+}
+
+// preparedOperation is a batch operation whose input has been validated and
+// whose links have been checked
+type preparedOperation struct {
+	batchOperation
+
+	// album is the album to create, with its default owner filled in
+	album *models.Album
+	// product is the newly linked product, stored once the album is written
+	product *models.Product
+	// version is the version of the album the links of an update were
+	// checked against
+	version uint
+	// failure is set when the operation failed before touching the database
+	failure *batchResult
+}
+
+// prepareOperation validates op and checks its links with the same rules as
+// the single-album endpoints. It only reads from the database and may call
+// the users and products services, so it must not run inside a transaction.
+func (h *AlbumHandler) prepareOperation(ctx context.Context, op batchOperation) preparedOperation {
+	p := preparedOperation{batchOperation: op}
+	fail := func(status int, message string) preparedOperation {
+		p.failure = &batchResult{Status: status, Error: message}
+		return p
+	}
+
+	switch op.Method {
+	case batchCreate:
+		if op.Album == nil {
+			return fail(http.StatusBadRequest, "album is required")
+		}
+		album := *op.Album
+		album.ID = 0
+		if err := album.Validate(); err != nil {
+			return fail(http.StatusBadRequest, err.Error())
+		}
+		product, err := h.checkLinks(ctx, &album, nil)
+		if err != nil {
+			return fail(linkStatus(err))
+		}
+		h.defaultOwner(ctx, &album)
+		p.product = product
+		p.album = &album
+
+	case batchUpdate:
+		if op.ID == 0 {
+			return fail(http.StatusBadRequest, "id is required")
+		}
+		if op.Album == nil {
+			return fail(http.StatusBadRequest, "album is required")
+		}
+		if err := op.Album.Validate(); err != nil {
+			return fail(http.StatusBadRequest, err.Error())
+		}
+
+		current, err := h.repo.FindByID(ctx, op.ID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fail(http.StatusNotFound, "album not found")
+			}
+			return fail(http.StatusInternalServerError, "Failed to fetch album")
+		}
+		product, err := h.checkLinks(ctx, op.Album, current)
+		if err != nil {
+			return fail(linkStatus(err))
+		}
+		p.version = current.Version
+		p.product = product
+
+	case batchDelete:
+		if op.ID == 0 {
+			return fail(http.StatusBadRequest, "id is required")
+		}
+
+	default:
+		return fail(http.StatusBadRequest, fmt.Sprintf("unknown method %q", op.Method))
+	}
+	return p
+}
+
+// applyOperation writes a prepared batch operation to albums. It makes no
+// calls outside the database, so it can run inside a transaction.
+func (h *AlbumHandler) applyOperation(ctx context.Context, albums repository.AlbumRepository, op preparedOperation) batchResult {
+	if op.failure != nil {
+		return *op.failure
+	}
+	fail := func(status int, message string) batchResult {
+		return batchResult{Status: status, Error: message}
+	}
+
+	if op.Method == batchCreate {
+		album := *op.album
+		if err := albums.Create(ctx, &album); err != nil {
+			return fail(writeErrorStatus(err, false))
+		}
+		return batchResult{Status: http.StatusCreated, ETag: album.ETag(), Album: &album}
+	}
+
+	current, err := albums.FindByID(ctx, op.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fail(http.StatusNotFound, "album not found")
+		}
+		return fail(http.StatusInternalServerError, "Failed to fetch album")
+	}
+	if status, message := h.preconditionStatus(op.IfMatch, current); status != 0 {
+		return batchResult{Status: status, ETag: current.ETag(), Error: message}
+	}
+
+	if op.Method == batchDelete {
+		if err := albums.Delete(ctx, current.ID, repository.IfVersion(current.Version)); err != nil {
+			return fail(writeErrorStatus(err, op.IfMatch != ""))
+		}
+		return batchResult{Status: http.StatusNoContent}
+	}
+
+	// The links were checked against this version; a write since then may
+	// have changed what counts as an unchanged link
+	if current.Version != op.version {
+		return fail(writeErrorStatus(repository.ErrVersionConflict, op.IfMatch != ""))
+	}
+	applyInput(current, op.Album)
+	if err := albums.Update(ctx, current); err != nil {
+		return fail(writeErrorStatus(err, op.IfMatch != ""))
+	}
+	return batchResult{Status: http.StatusOK, ETag: current.ETag(), Album: current}
+}
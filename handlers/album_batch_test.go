@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"golang-gin/repository"
+	"golang-gin/users"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupBatchTestRouter() (*gin.Engine, *repository.MockAlbumRepository) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	albums := repository.NewMockAlbumRepository()
+	handler := NewAlbumHandler(albums, WithUnitOfWork(repository.NewMockUnitOfWork(albums)))
+	router.POST("/albums:method", CustomMethods(map[string]gin.HandlerFunc{"batch": handler.BatchAlbums}))
+	return router, albums
+}
+
+func postBatch(router *gin.Engine, body string) (*httptest.ResponseRecorder, batchResponse) {
+	req, _ := http.NewRequest("POST", "/albums:batch", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp batchResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+const batchOperations = `[
+	{"method": "create", "album": {"title": "Blue Train", "artist": "John Coltrane", "price": 19.99, "tax": 0.1}},
+	{"method": "update", "id": 1, "if_match": "\"1\"", "album": {"title": "Hammerhead", "artist": "THE OFFSPRING", "price": 30, "tax": 0.1}},
+	{"method": "delete", "id": 2},
+	{"method": "update", "id": 3, "if_match": "\"9\"", "album": {"title": "mysterious love", "artist": "Miho Komatsu", "price": 1, "tax": 0.1}},
+	{"method": "create", "album": {"title": "", "artist": "Nobody", "price": 1}}
+]`
+
+func TestBatchAlbums_Independent(t *testing.T) {
+	router, albums := setupBatchTestRouter()
+
+	w, resp := postBatch(router, `{"operations": `+batchOperations+`}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	want := []int{http.StatusCreated, http.StatusOK, http.StatusNoContent, http.StatusPreconditionFailed, http.StatusBadRequest}
+	for i, status := range want {
+		if resp.Results[i].Status != status {
+			t.Errorf("Operation %d: expected status %d, got %+v", i, status, resp.Results[i])
+		}
+	}
+
+	live, _ := albums.FindAll(context.Background())
+	if len(live) != 3 {
+		t.Errorf("Expected 3 live albums, got %d", len(live))
+	}
+}
+
+func TestBatchAlbums_Atomic(t *testing.T) {
+	router, albums := setupBatchTestRouter()
+
+	w, resp := postBatch(router, `{"atomic": true, "operations": `+batchOperations+`}`)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusPreconditionFailed, w.Code, w.Body.String())
+	}
+
+	want := []int{http.StatusFailedDependency, http.StatusFailedDependency, http.StatusFailedDependency, http.StatusPreconditionFailed, http.StatusFailedDependency}
+	for i, status := range want {
+		if resp.Results[i].Status != status {
+			t.Errorf("Operation %d: expected status %d, got %+v", i, status, resp.Results[i])
+		}
+	}
+
+	album, _ := albums.FindByID(context.Background(), 1)
+	if album.Price != 25.05 || album.Version != 1 {
+		t.Errorf("Expected album 1 to be rolled back, got %+v", album)
+	}
+	if _, err := albums.FindByID(context.Background(), 2); err != nil {
+		t.Errorf("Expected album 2 to be restored by rollback, got %v", err)
+	}
+
+	w, resp = postBatch(router, `{"atomic": true, "operations": [
+		{"method": "create", "album": {"title": "Blue Train", "artist": "John Coltrane", "price": 19.99, "tax": 0.1}},
+		{"method": "delete", "id": 2}
+	]}`)
+	if w.Code != http.StatusOK || resp.Results[0].Status != http.StatusCreated || resp.Results[1].Status != http.StatusNoContent {
+		t.Errorf("Expected atomic batch to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBatchAlbums_InvalidRequest(t *testing.T) {
+	router, _ := setupBatchTestRouter()
+
+	for _, body := range []string{`{"operations": []}`, `{"operations": {}}`, `not json`} {
+		if w, _ := postBatch(router, body); w.Code != http.StatusBadRequest {
+			t.Errorf("Body %s: expected status %d, got %d", body, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+// txTrackingUnitOfWork reports whether a transaction is open
+type txTrackingUnitOfWork struct {
+	repository.UnitOfWork
+	open atomic.Bool
+}
+
+func (u *txTrackingUnitOfWork) WithTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	return u.UnitOfWork.WithTx(ctx, func(repos repository.Repositories) error {
+		u.open.Store(true)
+		defer u.open.Store(false)
+		return fn(repos)
+	})
+}
+
+// txCheckingUserService records user lookups made while a transaction is
+// open
+type txCheckingUserService struct {
+	users.UserService
+	uow    *txTrackingUnitOfWork
+	inTx   atomic.Int32
+	lookup atomic.Int32
+}
+
+func (s *txCheckingUserService) GetUser(ctx context.Context, id uint) (*users.User, error) {
+	s.lookup.Add(1)
+	if s.uow.open.Load() {
+		s.inTx.Add(1)
+	}
+	return s.UserService.GetUser(ctx, id)
+}
+
+func TestBatchAlbums_LinksCheckedOutsideTransaction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	albums := repository.NewMockAlbumRepository()
+	uow := &txTrackingUnitOfWork{UnitOfWork: repository.NewMockUnitOfWork(albums)}
+	svc := &txCheckingUserService{UserService: users.NewMockService(users.User{ID: 7, Name: "Grace"}), uow: uow}
+	handler := NewAlbumHandler(albums, WithUnitOfWork(uow), WithUsers(svc))
+	router := gin.New()
+	router.POST("/albums:method", CustomMethods(map[string]gin.HandlerFunc{"batch": handler.BatchAlbums}))
+
+	w, _ := postBatch(router, `{"atomic": true, "operations": [
+		{"method": "create", "album": {"title": "Blue Train", "artist": "John Coltrane", "price": 19.99, "tax": 0.1, "owner_id": 7}},
+		{"method": "update", "id": 1, "album": {"title": "Hammerhead", "artist": "THE OFFSPRING", "price": 30, "tax": 0.1, "owner_id": 7}}
+	]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if svc.lookup.Load() != 2 || svc.inTx.Load() != 0 {
+		t.Errorf("Expected 2 owner lookups outside the transaction, got %d (%d inside)", svc.lookup.Load(), svc.inTx.Load())
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"golang-gin/albumio"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMaxImportSize is the largest import body accepted by default
+const defaultMaxImportSize = 50 << 20
+
+// AlbumBulkHandler handles bulk album import and export requests
+type AlbumBulkHandler struct {
+	svc           *albumio.Service
+	maxImportSize int64
+}
+
+// AlbumBulkHandlerOption configures an AlbumBulkHandler
+type AlbumBulkHandlerOption func(*AlbumBulkHandler)
+
+// WithMaxImportSize limits import request bodies to n bytes (50MB by default)
+func WithMaxImportSize(n int64) AlbumBulkHandlerOption {
+	return func(h *AlbumBulkHandler) {
+		h.maxImportSize = n
+	}
+}
+
+// NewAlbumBulkHandler creates a new AlbumBulkHandler
+func NewAlbumBulkHandler(svc *albumio.Service, opts ...AlbumBulkHandlerOption) *AlbumBulkHandler {
+	h := &AlbumBulkHandler{svc: svc, maxImportSize: defaultMaxImportSize}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// ImportAlbums creates albums from a CSV or NDJSON request body. The format
+// is taken from ?format= or the Content-Type header, and ?mode= selects
+// atomic (default) or best-effort handling of invalid rows.
+func (h *AlbumBulkHandler) ImportAlbums(c *gin.Context) {
+	format, err := albumio.FormatFromContentType(c.ContentType())
+	if f := c.Query("format"); f != "" {
+		format, err = albumio.ParseFormat(f)
+	}
+	if err != nil {
+		c.IndentedJSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	}
+
+	mode, err := albumio.ParseMode(c.Query("mode"))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, h.maxImportSize)
+	result, err := h.svc.Import(c.Request.Context(), body, format, mode)
+	var inputErr *albumio.InputError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		c.IndentedJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("import file must be at most %d bytes", tooLarge.Limit)})
+		return
+	case errors.As(err, &inputErr):
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("Album import failed: %v", err)
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Failed to import albums"})
+		return
+	}
+
+	status := http.StatusOK
+	if mode == albumio.ModeAtomic && result.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	c.IndentedJSON(status, result)
+}
+
+// ExportAlbums streams all albums as ?format=csv (default), ndjson or xlsx
+func (h *AlbumBulkHandler) ExportAlbums(c *gin.Context) {
+	format, err := albumio.ParseFormat(c.DefaultQuery("format", string(albumio.FormatCSV)))
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", `attachment; filename="albums.`+string(format)+`"`)
+	c.Status(http.StatusOK)
+
+	// The status line has already been sent, so a failure can only be logged
+	// and the response cut short
+	if err := h.svc.Export(c.Request.Context(), c.Writer, format); err != nil {
+		log.Printf("Album export failed: %v", err)
+		c.Abort()
+	}
+}
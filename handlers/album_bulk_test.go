@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"golang-gin/albumio"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupBulkTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	albums := repository.NewMockAlbumRepository()
+	handler := NewAlbumBulkHandler(albumio.NewService(albums, repository.NewMockUnitOfWork(albums)))
+
+	router.GET("/albums/:id", func(c *gin.Context) { c.Status(http.StatusTeapot) })
+	router.POST("/albums:method", CustomMethods(map[string]gin.HandlerFunc{"import": handler.ImportAlbums}))
+	router.GET("/albums:method", CustomMethods(map[string]gin.HandlerFunc{"export": handler.ExportAlbums}))
+	return router
+}
+
+func TestImportAlbums(t *testing.T) {
+	router := setupBulkTestRouter()
+	body := "title,artist,price,tax\nBlue Train,John Coltrane,19.99,0.1\n,Nobody,1,0.1\n"
+
+	tests := []struct {
+		name        string
+		query       string
+		contentType string
+		wantStatus  int
+		wantFailed  int
+	}{
+		{"Atomic with invalid row", "", "text/csv", http.StatusUnprocessableEntity, 1},
+		{"Best effort", "?mode=best-effort", "text/csv; charset=utf-8", http.StatusOK, 1},
+		{"Unsupported content type", "", "application/xml", http.StatusUnsupportedMediaType, 0},
+		{"Unknown mode", "?mode=sometimes", "text/csv", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/albums:import"+tt.query, strings.NewReader(body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus >= http.StatusBadRequest && tt.wantStatus != http.StatusUnprocessableEntity {
+				return
+			}
+
+			var result albumio.Result
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if result.Failed != tt.wantFailed {
+				t.Errorf("Expected %d failed rows, got %d", tt.wantFailed, result.Failed)
+			}
+		})
+	}
+}
+
+func TestExportAlbums(t *testing.T) {
+	router := setupBulkTestRouter()
+
+	req, _ := http.NewRequest("GET", "/albums:export?format=ndjson", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Expected NDJSON content type, got %q", ct)
+	}
+	if lines := strings.Count(w.Body.String(), "\n"); lines != 3 {
+		t.Errorf("Expected 3 lines, got %d", lines)
+	}
+
+	req, _ = http.NewRequest("GET", "/albums:export?format=pdf", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for unknown format, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// failingCreateRepository fails every create, like a database that is down
+type failingCreateRepository struct {
+	repository.AlbumRepository
+}
+
+func (r *failingCreateRepository) Create(ctx context.Context, album *models.Album) error {
+	return errors.New("database is down")
+}
+
+func TestImportAlbums_Errors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockRepo := repository.NewMockAlbumRepository()
+	albums := &failingCreateRepository{AlbumRepository: mockRepo}
+	handler := NewAlbumBulkHandler(albumio.NewService(albums, repository.NewMockUnitOfWork(mockRepo)), WithMaxImportSize(64))
+	router := gin.New()
+	router.POST("/albums:method", CustomMethods(map[string]gin.HandlerFunc{"import": handler.ImportAlbums}))
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"Missing columns", "name\nx\n", http.StatusBadRequest},
+		{"Too large", "title,artist,price\n" + strings.Repeat(",B,1\n", 20), http.StatusRequestEntityTooLarge},
+		{"Database error", "title,artist,price\nA,B,1\n", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/albums:import?mode=best-effort", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "text/csv")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"golang-gin/repository"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheStatsProvider reports album cache metrics
+type CacheStatsProvider interface {
+	Stats() repository.CacheStats
+}
+
+// AlbumCacheStats returns the hit and miss counters of the album cache
+func AlbumCacheStats(provider CacheStatsProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.IndentedJSON(http.StatusOK, provider.Stats())
+	}
+}
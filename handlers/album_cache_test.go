@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"golang-gin/cache"
+	"golang-gin/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAlbumCacheStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cached := repository.NewCachedAlbumRepository(repository.NewMockAlbumRepository(), cache.NewLRU(10))
+	cached.FindByID(context.Background(), 1)
+	cached.FindByID(context.Background(), 1)
+
+	router := gin.New()
+	router.GET("/cache/stats", AlbumCacheStats(cached))
+
+	req, _ := http.NewRequest("GET", "/cache/stats", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var stats repository.CacheStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %+v", stats)
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Guardrails for ?include=
+const (
+	maxIncludes     = 5
+	maxIncludeDepth = 2
+)
+
+// AlbumInclude resolves an embedded sub-resource requested with ?include=
+type AlbumInclude struct {
+	// Columns lists the album columns the resolver reads
+	Columns []string
+	// Resolve returns one value per album, in the same order. Nil values
+	// are rendered as null.
+	Resolve func(ctx context.Context, albums []models.Album) ([]interface{}, error)
+}
+
+// WithInclude registers an include. Nested includes use dotted names such as
+// "creator.user"; their parent must be registered too and resolve to maps.
+func WithInclude(name string, include AlbumInclude) AlbumHandlerOption {
+	return func(h *AlbumHandler) {
+		h.includes[name] = include
+	}
+}
+
+// albumQuery holds the parsed ?fields= and ?include= parameters
+type albumQuery struct {
+	fields   []string // selected output fields, empty for all
+	includes []string // include names, parents before children
+}
+
+// findOptions returns the repository options that load the columns q needs
+func (q *albumQuery) findOptions(includes map[string]AlbumInclude) []repository.FindOption {
+	if len(q.fields) == 0 {
+		return nil
+	}
+
+	// id, version and updated_at are always loaded for ETags, Last-Modified
+	// and include resolvers
+	columns := append([]string{"id", "version", "updated_at"}, q.fields...)
+	for _, name := range q.includes {
+		columns = append(columns, includes[name].Columns...)
+	}
+	return []repository.FindOption{repository.WithFields(columns...)}
+}
+
+// parseAlbumQuery reads ?fields= and ?include=. It returns nil when neither
+// is set, and writes a 400 if either is invalid.
+func (h *AlbumHandler) parseAlbumQuery(c *gin.Context) (*albumQuery, bool) {
+	fieldsParam, hasFields := c.GetQuery("fields")
+	includeParam, hasInclude := c.GetQuery("include")
+	if !hasFields && !hasInclude {
+		return nil, true
+	}
+
+	q := &albumQuery{}
+	for _, field := range splitList(fieldsParam) {
+		if !isAlbumField(field) {
+			writeResponse(c, http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("unknown field %q, allowed fields are %s", field, strings.Join(repository.AlbumColumns, ",")),
+			})
+			return nil, false
+		}
+		q.fields = append(q.fields, field)
+	}
+
+	requested := splitList(includeParam)
+	if len(requested) > maxIncludes {
+		writeResponse(c, http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d includes are allowed", maxIncludes)})
+		return nil, false
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range requested {
+		if strings.Count(name, ".")+1 > maxIncludeDepth {
+			writeResponse(c, http.StatusBadRequest, gin.H{"error": fmt.Sprintf("include %q is nested deeper than %d levels", name, maxIncludeDepth)})
+			return nil, false
+		}
+
+		// Add parents first so that children can be attached to them
+		parts := strings.Split(name, ".")
+		for i := range parts {
+			path := strings.Join(parts[:i+1], ".")
+			if _, ok := h.includes[path]; !ok {
+				writeResponse(c, http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown include %q", path)})
+				return nil, false
+			}
+			if !seen[path] {
+				seen[path] = true
+				q.includes = append(q.includes, path)
+			}
+		}
+	}
+	return q, true
+}
+
+// albumResources builds the sparse representations of albums selected by q
+func (h *AlbumHandler) albumResources(ctx context.Context, albums []models.Album, q *albumQuery) []resource {
+	fields := q.fields
+	if len(fields) == 0 {
+		fields = repository.AlbumColumns
+	}
+
+	resources := make([]resource, len(albums))
+	for i := range albums {
+		r := resource{}
+		for _, field := range fields {
+			r[field] = albumFieldValue(&albums[i], field)
+		}
+		resources[i] = r
+	}
+
+	ctx = withIncludeCache(ctx)
+	for _, name := range q.includes {
+		values, err := h.includes[name].Resolve(ctx, albums)
+		if err != nil {
+			// Degrade to null rather than failing the whole response
+			log.Printf("Failed to resolve include %q: %v", name, err)
+			values = make([]interface{}, len(albums))
+		}
+
+		parent, key := "", name
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			parent, key = name[:i], name[i+1:]
+		}
+		for i, r := range resources {
+			if parent == "" {
+				r[key] = values[i]
+			} else if p, ok := lookupPath(r, parent); ok {
+				p[key] = values[i]
+			}
+		}
+	}
+	return resources
+}
+
+// lookupPath returns the nested resource at a dotted path
+func lookupPath(r resource, path string) (resource, bool) {
+	for _, key := range strings.Split(path, ".") {
+		next, ok := r[key].(resource)
+		if !ok {
+			return nil, false
+		}
+		r = next
+	}
+	return r, true
+}
+
+// isAlbumField reports whether name can be selected with ?fields=
+func isAlbumField(name string) bool {
+	for _, column := range repository.AlbumColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// albumFieldValue returns the value of a selectable album field
+func albumFieldValue(album *models.Album, field string) interface{} {
+	switch field {
+	case "id":
+		return album.ID
+	case "title":
+		return album.Title
+	case "artist":
+		return album.Artist
+	case "price":
+		return album.Price
+	case "tax":
+		return album.Tax
+	case "owner_id":
+		if album.OwnerID == nil {
+			return nil
+		}
+		return *album.OwnerID
+	case "product_id":
+		if album.ProductID == nil {
+			return nil
+		}
+		return *album.ProductID
+	case "version":
+		return album.Version
+	case "created_at":
+		return album.CreatedAt
+	case "updated_at":
+		return album.UpdatedAt
+	}
+	return nil
+}
+
+// splitList splits a comma-separated query parameter
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// resource is a map-based representation used for sparse fieldsets and
+// embedded sub-resources
+type resource map[string]interface{}
+
+// MarshalXML encodes the resource as one child element per key, in key order
+func (r resource) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(r))
+	for key := range r {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := r[key]
+		if m, ok := value.(map[string]interface{}); ok {
+			value = resource(m)
+		}
+		if value == nil {
+			continue
+		}
+		if err := e.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: key}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// namedResource encodes a resource as a top-level XML element
+type namedResource struct {
+	name string
+	resource
+}
+
+// MarshalXML encodes the resource with the configured element name
+func (n namedResource) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	return n.resource.MarshalXML(e, xml.StartElement{Name: xml.Name{Local: n.name}})
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupFieldsTestRouter(lookup UserLookup) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	audits := repository.NewMockAuditRepository()
+	audits.Record(context.Background(), &models.AlbumAudit{AlbumID: 1, Action: models.AuditActionCreate, Actor: "7", Source: "rest"})
+	audits.Record(context.Background(), &models.AlbumAudit{AlbumID: 2, Action: models.AuditActionCreate, Actor: "alice", Source: "cli"})
+
+	handler := NewAlbumHandler(repository.NewMockAlbumRepository(),
+		WithInclude("creator", CreatorInclude(audits)),
+		WithInclude("creator.user", CreatorUserInclude(audits, lookup)),
+	)
+
+	router := gin.New()
+	router.GET("/albums", handler.GetAlbums)
+	router.GET("/albums/:id", handler.GetAlbumByID)
+	return router
+}
+
+func getResources(t *testing.T, router *gin.Engine, path string) (int, []map[string]interface{}) {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		return w.Code, nil
+	}
+
+	var resources []map[string]interface{}
+	if strings.HasPrefix(strings.TrimSpace(w.Body.String()), "{") {
+		var single map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &single); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		return w.Code, []map[string]interface{}{single}
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resources); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	return w.Code, resources
+}
+
+func TestGetAlbums_SparseFields(t *testing.T) {
+	router := setupFieldsTestRouter(nil)
+
+	status, albums := getResources(t, router, "/albums?fields=id,title,artist")
+	if status != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+	}
+	if len(albums) != 3 {
+		t.Fatalf("Expected 3 albums, got %d", len(albums))
+	}
+	if len(albums[0]) != 3 || albums[0]["title"] != "Hammerhead" {
+		t.Errorf("Expected only id, title and artist, got %v", albums[0])
+	}
+}
+
+func TestGetAlbumByID_TaxBreakdown(t *testing.T) {
+	router := setupFieldsTestRouter(nil)
+
+	status, albums := getResources(t, router, "/albums/1?fields=title&include=tax_breakdown")
+	if status != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+	}
+
+	breakdown, ok := albums[0]["tax_breakdown"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected tax_breakdown, got %v", albums[0])
+	}
+	if breakdown["tax"] != 2.51 || breakdown["gross"] != 27.56 {
+		t.Errorf("Unexpected tax breakdown %v", breakdown)
+	}
+	if _, ok := albums[0]["price"]; ok {
+		t.Errorf("Expected price to be omitted, got %v", albums[0])
+	}
+}
+
+func TestGetAlbums_CreatorInclude(t *testing.T) {
+	lookups := 0
+	router := setupFieldsTestRouter(func(ctx context.Context, id string) (map[string]interface{}, error) {
+		lookups++
+		return map[string]interface{}{"id": id, "name": "Grace"}, nil
+	})
+
+	status, albums := getResources(t, router, "/albums?fields=id&include=creator.user")
+	if status != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+	}
+
+	creator, ok := albums[0]["creator"].(map[string]interface{})
+	if !ok || creator["actor"] != "7" {
+		t.Fatalf("Expected creator of album 1, got %v", albums[0])
+	}
+	if user, ok := creator["user"].(map[string]interface{}); !ok || user["name"] != "Grace" {
+		t.Errorf("Expected resolved user, got %v", creator)
+	}
+	if creator, ok := albums[1]["creator"].(map[string]interface{}); !ok || creator["user"] != nil {
+		t.Errorf("Expected non-numeric actor to have no user, got %v", albums[1])
+	}
+	if albums[2]["creator"] != nil {
+		t.Errorf("Expected no creator for album without audit entry, got %v", albums[2])
+	}
+	if lookups != 1 {
+		t.Errorf("Expected 1 user lookup, got %d", lookups)
+	}
+}
+
+func TestGetAlbums_IncludeFailureDegrades(t *testing.T) {
+	router := setupFieldsTestRouter(func(ctx context.Context, id string) (map[string]interface{}, error) {
+		return nil, errors.New("users API unavailable")
+	})
+
+	status, albums := getResources(t, router, "/albums?include=creator.user")
+	if status != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+	}
+	if creator, ok := albums[0]["creator"].(map[string]interface{}); !ok || creator["user"] != nil {
+		t.Errorf("Expected creator without user, got %v", albums[0])
+	}
+}
+
+func TestGetAlbums_FieldGuardrails(t *testing.T) {
+	router := setupFieldsTestRouter(nil)
+
+	for _, path := range []string{
+		"/albums?fields=id,password",
+		"/albums?include=owner",
+		"/albums?include=creator.user.organization",
+		"/albums?include=tax_breakdown,creator,creator.user,a,b,c",
+		"/albums/1?fields=deleted_at",
+	} {
+		if status, _ := getResources(t, router, path); status != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", path, http.StatusBadRequest, status)
+		}
+	}
+}
+
+func TestGetAlbumByID_SparseXML(t *testing.T) {
+	router := setupFieldsTestRouter(nil)
+
+	req, _ := http.NewRequest("GET", "/albums/1?fields=title&include=tax_breakdown", nil)
+	req.Header.Set("Accept", "application/xml")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	want := "<album><tax_breakdown><net>25.05</net><rate>0.1</rate><tax>2.51</tax><gross>27.56</gross></tax_breakdown><title>Hammerhead</title></album>"
+	if w.Body.String() != want {
+		t.Errorf("Expected %s, got %s", want, w.Body.String())
+	}
+}
+
+// countingAuditRepository counts FindByAction calls
+type countingAuditRepository struct {
+	repository.AuditRepository
+	calls int
+}
+
+func (r *countingAuditRepository) FindByAction(ctx context.Context, action string, albumIDs []uint) ([]models.AlbumAudit, error) {
+	r.calls++
+	return r.AuditRepository.FindByAction(ctx, action, albumIDs)
+}
+
+func TestGetAlbums_CreatorIncludeBatched(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	audits := &countingAuditRepository{AuditRepository: repository.NewMockAuditRepository()}
+	audits.Record(context.Background(), &models.AlbumAudit{AlbumID: 1, Action: models.AuditActionCreate, Actor: "7"})
+	audits.Record(context.Background(), &models.AlbumAudit{AlbumID: 2, Action: models.AuditActionCreate, Actor: "8"})
+
+	lookup := func(ctx context.Context, id string) (map[string]interface{}, error) {
+		if id == "8" {
+			return nil, errors.New("users API timed out")
+		}
+		return map[string]interface{}{"id": id, "name": "Grace"}, nil
+	}
+	handler := NewAlbumHandler(repository.NewMockAlbumRepository(),
+		WithInclude("creator", CreatorInclude(audits)),
+		WithInclude("creator.user", CreatorUserInclude(audits, lookup)),
+	)
+	router := gin.New()
+	router.GET("/albums", handler.GetAlbums)
+
+	status, albums := getResources(t, router, "/albums?fields=id&include=creator,creator.user")
+	if status != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+	}
+	if audits.calls != 1 {
+		t.Errorf("Expected 1 audit query, got %d", audits.calls)
+	}
+	if creator, ok := albums[0]["creator"].(map[string]interface{}); !ok || creator["user"] == nil {
+		t.Errorf("Expected album 1 to keep its user, got %v", albums[0])
+	}
+	if creator, ok := albums[1]["creator"].(map[string]interface{}); !ok || creator["actor"] != "8" || creator["user"] != nil {
+		t.Errorf("Expected album 2 to have a creator without user, got %v", albums[1])
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"golang-gin/repository"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AlbumHistoryHandler handles album audit trail requests
+type AlbumHistoryHandler struct {
+	audits repository.AuditRepository
+}
+
+// NewAlbumHistoryHandler creates a new AlbumHistoryHandler
+func NewAlbumHistoryHandler(audits repository.AuditRepository) *AlbumHistoryHandler {
+	return &AlbumHistoryHandler{audits: audits}
+}
+
+// GetAlbumHistory returns the change history of an album. With
+// ?as_of=<RFC3339 timestamp> it returns the album as it was at that time.
+func (h *AlbumHistoryHandler) GetAlbumHistory(c *gin.Context) {
+	id, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	if asOf := c.Query("as_of"); asOf != "" {
+		at, err := time.Parse(time.RFC3339, asOf)
+		if err != nil {
+			c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "as_of must be an RFC3339 timestamp"})
+			return
+		}
+
+		album, err := h.audits.FindAlbumAsOf(c.Request.Context(), id, at.UTC())
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.IndentedJSON(http.StatusNotFound, gin.H{"message": "album did not exist at the given time"})
+				return
+			}
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconstruct album"})
+			return
+		}
+
+		c.IndentedJSON(http.StatusOK, album)
+		return
+	}
+
+	entries, err := h.audits.FindByAlbumID(c.Request.Context(), id)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch album history"})
+		return
+	}
+	if len(entries) == 0 {
+		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "album history not found"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, entries)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetAlbumHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	audits := repository.NewMockAuditRepository()
+	handler := NewAlbumHistoryHandler(audits)
+	router.GET("/albums/:id/history", handler.GetAlbumHistory)
+
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := created.Add(24 * time.Hour)
+	ctx := context.Background()
+	audits.Record(ctx, &models.AlbumAudit{
+		AlbumID: 1, Action: models.AuditActionCreate, Actor: "alice", Source: "rest", CreatedAt: created,
+		Snapshot: &models.Album{ID: 1, Title: "Original", Price: 10},
+	})
+	audits.Record(ctx, &models.AlbumAudit{
+		AlbumID: 1, Action: models.AuditActionUpdate, Actor: "bob", Source: "grpc", CreatedAt: updated,
+		Changes:  map[string]models.FieldChange{"price": {From: 10.0, To: 12.0}},
+		Snapshot: &models.Album{ID: 1, Title: "Original", Price: 12},
+	})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedPrice  float64
+	}{
+		{"Full history", "", http.StatusOK, 0},
+		{"As of creation", "?as_of=2026-01-01T12:00:00Z", http.StatusOK, 10},
+		{"As of update", "?as_of=2026-01-03T00:00:00Z", http.StatusOK, 12},
+		{"Before creation", "?as_of=2025-12-31T00:00:00Z", http.StatusNotFound, 0},
+		{"Invalid as_of", "?as_of=yesterday", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/albums/1/history"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			if tt.query == "" {
+				var entries []models.AlbumAudit
+				if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if len(entries) != 2 || entries[1].Actor != "bob" {
+					t.Errorf("Unexpected history: %+v", entries)
+				}
+				return
+			}
+
+			var album models.Album
+			if err := json.Unmarshal(w.Body.Bytes(), &album); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if album.Price != tt.expectedPrice {
+				t.Errorf("Expected price %v, got %v", tt.expectedPrice, album.Price)
+			}
+		})
+	}
+
+	t.Run("Unknown album", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/albums/99/history", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"golang-gin/users"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// UserLookup fetches a user from the users API by ID
+type UserLookup func(ctx context.Context, id string) (map[string]interface{}, error)
+
+// taxBreakdown is the tax_breakdown include of an album
+type taxBreakdown struct {
+	Net   float64 `json:"net" xml:"net"`
+	Rate  float32 `json:"rate" xml:"rate"`
+	Tax   float64 `json:"tax" xml:"tax"`
+	Gross float64 `json:"gross" xml:"gross"`
+}
+
+// TaxBreakdownInclude computes the net price, tax amount and gross price
+func TaxBreakdownInclude() AlbumInclude {
+	return AlbumInclude{
+		Columns: []string{"price", "tax"},
+		Resolve: func(ctx context.Context, albums []models.Album) ([]interface{}, error) {
+			values := make([]interface{}, len(albums))
+			for i, album := range albums {
+				tax := math.Round(album.Price*float64(album.Tax)*100) / 100
+				values[i] = taxBreakdown{
+					Net:   album.Price,
+					Rate:  album.Tax,
+					Tax:   tax,
+					Gross: math.Round((album.Price+tax)*100) / 100,
+				}
+			}
+			return values, nil
+		},
+	}
+}
+
+// CreatorInclude embeds who created each album, taken from the audit trail
+func CreatorInclude(audits repository.AuditRepository) AlbumInclude {
+	return AlbumInclude{
+		Resolve: func(ctx context.Context, albums []models.Album) ([]interface{}, error) {
+			created, err := findCreateEntries(ctx, audits, albums)
+			if err != nil {
+				return nil, err
+			}
+
+			values := make([]interface{}, len(albums))
+			for i, album := range albums {
+				if entry, ok := created[album.ID]; ok {
+					values[i] = resource{
+						"actor":          entry.Actor,
+						"actor_verified": entry.ActorVerified,
+						"source":         entry.Source,
+						"created_at":     entry.CreatedAt,
+					}
+				}
+			}
+			return values, nil
+		},
+	}
+}
+
+// CreatorUserInclude resolves the creator's user record from the users API.
+// Only numeric actors are looked up; each user is fetched once per request
+// and a failed lookup leaves only the affected albums without a user.
+func CreatorUserInclude(audits repository.AuditRepository, lookup UserLookup) AlbumInclude {
+	return AlbumInclude{
+		Resolve: func(ctx context.Context, albums []models.Album) ([]interface{}, error) {
+			created, err := findCreateEntries(ctx, audits, albums)
+			if err != nil {
+				return nil, err
+			}
+
+			var actors []string
+			for _, entry := range created {
+				if _, err := strconv.ParseUint(entry.Actor, 10, 64); err == nil {
+					actors = append(actors, entry.Actor)
+				}
+			}
+			users, errs := lookupAll(ctx, actors, lookup)
+			for actor, err := range errs {
+				log.Printf("Failed to look up creator %s: %v", actor, err)
+			}
+
+			values := make([]interface{}, len(albums))
+			for i, album := range albums {
+				if entry, ok := created[album.ID]; ok && users[entry.Actor] != nil {
+					values[i] = resource(users[entry.Actor])
+				}
+			}
+			return values, nil
+		},
+	}
+}
+
+// UserServiceLookup adapts svc to a UserLookup. Unknown users resolve to nil.
+func UserServiceLookup(svc users.UserService) UserLookup {
+	return func(ctx context.Context, id string) (map[string]interface{}, error) {
+		n, err := strconv.ParseUint(id, 10, 0)
+		if err != nil {
+			return nil, nil
+		}
+		user, err := svc.GetUser(ctx, uint(n))
+		if errors.Is(err, users.ErrUserNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return userResource(user), nil
+	}
+}
+
+// OwnerInclude embeds the owner of each album from the users API. Each owner
+// is fetched once per request. An owner that is not a known user is embedded
+// as {"id", "missing": true}; an owner that cannot be fetched, for example
+// while the users API is unavailable, is embedded as {"id", "unavailable": true}
+// instead of failing the include.
+func OwnerInclude(svc users.UserService) AlbumInclude {
+	return AlbumInclude{
+		Columns: []string{"owner_id"},
+		Resolve: func(ctx context.Context, albums []models.Album) ([]interface{}, error) {
+			var ids []uint
+			for _, album := range albums {
+				if album.OwnerID != nil {
+					ids = append(ids, *album.OwnerID)
+				}
+			}
+			found, errs := lookupAll(ctx, ids, svc.GetUser)
+
+			owners := make(map[uint]resource, len(ids))
+			for id, user := range found {
+				owners[id] = userResource(user)
+			}
+			for id, err := range errs {
+				switch {
+				case errors.Is(err, users.ErrUserNotFound):
+					owners[id] = resource{"id": id, "missing": true}
+				case errors.Is(err, users.ErrUnavailable):
+					owners[id] = resource{"id": id, "unavailable": true}
+				default:
+					log.Printf("Failed to look up owner %d: %v", id, err)
+					owners[id] = resource{"id": id, "unavailable": true}
+				}
+			}
+
+			values := make([]interface{}, len(albums))
+			for i, album := range albums {
+				if album.OwnerID != nil {
+					values[i] = owners[*album.OwnerID]
+				}
+			}
+			return values, nil
+		},
+	}
+}
+
+// ProductInclude embeds the linked product with its price and stock as of
+// the last product sync. Products without a local copy are embedded with
+// their ID only.
+func ProductInclude(repo repository.ProductRepository) AlbumInclude {
+	return AlbumInclude{
+		Columns: []string{"product_id"},
+		Resolve: func(ctx context.Context, albums []models.Album) ([]interface{}, error) {
+			var ids []uint
+			for _, album := range albums {
+				if album.ProductID != nil {
+					ids = append(ids, *album.ProductID)
+				}
+			}
+
+			found, err := repo.FindByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[uint]models.Product, len(found))
+			for _, product := range found {
+				byID[product.ID] = product
+			}
+
+			values := make([]interface{}, len(albums))
+			for i, album := range albums {
+				if album.ProductID == nil {
+					continue
+				}
+				product, ok := byID[*album.ProductID]
+				if !ok {
+					values[i] = resource{"id": *album.ProductID}
+					continue
+				}
+				values[i] = resource{
+					"id":           product.ID,
+					"name":         product.Name,
+					"price":        product.Price,
+					"stock":        product.Stock,
+					"in_stock":     product.Stock > 0 && !product.Discontinued,
+					"discontinued": product.Discontinued,
+					"synced_at":    product.SyncedAt,
+				}
+			}
+			return values, nil
+		},
+	}
+}
+
+// userResource converts a user into an embedded resource. Contact details
+// such as the email address are left out, as albums are readable without
+// authentication.
+func userResource(user *users.User) resource {
+	return resource{
+		"id":        user.ID,
+		"name":      user.Name,
+		"user_type": user.UserType,
+	}
+}
+
+// findCreateEntries returns the create audit entry of each album by album ID.
+// The entries are fetched once per response and shared between includes.
+func findCreateEntries(ctx context.Context, audits repository.AuditRepository, albums []models.Album) (map[uint]models.AlbumAudit, error) {
+	return cachedInclude(ctx, "create_entries", func() (map[uint]models.AlbumAudit, error) {
+		ids := make([]uint, len(albums))
+		for i, album := range albums {
+			ids[i] = album.ID
+		}
+
+		entries, err := audits.FindByAction(ctx, models.AuditActionCreate, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		created := make(map[uint]models.AlbumAudit, len(entries))
+		for _, entry := range entries {
+			if _, ok := created[entry.AlbumID]; !ok {
+				created[entry.AlbumID] = entry
+			}
+		}
+		return created, nil
+	})
+}
+
+// maxIncludeLookups bounds the concurrent lookups made by one include
+const maxIncludeLookups = 8
+
+// lookupAll calls fetch once for each distinct key, at most maxIncludeLookups
+// at a time. Keys that fail are reported in errs instead of found.
+func lookupAll[K comparable, V any](ctx context.Context, keys []K, fetch func(context.Context, K) (V, error)) (found map[K]V, errs map[K]error) {
+	found = make(map[K]V)
+	errs = make(map[K]error)
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		seen = make(map[K]bool)
+		sem  = make(chan struct{}, maxIncludeLookups)
+	)
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			value, err := fetch(ctx, key)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[key] = err
+			} else {
+				found[key] = value
+			}
+		}()
+	}
+	wg.Wait()
+	return found, errs
+}
+
+type includeCacheKey struct{}
+
+// includeCache holds values shared by the includes of one response
+type includeCache struct {
+	mu      sync.Mutex
+	results map[string]includeResult
+}
+
+type includeResult struct {
+	value interface{}
+	err   error
+}
+
+// withIncludeCache returns a copy of ctx in which cachedInclude memoizes
+func withIncludeCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeCacheKey{}, &includeCache{results: map[string]includeResult{}})
+}
+
+// cachedInclude returns the result of load stored under key, calling load at
+// most once per response. Without a cache in ctx load is always called.
+func cachedInclude[T any](ctx context.Context, key string, load func() (T, error)) (T, error) {
+	c, ok := ctx.Value(includeCacheKey{}).(*includeCache)
+	if !ok {
+		return load()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r, ok := c.results[key]; ok {
+		value, _ := r.value.(T)
+		return value, r.err
+	}
+	value, err := load()
+	c.results[key] = includeResult{value: value, err: err}
+	return value, err
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-gin/audit"
+	"golang-gin/models"
+	"golang-gin/users"
+	"log"
+	"strconv"
+)
+
+// WithUsers links albums to users of the users API. A written owner_id must
+// name a known user, and albums created without one are owned by the acting
+// user when it is authenticated and a known user ID. The X-Actor header is
+// not trusted for this. While the users API is unavailable owners are
+// accepted unchecked.
+func WithUsers(svc users.UserService) AlbumHandlerOption {
+	return func(h *AlbumHandler) {
+		h.users = svc
+	}
+}
+
+// defaultOwner makes the authenticated acting user the owner of a new album
+// without one
+func (h *AlbumHandler) defaultOwner(ctx context.Context, album *models.Album) {
+	if h.users == nil || album.OwnerID != nil {
+		return
+	}
+	md := audit.FromContext(ctx)
+	if !md.ActorVerified {
+		return
+	}
+	actor, err := strconv.ParseUint(md.Actor, 10, 0)
+	if err != nil || actor == 0 {
+		return
+	}
+
+	id := uint(actor)
+	if _, err := h.users.GetUser(ctx, id); err != nil && !errors.Is(err, users.ErrUnavailable) {
+		return
+	}
+	album.OwnerID = &id
+}
+
+// checkOwner returns a ValidationError if the owner of album is not a known
+// user. previous is the owner before the write; an unchanged owner is not
+// checked again so albums of deleted users can still be edited.
+func (h *AlbumHandler) checkOwner(ctx context.Context, album *models.Album, previous *uint) error {
+	if h.users == nil || album.OwnerID == nil {
+		return nil
+	}
+	if previous != nil && *previous == *album.OwnerID {
+		return nil
+	}
+
+	_, err := h.users.GetUser(ctx, *album.OwnerID)
+	switch {
+	case errors.Is(err, users.ErrUserNotFound):
+		return models.ValidationError{fmt.Sprintf("owner_id %d is not a known user", *album.OwnerID)}
+	case errors.Is(err, users.ErrUnavailable):
+		log.Printf("Accepting unchecked owner %d: %v", *album.OwnerID, err)
+		return nil
+	}
+	return err
+}
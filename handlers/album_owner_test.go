@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"golang-gin/audit"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"golang-gin/users"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupOwnerTestRouter() (*gin.Engine, *users.MockService) {
+	gin.SetMode(gin.TestMode)
+	svc := users.NewMockService(users.User{ID: 7, Name: "Grace", Email: "grace@example.com", UserType: "admin"})
+	handler := NewAlbumHandler(repository.NewMockAlbumRepository(),
+		WithUsers(svc),
+		WithInclude("owner", OwnerInclude(svc)),
+	)
+
+	router := gin.New()
+	router.GET("/albums/:id", handler.GetAlbumByID)
+	router.POST("/albums", handler.PostAlbums)
+	router.PUT("/albums/:id", handler.UpdateAlbum)
+	return router, svc
+}
+
+// writeAlbum sends album with the given audit metadata and decodes the
+// response
+func writeAlbum(router *gin.Engine, method, path string, md audit.Metadata, album interface{}) (int, models.Album) {
+	body, _ := json.Marshal(album)
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(audit.WithMetadata(req.Context(), md))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var result models.Album
+	json.Unmarshal(w.Body.Bytes(), &result)
+	return w.Code, result
+}
+
+func TestPostAlbums_Owner(t *testing.T) {
+	tests := []struct {
+		name        string
+		ownerID     interface{}
+		actor       audit.Metadata
+		unavailable bool
+		status      int
+		wantOwner   uint
+	}{
+		{"Known owner", 7, audit.Metadata{}, false, http.StatusCreated, 7},
+		{"Unknown owner", 99, audit.Metadata{}, false, http.StatusBadRequest, 0},
+		{"Zero owner", 0, audit.Metadata{}, false, http.StatusBadRequest, 0},
+		{"Unchecked while unavailable", 99, audit.Metadata{}, true, http.StatusCreated, 99},
+		{"Defaults to verified actor", nil, audit.Metadata{Actor: "7", ActorVerified: true}, false, http.StatusCreated, 7},
+		{"Unverified actor", nil, audit.Metadata{Actor: "7"}, false, http.StatusCreated, 0},
+		{"Unknown actor", nil, audit.Metadata{Actor: "8", ActorVerified: true}, false, http.StatusCreated, 0},
+		{"Non-numeric actor", nil, audit.Metadata{Actor: "alice", ActorVerified: true}, false, http.StatusCreated, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, svc := setupOwnerTestRouter()
+			svc.SetUnavailable(tt.unavailable)
+
+			status, album := writeAlbum(router, "POST", "/albums", tt.actor, map[string]interface{}{
+				"title": "Blue Train", "artist": "John Coltrane", "price": 56.99, "tax": 0.1, "owner_id": tt.ownerID,
+			})
+			if status != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, status)
+			}
+			var got uint
+			if album.OwnerID != nil {
+				got = *album.OwnerID
+			}
+			if got != tt.wantOwner {
+				t.Errorf("Expected owner %d, got %d", tt.wantOwner, got)
+			}
+		})
+	}
+}
+
+func TestUpdateAlbum_Owner(t *testing.T) {
+	router, svc := setupOwnerTestRouter()
+	album := map[string]interface{}{"title": "Hammerhead", "artist": "THE OFFSPRING", "price": 25.05, "tax": 0.1}
+
+	album["owner_id"] = 99
+	if status, _ := writeAlbum(router, "PUT", "/albums/1", audit.Metadata{}, album); status != http.StatusBadRequest {
+		t.Errorf("Expected unknown owner to be rejected, got %d", status)
+	}
+
+	// An owner that became unknown after it was set does not block edits
+	svc.SetUnavailable(true)
+	if status, _ := writeAlbum(router, "PUT", "/albums/1", audit.Metadata{}, album); status != http.StatusOK {
+		t.Fatalf("Expected unchecked owner to be accepted, got %d", status)
+	}
+	svc.SetUnavailable(false)
+	album["price"] = 30
+	status, updated := writeAlbum(router, "PUT", "/albums/1", audit.Metadata{}, album)
+	if status != http.StatusOK || updated.OwnerID == nil || *updated.OwnerID != 99 {
+		t.Errorf("Expected update keeping owner 99, got %d %+v", status, updated)
+	}
+
+	// An absent owner_id keeps the current owner
+	delete(album, "owner_id")
+	if status, updated := writeAlbum(router, "PUT", "/albums/1", audit.Metadata{}, album); status != http.StatusOK || updated.OwnerID == nil || *updated.OwnerID != 99 {
+		t.Errorf("Expected owner 99 to be kept, got %d %+v", status, updated)
+	}
+}
+
+func TestGetAlbumByID_IncludeOwner(t *testing.T) {
+	router, svc := setupOwnerTestRouter()
+	writeAlbum(router, "PUT", "/albums/1", audit.Metadata{}, map[string]interface{}{
+		"title": "Hammerhead", "artist": "THE OFFSPRING", "price": 25.05, "tax": 0.1, "owner_id": 7,
+	})
+
+	_, albums := getResources(t, router, "/albums/1?fields=title&include=owner")
+	owner, ok := albums[0]["owner"].(map[string]interface{})
+	if !ok || owner["name"] != "Grace" || owner["user_type"] != "admin" {
+		t.Errorf("Expected owner Grace, got %v", albums[0])
+	}
+	if _, ok := owner["email"]; ok {
+		t.Errorf("Expected the owner's email to be left out, got %v", owner)
+	}
+
+	svc.SetUnavailable(true)
+	status, albums := getResources(t, router, "/albums/1?include=owner")
+	if status != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+	}
+	owner, ok = albums[0]["owner"].(map[string]interface{})
+	if !ok || owner["id"] != 7.0 || owner["unavailable"] != true || owner["name"] != nil {
+		t.Errorf("Expected unavailable owner stub, got %v", albums[0])
+	}
+
+	if _, albums := getResources(t, router, "/albums/2?include=owner"); albums[0]["owner"] != nil {
+		t.Errorf("Expected no owner for unowned album, got %v", albums[0])
+	}
+}
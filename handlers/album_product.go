@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-gin/models"
+	"golang-gin/products"
+	"golang-gin/repository"
+	"log"
+	"net/http"
+	"time"
+)
+
+// WithProducts links albums to product SKUs of the ProductService. A written
+// product_id must be a product GetProductByID knows; the product is then
+// stored in repo, from which ?include=product shows its price and stock.
+// While the ProductService is unavailable new links fail with 503.
+func WithProducts(svc products.ProductService, repo repository.ProductRepository) AlbumHandlerOption {
+	return func(h *AlbumHandler) {
+		h.products = svc
+		h.productRepo = repo
+	}
+}
+
+// checkProduct returns a ValidationError if the product of album is not a
+// known product. previous is the product before the write; an unchanged
+// link is not checked again so albums of discontinued products can still be
+// edited. A newly linked product is returned so that it can be stored with
+// storeProduct once the album has been written.
+func (h *AlbumHandler) checkProduct(ctx context.Context, album *models.Album, previous *uint) (*models.Product, error) {
+	if h.products == nil || album.ProductID == nil {
+		return nil, nil
+	}
+	if previous != nil && *previous == *album.ProductID {
+		return nil, nil
+	}
+
+	product, err := h.products.GetProduct(ctx, *album.ProductID)
+	if errors.Is(err, products.ErrProductNotFound) {
+		return nil, models.ValidationError{fmt.Sprintf("product_id %d is not a known product", *album.ProductID)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return product.Model(time.Now().UTC()), nil
+}
+
+// storeProduct saves a product returned by checkProduct. It must not run
+// inside a transaction: the product repository writes through its own
+// connection, which a transaction may be holding (SQLite has only one).
+func (h *AlbumHandler) storeProduct(ctx context.Context, product *models.Product) {
+	if product == nil {
+		return
+	}
+	if err := h.productRepo.Upsert(ctx, product); err != nil {
+		log.Printf("Failed to store product %d: %v", product.ID, err)
+	}
+}
+
+// checkLinks validates the owner and product of album against their
+// previous values and returns the newly linked product, if any
+func (h *AlbumHandler) checkLinks(ctx context.Context, album *models.Album, previous *models.Album) (*models.Product, error) {
+	var previousOwner, previousProduct *uint
+	if previous != nil {
+		previousOwner, previousProduct = previous.OwnerID, previous.ProductID
+	}
+	if err := h.checkOwner(ctx, album, previousOwner); err != nil {
+		return nil, err
+	}
+	return h.checkProduct(ctx, album, previousProduct)
+}
+
+// linkStatus returns the status and message of a failed link check
+func linkStatus(err error) (int, string) {
+	var validationErr models.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, products.ErrUnavailable):
+		return http.StatusServiceUnavailable, "Product service unavailable, try again later"
+	}
+	return http.StatusInternalServerError, "Failed to check album links"
+}
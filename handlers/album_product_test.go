@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"golang-gin/audit"
+	"golang-gin/database"
+	"golang-gin/models"
+	"golang-gin/products"
+	"golang-gin/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/logger"
+)
+
+func setupProductTestRouter() (*gin.Engine, *products.MockService, *repository.MockProductRepository) {
+	gin.SetMode(gin.TestMode)
+	svc := products.NewMockService(
+		products.Product{ID: 1, Name: "Laptop", Price: 1299.99, Stock: 50},
+		products.Product{ID: 2, Name: "Mouse", Price: 29.99, Stock: 0},
+	)
+	repo := repository.NewMockProductRepository()
+	handler := NewAlbumHandler(repository.NewMockAlbumRepository(),
+		WithProducts(svc, repo),
+		WithInclude("product", ProductInclude(repo)),
+	)
+
+	router := gin.New()
+	router.GET("/albums/:id", handler.GetAlbumByID)
+	router.POST("/albums", handler.PostAlbums)
+	router.PUT("/albums/:id", handler.UpdateAlbum)
+	return router, svc, repo
+}
+
+func TestPostAlbums_Product(t *testing.T) {
+	tests := []struct {
+		name        string
+		productID   uint
+		unavailable bool
+		status      int
+	}{
+		{"Known product", 1, false, http.StatusCreated},
+		{"Unknown product", 9, false, http.StatusBadRequest},
+		{"Product service unavailable", 1, true, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, svc, repo := setupProductTestRouter()
+			svc.SetUnavailable(tt.unavailable)
+
+			status, album := writeAlbum(router, "POST", "/albums", audit.Metadata{}, map[string]interface{}{
+				"title": "Blue Train", "artist": "John Coltrane", "price": 56.99, "tax": 0.1, "product_id": tt.productID,
+			})
+			if status != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, status)
+			}
+			stored, _ := repo.FindByIDs(context.Background(), []uint{tt.productID})
+			if tt.status == http.StatusCreated && (album.ProductID == nil || len(stored) != 1 || stored[0].Stock != 50) {
+				t.Errorf("Expected linked and stored product, got %+v %+v", album, stored)
+			}
+			if tt.status != http.StatusCreated && len(stored) != 0 {
+				t.Errorf("Expected no stored product, got %+v", stored)
+			}
+		})
+	}
+}
+
+func TestUpdateAlbum_Product(t *testing.T) {
+	router, svc, _ := setupProductTestRouter()
+	album := map[string]interface{}{"title": "Hammerhead", "artist": "THE OFFSPRING", "price": 25.05, "tax": 0.1, "product_id": 2}
+
+	if status, _ := writeAlbum(router, "PUT", "/albums/1", audit.Metadata{}, album); status != http.StatusOK {
+		t.Fatalf("Expected product to be linked, got %d", status)
+	}
+
+	// An unchanged link is not validated again
+	svc.SetUnavailable(true)
+	album["price"] = 30
+	if status, _ := writeAlbum(router, "PUT", "/albums/1", audit.Metadata{}, album); status != http.StatusOK {
+		t.Errorf("Expected unchanged link to be accepted, got %d", status)
+	}
+	album["product_id"] = 1
+	if status, _ := writeAlbum(router, "PUT", "/albums/1", audit.Metadata{}, album); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected new link to fail while unavailable, got %d", status)
+	}
+
+	// A PUT without product_id keeps the link
+	delete(album, "product_id")
+	status, updated := writeAlbum(router, "PUT", "/albums/1", audit.Metadata{}, album)
+	if status != http.StatusOK || updated.ProductID == nil || *updated.ProductID != 2 {
+		t.Errorf("Expected product link to be kept, got %d %+v", status, updated)
+	}
+}
+
+func TestGetAlbumByID_IncludeProduct(t *testing.T) {
+	router, _, repo := setupProductTestRouter()
+	writeAlbum(router, "PUT", "/albums/1", audit.Metadata{}, map[string]interface{}{
+		"title": "Hammerhead", "artist": "THE OFFSPRING", "price": 25.05, "tax": 0.1, "product_id": 1,
+	})
+
+	_, albums := getResources(t, router, "/albums/1?fields=title&include=product")
+	product, ok := albums[0]["product"].(map[string]interface{})
+	if !ok || product["name"] != "Laptop" || product["stock"] != 50.0 || product["in_stock"] != true {
+		t.Errorf("Expected Laptop in stock, got %v", albums[0])
+	}
+
+	// The include shows the stock of the last sync
+	repo.MarkDiscontinued(context.Background(), 1, time.Now())
+	_, albums = getResources(t, router, "/albums/1?include=product")
+	if product, ok := albums[0]["product"].(map[string]interface{}); !ok || product["discontinued"] != true || product["in_stock"] != false {
+		t.Errorf("Expected discontinued product, got %v", albums[0])
+	}
+
+	repo.Upsert(context.Background(), &models.Product{ID: 1, Name: "Laptop", Stock: 0})
+	_, albums = getResources(t, router, "/albums/1?include=product")
+	if product, ok := albums[0]["product"].(map[string]interface{}); !ok || product["in_stock"] != false || product["discontinued"] != false {
+		t.Errorf("Expected out of stock product, got %v", albums[0])
+	}
+
+	if _, albums := getResources(t, router, "/albums/2?include=product"); albums[0]["product"] != nil {
+		t.Errorf("Expected no product for unlinked album, got %v", albums[0])
+	}
+}
+
+func TestBatchAlbums_ProductOnSQLite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := database.Connect(&database.Config{Driver: database.DriverSQLiteMemory, LogLevel: logger.Silent})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	if err := database.Migrate(db, &models.Album{}, &models.AlbumAudit{}, &models.Product{}); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	// The only SQLite connection is held by the batch transaction, so the
+	// product must not be written while it is open
+	productRepo := repository.NewProductRepository(db)
+	handler := NewAlbumHandler(repository.NewAlbumRepository(db),
+		WithUnitOfWork(repository.NewUnitOfWork(db)),
+		WithProducts(products.NewMockService(products.Product{ID: 1, Name: "Laptop", Price: 1299.99, Stock: 50}), productRepo),
+	)
+	router := gin.New()
+	router.POST("/albums:method", CustomMethods(map[string]gin.HandlerFunc{"batch": handler.BatchAlbums}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "POST", "/albums:batch", bytes.NewBufferString(`{"atomic": true, "operations": [
+		{"method": "create", "album": {"title": "Blue Train", "artist": "John Coltrane", "price": 19.99, "tax": 0.1, "product_id": 1}}
+	]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if stored, _ := productRepo.FindByIDs(context.Background(), []uint{1}); len(stored) != 1 || stored[0].Name != "Laptop" {
+		t.Errorf("Expected the product to be stored after the batch, got %+v", stored)
+	}
+}
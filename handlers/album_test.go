@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"golang-gin/models"
 	"golang-gin/repository"
@@ -132,3 +133,201 @@ func TestPostAlbums_InvalidJSON(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
 	}
 }
+
+func TestGetAlbumByID_ETag(t *testing.T) {
+	router, handler := setupTestRouter()
+	router.GET("/albums/:id", handler.GetAlbumByID)
+
+	req, _ := http.NewRequest("GET", "/albums/1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected ETag header")
+	}
+
+	req, _ = http.NewRequest("GET", "/albums/1", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected status %d, got %d", http.StatusNotModified, w.Code)
+	}
+}
+
+func TestGetAlbumByID_RepresentationETag(t *testing.T) {
+	router, handler := setupTestRouter()
+	router.GET("/albums/:id", handler.GetAlbumByID)
+
+	get := func(path, accept, ifNoneMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	etags := map[string]bool{}
+	for _, r := range []struct{ path, accept string }{
+		{"/albums/1", ""},
+		{"/albums/1", "application/xml"},
+		{"/albums/1", "application/msgpack"},
+		{"/albums/1?fields=title", ""},
+		{"/albums/1?pretty", ""},
+	} {
+		etag := get(r.path, r.accept, "").Header().Get("ETag")
+		if etags[etag] {
+			t.Errorf("Expected a distinct ETag for %s (%s), got %s again", r.path, r.accept, etag)
+		}
+		etags[etag] = true
+	}
+
+	// A tag of one representation does not validate another
+	jsonETag := get("/albums/1", "", "").Header().Get("ETag")
+	if jsonETag != `"1"` {
+		t.Errorf("Expected the plain JSON ETag to be the version, got %s", jsonETag)
+	}
+	if w := get("/albums/1", "application/xml", jsonETag); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d for another representation, got %d", http.StatusOK, w.Code)
+	}
+	xmlETag := get("/albums/1", "application/xml", "").Header().Get("ETag")
+	if w := get("/albums/1", "application/xml", xmlETag); w.Code != http.StatusNotModified {
+		t.Errorf("Expected status %d for the same representation, got %d", http.StatusNotModified, w.Code)
+	}
+
+	// A sparse fieldset has no protobuf message
+	w := get("/albums/1?fields=title", "application/x-protobuf", "")
+	if w.Code != http.StatusNotAcceptable || w.Header().Get("ETag") != "" {
+		t.Errorf("Expected status %d without ETag, got %d (%s)", http.StatusNotAcceptable, w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestUpdateAlbum_IfMatch(t *testing.T) {
+	router, handler := setupTestRouter()
+	router.PUT("/albums/:id", handler.UpdateAlbum)
+
+	body, _ := json.Marshal(models.Album{Title: "Updated", Artist: "Artist", Price: 10, Tax: 0.1})
+
+	tests := []struct {
+		name           string
+		ifMatch        string
+		expectedStatus int
+	}{
+		{"Matching ETag", `"1"`, http.StatusOK},
+		{"Stale ETag", `"1"`, http.StatusPreconditionFailed},
+		{"Weak ETag", `W/"2"`, http.StatusPreconditionFailed},
+		{"Representation ETag", `"2-1a2b3c4d"`, http.StatusOK},
+		{"Wildcard", "*", http.StatusOK},
+		{"No If-Match", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("PUT", "/albums/1", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestUpdateAlbum_IfMatchRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewAlbumHandler(repository.NewMockAlbumRepository(), WithIfMatchRequired())
+	router.PUT("/albums/:id", handler.UpdateAlbum)
+
+	body, _ := json.Marshal(models.Album{Title: "Updated", Artist: "Artist", Price: 10, Tax: 0.1})
+	req, _ := http.NewRequest("PUT", "/albums/1", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPreconditionRequired {
+		t.Errorf("Expected status %d, got %d", http.StatusPreconditionRequired, w.Code)
+	}
+}
+
+func TestDeleteAlbum(t *testing.T) {
+	router, handler := setupTestRouter()
+	router.DELETE("/albums/:id", handler.DeleteAlbum)
+
+	tests := []struct {
+		name           string
+		id             string
+		ifMatch        string
+		expectedStatus int
+	}{
+		{"Stale ETag", "1", `"9"`, http.StatusPreconditionFailed},
+		{"Matching ETag", "1", `"1"`, http.StatusNoContent},
+		{"Already deleted", "1", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("DELETE", "/albums/"+tt.id, nil)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+// racingAlbumRepository updates an album right after it is read, simulating a
+// concurrent writer between the precondition check and the write
+type racingAlbumRepository struct {
+	repository.AlbumRepository
+}
+
+func (r *racingAlbumRepository) FindByID(ctx context.Context, id uint, opts ...repository.FindOption) (*models.Album, error) {
+	album, err := r.AlbumRepository.FindByID(ctx, id, opts...)
+	if err != nil {
+		return nil, err
+	}
+	concurrent := *album
+	concurrent.Title = "Concurrent edit"
+	if err := r.AlbumRepository.Update(ctx, &concurrent); err != nil {
+		return nil, err
+	}
+	return album, nil
+}
+
+func TestDeleteAlbum_ConcurrentUpdate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockRepo := repository.NewMockAlbumRepository()
+	handler := NewAlbumHandler(&racingAlbumRepository{AlbumRepository: mockRepo})
+	router := gin.New()
+	router.DELETE("/albums/:id", handler.DeleteAlbum)
+
+	req, _ := http.NewRequest("DELETE", "/albums/1", nil)
+	req.Header.Set("If-Match", `"1"`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status %d, got %d", http.StatusPreconditionFailed, w.Code)
+	}
+	if album, err := mockRepo.FindByID(context.Background(), 1); err != nil || album.Title != "Concurrent edit" {
+		t.Errorf("Expected the concurrent update to survive, got %v, %v", album, err)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"golang-gin/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// deletedAlbum is the trash listing representation of an album
+type deletedAlbum struct {
+	models.Album
+	DeletedAt time.Time `json:"deleted_at" xml:"deleted_at"`
+}
+
+// GetDeletedAlbums returns all soft-deleted albums
+func (h *AlbumHandler) GetDeletedAlbums(c *gin.Context) {
+	albums, err := h.repo.FindDeleted(c.Request.Context())
+	if err != nil {
+		writeResponse(c, http.StatusInternalServerError, gin.H{"error": "Failed to fetch deleted albums"})
+		return
+	}
+
+	deleted := make([]deletedAlbum, 0, len(albums))
+	for _, album := range albums {
+		deleted = append(deleted, deletedAlbum{Album: album, DeletedAt: album.DeletedAt.Time})
+	}
+	writeResponse(c, http.StatusOK, deleted)
+}
+
+// RestoreAlbum restores a soft-deleted album
+func (h *AlbumHandler) RestoreAlbum(c *gin.Context) {
+	id, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	album, err := h.repo.Restore(c.Request.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			writeResponse(c, http.StatusNotFound, gin.H{"message": "deleted album not found"})
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			writeResponse(c, http.StatusConflict, gin.H{"error": "an album with the same title and artist already exists"})
+		default:
+			writeResponse(c, http.StatusInternalServerError, gin.H{"error": "Failed to restore album"})
+		}
+		return
+	}
+
+	c.Header("ETag", representationETag(c, album))
+	writeResponse(c, http.StatusOK, album)
+}
+
+// PurgeAlbum permanently deletes a soft-deleted album
+func (h *AlbumHandler) PurgeAlbum(c *gin.Context) {
+	id, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Purge(c.Request.Context(), id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeResponse(c, http.StatusNotFound, gin.H{"message": "deleted album not found"})
+			return
+		}
+		writeResponse(c, http.StatusInternalServerError, gin.H{"error": "Failed to purge album"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAlbumTrashLifecycle(t *testing.T) {
+	router, handler := setupTestRouter()
+	router.DELETE("/albums/:id", handler.DeleteAlbum)
+	router.GET("/admin/albums/trash", handler.GetDeletedAlbums)
+	router.POST("/admin/albums/:id/restore", handler.RestoreAlbum)
+	router.DELETE("/admin/albums/:id", handler.PurgeAlbum)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("DELETE", "/albums/1"); w.Code != http.StatusNoContent {
+		t.Fatalf("Delete failed: status %d", w.Code)
+	}
+	if w := do("DELETE", "/albums/2"); w.Code != http.StatusNoContent {
+		t.Fatalf("Delete failed: status %d", w.Code)
+	}
+
+	t.Run("List trash", func(t *testing.T) {
+		w := do("GET", "/admin/albums/trash")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+
+		var deleted []deletedAlbum
+		if err := json.Unmarshal(w.Body.Bytes(), &deleted); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(deleted) != 2 {
+			t.Errorf("Expected 2 deleted albums, got %d", len(deleted))
+		}
+		for _, album := range deleted {
+			if album.DeletedAt.IsZero() {
+				t.Errorf("Expected deleted_at for album %d", album.ID)
+			}
+		}
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		if w := do("POST", "/admin/albums/1/restore"); w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if w := do("POST", "/admin/albums/1/restore"); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d for live album, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		if w := do("DELETE", "/admin/albums/1"); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d for live album, got %d", http.StatusNotFound, w.Code)
+		}
+		if w := do("DELETE", "/admin/albums/2"); w.Code != http.StatusNoContent {
+			t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+		}
+		if w := do("POST", "/admin/albums/2/restore"); w.Code != http.StatusNotFound {
+			t.Errorf("Expected purged album to be gone, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultCacheControl makes clients revalidate album responses before reuse
+const DefaultCacheControl = "private, no-cache"
+
+// setCacheHeaders writes Cache-Control and, when known, Last-Modified
+func (h *AlbumHandler) setCacheHeaders(c *gin.Context, lastModified time.Time) {
+	c.Header("Cache-Control", h.cacheControl)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModifiedSince reports whether If-Modified-Since allows a 304 response.
+// It is ignored when If-None-Match is present, as RFC 9110 requires, and
+// compares at second precision since HTTP dates carry no fractions.
+func notModifiedSince(c *gin.Context, lastModified time.Time) bool {
+	if lastModified.IsZero() || c.GetHeader("If-None-Match") != "" {
+		return false
+	}
+
+	since, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAlbumHandler_LastModified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := repository.NewMockAlbumRepository()
+	album := &models.Album{Title: "Cached", Artist: "Artist", Price: 10}
+	if err := repo.Create(context.Background(), album); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	handler := NewAlbumHandler(repo, WithCacheControl("private, max-age=60"))
+	router := gin.New()
+	router.GET("/albums", handler.GetAlbums)
+	router.GET("/albums/:id", handler.GetAlbumByID)
+
+	lastModified := album.UpdatedAt.UTC().Format(http.TimeFormat)
+	before := album.UpdatedAt.Add(-time.Hour).UTC().Format(http.TimeFormat)
+	detail := fmt.Sprintf("/albums/%d", album.ID)
+
+	tests := []struct {
+		name           string
+		path           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{"List without validator", "/albums", nil, http.StatusOK},
+		{"List not modified", "/albums", map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified},
+		{"List modified", "/albums", map[string]string{"If-Modified-Since": before}, http.StatusOK},
+		{"List invalid date", "/albums", map[string]string{"If-Modified-Since": "yesterday"}, http.StatusOK},
+		{"Detail not modified", detail, map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified},
+		{"Detail modified", detail, map[string]string{"If-Modified-Since": before}, http.StatusOK},
+		{"If-None-Match takes precedence", detail, map[string]string{
+			"If-Modified-Since": lastModified,
+			"If-None-Match":     `"stale"`,
+		}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if got := w.Header().Get("Last-Modified"); got != lastModified {
+				t.Errorf("Expected Last-Modified %q, got %q", lastModified, got)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "private, max-age=60" {
+				t.Errorf("Expected configured Cache-Control, got %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CustomMethods dispatches custom methods such as POST /albums:import.
+// The gin router treats ":" as a parameter marker, so the route is registered
+// once as "/albums:method" and the handler is picked by the method name.
+func CustomMethods(methods map[string]gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name, ok := strings.CutPrefix(c.Param("method"), ":")
+		if handler, found := methods[name]; ok && found {
+			handler(c)
+			return
+		}
+		c.IndentedJSON(http.StatusNotFound, gin.H{"error": "unknown method"})
+	}
+}
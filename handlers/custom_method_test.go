@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCustomMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/albums/:id", func(c *gin.Context) { c.String(http.StatusOK, "album") })
+	router.POST("/albums:method", CustomMethods(map[string]gin.HandlerFunc{
+		"import": func(c *gin.Context) { c.String(http.StatusOK, "import") },
+	}))
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"POST", "/albums:import", http.StatusOK, "import"},
+		{"GET", "/albums/1", http.StatusOK, "album"},
+		{"POST", "/albums:unknown", http.StatusNotFound, ""},
+		{"POST", "/albumsimport", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, tt.path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.wantStatus, w.Code)
+		}
+		if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+			t.Errorf("%s %s: expected body %q, got %q", tt.method, tt.path, tt.wantBody, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultEmailLimit and maxEmailLimit bound the page size of ListEmails
+	defaultEmailLimit = 50
+	maxEmailLimit     = 200
+)
+
+// EmailHandler serves the delivery status of queued emails
+type EmailHandler struct {
+	emails repository.EmailRepository
+}
+
+// NewEmailHandler creates a new EmailHandler
+func NewEmailHandler(emails repository.EmailRepository) *EmailHandler {
+	return &EmailHandler{emails: emails}
+}
+
+// ListEmails lists queued emails, newest first, without their bodies.
+// Supports ?status=pending|sent|failed, ?recipient=<address>, ?limit and
+// ?offset.
+func (h *EmailHandler) ListEmails(c *gin.Context) {
+	filter := repository.EmailFilter{
+		Status:    c.Query("status"),
+		Recipient: c.Query("recipient"),
+		Limit:     defaultEmailLimit,
+	}
+	switch filter.Status {
+	case "", models.EmailStatusPending, models.EmailStatusSent, models.EmailStatusFailed:
+	default:
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "status must be pending, sent or failed"})
+		return
+	}
+
+	var ok bool
+	if filter.Limit, ok = queryInt(c, "limit", defaultEmailLimit, 1, maxEmailLimit); !ok {
+		return
+	}
+	if filter.Offset, ok = queryInt(c, "offset", 0, 0, -1); !ok {
+		return
+	}
+
+	messages, total, err := h.emails.List(c.Request.Context(), filter)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch emails"})
+		return
+	}
+	for i := range messages {
+		messages[i].Text = ""
+		messages[i].HTML = ""
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"emails": messages, "total": total})
+}
+
+// GetEmail returns a queued email with its delivery status
+func (h *EmailHandler) GetEmail(c *gin.Context) {
+	id, ok := parseEmailID(c)
+	if !ok {
+		return
+	}
+
+	msg, err := h.emails.FindByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.IndentedJSON(http.StatusNotFound, gin.H{"message": "email not found"})
+			return
+		}
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch email"})
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, msg)
+}
+
+// RetryEmail queues a failed email again
+func (h *EmailHandler) RetryEmail(c *gin.Context) {
+	id, ok := parseEmailID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.emails.Requeue(ctx, id, time.Now().UTC()); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.IndentedJSON(http.StatusNotFound, gin.H{"message": "email not found"})
+		case errors.Is(err, repository.ErrEmailNotFailed):
+			c.IndentedJSON(http.StatusConflict, gin.H{"error": "Only failed emails can be retried"})
+		default:
+			c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry email"})
+		}
+		return
+	}
+
+	msg, err := h.emails.FindByID(ctx, id)
+	if err != nil {
+		c.IndentedJSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch email"})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, msg)
+}
+
+// parseEmailID parses the :id path parameter, writing a 400 response if it
+// is invalid
+func parseEmailID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": "Invalid email ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// queryInt parses an integer query parameter within [min, max], writing a
+// 400 response if it is invalid. A negative max means no upper bound.
+func queryInt(c *gin.Context, name string, defaultValue, min, max int) (int, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return defaultValue, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || (max >= 0 && n > max) {
+		msg := fmt.Sprintf("%s must be an integer >= %d", name, min)
+		if max >= 0 {
+			msg = fmt.Sprintf("%s must be an integer between %d and %d", name, min, max)
+		}
+		c.IndentedJSON(http.StatusBadRequest, gin.H{"error": msg})
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEmailHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	repo := repository.NewMockEmailRepository()
+	for _, to := range []string{"a@example.com", "b@example.com", "a@example.com"} {
+		repo.Enqueue(ctx, &models.EmailMessage{To: []string{to}, Subject: "hello", Text: "body"})
+	}
+	repo.MarkSent(ctx, 1, time.Now().UTC())
+	repo.MarkFailed(ctx, 2, "550 5.1.1 No such user")
+
+	handler := NewEmailHandler(repo)
+	router := gin.New()
+	router.GET("/admin/emails", handler.ListEmails)
+	router.GET("/admin/emails/:id", handler.GetEmail)
+	router.POST("/admin/emails/:id/retry", handler.RetryEmail)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("List", func(t *testing.T) {
+		tests := []struct {
+			query    string
+			status   int
+			expected []uint
+		}{
+			{"", http.StatusOK, []uint{3, 2, 1}},
+			{"?status=sent", http.StatusOK, []uint{1}},
+			{"?recipient=a@example.com", http.StatusOK, []uint{3, 1}},
+			{"?limit=1&offset=1", http.StatusOK, []uint{2}},
+			{"?status=unknown", http.StatusBadRequest, nil},
+			{"?limit=0", http.StatusBadRequest, nil},
+			{"?offset=-1", http.StatusBadRequest, nil},
+		}
+
+		for _, tt := range tests {
+			w := do("GET", "/admin/emails"+tt.query)
+			if w.Code != tt.status {
+				t.Errorf("%s: Expected status %d, got %d", tt.query, tt.status, w.Code)
+				continue
+			}
+			if tt.status != http.StatusOK {
+				continue
+			}
+
+			var body struct {
+				Emails []models.EmailMessage `json:"emails"`
+				Total  int64                 `json:"total"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(body.Emails) != len(tt.expected) {
+				t.Errorf("%s: Expected emails %v, got %d", tt.query, tt.expected, len(body.Emails))
+				continue
+			}
+			for i, msg := range body.Emails {
+				if msg.ID != tt.expected[i] || msg.Text != "" {
+					t.Errorf("%s: Expected email %d without body, got %+v", tt.query, tt.expected[i], msg)
+				}
+			}
+		}
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		w := do("GET", "/admin/emails/2")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		var msg models.EmailMessage
+		if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if msg.Status != models.EmailStatusFailed || msg.LastError == "" || msg.Text != "body" {
+			t.Errorf("Unexpected email %+v", msg)
+		}
+
+		if w := do("GET", "/admin/emails/99"); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+		if w := do("GET", "/admin/emails/abc"); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		tests := []struct {
+			id     string
+			status int
+		}{
+			{"2", http.StatusOK},
+			{"2", http.StatusConflict},
+			{"1", http.StatusConflict},
+			{"99", http.StatusNotFound},
+		}
+
+		for _, tt := range tests {
+			if w := do("POST", "/admin/emails/"+tt.id+"/retry"); w.Code != tt.status {
+				t.Errorf("Retry %s: Expected status %d, got %d", tt.id, tt.status, w.Code)
+			}
+		}
+		if msg, _ := repo.FindByID(ctx, 2); msg.Status != models.EmailStatusPending || msg.Attempts != 0 {
+			t.Errorf("Expected email to be pending again, got %+v", msg)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"golang-gin/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// matchETag reports whether an If-None-Match header value matches the given
+// entity tag. The header may be "*" or a comma separated list of tags; weak
+// validators (W/"...") are compared by their opaque value.
+func matchETag(header, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return true
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// matchIfMatch reports whether an If-Match header value matches a
+// representation of the album at the given version. Tags are compared
+// strongly as RFC 9110 requires for If-Match, so weak validators never
+// match.
+func matchIfMatch(header string, version uint) bool {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return true
+	}
+
+	want := strconv.FormatUint(uint64(version), 10)
+	for _, candidate := range strings.Split(header, ",") {
+		if tagVersion(strings.TrimSpace(candidate)) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// tagVersion returns the album version of a strong tag written by
+// representationETag ("3" or "3-1a2b3c4d"), or "" for weak or malformed tags
+func tagVersion(tag string) string {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return ""
+	}
+	version, _, _ := strings.Cut(tag[1:len(tag)-1], "-")
+	return version
+}
+
+// representationETag returns the entity tag of the representation of album
+// sent to c. A strong tag promises byte-identical bodies, so every media
+// type and query (?fields=, ?include=, ?pretty) gets its own tag. The plain
+// JSON representation keeps the version tag of Album.ETag.
+func representationETag(c *gin.Context, album *models.Album) string {
+	mediaType := negotiateMediaType(c)
+	query := c.Request.URL.Query().Encode()
+	if mediaType == mimeJSON && query == "" {
+		return album.ETag()
+	}
+
+	sum := sha256.Sum256([]byte(mediaType + "\x00" + query))
+	return fmt.Sprintf("\"%d-%x\"", album.Version, sum[:4])
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import "testing"
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		header string
+		etag   string
+		want   bool
+	}{
+		{`"1"`, `"1"`, true},
+		{`"2"`, `"1"`, false},
+		{`*`, `"1"`, true},
+		{`"3", "1"`, `"1"`, true},
+		{`W/"1"`, `"1"`, true},
+	}
+
+	for _, tt := range tests {
+		if got := matchETag(tt.header, tt.etag); got != tt.want {
+			t.Errorf("matchETag(%q, %q) = %v, want %v", tt.header, tt.etag, got, tt.want)
+		}
+	}
+}
+
+func TestMatchIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		version uint
+		want    bool
+	}{
+		{`"1"`, 1, true},
+		{`"2"`, 1, false},
+		{`*`, 1, true},
+		{`"3", "1"`, 1, true},
+		{`"1-1a2b3c4d"`, 1, true},
+		{`"1-gzip"`, 1, true},
+		{`"12"`, 1, false},
+		{`W/"1"`, 1, false},
+		{`1`, 1, false},
+	}
+
+	for _, tt := range tests {
+		if got := matchIfMatch(tt.header, tt.version); got != tt.want {
+			t.Errorf("matchIfMatch(%q, %d) = %v, want %v", tt.header, tt.version, got, tt.want)
+		}
+	}
+}
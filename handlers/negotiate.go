@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"golang-gin/models"
+	pb "golang-gin/grpc/proto"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"google.golang.org/protobuf/proto"
+)
+
+// Media types understood by the album endpoints
+const (
+	mimeJSON     = "application/json"
+	mimeXML      = "application/xml"
+	mimeYAML     = "application/yaml"
+	mimeMsgPack  = "application/msgpack"
+	mimeProtobuf = "application/x-protobuf"
+)
+
+// mediaTypes maps every accepted media type, including common aliases, to
+// its canonical form. The first entry is the default.
+var mediaTypes = []struct{ name, canonical string }{
+	{mimeJSON, mimeJSON},
+	{mimeXML, mimeXML},
+	{"text/xml", mimeXML},
+	{mimeYAML, mimeYAML},
+	{"application/x-yaml", mimeYAML},
+	{"text/yaml", mimeYAML},
+	{mimeMsgPack, mimeMsgPack},
+	{"application/x-msgpack", mimeMsgPack},
+	{mimeProtobuf, mimeProtobuf},
+	{"application/protobuf", mimeProtobuf},
+}
+
+// errUnsupportedMediaType is returned by bindBody for unknown Content-Types
+var errUnsupportedMediaType = errors.New("unsupported content type")
+
+// canonicalMediaType returns the canonical form of a media type, or "" if
+// it is not supported
+func canonicalMediaType(mediaType string) string {
+	for _, t := range mediaTypes {
+		if t.name == mediaType {
+			return t.canonical
+		}
+	}
+	return ""
+}
+
+// negotiateMediaType returns the canonical media type negotiated from the
+// Accept header, JSON if none of the offered types is acceptable
+func negotiateMediaType(c *gin.Context) string {
+	offered := make([]string, len(mediaTypes))
+	for i, t := range mediaTypes {
+		offered[i] = t.name
+	}
+	if mediaType := canonicalMediaType(c.NegotiateFormat(offered...)); mediaType != "" {
+		return mediaType
+	}
+	return mimeJSON
+}
+
+// writeResponse renders body in the format negotiated from the Accept
+// header. JSON is compact unless ?pretty is set. Error bodies have no
+// protobuf representation and fall back to JSON; other bodies without one,
+// such as sparse fieldsets, are answered with 406.
+func writeResponse(c *gin.Context, status int, body interface{}) {
+	c.Writer.Header().Add("Vary", "Accept")
+
+	switch negotiateMediaType(c) {
+	case mimeXML:
+		c.XML(status, xmlBody(body))
+		return
+	case mimeYAML:
+		c.YAML(status, body)
+		return
+	case mimeMsgPack:
+		c.Render(status, render.MsgPack{Data: body})
+		return
+	case mimeProtobuf:
+		if msg := protoBody(body); msg != nil {
+			c.ProtoBuf(status, msg)
+			return
+		}
+		if status < http.StatusBadRequest {
+			// The ETag set by the handler describes a body that is not sent
+			c.Header("ETag", "")
+			c.JSON(http.StatusNotAcceptable, gin.H{"error": "this resource has no protobuf representation"})
+			return
+		}
+	}
+
+	if pretty, ok := c.GetQuery("pretty"); ok && pretty != "false" && pretty != "0" {
+		c.IndentedJSON(status, body)
+		return
+	}
+	c.JSON(status, body)
+}
+
+// bindBody decodes the request body according to its Content-Type. A
+// missing Content-Type is treated as JSON.
+func bindBody(c *gin.Context, obj interface{}) error {
+	mediaType := mimeJSON
+	if ct := c.GetHeader("Content-Type"); ct != "" {
+		parsed, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return errUnsupportedMediaType
+		}
+		mediaType = parsed
+	}
+
+	switch canonicalMediaType(mediaType) {
+	case mimeJSON:
+		return c.ShouldBindWith(obj, binding.JSON)
+	case mimeXML:
+		return c.ShouldBindWith(obj, binding.XML)
+	case mimeYAML:
+		return c.ShouldBindWith(obj, binding.YAML)
+	case mimeMsgPack:
+		return c.ShouldBindWith(obj, binding.MsgPack)
+	case mimeProtobuf:
+		album, ok := obj.(*models.Album)
+		if !ok {
+			return errUnsupportedMediaType
+		}
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return err
+		}
+		var msg pb.Album
+		if err := proto.Unmarshal(data, &msg); err != nil {
+			return err
+		}
+		*album = albumFromPB(&msg)
+		return nil
+	default:
+		return errUnsupportedMediaType
+	}
+}
+
+// writeBindError responds to a request body that could not be decoded
+func writeBindError(c *gin.Context, err error) {
+	if errors.Is(err, errUnsupportedMediaType) {
+		writeResponse(c, http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	}
+	writeResponse(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// xmlList wraps slices so that they encode as a single XML document
+type xmlList struct {
+	XMLName xml.Name    `xml:"items"`
+	Items   interface{} `xml:"item"`
+}
+
+// xmlAlbum gives a single album a lower-case root element
+type xmlAlbum struct {
+	XMLName xml.Name `xml:"album"`
+	*models.Album
+}
+
+// xmlBody adapts body for XML encoding
+func xmlBody(body interface{}) interface{} {
+	switch v := body.(type) {
+	case models.Album:
+		return xmlAlbum{Album: &v}
+	case *models.Album:
+		return xmlAlbum{Album: v}
+	case []models.Album:
+		return struct {
+			XMLName xml.Name       `xml:"albums"`
+			Albums  []models.Album `xml:"album"`
+		}{Albums: v}
+	case resource:
+		return namedResource{name: "album", resource: v}
+	case []resource:
+		return struct {
+			XMLName xml.Name   `xml:"albums"`
+			Albums  []resource `xml:"album"`
+		}{Albums: v}
+	}
+
+	if rv := reflect.ValueOf(body); rv.Kind() == reflect.Slice {
+		return xmlList{Items: body}
+	}
+	return body
+}
+
+// protoBody converts album bodies to their protobuf messages, and returns
+// nil for bodies without a protobuf representation
+func protoBody(body interface{}) proto.Message {
+	switch v := body.(type) {
+	case models.Album:
+		return albumToPB(&v)
+	case *models.Album:
+		return albumToPB(v)
+	case []models.Album:
+		resp := &pb.GetAlbumsResponse{Albums: make([]*pb.Album, len(v))}
+		for i := range v {
+			resp.Albums[i] = albumToPB(&v[i])
+		}
+		return resp
+	}
+	return nil
+}
+
+// albumToPB converts an album to its protobuf message
+func albumToPB(album *models.Album) *pb.Album {
+	return &pb.Album{
+		Id:     strconv.FormatUint(uint64(album.ID), 10),
+		Title:  album.Title,
+		Artist: album.Artist,
+		Price:  album.Price,
+		Tax:    album.Tax,
+		Etag:   album.ETag(),
+	}
+}
+
+// albumFromPB converts a protobuf album message to an album. The ID is taken
+// from the URL and the version from If-Match, so both are ignored here.
+func albumFromPB(msg *pb.Album) models.Album {
+	return models.Album{
+		Title:  msg.Title,
+		Artist: msg.Artist,
+		Price:  msg.Price,
+		Tax:    msg.Tax,
+	}
+}
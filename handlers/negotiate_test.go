@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"golang-gin/models"
+	pb "golang-gin/grpc/proto"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGetAlbumByID_ContentNegotiation(t *testing.T) {
+	router, handler := setupTestRouter()
+	router.GET("/albums/:id", handler.GetAlbumByID)
+	router.GET("/albums", handler.GetAlbums)
+
+	tests := []struct {
+		name            string
+		path            string
+		accept          string
+		wantContentType string
+		decode          func([]byte) (string, error)
+	}{
+		{"Default JSON", "/albums/1", "", "application/json", func(b []byte) (string, error) {
+			var a models.Album
+			err := json.Unmarshal(b, &a)
+			return a.Title, err
+		}},
+		{"XML", "/albums/1", "application/xml", "application/xml", func(b []byte) (string, error) {
+			var a models.Album
+			err := xml.Unmarshal(b, &a)
+			return a.Title, err
+		}},
+		{"YAML alias", "/albums/1", "application/x-yaml", "application/yaml", func(b []byte) (string, error) {
+			var a models.Album
+			err := yaml.Unmarshal(b, &a)
+			return a.Title, err
+		}},
+		{"MessagePack", "/albums/1", "application/msgpack", "application/msgpack", func(b []byte) (string, error) {
+			var a models.Album
+			err := codec.NewDecoderBytes(b, &codec.MsgpackHandle{}).Decode(&a)
+			return a.Title, err
+		}},
+		{"Protobuf", "/albums/1", "application/x-protobuf", "application/x-protobuf", func(b []byte) (string, error) {
+			var a pb.Album
+			err := proto.Unmarshal(b, &a)
+			return a.Title, err
+		}},
+		{"Protobuf list", "/albums", "application/x-protobuf", "application/x-protobuf", func(b []byte) (string, error) {
+			var resp pb.GetAlbumsResponse
+			err := proto.Unmarshal(b, &resp)
+			if err != nil || len(resp.Albums) == 0 {
+				return "", err
+			}
+			return resp.Albums[0].Title, nil
+		}},
+		{"Unsupported falls back to JSON", "/albums/1", "text/csv", "application/json", func(b []byte) (string, error) {
+			var a models.Album
+			err := json.Unmarshal(b, &a)
+			return a.Title, err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.wantContentType) {
+				t.Errorf("Expected content type %s, got %s", tt.wantContentType, ct)
+			}
+			title, err := tt.decode(w.Body.Bytes())
+			if err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if title != "Hammerhead" {
+				t.Errorf("Expected title 'Hammerhead', got '%s'", title)
+			}
+		})
+	}
+}
+
+func TestGetAlbums_PrettyJSON(t *testing.T) {
+	router, handler := setupTestRouter()
+	router.GET("/albums", handler.GetAlbums)
+
+	for _, tt := range []struct {
+		path       string
+		wantIndent bool
+	}{
+		{"/albums", false},
+		{"/albums?pretty", true},
+		{"/albums?pretty=false", false},
+	} {
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if got := strings.Contains(w.Body.String(), "\n    "); got != tt.wantIndent {
+			t.Errorf("%s: expected indented=%v, got body %q", tt.path, tt.wantIndent, w.Body.String())
+		}
+	}
+}
+
+func TestPostAlbums_RequestFormats(t *testing.T) {
+	album := models.Album{Title: "Blue Train", Artist: "John Coltrane", Price: 19.99, Tax: 0.1}
+
+	xmlBody, _ := xml.Marshal(album)
+	yamlBody, _ := yaml.Marshal(album)
+	var msgpackBody []byte
+	codec.NewEncoderBytes(&msgpackBody, &codec.MsgpackHandle{}).Encode(album)
+	protoBody, _ := proto.Marshal(&pb.Album{Title: album.Title, Artist: album.Artist, Price: album.Price, Tax: album.Tax})
+
+	tests := []struct {
+		contentType string
+		body        []byte
+		wantStatus  int
+	}{
+		{"application/xml", xmlBody, http.StatusCreated},
+		{"application/yaml", yamlBody, http.StatusCreated},
+		{"application/msgpack", msgpackBody, http.StatusCreated},
+		{"application/x-protobuf", protoBody, http.StatusCreated},
+		{"text/plain", []byte("Blue Train"), http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			router, handler := setupTestRouter()
+			router.POST("/albums", handler.PostAlbums)
+
+			req, _ := http.NewRequest("POST", "/albums", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+
+			var created models.Album
+			if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if created.Title != album.Title || created.Price != album.Price {
+				t.Errorf("Expected %+v, got %+v", album, created)
+			}
+		})
+	}
+}
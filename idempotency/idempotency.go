@@ -0,0 +1,143 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"time"
+)
+
+var (
+	// ErrInFlight is returned when a request with the same key is still running
+	ErrInFlight = errors.New("a request with this idempotency key is in progress")
+	// ErrFingerprintMismatch is returned when a key is reused for a different request
+	ErrFingerprintMismatch = errors.New("idempotency key was used for a different request")
+	// ErrClaimLost is returned by Complete when the request ran longer than
+	// the lock timeout and a retry has taken over its key
+	ErrClaimLost = repository.ErrClaimLost
+)
+
+// lockTimeout is how long an in-progress key blocks retries before it is
+// considered abandoned (e.g. the process crashed mid-request)
+const lockTimeout = time.Minute
+
+// Response is a stored response that can be replayed
+type Response struct {
+	StatusCode  int
+	ContentType string
+	ETag        string
+	Vary        string
+	Body        []byte
+}
+
+// Claim is a key held by one request. Only the holder can complete or
+// release it.
+type Claim struct {
+	Scope string
+	Key   string
+	Owner string
+}
+
+// Service coordinates idempotent requests on top of an IdempotencyRepository
+type Service struct {
+	repo repository.IdempotencyRepository
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// NewService creates a new Service keeping responses for ttl
+func NewService(repo repository.IdempotencyRepository, ttl time.Duration) *Service {
+	return &Service{repo: repo, ttl: ttl, now: time.Now}
+}
+
+// Begin claims key within scope for a request with the given fingerprint.
+// It returns the claim when the caller should execute the request and then
+// call Complete or Release, or the stored response when the request has
+// already been completed. Concurrent duplicates get ErrInFlight and reuses
+// with a different fingerprint get ErrFingerprintMismatch.
+func (s *Service) Begin(ctx context.Context, scope, key, fingerprint string) (*Claim, *Response, error) {
+	owner, err := newOwner()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := s.now().UTC()
+	existing, err := s.repo.Claim(ctx, &models.IdempotencyKey{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		Owner:       owner,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	}, now.Add(-lockTimeout))
+	if err != nil {
+		return nil, nil, err
+	}
+	if existing == nil {
+		return &Claim{Scope: scope, Key: key, Owner: owner}, nil, nil
+	}
+
+	if existing.Fingerprint != fingerprint {
+		return nil, nil, ErrFingerprintMismatch
+	}
+	if !existing.Completed {
+		return nil, nil, ErrInFlight
+	}
+
+	return nil, &Response{
+		StatusCode:  existing.StatusCode,
+		ContentType: existing.ContentType,
+		ETag:        existing.ETag,
+		Vary:        existing.Vary,
+		Body:        existing.Body,
+	}, nil
+}
+
+// Complete stores the response for a claimed key. It returns ErrClaimLost
+// if the claim is no longer held.
+func (s *Service) Complete(ctx context.Context, claim *Claim, resp Response) error {
+	return s.repo.Complete(context.WithoutCancel(ctx), &models.IdempotencyKey{
+		Scope:       claim.Scope,
+		Key:         claim.Key,
+		Owner:       claim.Owner,
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.ContentType,
+		ETag:        resp.ETag,
+		Vary:        resp.Vary,
+		Body:        resp.Body,
+	})
+}
+
+// Release frees a claimed key after a failed request so that it can be
+// retried. A claim that has been taken over is left alone.
+func (s *Service) Release(ctx context.Context, claim *Claim) error {
+	return s.repo.Release(context.WithoutCancel(ctx), claim.Scope, claim.Key, claim.Owner)
+}
+
+// DeleteExpired removes stored responses whose TTL has passed
+func (s *Service) DeleteExpired(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpired(ctx, s.now().UTC())
+}
+
+// Fingerprint returns a stable hash identifying a request
+func Fingerprint(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write(part)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// newOwner returns a random claim owner token
+func newOwner() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,132 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"golang-gin/repository"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestService() (*Service, *time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := NewService(repository.NewMockIdempotencyRepository(), time.Hour)
+	svc.now = func() time.Time { return now }
+	return svc, &now
+}
+
+func TestService_Replay(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+
+	claim, replay, err := svc.Begin(ctx, "s", "k", "f")
+	if err != nil || claim == nil || replay != nil {
+		t.Fatalf("Expected a claim, got %+v, %+v, %v", claim, replay, err)
+	}
+	if err := svc.Complete(ctx, claim, Response{StatusCode: 201, ContentType: "application/json", Body: []byte(`{"id":1}`)}); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	claim, replay, err = svc.Begin(ctx, "s", "k", "f")
+	if err != nil || claim != nil {
+		t.Fatalf("Expected a replay, got %+v, %v", claim, err)
+	}
+	if replay.StatusCode != 201 || replay.ContentType != "application/json" || string(replay.Body) != `{"id":1}` {
+		t.Errorf("Expected the stored response, got %+v", replay)
+	}
+
+	if claim, _, err := svc.Begin(ctx, "other", "k", "f"); err != nil || claim == nil {
+		t.Errorf("Expected keys to be scoped, got %+v, %v", claim, err)
+	}
+}
+
+func TestService_FingerprintMismatch(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+
+	claim, _, _ := svc.Begin(ctx, "s", "k", Fingerprint([]byte(`{"title":"a"}`)))
+	svc.Complete(ctx, claim, Response{StatusCode: 201})
+
+	if _, _, err := svc.Begin(ctx, "s", "k", Fingerprint([]byte(`{"title":"b"}`))); !errors.Is(err, ErrFingerprintMismatch) {
+		t.Errorf("Expected ErrFingerprintMismatch, got %v", err)
+	}
+}
+
+func TestService_ConcurrentClaim(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		claims   int
+		inFlight int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claim, _, err := svc.Begin(ctx, "s", "k", "f")
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case claim != nil:
+				claims++
+			case errors.Is(err, ErrInFlight):
+				inFlight++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if claims != 1 || inFlight != 9 {
+		t.Errorf("Expected 1 claim and 9 in-flight duplicates, got %d and %d", claims, inFlight)
+	}
+}
+
+func TestService_StaleClaim(t *testing.T) {
+	svc, now := newTestService()
+	ctx := context.Background()
+
+	first, _, _ := svc.Begin(ctx, "s", "k", "f")
+
+	*now = now.Add(30 * time.Second)
+	if _, _, err := svc.Begin(ctx, "s", "k", "f"); !errors.Is(err, ErrInFlight) {
+		t.Fatalf("Expected ErrInFlight within the lock timeout, got %v", err)
+	}
+
+	*now = now.Add(lockTimeout)
+	second, _, err := svc.Begin(ctx, "s", "k", "f")
+	if err != nil || second == nil {
+		t.Fatalf("Expected a stale claim to be taken over, got %+v, %v", second, err)
+	}
+
+	// The first request must not release or overwrite the new claim
+	if err := svc.Release(ctx, first); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if err := svc.Complete(ctx, first, Response{StatusCode: 201, Body: []byte("first")}); !errors.Is(err, ErrClaimLost) {
+		t.Errorf("Expected ErrClaimLost for the stale claim, got %v", err)
+	}
+
+	if err := svc.Complete(ctx, second, Response{StatusCode: 201, Body: []byte("second")}); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if _, replay, _ := svc.Begin(ctx, "s", "k", "f"); replay == nil || string(replay.Body) != "second" {
+		t.Errorf("Expected the response of the current holder, got %+v", replay)
+	}
+}
+
+func TestService_Release(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+
+	claim, _, _ := svc.Begin(ctx, "s", "k", "f")
+	if err := svc.Release(ctx, claim); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if claim, _, err := svc.Begin(ctx, "s", "k", "f"); err != nil || claim == nil {
+		t.Errorf("Expected a released key to be claimable, got %+v, %v", claim, err)
+	}
+}
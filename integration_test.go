@@ -17,9 +17,13 @@ import (
 func setupIntegrationTestRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
+	router.Use(middleware.RequestID())
 	router.Use(middleware.Logger())
 	router.Use(middleware.CORS())
+	router.Use(middleware.AuditContext())
 	router.Use(gin.Recovery())
+	router.Use(middleware.Decompress(10 << 20))
+	router.Use(middleware.Compress(middleware.DefaultCompressConfig))
 
 	// Initialize mock repository and handler
 	mockRepo := repository.NewMockAlbumRepository()
@@ -34,6 +38,8 @@ func setupIntegrationTestRouter() *gin.Engine {
 		v1.GET("/albums", albumHandler.GetAlbums)
 		v1.GET("/albums/:id", albumHandler.GetAlbumByID)
 		v1.POST("/albums", albumHandler.PostAlbums)
+		v1.PUT("/albums/:id", albumHandler.UpdateAlbum)
+		v1.DELETE("/albums/:id", albumHandler.DeleteAlbum)
 	}
 
 	return router
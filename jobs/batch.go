@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// batchLoop drives a job that claims and processes rows in batches, such as
+// the outbox relay and the email worker
+type batchLoop struct {
+	name      string // used in log messages, e.g. "Outbox relay"
+	interval  time.Duration
+	batchSize int
+	now       func() time.Time
+	// runOnce processes one batch and returns the number of rows handled
+	runOnce func(ctx context.Context) (int, error)
+	// cleanup deletes finished rows; it runs at most once an hour
+	cleanup func(ctx context.Context, now time.Time) error
+}
+
+// run calls runOnce every interval until ctx is canceled, and again right
+// away while it returns full batches
+func (l batchLoop) run(ctx context.Context) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Time{}
+	for {
+		// Keep draining while full batches come back
+		for {
+			n, err := l.runOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("%s failed: %v", l.name, err)
+			}
+			if err != nil || n < l.batchSize {
+				break
+			}
+		}
+
+		if now := l.now(); now.Sub(lastCleanup) >= time.Hour {
+			lastCleanup = now
+			if err := l.cleanup(ctx, now); err != nil && ctx.Err() == nil {
+				log.Printf("%s cleanup failed: %v", l.name, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package jobs
+
+import (
+	"context"
+	"golang-gin/backoff"
+	"testing"
+	"time"
+)
+
+func TestBatchLoop_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Two full batches are drained right away, the third is partial
+	sizes := []int{10, 10, 3}
+	runs, cleanups := 0, 0
+	batchLoop{
+		name:      "Test job",
+		interval:  time.Hour,
+		batchSize: 10,
+		now:       time.Now,
+		runOnce: func(ctx context.Context) (int, error) {
+			n := sizes[runs]
+			runs++
+			if runs == len(sizes) {
+				cancel()
+			}
+			return n, nil
+		},
+		cleanup: func(ctx context.Context, now time.Time) error {
+			cleanups++
+			return nil
+		},
+	}.run(ctx)
+
+	if runs != 3 || cleanups != 1 {
+		t.Errorf("Expected 3 batches and 1 cleanup, got %d and %d", runs, cleanups)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt  int
+		min      time.Duration
+		max      time.Duration
+		expected time.Duration
+	}{
+		{1, outboxMinBackoff, outboxMaxBackoff, time.Second},
+		{2, outboxMinBackoff, outboxMaxBackoff, 2 * time.Second},
+		{5, outboxMinBackoff, outboxMaxBackoff, 16 * time.Second},
+		{30, outboxMinBackoff, outboxMaxBackoff, outboxMaxBackoff},
+		{1, emailMinBackoff, emailMaxBackoff, 30 * time.Second},
+		{2, emailMinBackoff, emailMaxBackoff, time.Minute},
+		{4, emailMinBackoff, emailMaxBackoff, 4 * time.Minute},
+		{20, emailMinBackoff, emailMaxBackoff, emailMaxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := backoff.Exponential(tt.attempt, tt.min, tt.max); got != tt.expected {
+			t.Errorf("Expected backoff %v for attempt %d, got %v", tt.expected, tt.attempt, got)
+		}
+	}
+}
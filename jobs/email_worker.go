@@ -0,0 +1,156 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"golang-gin/backoff"
+	"golang-gin/clients"
+	"golang-gin/email"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"log"
+	"time"
+)
+
+const (
+	// emailBatchSize is the number of emails claimed per poll
+	emailBatchSize = 50
+	// emailLeaseMargin is added to the time a whole batch may take to send
+	// when leasing claimed emails
+	emailLeaseMargin = time.Minute
+	// defaultSendTimeout matches the default timeout of clients.MailClient
+	defaultSendTimeout = 30 * time.Second
+	// emailMinBackoff and emailMaxBackoff bound the delay between attempts
+	emailMinBackoff = 30 * time.Second
+	emailMaxBackoff = time.Hour
+	// emailRetention is how long sent emails are kept for the status API
+	emailRetention = 30 * 24 * time.Hour
+)
+
+// EmailSender sends email messages, e.g. clients.MailClient
+type EmailSender interface {
+	SendContext(ctx context.Context, msg *email.Message) error
+}
+
+// EmailWorker sends queued emails. Transient failures (connection errors,
+// 4xx replies) are retried with exponential backoff up to maxAttempts;
+// permanent failures (invalid messages, 5xx replies) fail the email at once.
+// Like the outbox relay, delivery is at-least-once.
+type EmailWorker struct {
+	repo        repository.EmailRepository
+	sender      EmailSender
+	interval    time.Duration
+	maxAttempts int
+	sendTimeout time.Duration
+	permanent   func(error) bool
+	now         func() time.Time
+}
+
+// EmailWorkerOption configures an EmailWorker
+type EmailWorkerOption func(*EmailWorker)
+
+// WithSendTimeout sets the longest a single send may take (30s by default).
+// Claimed emails are hidden from other workers long enough for a whole batch
+// to time out.
+func WithSendTimeout(timeout time.Duration) EmailWorkerOption {
+	return func(j *EmailWorker) {
+		j.sendTimeout = timeout
+	}
+}
+
+// NewEmailWorker creates a new EmailWorker polling every interval
+func NewEmailWorker(repo repository.EmailRepository, sender EmailSender, interval time.Duration, maxAttempts int, opts ...EmailWorkerOption) *EmailWorker {
+	j := &EmailWorker{
+		repo:        repo,
+		sender:      sender,
+		interval:    interval,
+		maxAttempts: maxAttempts,
+		sendTimeout: defaultSendTimeout,
+		permanent:   clients.IsPermanentMailError,
+		now:         func() time.Time { return time.Now().UTC() },
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// lease returns how long claimed emails are hidden from other workers: long
+// enough for every email of a batch to hit the send timeout
+func (j *EmailWorker) lease() time.Duration {
+	return emailBatchSize*j.sendTimeout + emailLeaseMargin
+}
+
+// Run sends due emails every interval until ctx is canceled
+func (j *EmailWorker) Run(ctx context.Context) {
+	log.Printf("✉️ Email worker started (interval: %v, max attempts: %d)", j.interval, j.maxAttempts)
+	batchLoop{
+		name:      "Email worker",
+		interval:  j.interval,
+		batchSize: emailBatchSize,
+		now:       j.now,
+		runOnce:   j.RunOnce,
+		cleanup: func(ctx context.Context, now time.Time) error {
+			_, err := j.repo.DeleteSentBefore(ctx, now.Add(-emailRetention))
+			return err
+		},
+	}.run(ctx)
+	log.Println("✉️ Email worker stopped")
+}
+
+// RunOnce sends one batch of due emails and returns the number of emails
+// claimed
+func (j *EmailWorker) RunOnce(ctx context.Context) (int, error) {
+	messages, err := j.repo.Claim(ctx, j.now(), emailBatchSize, j.lease())
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range messages {
+		msg := &messages[i]
+		err := j.sender.SendContext(ctx, toEmail(msg))
+		if err == nil {
+			if err := j.repo.MarkSent(ctx, msg.ID, j.now()); err != nil {
+				return len(messages), err
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			// The lease expires and the email is retried on the next start
+			return len(messages), ctx.Err()
+		}
+
+		attempt := msg.Attempts + 1
+		if j.permanent(err) || attempt >= j.maxAttempts {
+			log.Printf("Giving up on email %d after %d attempts: %v", msg.ID, attempt, err)
+			if err := j.repo.MarkFailed(ctx, msg.ID, err.Error()); err != nil {
+				return len(messages), err
+			}
+			continue
+		}
+
+		delay := backoff.Exponential(attempt, emailMinBackoff, emailMaxBackoff)
+		log.Printf("Failed to send email %d (attempt %d, retry in %v): %v", msg.ID, attempt, delay, err)
+		if err := j.repo.MarkRetry(ctx, msg.ID, j.now().Add(delay), err.Error()); err != nil {
+			return len(messages), err
+		}
+	}
+	return len(messages), nil
+}
+
+// toEmail converts a queued email into a message. An empty From is replaced
+// by the sender's default address. The Message-ID stored at queue time is
+// reused by every attempt, so receivers can drop duplicate deliveries.
+func toEmail(msg *models.EmailMessage) *email.Message {
+	return &email.Message{
+		From:      msg.From,
+		To:        msg.To,
+		Cc:        msg.Cc,
+		Bcc:       msg.Bcc,
+		Subject:   msg.Subject,
+		Text:      msg.Text,
+		HTML:      msg.HTML,
+		Headers:   map[string]string{"X-Email-ID": fmt.Sprint(msg.ID)},
+		MessageID: msg.MessageID,
+	}
+}
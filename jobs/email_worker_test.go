@@ -0,0 +1,114 @@
+package jobs
+
+import (
+	"context"
+	"golang-gin/clients"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailWorker_RunOnce(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	server, err := clients.NewFakeSMTPServer()
+	if err != nil {
+		t.Fatalf("Failed to start fake SMTP server: %v", err)
+	}
+	defer server.Close()
+	server.Reject("unknown@example.com", "550 5.1.1 No such user")
+	server.Reject("busy@example.com", "451 4.3.0 Try again later")
+
+	mail := clients.NewMailClient(server.Host(), server.Port(), "", "", "noreply@golang-gin.test")
+	defer mail.Close()
+
+	repo := repository.NewMockEmailRepository()
+	for _, to := range []string{"ok@example.com", "unknown@example.com", "busy@example.com", "not an address"} {
+		repo.Enqueue(ctx, &models.EmailMessage{To: []string{to}, Subject: to, Text: "body", NextAttemptAt: now})
+	}
+
+	worker := NewEmailWorker(repo, mail, time.Second, 3)
+	worker.now = func() time.Time { return now }
+
+	claimed, err := worker.RunOnce(ctx)
+	if err != nil || claimed != 4 {
+		t.Fatalf("Expected 4 claimed emails, got %d (%v)", claimed, err)
+	}
+
+	expected := map[string]string{
+		"ok@example.com":      models.EmailStatusSent,
+		"unknown@example.com": models.EmailStatusFailed,
+		"busy@example.com":    models.EmailStatusPending,
+		"not an address":      models.EmailStatusFailed,
+	}
+	for _, msg := range repo.Messages() {
+		if msg.Status != expected[msg.Subject] || msg.Attempts != 1 {
+			t.Errorf("Expected %s to be %s after 1 attempt, got %s after %d (%s)", msg.Subject, expected[msg.Subject], msg.Status, msg.Attempts, msg.LastError)
+		}
+		if msg.Subject == "busy@example.com" && !msg.NextAttemptAt.Equal(now.Add(emailMinBackoff)) {
+			t.Errorf("Expected retry after %v, got %v", emailMinBackoff, msg.NextAttemptAt)
+		}
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 || !strings.Contains(string(messages[0].Data), "X-Email-Id: 1") {
+		t.Errorf("Expected 1 delivered message with its email ID, got %+v", messages)
+	}
+
+	// Transient failures give up after maxAttempts
+	for i := 0; i < 2; i++ {
+		now = now.Add(emailMaxBackoff)
+		if _, err := worker.RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+	}
+	busy, _ := repo.FindByID(ctx, 3)
+	if busy.Status != models.EmailStatusFailed || busy.Attempts != 3 || !strings.Contains(busy.LastError, "451") {
+		t.Errorf("Expected busy email to fail after 3 attempts, got %+v", busy)
+	}
+}
+
+func TestEmailWorker_RetryKeepsMessageID(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	server, err := clients.NewFakeSMTPServer()
+	if err != nil {
+		t.Fatalf("Failed to start fake SMTP server: %v", err)
+	}
+	defer server.Close()
+	server.Reject("retry@example.com", "451 4.3.0 Try again later")
+
+	mail := clients.NewMailClient(server.Host(), server.Port(), "", "", "noreply@golang-gin.test")
+	defer mail.Close()
+
+	repo := repository.NewMockEmailRepository()
+	repo.Enqueue(ctx, &models.EmailMessage{To: []string{"retry@example.com"}, Subject: "retry", Text: "body", NextAttemptAt: now, MessageID: "queued@golang-gin.test"})
+
+	worker := NewEmailWorker(repo, mail, time.Second, 3)
+	worker.now = func() time.Time { return now }
+	if _, err := worker.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	server.Reject("retry@example.com", "")
+	now = now.Add(emailMaxBackoff)
+	if _, err := worker.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 || !strings.Contains(string(messages[0].Data), "Message-ID: <queued@golang-gin.test>") {
+		t.Errorf("Expected the retry to send the queued Message-ID, got %+v", messages)
+	}
+}
+
+func TestEmailWorker_Lease(t *testing.T) {
+	worker := NewEmailWorker(repository.NewMockEmailRepository(), nil, time.Second, 3, WithSendTimeout(10*time.Second))
+	if got, want := worker.lease(), emailBatchSize*10*time.Second+emailLeaseMargin; got != want {
+		t.Errorf("Expected lease %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"context"
+	"golang-gin/idempotency"
+	"log"
+	"time"
+)
+
+// IdempotencyCleanupJob deletes idempotency keys whose TTL has passed
+type IdempotencyCleanupJob struct {
+	svc      *idempotency.Service
+	interval time.Duration
+}
+
+// NewIdempotencyCleanupJob creates a new IdempotencyCleanupJob
+func NewIdempotencyCleanupJob(svc *idempotency.Service, interval time.Duration) *IdempotencyCleanupJob {
+	return &IdempotencyCleanupJob{svc: svc, interval: interval}
+}
+
+// Run deletes expired keys every interval until ctx is canceled
+func (j *IdempotencyCleanupJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := j.svc.DeleteExpired(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Idempotency cleanup failed: %v", err)
+			}
+			continue
+		}
+		if deleted > 0 {
+			log.Printf("🧹 Deleted %d expired idempotency keys", deleted)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"context"
+	"golang-gin/backoff"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"log"
+	"time"
+)
+
+const (
+	// outboxBatchSize is the number of messages claimed per poll
+	outboxBatchSize = 100
+	// outboxLease hides claimed messages from other relays while they are
+	// being published
+	outboxLease = time.Minute
+	// outboxMinBackoff and outboxMaxBackoff bound the delay between attempts
+	outboxMinBackoff = time.Second
+	outboxMaxBackoff = 10 * time.Minute
+	// outboxRetention is how long published messages are kept
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// OutboxPublisher publishes outbox messages. Publish must only return nil
+// once the broker has confirmed the message.
+type OutboxPublisher interface {
+	Publish(ctx context.Context, msg *models.OutboxMessage) error
+}
+
+// OutboxRelay publishes pending outbox messages and retries failed ones with
+// exponential backoff. Delivery is at-least-once: a crash between the broker
+// confirm and MarkPublished publishes the message again.
+type OutboxRelay struct {
+	repo      repository.OutboxRepository
+	publisher OutboxPublisher
+	interval  time.Duration
+	now       func() time.Time
+}
+
+// NewOutboxRelay creates a new OutboxRelay polling every interval
+func NewOutboxRelay(repo repository.OutboxRepository, publisher OutboxPublisher, interval time.Duration) *OutboxRelay {
+	return &OutboxRelay{
+		repo:      repo,
+		publisher: publisher,
+		interval:  interval,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run publishes pending messages every interval until ctx is canceled
+func (j *OutboxRelay) Run(ctx context.Context) {
+	log.Printf("📤 Outbox relay started (interval: %v)", j.interval)
+	batchLoop{
+		name:      "Outbox relay",
+		interval:  j.interval,
+		batchSize: outboxBatchSize,
+		now:       j.now,
+		runOnce:   j.RunOnce,
+		cleanup: func(ctx context.Context, now time.Time) error {
+			_, err := j.repo.DeletePublishedBefore(ctx, now.Add(-outboxRetention))
+			return err
+		},
+	}.run(ctx)
+	log.Println("📤 Outbox relay stopped")
+}
+
+// RunOnce publishes one batch of due messages and returns the number of
+// messages the broker confirmed
+func (j *OutboxRelay) RunOnce(ctx context.Context) (int, error) {
+	messages, err := j.repo.Claim(ctx, j.now(), outboxBatchSize, outboxLease)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for i := range messages {
+		msg := &messages[i]
+		if err := j.publisher.Publish(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				// The lease expires and the message is retried on the next start
+				return published, ctx.Err()
+			}
+
+			delay := backoff.Exponential(msg.Attempts+1, outboxMinBackoff, outboxMaxBackoff)
+			log.Printf("Failed to publish outbox message %s (attempt %d, retry in %v): %v", msg.EventID, msg.Attempts+1, delay, err)
+			if err := j.repo.MarkFailed(ctx, msg.ID, j.now().Add(delay), err.Error()); err != nil {
+				return published, err
+			}
+			continue
+		}
+
+		if err := j.repo.MarkPublished(ctx, msg.ID, j.now()); err != nil {
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
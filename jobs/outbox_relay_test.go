@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"testing"
+	"time"
+)
+
+// fakePublisher records published messages and fails while err is set
+type fakePublisher struct {
+	published []string
+	err       error
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, msg *models.OutboxMessage) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, msg.EventID)
+	return nil
+}
+
+func TestOutboxRelay_RunOnce(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := repository.NewMockOutboxRepository()
+	for _, id := range []string{"e1", "e2"} {
+		repo.Add(ctx, &models.OutboxMessage{EventID: id, RoutingKey: "album.created", Payload: "{}", NextAttemptAt: now})
+	}
+
+	publisher := &fakePublisher{err: errors.New("broker unavailable")}
+	relay := NewOutboxRelay(repo, publisher, time.Second)
+	relay.now = func() time.Time { return now }
+
+	// Failed messages are rescheduled with backoff
+	published, err := relay.RunOnce(ctx)
+	if err != nil || published != 0 {
+		t.Fatalf("Expected 0 published messages, got %d (%v)", published, err)
+	}
+	for _, msg := range repo.Messages() {
+		if msg.Attempts != 1 || !msg.NextAttemptAt.Equal(now.Add(outboxMinBackoff)) {
+			t.Errorf("Expected retry after %v, got %+v", outboxMinBackoff, msg)
+		}
+	}
+
+	// Nothing is due before the backoff has passed
+	publisher.err = nil
+	if published, _ := relay.RunOnce(ctx); published != 0 {
+		t.Errorf("Expected no messages before backoff, got %d", published)
+	}
+
+	now = now.Add(outboxMinBackoff)
+	published, err = relay.RunOnce(ctx)
+	if err != nil || published != 2 {
+		t.Fatalf("Expected 2 published messages, got %d (%v)", published, err)
+	}
+	if len(publisher.published) != 2 || publisher.published[0] != "e1" {
+		t.Errorf("Expected messages in order, got %v", publisher.published)
+	}
+	for _, msg := range repo.Messages() {
+		if msg.PublishedAt == nil {
+			t.Errorf("Expected message %s to be marked published", msg.EventID)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"golang-gin/products"
+	"golang-gin/repository"
+	"log"
+	"time"
+)
+
+// productSyncBatchSize is the number of products refreshed per page
+const productSyncBatchSize = 100
+
+// ProductSyncJob refreshes the local copies of linked products (name, price,
+// stock) from the ProductService
+type ProductSyncJob struct {
+	svc      products.ProductService
+	repo     repository.ProductRepository
+	interval time.Duration
+	now      func() time.Time
+}
+
+// NewProductSyncJob creates a new ProductSyncJob
+func NewProductSyncJob(svc products.ProductService, repo repository.ProductRepository, interval time.Duration) *ProductSyncJob {
+	return &ProductSyncJob{
+		svc:      svc,
+		repo:     repo,
+		interval: interval,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run refreshes products every interval until ctx is canceled. An interval
+// of zero or less disables the sync.
+func (j *ProductSyncJob) Run(ctx context.Context) {
+	if j.interval <= 0 {
+		log.Println("📦 Product sync job disabled")
+		return
+	}
+	log.Printf("📦 Product sync job started (interval: %v)", j.interval)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Product sync failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("📦 Product sync job stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce refreshes every product synced before the run started and returns
+// the number of refreshed products. Products the ProductService no longer
+// knows are marked discontinued. The run stops at the first unavailable
+// error; the remaining products keep their last synced state.
+func (j *ProductSyncJob) RunOnce(ctx context.Context) (int, error) {
+	started := j.now()
+	refreshed := 0
+	var afterID uint
+
+	for {
+		due, err := j.repo.FindSyncedBefore(ctx, started, afterID, productSyncBatchSize)
+		if err != nil {
+			return refreshed, err
+		}
+
+		for _, cached := range due {
+			afterID = cached.ID
+			product, err := j.svc.GetProduct(ctx, cached.ID)
+			switch {
+			case errors.Is(err, products.ErrProductNotFound):
+				if !cached.Discontinued {
+					log.Printf("📦 Product %d was discontinued", cached.ID)
+				}
+				err = j.repo.MarkDiscontinued(ctx, cached.ID, j.now())
+			case err == nil:
+				err = j.repo.Upsert(ctx, product.Model(j.now()))
+			}
+			if err != nil {
+				return refreshed, err
+			}
+			refreshed++
+		}
+
+		if len(due) < productSyncBatchSize {
+			return refreshed, nil
+		}
+	}
+}
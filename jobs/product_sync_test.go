@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"golang-gin/models"
+	"golang-gin/products"
+	"golang-gin/repository"
+	"testing"
+	"time"
+)
+
+func TestProductSyncJob_RunOnce(t *testing.T) {
+	svc := products.NewMockService(
+		products.Product{ID: 1, Name: "Laptop", Price: 1299.99, Stock: 50},
+		products.Product{ID: 2, Name: "Mouse", Price: 29.99, Stock: 200},
+	)
+	repo := repository.NewMockProductRepository()
+	ctx := context.Background()
+
+	synced := time.Now().UTC().Add(-time.Hour)
+	for id := uint(1); id <= 3; id++ {
+		repo.Upsert(ctx, &models.Product{ID: id, Name: "stale", Stock: 1, SyncedAt: synced})
+	}
+	svc.SetProduct(products.Product{ID: 1, Name: "Laptop", Price: 1199.99, Stock: 0})
+
+	job := NewProductSyncJob(svc, repo, time.Minute)
+	refreshed, err := job.RunOnce(ctx)
+	if err != nil || refreshed != 3 {
+		t.Fatalf("Expected 3 refreshed products, got %d (%v)", refreshed, err)
+	}
+
+	cached, _ := repo.FindByIDs(ctx, []uint{1, 2, 3})
+	if cached[0].Name != "Laptop" || cached[0].Price != 1199.99 || cached[0].Stock != 0 || !cached[0].SyncedAt.After(synced) {
+		t.Errorf("Expected refreshed Laptop, got %+v", cached[0])
+	}
+	if cached[1].Stock != 200 || cached[1].Discontinued {
+		t.Errorf("Expected refreshed Mouse, got %+v", cached[1])
+	}
+	if !cached[2].Discontinued || cached[2].Name != "stale" {
+		t.Errorf("Expected unknown product to be discontinued, got %+v", cached[2])
+	}
+}
+
+func TestProductSyncJob_Unavailable(t *testing.T) {
+	svc := products.NewMockService(products.Product{ID: 1, Name: "Laptop", Stock: 50})
+	repo := repository.NewMockProductRepository()
+	ctx := context.Background()
+
+	synced := time.Now().UTC().Add(-time.Hour)
+	repo.Upsert(ctx, &models.Product{ID: 1, Name: "Laptop", Stock: 7, SyncedAt: synced})
+	svc.SetUnavailable(true)
+
+	job := NewProductSyncJob(svc, repo, time.Minute)
+	if _, err := job.RunOnce(ctx); !errors.Is(err, products.ErrUnavailable) {
+		t.Fatalf("Expected ErrUnavailable, got %v", err)
+	}
+	if cached, _ := repo.FindByIDs(ctx, []uint{1}); cached[0].Stock != 7 || cached[0].Discontinued {
+		t.Errorf("Expected last synced state to be kept, got %+v", cached[0])
+	}
+}
+
+func TestProductSyncJob_RunDisabled(t *testing.T) {
+	svc := products.NewMockService()
+	job := NewProductSyncJob(svc, repository.NewMockProductRepository(), 0)
+
+	done := make(chan struct{})
+	go func() {
+		job.Run(context.Background())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Run to return when the sync is disabled")
+	}
+}
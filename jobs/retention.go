@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"context"
+	"golang-gin/audit"
+	"golang-gin/repository"
+	"log"
+	"time"
+)
+
+// RetentionJob permanently deletes albums that have been soft-deleted for
+// longer than the retention period
+type RetentionJob struct {
+	repo      repository.AlbumRepository
+	retention time.Duration
+	interval  time.Duration
+}
+
+// NewRetentionJob creates a new RetentionJob
+func NewRetentionJob(repo repository.AlbumRepository, retention, interval time.Duration) *RetentionJob {
+	return &RetentionJob{
+		repo:      repo,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Run purges expired albums every interval until ctx is canceled
+func (j *RetentionJob) Run(ctx context.Context) {
+	log.Printf("🧹 Retention job started (retention: %v, interval: %v)", j.retention, j.interval)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Retention job failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("🧹 Retention job stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce purges albums deleted before now minus the retention period and
+// returns the number of purged albums
+func (j *RetentionJob) RunOnce(ctx context.Context) (int64, error) {
+	cutoff := time.Now().UTC().Add(-j.retention)
+	ctx = audit.WithMetadata(ctx, audit.Metadata{Actor: "retention-job", ActorVerified: true, Source: audit.SourceSystem})
+
+	purged, err := j.repo.PurgeDeletedBefore(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	if purged > 0 {
+		log.Printf("🧹 Purged %d albums deleted before %s", purged, cutoff.Format(time.RFC3339))
+	}
+	return purged, nil
+}
@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"context"
+	"golang-gin/repository"
+	"testing"
+	"time"
+)
+
+func TestRetentionJob_RunOnce(t *testing.T) {
+	repo := repository.NewMockAlbumRepository()
+	ctx := context.Background()
+
+	if err := repo.Delete(ctx, 1); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// Nothing is older than the retention period yet
+	job := NewRetentionJob(repo, time.Hour, time.Minute)
+	purged, err := job.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if purged != 0 {
+		t.Errorf("Expected 0 purged albums, got %d", purged)
+	}
+
+	// A negative retention puts the cutoff in the future
+	job = NewRetentionJob(repo, -time.Hour, time.Minute)
+	purged, err = job.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected 1 purged album, got %d", purged)
+	}
+
+	deleted, _ := repo.FindDeleted(ctx)
+	if len(deleted) != 0 {
+		t.Errorf("Expected empty trash, got %d albums", len(deleted))
+	}
+}
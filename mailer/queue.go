@@ -0,0 +1,72 @@
+package mailer
+
+import (
+	"context"
+	"golang-gin/email"
+	"golang-gin/models"
+	"golang-gin/repository"
+)
+
+// Email is a templated email to be queued
+type Email struct {
+	Template string
+	Locale   string
+	Data     interface{}
+	To       []string
+	Cc       []string
+	Bcc      []string
+	// DedupKey, if set, queues the email only once, e.g.
+	// "album.created:<event ID>:<recipient>"
+	DedupKey string
+}
+
+// Queue renders templated emails and queues them for the email worker
+type Queue struct {
+	repo      repository.EmailRepository
+	templates *email.Templates
+	from      string
+}
+
+// NewQueue creates a new Queue. Emails are sent from the given address, or
+// from the mail client's default sender if from is empty.
+func NewQueue(repo repository.EmailRepository, templates *email.Templates, from string) *Queue {
+	return &Queue{repo: repo, templates: templates, from: from}
+}
+
+// Enqueue renders e and queues it. If an email with the same dedup key was
+// queued before, the existing email is returned with created set to false.
+func (q *Queue) Enqueue(ctx context.Context, e Email) (msg *models.EmailMessage, created bool, err error) {
+	if len(e.To)+len(e.Cc)+len(e.Bcc) == 0 {
+		return nil, false, email.ErrNoRecipients
+	}
+
+	var dedupKey *string
+	if e.DedupKey != "" {
+		dedupKey = &e.DedupKey
+	}
+
+	rendered, err := q.templates.Render(e.Template, e.Locale, e.Data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	msg = &models.EmailMessage{
+		DedupKey: dedupKey,
+		Template: e.Template,
+		Locale:   e.Locale,
+		From:     q.from,
+		To:       e.To,
+		Cc:       e.Cc,
+		Bcc:      e.Bcc,
+		Subject:  rendered.Subject,
+		Text:     rendered.Text,
+		HTML:     rendered.HTML,
+		// Generated once so that every delivery attempt uses the same ID
+		MessageID: email.NewMessageID(q.from),
+	}
+	created, err = q.repo.Enqueue(ctx, msg)
+	if err != nil {
+		return nil, false, err
+	}
+	return msg, created, nil
+}
@@ -0,0 +1,65 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"golang-gin/email"
+	"golang-gin/models"
+	"golang-gin/repository"
+	"strings"
+	"testing"
+)
+
+func TestQueue_Enqueue(t *testing.T) {
+	ctx := context.Background()
+	templates, err := email.DefaultTemplates()
+	if err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+	repo := repository.NewMockEmailRepository()
+	queue := NewQueue(repo, templates, "アルバム管理 <noreply@example.com>")
+
+	data := map[string]interface{}{
+		"Album": map[string]interface{}{"Title": "Blue Train", "Artist": "John Coltrane", "Price": 56.99},
+		"URL":   "",
+	}
+	e := Email{Template: "album_created", Locale: "en", Data: data, To: []string{"a@example.com"}, DedupKey: "k1"}
+
+	msg, created, err := queue.Enqueue(ctx, e)
+	if err != nil || !created {
+		t.Fatalf("Expected email to be queued, got %v (%v)", created, err)
+	}
+	if msg.Status != models.EmailStatusPending || msg.From != "アルバム管理 <noreply@example.com>" || msg.Template != "album_created" {
+		t.Errorf("Unexpected email %+v", msg)
+	}
+	if !strings.Contains(msg.Subject, "Blue Train") || !strings.Contains(msg.Text, "56.99") || msg.HTML == "" {
+		t.Errorf("Expected rendered template, got %q / %q", msg.Subject, msg.Text)
+	}
+	if !strings.HasSuffix(msg.MessageID, "@example.com") {
+		t.Errorf("Expected a Message-ID at the sender's domain, got %q", msg.MessageID)
+	}
+
+	again, created, err := queue.Enqueue(ctx, e)
+	if err != nil || created || again.ID != msg.ID {
+		t.Errorf("Expected duplicate to return email %d, got %+v (%v, %v)", msg.ID, again, created, err)
+	}
+	if len(repo.Messages()) != 1 {
+		t.Errorf("Expected 1 queued email, got %d", len(repo.Messages()))
+	}
+
+	tests := []struct {
+		name    string
+		email   Email
+		wantErr error
+	}{
+		{"No recipients", Email{Template: "album_created", Data: data}, email.ErrNoRecipients},
+		{"Unknown template", Email{Template: "missing", To: []string{"a@example.com"}}, email.ErrTemplateNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := queue.Enqueue(ctx, tt.email); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
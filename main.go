@@ -2,18 +2,35 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"golang-gin/albumio"
+	"golang-gin/cache"
+	"golang-gin/cli"
+	"golang-gin/clients"
+	"golang-gin/consumer"
 	"golang-gin/database"
+	"golang-gin/email"
+	"golang-gin/events"
 	"golang-gin/handlers"
+	"golang-gin/idempotency"
+	"golang-gin/jobs"
+	"golang-gin/mailer"
 	"golang-gin/models"
+	"golang-gin/products"
 	"golang-gin/repository"
+	"golang-gin/users"
 	grpcServer "golang-gin/grpc"
 	"golang-gin/middleware"
 	pb "golang-gin/grpc/proto"
@@ -21,6 +38,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"google.golang.org/grpc"
+	"gorm.io/gorm/logger"
 )
 
 func main() {
@@ -31,6 +49,10 @@ func main() {
 
 	// Initialize database
 	dbConfig := database.GetConfigFromEnv()
+	if len(os.Args) > 1 {
+		// SQL logs go to stdout, where CLI commands write their output
+		dbConfig.LogLevel = logger.Silent
+	}
 	db, err := database.Connect(dbConfig)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
@@ -38,7 +60,10 @@ func main() {
 	defer database.Close()
 
 	// Run migrations
-	if err := database.Migrate(db, &models.Album{}); err != nil {
+	if err := models.PrepareAlbumIndexes(db, os.Getenv("MIGRATE_RESOLVE_DUPLICATES") == "true"); err != nil {
+		log.Fatalf("Failed to prepare album indexes: %v", err)
+	}
+	if err := database.Migrate(db, &models.Album{}, &models.AlbumAudit{}, &models.IdempotencyKey{}, &models.OutboxMessage{}, &models.EmailMessage{}, &models.Product{}); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
@@ -48,10 +73,75 @@ func main() {
 	}
 
 	// Initialize repositories
-	albumRepo := repository.NewAlbumRepository(db)
+	albumHooks := []repository.AlbumHook{repository.AuditHook()}
+	// Album events are written to the outbox whenever a broker is configured,
+	// and published by the outbox relay once it is reachable
+	rabbitMQURL := os.Getenv("RABBITMQ_URL")
+	if rabbitMQURL != "" {
+		albumHooks = append(albumHooks, repository.OutboxHook())
+	}
+	albumRepo := repository.NewAlbumRepository(db, albumHooks...)
+	auditRepo := repository.NewAuditRepository(db)
+	uow := repository.NewUnitOfWork(db, albumHooks...)
+
+	// Cache album lookups by ID (ALBUM_CACHE_SIZE=0 disables the cache)
+	var albumCache *repository.CachedAlbumRepository
+	if size := getEnvInt64("ALBUM_CACHE_SIZE", 1000); size > 0 {
+		albumCache = repository.NewCachedAlbumRepository(albumRepo, cache.NewLRU(int(size)),
+			repository.WithCacheTTL(getEnvDuration("ALBUM_CACHE_TTL", time.Minute)),
+			repository.WithNegativeCacheTTL(getEnvDuration("ALBUM_CACHE_NEGATIVE_TTL", 10*time.Second)),
+		)
+		albumRepo = albumCache
+		uow = albumCache.WrapUnitOfWork(uow)
+	}
+	albumIO := albumio.NewService(albumRepo, uow)
+	idempotencySvc := idempotency.NewService(
+		repository.NewIdempotencyRepository(db),
+		getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+	)
+
+	// Run a CLI subcommand instead of the servers, e.g. "albums export"
+	if len(os.Args) > 1 {
+		app := &cli.App{Albums: albumIO, Actor: cliActor(), Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+		if err := app.Run(context.Background(), os.Args[1:]); err != nil {
+			database.Close()
+			log.Fatal(err)
+		}
+		return
+	}
 
 	// Initialize handlers
-	albumHandler := handlers.NewAlbumHandler(albumRepo)
+	albumHandlerOpts := []handlers.AlbumHandlerOption{
+		handlers.WithUnitOfWork(uow),
+		handlers.WithInclude("creator", handlers.CreatorInclude(auditRepo)),
+	}
+	if usersAPI := os.Getenv("USERS_API_URL"); usersAPI != "" {
+		userService := newUserService(usersAPI)
+		albumHandlerOpts = append(albumHandlerOpts,
+			handlers.WithUsers(userService),
+			handlers.WithInclude("owner", handlers.OwnerInclude(userService)),
+			handlers.WithInclude("creator.user", handlers.CreatorUserInclude(auditRepo, handlers.UserServiceLookup(userService))))
+	}
+	productRepo := repository.NewProductRepository(db)
+	productClient := newProductClient()
+	if productClient != nil {
+		defer productClient.Close()
+		albumHandlerOpts = append(albumHandlerOpts,
+			handlers.WithProducts(productClient, productRepo),
+			handlers.WithInclude("product", handlers.ProductInclude(productRepo)))
+	}
+	if cacheControl := os.Getenv("ALBUM_CACHE_CONTROL"); cacheControl != "" {
+		albumHandlerOpts = append(albumHandlerOpts, handlers.WithCacheControl(cacheControl))
+	}
+	if os.Getenv("REQUIRE_IF_MATCH") == "true" {
+		albumHandlerOpts = append(albumHandlerOpts, handlers.WithIfMatchRequired())
+	}
+	albumHandler := handlers.NewAlbumHandler(albumRepo, albumHandlerOpts...)
+	albumHistoryHandler := handlers.NewAlbumHistoryHandler(auditRepo)
+	albumBulkHandler := handlers.NewAlbumBulkHandler(albumIO,
+		handlers.WithMaxImportSize(getEnvInt64("MAX_IMPORT_SIZE", 50<<20)))
+	emailRepo := repository.NewEmailRepository(db)
+	emailHandler := handlers.NewEmailHandler(emailRepo)
 
 	// Create channels for graceful shutdown
 	done := make(chan bool, 1)
@@ -60,9 +150,14 @@ func main() {
 
 	// Setup Gin HTTP server
 	router := gin.New()
+	router.Use(middleware.RequestID())
 	router.Use(middleware.Logger())
 	router.Use(middleware.CORS())
+	router.Use(middleware.AuditContext())
 	router.Use(gin.Recovery())
+	maxRequestBodySize := getEnvInt64("MAX_REQUEST_BODY_SIZE", 10<<20)
+	router.Use(middleware.Decompress(maxRequestBodySize))
+	router.Use(middleware.Compress(middleware.DefaultCompressConfig))
 
 	// Health check
 	router.GET("/health", handlers.HealthCheck)
@@ -72,7 +167,110 @@ func main() {
 	{
 		v1.GET("/albums", albumHandler.GetAlbums)
 		v1.GET("/albums/:id", albumHandler.GetAlbumByID)
-		v1.POST("/albums", albumHandler.PostAlbums)
+		v1.GET("/albums/:id/history", albumHistoryHandler.GetAlbumHistory)
+		v1.POST("/albums", middleware.Idempotency(idempotencySvc, maxRequestBodySize), albumHandler.PostAlbums)
+		v1.PUT("/albums/:id", albumHandler.UpdateAlbum)
+		v1.DELETE("/albums/:id", albumHandler.DeleteAlbum)
+
+		// Custom methods: POST /albums:import, POST /albums:batch, GET /albums:export
+		v1.POST("/albums:method", handlers.CustomMethods(map[string]gin.HandlerFunc{
+			"import": albumBulkHandler.ImportAlbums,
+			"batch":  albumHandler.BatchAlbums,
+		}))
+		v1.GET("/albums:method", handlers.CustomMethods(map[string]gin.HandlerFunc{
+			"export": albumBulkHandler.ExportAlbums,
+		}))
+	}
+
+	// Admin routes
+	admin := v1.Group("/admin", middleware.AdminAuth(os.Getenv("ADMIN_TOKEN")))
+	{
+		admin.GET("/albums/trash", albumHandler.GetDeletedAlbums)
+		admin.POST("/albums/:id/restore", albumHandler.RestoreAlbum)
+		admin.DELETE("/albums/:id", albumHandler.PurgeAlbum)
+		if albumCache != nil {
+			admin.GET("/cache/stats", handlers.AlbumCacheStats(albumCache))
+		}
+		admin.GET("/emails", emailHandler.ListEmails)
+		admin.GET("/emails/:id", emailHandler.GetEmail)
+		admin.POST("/emails/:id/retry", emailHandler.RetryEmail)
+	}
+
+	// Background jobs are stopped on shutdown via workerCtx
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	if retention := getEnvDuration("SOFT_DELETE_RETENTION", 30*24*time.Hour); retention > 0 {
+		retentionJob := jobs.NewRetentionJob(albumRepo, retention, getEnvDuration("RETENTION_INTERVAL", time.Hour))
+		go retentionJob.Run(workerCtx)
+	}
+	go jobs.NewIdempotencyCleanupJob(idempotencySvc, time.Hour).Run(workerCtx)
+	if interval := getEnvDuration("PRODUCT_SYNC_INTERVAL", 15*time.Minute); productClient != nil && interval > 0 {
+		productSync := jobs.NewProductSyncJob(productClient, productRepo, interval)
+		go productSync.Run(workerCtx)
+	}
+
+	// Queued emails are sent when an SMTP server is configured and stay
+	// pending otherwise
+	if mailClient := newMailClient(); mailClient != nil {
+		defer mailClient.Close()
+		emailWorker := jobs.NewEmailWorker(emailRepo, mailClient,
+			getEnvDuration("EMAIL_POLL_INTERVAL", 5*time.Second),
+			int(getEnvInt64("EMAIL_MAX_ATTEMPTS", 8)),
+			jobs.WithSendTimeout(getEnvDuration("SMTP_TIMEOUT", 30*time.Second)))
+		go emailWorker.Run(workerCtx)
+	} else {
+		log.Println("⚠️  Email worker disabled (SMTP_HOST is not set), emails stay queued")
+	}
+
+	// Consumers are stopped before the background jobs so in-flight messages
+	// can still use them while draining
+	consumerCtx, stopConsumers := context.WithCancel(context.Background())
+	defer stopConsumers()
+	var consumers sync.WaitGroup
+
+	if rabbitMQURL != "" {
+		exchange := getEnv("OUTBOX_EXCHANGE", "albums")
+		// The client connects in the background, so the relay and consumers
+		// start right away and pick up once the broker is reachable
+		rabbitMQ, err := connectEventPublisher(rabbitMQURL, exchange)
+		if err != nil {
+			log.Fatalf("Failed to configure RabbitMQ: %v", err)
+		}
+		defer rabbitMQ.Close()
+		relay := jobs.NewOutboxRelay(repository.NewOutboxRepository(db),
+			events.NewRabbitMQPublisher(rabbitMQ, exchange),
+			getEnvDuration("OUTBOX_POLL_INTERVAL", time.Second))
+		go relay.Run(workerCtx)
+
+		albumEvents := consumer.New(rabbitMQ, consumer.Config{
+			Queue:       getEnv("CONSUMER_QUEUE", "golang-gin.album-events"),
+			Exchange:    exchange,
+			Prefetch:    int(getEnvInt64("CONSUMER_PREFETCH", 10)),
+			Concurrency: int(getEnvInt64("CONSUMER_CONCURRENCY", 4)),
+			MaxRetries:  int(getEnvInt64("CONSUMER_MAX_RETRIES", 5)),
+			RetryDelay:  getEnvDuration("CONSUMER_RETRY_DELAY", time.Second),
+		})
+		for _, eventType := range []string{events.AlbumCreated, events.AlbumUpdated, events.AlbumDeleted} {
+			consumer.On(albumEvents, eventType, logAlbumEvent)
+		}
+		if recipients := splitList(os.Getenv("ALBUM_NOTIFY_EMAILS")); len(recipients) > 0 {
+			templates, err := email.DefaultTemplates()
+			if err != nil {
+				log.Fatalf("Failed to load email templates: %v", err)
+			}
+			queue := mailer.NewQueue(emailRepo, templates, os.Getenv("SMTP_FROM"))
+			consumer.On(albumEvents, events.AlbumCreated,
+				notifyAlbumCreated(queue, recipients, getEnv("ALBUM_NOTIFY_LOCALE", "ja"), os.Getenv("APP_BASE_URL")))
+		}
+
+		consumers.Add(1)
+		go func() {
+			defer consumers.Done()
+			if err := albumEvents.Run(consumerCtx); err != nil {
+				log.Printf("Album event consumer failed: %v", err)
+			}
+		}()
 	}
 
 	// HTTP server
@@ -95,8 +293,11 @@ func main() {
 		log.Fatalf("Failed to listen for gRPC: %v", err)
 	}
 
-	grpcSrv := grpc.NewServer()
-	pb.RegisterAlbumServiceServer(grpcSrv, grpcServer.NewServer(albumRepo))
+	grpcSrv := grpc.NewServer(grpc.UnaryInterceptor(grpcServer.AuditUnaryInterceptor()))
+	pb.RegisterAlbumServiceServer(grpcSrv, grpcServer.NewServer(albumRepo,
+		grpcServer.WithAuditRepository(auditRepo),
+		grpcServer.WithIdempotency(idempotencySvc),
+	))
 
 	// Start gRPC server in goroutine
 	go func() {
@@ -122,6 +323,13 @@ func main() {
 		// Graceful stop gRPC server
 		grpcSrv.GracefulStop()
 
+		// Drain message consumers
+		stopConsumers()
+		consumers.Wait()
+
+		// Stop background jobs
+		stopWorkers()
+
 		close(done)
 	}()
 
@@ -131,3 +339,171 @@ func main() {
 	<-done
 	log.Println("✅ Servers stopped gracefully")
 }
+
+// getEnv reads a string from the environment, falling back to defaultValue
+// when the variable is unset
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// connectEventPublisher creates a RabbitMQ client that declares the topic
+// exchange album events are published to once it is connected
+func connectEventPublisher(url, exchange string) (*clients.RabbitMQClient, error) {
+	return clients.NewRabbitMQClient(url, clients.WithTopology(clients.Topology{
+		Exchanges: []clients.ExchangeSpec{{Name: exchange, Kind: "topic"}},
+	}))
+}
+
+// logAlbumEvent logs album events received from the broker
+func logAlbumEvent(ctx context.Context, event events.AlbumEvent, d consumer.Delivery) error {
+	if event.SchemaVersion != events.SchemaVersion {
+		return consumer.Permanent(fmt.Errorf("unsupported schema version %d", event.SchemaVersion))
+	}
+	log.Printf("📬 %s: album %d v%d by %q (%s)", event.Type, event.Album.ID, event.Album.Version, event.Actor, event.Source)
+	return nil
+}
+
+// notifyAlbumCreated returns a consumer handler that logs album.created
+// events and queues a notification email to each recipient. The event ID is
+// part of the dedup key, so redelivered events do not send emails twice.
+func notifyAlbumCreated(queue *mailer.Queue, recipients []string, locale, baseURL string) func(context.Context, events.AlbumEvent, consumer.Delivery) error {
+	return func(ctx context.Context, event events.AlbumEvent, d consumer.Delivery) error {
+		if err := logAlbumEvent(ctx, event, d); err != nil {
+			return err
+		}
+
+		var albumURL string
+		if baseURL != "" {
+			albumURL = fmt.Sprintf("%s/api/v1/albums/%d", strings.TrimRight(baseURL, "/"), event.Album.ID)
+		}
+		for _, to := range recipients {
+			_, _, err := queue.Enqueue(ctx, mailer.Email{
+				Template: "album_created",
+				Locale:   locale,
+				Data:     map[string]interface{}{"Album": event.Album, "URL": albumURL},
+				To:       []string{to},
+				DedupKey: fmt.Sprintf("%s:%s:%s", event.Type, event.ID, to),
+			})
+			if errors.Is(err, email.ErrTemplateNotFound) {
+				return consumer.Permanent(err)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// newMailClient creates a mail client from the SMTP_* environment variables,
+// falling back to MailHog. It returns nil if no SMTP server is configured.
+func newMailClient() *clients.MailClient {
+	host := getEnv("SMTP_HOST", os.Getenv("MAILHOG_HOST"))
+	if host == "" {
+		return nil
+	}
+	port := getEnv("SMTP_PORT", getEnv("MAILHOG_PORT", getEnv("MAILHOG_SMTP_PORT", "25")))
+
+	tlsMode, err := clients.ParseMailTLSMode(os.Getenv("SMTP_TLS"))
+	if err != nil {
+		log.Fatalf("Invalid SMTP_TLS: %v", err)
+	}
+	return clients.NewMailClient(host, port,
+		os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"),
+		getEnv("SMTP_FROM", "noreply@golang-gin.local"),
+		clients.WithMailTLS(tlsMode),
+		clients.WithMailAuth(os.Getenv("SMTP_AUTH")),
+		clients.WithMailTimeout(getEnvDuration("SMTP_TIMEOUT", 30*time.Second)),
+	)
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvDuration reads a time.Duration from the environment, falling back to
+// defaultValue when the variable is unset or invalid
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %v: %v", key, value, defaultValue, err)
+		return defaultValue
+	}
+	return d
+}
+
+// getEnvInt64 reads an int64 from the environment, falling back to
+// defaultValue when the variable is unset or invalid
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %d: %v", key, value, defaultValue, err)
+		return defaultValue
+	}
+	return n
+}
+
+// newProductClient returns a client of the ProductService at
+// PRODUCT_SERVICE_ADDR (falling back to GRPC_MOCK_URL), or nil if neither is
+// set
+func newProductClient() *products.Client {
+	addr := getEnv("PRODUCT_SERVICE_ADDR", os.Getenv("GRPC_MOCK_URL"))
+	if addr == "" {
+		return nil
+	}
+
+	client, err := products.NewClient(addr,
+		products.WithCallTimeout(getEnvDuration("PRODUCT_SERVICE_TIMEOUT", 2*time.Second)),
+		products.WithRetries(int(getEnvInt64("PRODUCT_SERVICE_MAX_ATTEMPTS", 3)), 100*time.Millisecond),
+	)
+	if err != nil {
+		log.Printf("⚠️  Product linking disabled: %v", err)
+		return nil
+	}
+	return client
+}
+
+// newUserService returns a client of the users API at baseURL, cached in
+// memory unless USERS_CACHE_SIZE is 0
+func newUserService(baseURL string) users.UserService {
+	svc := users.NewClient(clients.NewHTTPClient(baseURL,
+		clients.WithHTTPTimeout(getEnvDuration("USERS_API_TIMEOUT", 2*time.Second)),
+	))
+	size := getEnvInt64("USERS_CACHE_SIZE", 1000)
+	if size <= 0 {
+		return svc
+	}
+	return users.NewCachedService(svc, cache.NewLRU(int(size)),
+		users.WithTTL(getEnvDuration("USERS_CACHE_TTL", 5*time.Minute)),
+		users.WithStaleTTL(getEnvDuration("USERS_CACHE_STALE_TTL", time.Hour)),
+		users.WithNegativeTTL(getEnvDuration("USERS_CACHE_NEGATIVE_TTL", 30*time.Second)),
+	)
+}
+
+// cliActor returns the audit actor recorded for CLI writes
+func cliActor() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "cli"
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"golang-gin/audit"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth protects admin routes with a static bearer token.
+// An empty token disables the admin API entirely. Authenticated requests are
+// audited as the verified AdminActor.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin API is disabled"})
+			return
+		}
+
+		scheme, provided, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+			return
+		}
+
+		md := audit.FromContext(c.Request.Context())
+		md.Actor, md.ActorVerified = AdminActor, true
+		c.Request = c.Request.WithContext(audit.WithMetadata(c.Request.Context(), md))
+
+		c.Next()
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"golang-gin/audit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		token          string
+		authorization  string
+		expectedStatus int
+	}{
+		{"Valid token", "secret", "Bearer secret", http.StatusOK},
+		{"Invalid token", "secret", "Bearer wrong", http.StatusUnauthorized},
+		{"Lowercase scheme", "secret", "bearer secret", http.StatusOK},
+		{"Bare token", "secret", "secret", http.StatusUnauthorized},
+		{"Other scheme", "secret", "Basic secret", http.StatusUnauthorized},
+		{"Missing token", "secret", "", http.StatusUnauthorized},
+		{"Admin API disabled", "", "Bearer ", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(AdminAuth(tt.token))
+			router.GET("/admin", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req, _ := http.NewRequest("GET", "/admin", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestAdminAuth_VerifiedActor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var md audit.Metadata
+	router := gin.New()
+	router.Use(RequestID(), AuditContext(), AdminAuth("secret"))
+	router.GET("/admin", func(c *gin.Context) {
+		md = audit.FromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req, _ := http.NewRequest("GET", "/admin", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set(ActorHeader, "mallory")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if md.Actor != AdminActor || !md.ActorVerified || md.Source != audit.SourceREST {
+		t.Errorf("Expected the verified admin actor, got %+v", md)
+	}
+}
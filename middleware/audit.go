@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"golang-gin/audit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActorHeader identifies the user performing a request. It is supplied by
+// the client and not authenticated, so it is recorded as an unverified actor.
+const ActorHeader = "X-Actor"
+
+// AdminActor is the verified actor recorded for requests authenticated with
+// the admin token
+const AdminActor = "admin"
+
+// AuditContext middleware attaches audit metadata (actor, request ID and the
+// REST source) to the request context so repository writes can record it.
+// It must run after RequestID.
+func AuditContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := audit.WithMetadata(c.Request.Context(), audit.Metadata{
+			Actor:     c.GetHeader(ActorHeader),
+			RequestID: c.GetString(RequestIDKey),
+			Source:    audit.SourceREST,
+		})
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,324 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported content codings, in order of preference
+const (
+	EncodingBrotli = "br"
+	EncodingZstd   = "zstd"
+	EncodingGzip   = "gzip"
+)
+
+// CompressConfig configures the Compress middleware
+type CompressConfig struct {
+	// MinSize is the smallest response body, in bytes, that is compressed
+	MinSize int
+	// ContentTypes lists the media types that may be compressed
+	ContentTypes []string
+}
+
+// DefaultCompressConfig compresses text-based responses of 1 KiB or more
+var DefaultCompressConfig = CompressConfig{
+	MinSize: 1024,
+	ContentTypes: []string{
+		"application/json",
+		"application/xml",
+		"application/yaml",
+		"application/x-ndjson",
+		"text/csv",
+		"text/html",
+		"text/plain",
+	},
+}
+
+// encoders pools the compressors of each content coding
+var encoders = map[string]*sync.Pool{
+	EncodingBrotli: {New: func() interface{} { return brotli.NewWriterLevel(nil, brotli.DefaultCompression) }},
+	EncodingZstd: {New: func() interface{} {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		return w
+	}},
+	EncodingGzip: {New: func() interface{} { return gzip.NewWriter(nil) }},
+}
+
+// encoder is implemented by the pooled compressors
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressWriter buffers the start of a response until it knows whether the
+// body is worth compressing, then streams the rest through the encoder
+type compressWriter struct {
+	gin.ResponseWriter
+	cfg      *CompressConfig
+	method   string
+	encoding string
+	buf      bytes.Buffer
+	decided  bool
+	enc      encoder
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf.Write(b)
+		if w.buf.Len() >= w.cfg.MinSize {
+			if err := w.decide(); err != nil {
+				return 0, err
+			}
+		}
+		return len(b), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush decides on compression early so that streamed responses reach the
+// client without waiting for MinSize bytes
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide picks compressed or identity output and writes the buffered body
+func (w *compressWriter) decide() error {
+	w.decided = true
+
+	if w.compressible() && w.buf.Len() > 0 {
+		h := w.Header()
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		if etag := h.Get("ETag"); etag != "" {
+			h.Set("ETag", codingETag(etag, w.encoding))
+		}
+		w.enc = encoders[w.encoding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+		_, err := w.enc.Write(w.buf.Bytes())
+		return err
+	}
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	return err
+}
+
+// codingETag returns the entity tag of the encoded form of a representation.
+// A strong tag promises byte-identical bodies, so the coding is appended to
+// the opaque value, e.g. "3" becomes "3-gzip".
+func codingETag(etag, encoding string) string {
+	if !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return etag[:len(etag)-1] + "-" + encoding + `"`
+}
+
+// identityETags removes the coding suffixes added by codingETag from the
+// tags of an If-Match or If-None-Match header, so that handlers compare
+// them with the tags of their identity responses
+func identityETags(header string) string {
+	tags := strings.Split(header, ",")
+	for i, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		for encoding := range encoders {
+			if suffix := "-" + encoding + `"`; strings.HasSuffix(tag, suffix) {
+				tag = strings.TrimSuffix(tag, suffix) + `"`
+				break
+			}
+		}
+		tags[i] = tag
+	}
+	return strings.Join(tags, ", ")
+}
+
+// compressible reports whether the response may be compressed
+func (w *compressWriter) compressible() bool {
+	status := w.Status()
+	if w.method == http.MethodHead || status < http.StatusOK ||
+		status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	return allowedContentType(w.cfg.ContentTypes, h.Get("Content-Type"))
+}
+
+// close flushes the buffered body or finishes the compressed stream
+func (w *compressWriter) close() {
+	if !w.decided {
+		// The whole body is below MinSize, so send it as is
+		w.decided = true
+		if w.buf.Len() > 0 {
+			w.ResponseWriter.Write(w.buf.Bytes())
+		}
+		return
+	}
+	if w.enc != nil {
+		w.enc.Close()
+		w.enc.Reset(nil)
+		encoders[w.encoding].Put(w.enc)
+		w.enc = nil
+	}
+}
+
+// Compress compresses responses with brotli, zstd or gzip, as negotiated
+// from Accept-Encoding. Only bodies of at least cfg.MinSize bytes with an
+// allowed Content-Type are compressed. Compressed responses get their own
+// ETag with the coding appended ("3-gzip"), which is accepted back in
+// If-Match and If-None-Match.
+func Compress(cfg CompressConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, name := range []string{"If-Match", "If-None-Match"} {
+			if header := c.GetHeader(name); header != "" {
+				c.Request.Header.Set(name, identityETags(header))
+			}
+		}
+
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, cfg: &cfg, method: c.Request.Method, encoding: encoding}
+		c.Writer = w
+		defer func() {
+			w.close()
+			c.Writer = w.ResponseWriter
+		}()
+
+		c.Next()
+	}
+}
+
+// Decompress transparently decodes request bodies sent with a gzip, br or
+// zstd Content-Encoding. Decoded bodies larger than maxSize bytes fail to
+// read, which guards against decompression bombs. Unknown encodings are
+// rejected with 415.
+func Decompress(maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		var body io.ReadCloser
+		switch encoding {
+		case EncodingGzip, "x-gzip":
+			r, err := gzip.NewReader(c.Request.Body)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid gzip request body"})
+				return
+			}
+			body = r
+		case EncodingBrotli:
+			body = io.NopCloser(brotli.NewReader(c.Request.Body))
+		case EncodingZstd:
+			r, err := zstd.NewReader(c.Request.Body)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid zstd request body"})
+				return
+			}
+			body = r.IOReadCloser()
+		default:
+			c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported Content-Encoding " + strconv.Quote(encoding)})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, body, maxSize)
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Request.ContentLength = -1
+		c.Next()
+	}
+}
+
+// negotiateEncoding returns the preferred supported coding accepted by the
+// client, honoring q-values, or "" if none is acceptable
+func negotiateEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	preference := []string{EncodingBrotli, EncodingZstd, EncodingGzip}
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "*" {
+			wildcard = q
+		} else {
+			weights[name] = q
+		}
+	}
+
+	candidates := make([]string, 0, len(preference))
+	for _, enc := range preference {
+		q, ok := weights[enc]
+		if !ok {
+			q = wildcard
+		}
+		if q > 0 {
+			weights[enc] = q
+			candidates = append(candidates, enc)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return weights[candidates[i]] > weights[candidates[j]] })
+
+	if len(candidates) == 0 {
+		return ""
+	}
+	return candidates[0]
+}
+
+// allowedContentType reports whether contentType's media type is in allowed
+func allowedContentType(allowed []string, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range allowed {
+		if t == mediaType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+func decodeBody(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+
+	var r io.Reader
+	switch encoding {
+	case EncodingGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Invalid gzip body: %v", err)
+		}
+		r = gr
+	case EncodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	case EncodingZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Invalid zstd body: %v", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return string(body)
+	}
+
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to decode %s body: %v", encoding, err)
+	}
+	return string(decoded)
+}
+
+func TestCompress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Compress(CompressConfig{MinSize: 100, ContentTypes: []string{"application/json"}}))
+
+	large := strings.Repeat("a", 500)
+	router.GET("/large", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"data": large}) })
+	router.GET("/small", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"data": "a"}) })
+	router.GET("/binary", func(c *gin.Context) { c.Data(http.StatusOK, "image/png", []byte(large)) })
+	router.GET("/empty", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		wantEncoding   string
+		wantStatus     int
+	}{
+		{"Brotli preferred", "/large", "gzip, deflate, br, zstd", EncodingBrotli, http.StatusOK},
+		{"Zstd", "/large", "zstd", EncodingZstd, http.StatusOK},
+		{"Gzip by q-value", "/large", "br;q=0.5, gzip", EncodingGzip, http.StatusOK},
+		{"Wildcard", "/large", "*", EncodingBrotli, http.StatusOK},
+		{"Refused codings", "/large", "br;q=0, zstd;q=0, gzip;q=0", "", http.StatusOK},
+		{"No Accept-Encoding", "/large", "", "", http.StatusOK},
+		{"Below minimum size", "/small", "gzip", "", http.StatusOK},
+		{"Content type not allowed", "/binary", "gzip", "", http.StatusOK},
+		{"No body", "/empty", "gzip", "", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Expected Content-Encoding %q, got %q", tt.wantEncoding, got)
+			}
+			if w.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("Expected Vary: Accept-Encoding, got %q", w.Header().Get("Vary"))
+			}
+
+			body := decodeBody(t, tt.wantEncoding, w.Body.Bytes())
+			if tt.path == "/large" && !strings.Contains(body, large) {
+				t.Errorf("Unexpected decoded body %q", body)
+			}
+		})
+	}
+}
+
+func TestCompress_ETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Compress(CompressConfig{MinSize: 100, ContentTypes: []string{"application/json"}}))
+
+	large := strings.Repeat("a", 500)
+	router.GET("/large", func(c *gin.Context) {
+		c.Header("ETag", `"3"`)
+		if c.GetHeader("If-None-Match") == `"3"` {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": large})
+	})
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		ifNoneMatch    string
+		wantStatus     int
+		wantETag       string
+	}{
+		{"Identity", "", "", http.StatusOK, `"3"`},
+		{"Gzip", "gzip", "", http.StatusOK, `"3-gzip"`},
+		{"Brotli", "br", "", http.StatusOK, `"3-br"`},
+		{"Revalidated gzip", "gzip", `"3-gzip"`, http.StatusNotModified, `"3"`},
+		{"Revalidated identity", "", `"3"`, http.StatusNotModified, `"3"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/large", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus || w.Header().Get("ETag") != tt.wantETag {
+				t.Errorf("Expected %d with ETag %s, got %d with %s", tt.wantStatus, tt.wantETag, w.Code, w.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestIdentityETags(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{`"3-gzip"`, `"3"`},
+		{`W/"3-br", "4-zstd"`, `W/"3", "4"`},
+		{`"3-1a2b3c4d"`, `"3-1a2b3c4d"`},
+		{`*`, `*`},
+	}
+
+	for _, tt := range tests {
+		if got := identityETags(tt.header); got != tt.want {
+			t.Errorf("identityETags(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Decompress(1024))
+	router.POST("/echo", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.String(http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		c.String(http.StatusOK, string(body))
+	})
+
+	compress := func(encoding, s string) []byte {
+		var buf bytes.Buffer
+		var w io.WriteCloser
+		switch encoding {
+		case EncodingGzip:
+			w = gzip.NewWriter(&buf)
+		case EncodingBrotli:
+			w = brotli.NewWriter(&buf)
+		case EncodingZstd:
+			w, _ = zstd.NewWriter(&buf)
+		}
+		w.Write([]byte(s))
+		w.Close()
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name       string
+		encoding   string
+		body       []byte
+		wantStatus int
+		wantBody   string
+	}{
+		{"Gzip", EncodingGzip, compress(EncodingGzip, "hello"), http.StatusOK, "hello"},
+		{"Brotli", EncodingBrotli, compress(EncodingBrotli, "hello"), http.StatusOK, "hello"},
+		{"Zstd", EncodingZstd, compress(EncodingZstd, "hello"), http.StatusOK, "hello"},
+		{"Identity", "", []byte("hello"), http.StatusOK, "hello"},
+		{"Too large", EncodingGzip, compress(EncodingGzip, strings.Repeat("a", 4096)), http.StatusRequestEntityTooLarge, ""},
+		{"Invalid gzip", EncodingGzip, []byte("not gzip"), http.StatusBadRequest, ""},
+		{"Unsupported", "compress", []byte("hello"), http.StatusUnsupportedMediaType, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/echo", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golang-gin/idempotency"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader is the header carrying the client's idempotency key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyResponseWriter captures the response body for later replay
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyResponseWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency middleware replays the stored response for requests that repeat
+// an Idempotency-Key. Reusing a key with a different body yields 422 and a
+// duplicate that arrives while the original is still running yields 409.
+// Requests without the header pass through unchanged. 5xx responses are not
+// stored so that the client can retry them. Request bodies larger than
+// maxBodySize bytes are rejected with 413.
+func Idempotency(svc *idempotency.Service, maxBodySize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize))
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body must be at most %d bytes", tooLarge.Limit)})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		scope := "rest:" + c.Request.Method + " " + c.FullPath()
+		// The response depends on the negotiated representation, so a retry
+		// asking for another one is a different request
+		fingerprint := idempotency.Fingerprint([]byte(c.Request.Method), []byte(c.Request.URL.RequestURI()), []byte(c.GetHeader("Accept")), body)
+
+		claim, replay, err := svc.Begin(ctx, scope, key, fingerprint)
+		switch {
+		case errors.Is(err, idempotency.ErrFingerprintMismatch):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, idempotency.ErrInFlight):
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check idempotency key"})
+			return
+		case replay != nil:
+			c.Header("Idempotent-Replayed", "true")
+			if replay.ETag != "" {
+				c.Header("ETag", replay.ETag)
+			}
+			if replay.Vary != "" {
+				c.Header("Vary", replay.Vary)
+			}
+			c.Data(replay.StatusCode, replay.ContentType, replay.Body)
+			c.Abort()
+			return
+		}
+
+		writer := &idempotencyResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		defer func() {
+			if r := recover(); r != nil {
+				svc.Release(ctx, claim)
+				panic(r)
+			}
+		}()
+
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			if err := svc.Release(ctx, claim); err != nil {
+				log.Printf("Failed to release idempotency key %q: %v", key, err)
+			}
+			return
+		}
+
+		if err := svc.Complete(ctx, claim, idempotency.Response{
+			StatusCode:  status,
+			ContentType: writer.Header().Get("Content-Type"),
+			ETag:        writer.Header().Get("ETag"),
+			Vary:        strings.Join(writer.Header().Values("Vary"), ", "),
+			Body:        writer.body.Bytes(),
+		}); err != nil {
+			log.Printf("Failed to store idempotent response for key %q: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"bytes"
+	"golang-gin/idempotency"
+	"golang-gin/repository"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupIdempotencyRouter(handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	svc := idempotency.NewService(repository.NewMockIdempotencyRepository(), time.Hour)
+	router.POST("/albums", Idempotency(svc, 1<<10), handler)
+	return router
+}
+
+func postWithKey(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/albums", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_Replay(t *testing.T) {
+	var calls int32
+	router := setupIdempotencyRouter(func(c *gin.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.JSON(http.StatusCreated, gin.H{"id": n})
+	})
+
+	first := postWithKey(router, "key-1", `{"title":"A"}`)
+	second := postWithKey(router, "key-1", `{"title":"A"}`)
+
+	if first.Code != http.StatusCreated || second.Code != http.StatusCreated {
+		t.Fatalf("Expected both responses to be 201, got %d and %d", first.Code, second.Code)
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("Expected replayed body %s, got %s", first.Body.String(), second.Body.String())
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("Expected Idempotent-Replayed header on replay")
+	}
+	if calls != 1 {
+		t.Errorf("Expected handler to run once, ran %d times", calls)
+	}
+
+	t.Run("Different body", func(t *testing.T) {
+		w := postWithKey(router, "key-1", `{"title":"B"}`)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+	})
+
+	t.Run("Without key", func(t *testing.T) {
+		postWithKey(router, "", `{"title":"A"}`)
+		postWithKey(router, "", `{"title":"A"}`)
+		if calls != 3 {
+			t.Errorf("Expected requests without key to run, ran %d times", calls)
+		}
+	})
+}
+
+func TestIdempotency_ServerErrorsAreRetryable(t *testing.T) {
+	var calls int32
+	router := setupIdempotencyRouter(func(c *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "boom"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	if w := postWithKey(router, "key-1", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, got %d", w.Code)
+	}
+	if w := postWithKey(router, "key-1", `{}`); w.Code != http.StatusCreated {
+		t.Errorf("Expected retry to run the handler again, got %d", w.Code)
+	}
+}
+
+func TestIdempotency_ConcurrentDuplicates(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	router := setupIdempotencyRouter(func(c *gin.Context) {
+		close(started)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	var wg sync.WaitGroup
+	var first *httptest.ResponseRecorder
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first = postWithKey(router, "key-1", `{}`)
+	}()
+
+	<-started
+	duplicate := postWithKey(router, "key-1", `{}`)
+	close(release)
+	wg.Wait()
+
+	if duplicate.Code != http.StatusConflict {
+		t.Errorf("Expected in-flight duplicate to get %d, got %d", http.StatusConflict, duplicate.Code)
+	}
+	if duplicate.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header on in-flight duplicate")
+	}
+	if first.Code != http.StatusCreated {
+		t.Errorf("Expected original request to succeed, got %d", first.Code)
+	}
+}
+
+func TestIdempotency_BinaryResponse(t *testing.T) {
+	var calls int32
+	router := setupIdempotencyRouter(func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		c.Header("ETag", `"1"`)
+		c.Header("Vary", "Accept")
+		c.Data(http.StatusCreated, "application/x-msgpack", []byte("\x81\xa2id\x01\x00\xff"))
+	})
+
+	first := postWithKey(router, "key-1", `{}`)
+	second := postWithKey(router, "key-1", `{}`)
+
+	if second.Code != http.StatusCreated || !bytes.Equal(second.Body.Bytes(), first.Body.Bytes()) {
+		t.Errorf("Expected binary body to be replayed, got %d %q", second.Code, second.Body.Bytes())
+	}
+	if second.Header().Get("ETag") != `"1"` || second.Header().Get("Vary") != "Accept" {
+		t.Errorf("Expected ETag and Vary to be replayed, got %v", second.Header())
+	}
+	if calls != 1 {
+		t.Errorf("Expected handler to run once, ran %d times", calls)
+	}
+}
+
+func TestIdempotency_Fingerprint(t *testing.T) {
+	router := setupIdempotencyRouter(func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+	postWithKey(router, "key-1", `{}`)
+
+	req, _ := http.NewRequest("POST", "/albums", bytes.NewBufferString(`{}`))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	req.Header.Set("Accept", "application/x-msgpack")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected a different Accept to be a different request, got %d", w.Code)
+	}
+
+	if w := postWithKey(router, "key-2", strings.Repeat("x", 2<<10)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d for a large body, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"golang-gin/audit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(RequestIDKey))
+	})
+
+	t.Run("Generates ID", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/test", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		id := w.Header().Get(RequestIDHeader)
+		if len(id) != 32 {
+			t.Errorf("Expected 32 character request ID, got '%s'", id)
+		}
+		if w.Body.String() != id {
+			t.Errorf("Expected context request ID '%s', got '%s'", id, w.Body.String())
+		}
+	})
+
+	t.Run("Propagates ID", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set(RequestIDHeader, "abc-123")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Header().Get(RequestIDHeader) != "abc-123" {
+			t.Errorf("Expected propagated request ID, got '%s'", w.Header().Get(RequestIDHeader))
+		}
+	})
+}
+
+func TestAuditContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	router.Use(AuditContext())
+
+	var md audit.Metadata
+	router.GET("/test", func(c *gin.Context) {
+		md = audit.FromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	req.Header.Set(ActorHeader, "alice")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if md.Actor != "alice" || md.RequestID != "req-42" || md.Source != audit.SourceREST {
+		t.Errorf("Unexpected audit metadata: %+v", md)
+	}
+}
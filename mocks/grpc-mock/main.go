@@ -8,6 +8,8 @@ import (
 	pb "grpc-mock/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Server implements the ProductService gRPC server
@@ -45,7 +47,7 @@ func (s *Server) GetProductByID(ctx context.Context, req *pb.GetProductByIDReque
 		}
 	}
 
-	return nil, nil
+	return nil, status.Errorf(codes.NotFound, "product %d not found", req.Id)
 }
 
 // CreateProduct creates a new product
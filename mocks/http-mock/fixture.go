@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Body is a request or response body. JSON objects and arrays are stored as
+// JSON in fixture files so they stay readable, binary bodies (not valid
+// UTF-8) as {"base64": "..."} and anything else as a string.
+type Body []byte
+
+// base64Body is the stored form of a binary body
+type base64Body struct {
+	Base64 string `json:"base64"`
+}
+
+// MarshalJSON writes JSON objects and arrays as-is, binary bodies base64
+// encoded and other bodies as a string
+func (b Body) MarshalJSON() ([]byte, error) {
+	if !utf8.Valid(b) {
+		return json.Marshal(base64Body{Base64: base64.StdEncoding.EncodeToString(b)})
+	}
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) && !isBase64Body(trimmed) {
+		return trimmed, nil
+	}
+	return json.Marshal(string(b))
+}
+
+// UnmarshalJSON reads a body written by MarshalJSON. JSON bodies are
+// compacted.
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = Body(s)
+		return nil
+	}
+	if isBase64Body(data) {
+		var encoded base64Body
+		json.Unmarshal(data, &encoded)
+		decoded, err := base64.StdEncoding.DecodeString(encoded.Base64)
+		if err != nil {
+			return fmt.Errorf("invalid base64 body: %w", err)
+		}
+		*b = decoded
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return err
+	}
+	*b = buf.Bytes()
+	return nil
+}
+
+// isBase64Body reports whether data is a JSON object holding only a base64
+// string. JSON bodies of that shape are stored as strings so they are not
+// mistaken for binary bodies.
+func isBase64Body(data []byte) bool {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(data, &fields) != nil || len(fields) != 1 {
+		return false
+	}
+	var s string
+	return json.Unmarshal(fields["base64"], &s) == nil
+}
+
+// RecordedRequest is the part of a request used for matching. Request
+// headers are not recorded so credentials never end up in fixtures.
+type RecordedRequest struct {
+	Method string     `json:"method"`
+	Path   string     `json:"path"`
+	Query  url.Values `json:"query,omitempty"`
+	Body   Body       `json:"body,omitempty"`
+}
+
+// RecordedResponse is the upstream response replayed for a request. Cookies
+// and authentication headers are removed before it is saved.
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    Body        `json:"body,omitempty"`
+}
+
+// Interaction is one request/response pair, stored as one fixture file
+type Interaction struct {
+	// File is the fixture file name. It is not part of the file itself.
+	File       string           `json:"file,omitempty"`
+	Request    RecordedRequest  `json:"request"`
+	Response   RecordedResponse `json:"response"`
+	RecordedAt time.Time        `json:"recorded_at"`
+}
+
+// LoadInteractions reads the fixture files in dir in file name order, which
+// is the order they were recorded in
+func LoadInteractions(dir string) ([]Interaction, error) {
+	names, err := fixtureFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	interactions := make([]Interaction, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		var interaction Interaction
+		if err := json.Unmarshal(data, &interaction); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", name, err)
+		}
+		interaction.File = name
+		interactions = append(interactions, interaction)
+	}
+	return interactions, nil
+}
+
+// lastSeq returns the largest sequence number prefixing the given fixture
+// file names, or 0 if none is numbered
+func lastSeq(names []string) int {
+	last := 0
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		if seq, err := strconv.Atoi(prefix); err == nil && seq > last {
+			last = seq
+		}
+	}
+	return last
+}
+
+// fixtureFiles lists the fixture file names in dir, sorted
+func fixtureFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// saveInteraction writes the seq-th interaction to dir and returns its file
+// name, e.g. 0003_GET_api_v1_users_1.json. An existing file is never
+// overwritten; os.ErrExist is returned instead.
+func saveInteraction(dir string, seq int, interaction Interaction) (string, error) {
+	name := fmt.Sprintf("%04d_%s_%s.json", seq, interaction.Request.Method, slug(interaction.Request.Path))
+	interaction.File = ""
+
+	data, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", errors.Join(err, os.Remove(filepath.Join(dir, name)))
+	}
+	return name, nil
+}
+
+// slug turns a path into a file name fragment
+func slug(p string) string {
+	s := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '_'
+	}, p)
+	s = strings.Trim(s, "_")
+	if len(s) > 80 {
+		s = s[:80]
+	}
+	if s == "" {
+		s = "root"
+	}
+	return s
+}
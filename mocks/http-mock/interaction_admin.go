@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerAdmin adds the record mode admin API:
+//
+//	GET /__admin/interactions  list the interactions recorded so far
+func (r *Recorder) registerAdmin(router *gin.Engine) {
+	router.GET(adminPrefix+"/interactions", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"mode":         "record",
+			"dir":          r.dir,
+			"interactions": r.Recorded(),
+		})
+	})
+}
+
+// registerAdmin adds the replay mode admin API:
+//
+//	GET  /__admin/interactions         list interactions with replay counts
+//	GET  /__admin/interactions/verify  list unmatched requests and unused interactions
+//	POST /__admin/interactions/reset   clear replay counts and unmatched requests
+func (r *Replayer) registerAdmin(router *gin.Engine) {
+	admin := router.Group(adminPrefix + "/interactions")
+
+	admin.GET("", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"mode":         "replay",
+			"match":        r.rules,
+			"interactions": r.List(),
+		})
+	})
+
+	admin.GET("/verify", func(c *gin.Context) {
+		c.JSON(http.StatusOK, r.Verify())
+	})
+
+	admin.POST("/reset", func(c *gin.Context) {
+		r.Reset()
+		c.Status(http.StatusNoContent)
+	})
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,8 +27,87 @@ type MockProduct struct {
 	Price float64 `json:"price"`
 }
 
+// mockUsers are the users served by the users endpoints
+var mockUsers = []MockUser{
+	{ID: 1, Name: "Alice", Email: "alice@example.com", UserType: "admin"},
+	{ID: 2, Name: "Bob", Email: "bob@example.com", UserType: "user"},
+	{ID: 3, Name: "Charlie", Email: "charlie@example.com", UserType: "user"},
+}
+
 func main() {
+	seed, _ := strconv.ParseUint(os.Getenv("SCENARIO_SEED"), 10, 64)
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	scenarios := NewScenarioEngine(seed)
+	if file := os.Getenv("SCENARIO_FILE"); file != "" {
+		cfg, err := LoadScenarioFile(file)
+		if err != nil {
+			log.Fatalf("Failed to load scenarios: %v", err)
+		}
+		if err := scenarios.Set(cfg.Scenarios); err != nil {
+			log.Fatalf("Invalid scenarios in %s: %v", file, err)
+		}
+		log.Printf("💥 Loaded %d scenarios from %s", len(cfg.Scenarios), file)
+	}
+
+	backend, err := newBackend(os.Getenv("MOCK_MODE"))
+	if err != nil {
+		log.Fatalf("Failed to set up %s mode: %v", os.Getenv("MOCK_MODE"), err)
+	}
+
+	router := setupRouter(scenarios, backend)
+
+	log.Println("🚀 HTTP Mock Server starting on :17002")
+	if err := router.Run(":17002"); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
+
+// newBackend creates the backend for a MOCK_MODE. The default mock mode
+// uses the built-in endpoints and returns nil.
+//
+//	record  proxy to UPSTREAM_URL and write fixtures to FIXTURE_DIR
+//	replay  answer from the fixtures in FIXTURE_DIR, matched by REPLAY_MATCH
+func newBackend(mode string) (Backend, error) {
+	dir := os.Getenv("FIXTURE_DIR")
+	if dir == "" {
+		dir = "fixtures"
+	}
+
+	switch mode {
+	case "", "mock":
+		return nil, nil
+	case "record":
+		recorder, err := NewRecorder(os.Getenv("UPSTREAM_URL"), dir)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("📼 Recording %s to %s", os.Getenv("UPSTREAM_URL"), dir)
+		return recorder, nil
+	case "replay":
+		rules, err := ParseMatchRules(os.Getenv("REPLAY_MATCH"))
+		if err != nil {
+			return nil, err
+		}
+		interactions, err := LoadInteractions(dir)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("📼 Replaying %d interactions from %s", len(interactions), dir)
+		return NewReplayer(interactions, rules), nil
+	default:
+		return nil, fmt.Errorf("unknown mode %q (use mock, record or replay)", mode)
+	}
+}
+
+// setupRouter creates the mock API with the scenario engine in front of it.
+// With a backend, API requests go to the backend instead of the built-in
+// endpoints.
+func setupRouter(scenarios *ScenarioEngine, backend Backend) *gin.Engine {
 	router := gin.Default()
+	router.Use(scenarios.Middleware())
+	registerScenarioAdmin(router, scenarios)
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
@@ -33,30 +117,37 @@ func main() {
 		})
 	})
 
+	if backend != nil {
+		backend.registerAdmin(router)
+		router.NoRoute(func(c *gin.Context) {
+			if strings.HasPrefix(c.Request.URL.Path, adminPrefix) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+				return
+			}
+			backend.Serve(c)
+		})
+		return router
+	}
+
 	// Mock API endpoints
 	api := router.Group("/api/v1")
 	{
 		// Get users
 		api.GET("/users", func(c *gin.Context) {
-			users := []MockUser{
-				{ID: 1, Name: "Alice", Email: "alice@example.com", UserType: "admin"},
-				{ID: 2, Name: "Bob", Email: "bob@example.com", UserType: "user"},
-				{ID: 3, Name: "Charlie", Email: "charlie@example.com", UserType: "user"},
-			}
-			c.JSON(http.StatusOK, users)
+			c.JSON(http.StatusOK, mockUsers)
 		})
 
 		// Get user by ID
 		api.GET("/users/:id", func(c *gin.Context) {
 			id := c.Param("id")
-			user := MockUser{
-				ID:       1,
-				Name:     "Alice",
-				Email:    "alice@example.com",
-				UserType: "admin",
-			}
 			log.Printf("Requested user ID: %s", id)
-			c.JSON(http.StatusOK, user)
+			for _, user := range mockUsers {
+				if strconv.Itoa(user.ID) == id {
+					c.JSON(http.StatusOK, user)
+					return
+				}
+			}
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
 		})
 
 		// Create user
@@ -81,9 +172,14 @@ func main() {
 			c.JSON(http.StatusOK, products)
 		})
 
-		// Simulate slow API
+		// Simulate slow API (?delay=500ms, default 3s)
 		api.GET("/slow", func(c *gin.Context) {
-			// time.Sleep(3 * time.Second)
+			delay, err := time.ParseDuration(c.DefaultQuery("delay", "3s"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			sleepContext(c.Request, delay)
 			c.JSON(http.StatusOK, gin.H{"message": "This was a slow endpoint"})
 		})
 
@@ -93,8 +189,5 @@ func main() {
 		})
 	}
 
-	log.Println("🚀 HTTP Mock Server starting on :17002")
-	if err := router.Run(":17002"); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
-	}
+	return router
 }
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Backend answers API requests in place of the built-in mock endpoints
+type Backend interface {
+	// Serve answers one API request
+	Serve(c *gin.Context)
+	// registerAdmin adds the backend's admin API
+	registerAdmin(router *gin.Engine)
+}
+
+// sensitiveHeaders are response headers that may carry credentials and are
+// never written to fixtures
+var sensitiveHeaders = []string{
+	"Set-Cookie",
+	"Cookie",
+	"Authorization",
+	"Proxy-Authorization",
+	"WWW-Authenticate",
+	"Proxy-Authenticate",
+	"X-Auth-Token",
+}
+
+// recordedRequestKey is the context key of the captured incoming request
+type recordedRequestKey struct{}
+
+// Recorder proxies requests to an upstream and writes every request/response
+// pair to a fixture file
+type Recorder struct {
+	dir   string
+	proxy *httputil.ReverseProxy
+
+	mu       sync.Mutex
+	seq      int
+	recorded []Interaction
+}
+
+// NewRecorder creates a recorder for upstream that writes fixtures to dir.
+// Fixtures already in dir are kept and new ones are numbered after the
+// highest existing number.
+func NewRecorder(upstream, dir string) (*Recorder, error) {
+	target, err := url.Parse(upstream)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("invalid upstream URL %q", upstream)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	existing, err := fixtureFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{dir: dir, seq: lastSeq(existing)}
+	r.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			// Let the transport decompress responses so bodies are recorded
+			// in plain text
+			pr.Out.Header.Del("Accept-Encoding")
+		},
+		ModifyResponse: r.record,
+	}
+	return r, nil
+}
+
+// Serve proxies the request to the upstream
+func (r *Recorder) Serve(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	recorded := RecordedRequest{
+		Method: c.Request.Method,
+		Path:   c.Request.URL.Path,
+		Query:  c.Request.URL.Query(),
+		Body:   body,
+	}
+	if len(recorded.Query) == 0 {
+		recorded.Query = nil
+	}
+	req := c.Request.WithContext(context.WithValue(c.Request.Context(), recordedRequestKey{}, recorded))
+	r.proxy.ServeHTTP(c.Writer, req)
+}
+
+// record saves the upstream response together with its request
+func (r *Recorder) record(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	headers := resp.Header.Clone()
+	headers.Del("Date")
+	headers.Del("Content-Length")
+	for _, name := range sensitiveHeaders {
+		headers.Del(name)
+	}
+
+	interaction := Interaction{
+		Request: resp.Request.Context().Value(recordedRequestKey{}).(RecordedRequest),
+		Response: RecordedResponse{
+			Status:  resp.StatusCode,
+			Headers: headers,
+			Body:    body,
+		},
+		RecordedAt: time.Now().UTC(),
+	}
+	req := interaction.Request
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// A fixture written by someone else since the recorder started keeps
+	// its number, even when it is for another route
+	if files, err := fixtureFiles(r.dir); err == nil {
+		r.seq = max(r.seq, lastSeq(files))
+	}
+	var name string
+	for {
+		r.seq++
+		name, err = saveInteraction(r.dir, r.seq, interaction)
+		if !errors.Is(err, os.ErrExist) {
+			break
+		}
+	}
+	if err != nil {
+		// The client still gets the upstream response
+		log.Printf("Failed to record %s %s: %v", req.Method, req.Path, err)
+		return nil
+	}
+	interaction.File = name
+	r.recorded = append(r.recorded, interaction)
+	log.Printf("📼 Recorded %s %s -> %d (%s)", req.Method, req.Path, resp.StatusCode, name)
+	return nil
+}
+
+// Recorded returns the interactions recorded since the recorder started
+func (r *Recorder) Recorded() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.recorded...)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newUpstream returns a server standing in for a real users API
+func newUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Upstream-Path", r.URL.Path)
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Auth-Token", "secret")
+		switch {
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		case r.URL.Path == "/base/users/404":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "user not found"}`))
+		case strings.Contains(r.Header.Get("Accept-Encoding"), "gzip"):
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte(`{"id": 1, "name": "Alice", "query": "` + r.URL.RawQuery + `"}`))
+			gz.Close()
+		default:
+			w.Write([]byte(`{"id": 1, "name": "Alice", "query": "` + r.URL.RawQuery + `"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	upstream := newUpstream(t)
+
+	recorder, err := NewRecorder(upstream.URL+"/base", dir)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	// The reverse proxy needs a real connection, not a ResponseRecorder
+	server := httptest.NewServer(setupRouter(NewScenarioEngine(1), recorder))
+	defer server.Close()
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"GET", "/users/1?fields=name", "", http.StatusOK},
+		{"GET", "/users/404", "", http.StatusNotFound},
+		{"POST", "/users", `{"name": "Dave"}`, http.StatusCreated},
+	}
+	recorded := make([]string, len(requests))
+	for i, req := range requests {
+		resp, body := send(t, server.URL, req.method, req.path, req.body)
+		if resp.StatusCode != req.status {
+			t.Fatalf("Record %s %s: expected status %d, got %d", req.method, req.path, req.status, resp.StatusCode)
+		}
+		if resp.Header.Get("X-Upstream-Path") != "/base"+strings.Split(req.path, "?")[0] {
+			t.Errorf("Expected request to be proxied below the upstream path, got %s", resp.Header.Get("X-Upstream-Path"))
+		}
+		recorded[i] = body
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 3 || filepath.Base(files[0]) != "0001_GET_users_1.json" {
+		t.Fatalf("Expected 3 fixtures starting with 0001_GET_users_1.json, got %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(data), `"name": "Alice"`) || strings.Contains(string(data), "Content-Encoding") {
+		t.Errorf("Expected a readable uncompressed fixture, got %s", data)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("Expected cookies and auth headers to be stripped, got %s", data)
+	}
+
+	interactions, err := LoadInteractions(dir)
+	if err != nil {
+		t.Fatalf("LoadInteractions failed: %v", err)
+	}
+	replayer := NewReplayer(interactions, MatchRules{Method: true, Path: true, Query: true, Body: true})
+	router := setupRouter(NewScenarioEngine(1), replayer)
+
+	for i, req := range requests {
+		w := serve(router, req.method, req.path, req.body)
+		if w.Code != req.status {
+			t.Errorf("Replay %s %s: expected status %d, got %d", req.method, req.path, req.status, w.Code)
+		}
+		var got, want interface{}
+		json.Unmarshal(w.Body.Bytes(), &got)
+		json.Unmarshal([]byte(recorded[i]), &want)
+		if got == nil || !jsonEqual(got, want) {
+			t.Errorf("Replay %s %s: expected body %s, got %s", req.method, req.path, recorded[i], w.Body.String())
+		}
+		if w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Expected recorded headers to be replayed, got %v", w.Header())
+		}
+	}
+
+	// A recorder started on the same directory numbers new fixtures after
+	// the existing ones
+	recorder, _ = NewRecorder(upstream.URL, dir)
+	server = httptest.NewServer(setupRouter(NewScenarioEngine(1), recorder))
+	defer server.Close()
+	send(t, server.URL, "GET", "/", "")
+	if files, _ := filepath.Glob(filepath.Join(dir, "0004_GET_root.json")); len(files) != 1 {
+		t.Errorf("Expected 0004_GET_root.json, got %v", files)
+	}
+}
+
+func TestRecorder_Numbering(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	upstream := newUpstream(t)
+
+	// Fixtures 0002 to 0004 were removed and a non-fixture JSON file sits
+	// in the directory
+	for _, name := range []string{"0001_GET_users_1.json", "0005_GET_users_5.json", "notes.json"} {
+		os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o644)
+	}
+
+	recorder, err := NewRecorder(upstream.URL, dir)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	server := httptest.NewServer(setupRouter(NewScenarioEngine(1), recorder))
+	defer server.Close()
+
+	send(t, server.URL, "GET", "/", "")
+	if _, err := os.Stat(filepath.Join(dir, "0006_GET_root.json")); err != nil {
+		t.Errorf("Expected 0006_GET_root.json after the highest fixture, got %v", err)
+	}
+
+	// A file created behind the recorder's back is not overwritten
+	os.WriteFile(filepath.Join(dir, "0007_GET_root.json"), []byte("{}"), 0o644)
+	send(t, server.URL, "GET", "/", "")
+	if data, _ := os.ReadFile(filepath.Join(dir, "0007_GET_root.json")); string(data) != "{}" {
+		t.Errorf("Expected existing fixture to be kept, got %s", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "0008_GET_root.json")); err != nil {
+		t.Errorf("Expected 0008_GET_root.json, got %v", err)
+	}
+
+	// The number of a fixture for another route is not reused either
+	os.WriteFile(filepath.Join(dir, "0009_GET_users_1.json"), []byte("{}"), 0o644)
+	send(t, server.URL, "GET", "/", "")
+	if _, err := os.Stat(filepath.Join(dir, "0009_GET_root.json")); err == nil {
+		t.Error("Expected sequence number 0009 not to be reused")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "0010_GET_root.json")); err != nil {
+		t.Errorf("Expected 0010_GET_root.json, got %v", err)
+	}
+}
+
+func TestNewRecorder_InvalidUpstream(t *testing.T) {
+	for _, upstream := range []string{"", "localhost:8080", "://x"} {
+		if _, err := NewRecorder(upstream, t.TempDir()); err == nil {
+			t.Errorf("Expected error for upstream %q", upstream)
+		}
+	}
+}
+
+func TestBody_JSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"Object", `{"id": 1}`, `{"id":1}`},
+		{"Array", `[1, 2]`, `[1,2]`},
+		{"Text", `hello`, `"hello"`},
+		{"JSON string", `"hello"`, `"\"hello\""`},
+		{"Number", `42`, `"42"`},
+		{"Binary", "\xff\x00\x01", `{"base64":"/wAB"}`},
+		{"Base64 lookalike", `{"base64": "/wAB"}`, `"{\"base64\": \"/wAB\"}"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(Body(tt.body))
+			if err != nil || string(data) != tt.expected {
+				t.Fatalf("Expected %s, got %s (%v)", tt.expected, data, err)
+			}
+
+			var body Body
+			if err := json.Unmarshal(data, &body); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if !bodiesEqual(body, Body(tt.body)) {
+				t.Errorf("Expected %s after round trip, got %s", tt.body, body)
+			}
+		})
+	}
+}
+
+// send makes a request to a running server and returns the response and body
+func send(t *testing.T, baseURL, method, path, body string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest(method, baseURL+path, strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func jsonEqual(a, b interface{}) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MatchRules selects which parts of a request must equal the recorded
+// request for an interaction to be replayed
+type MatchRules struct {
+	Method bool `json:"method"`
+	Path   bool `json:"path"`
+	Query  bool `json:"query"`
+	Body   bool `json:"body"`
+}
+
+// DefaultMatchRules match on method, path and query
+var DefaultMatchRules = MatchRules{Method: true, Path: true, Query: true}
+
+// ParseMatchRules parses a comma-separated list of method, path, query and
+// body. An empty string returns DefaultMatchRules.
+func ParseMatchRules(s string) (MatchRules, error) {
+	if strings.TrimSpace(s) == "" {
+		return DefaultMatchRules, nil
+	}
+
+	var rules MatchRules
+	for _, field := range strings.Split(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "method":
+			rules.Method = true
+		case "path":
+			rules.Path = true
+		case "query":
+			rules.Query = true
+		case "body":
+			rules.Body = true
+		default:
+			return MatchRules{}, fmt.Errorf("unknown match rule %q (use method, path, query or body)", field)
+		}
+	}
+	return rules, nil
+}
+
+// Match reports whether req matches the recorded request
+func (m MatchRules) Match(recorded, req RecordedRequest) bool {
+	if m.Method && !strings.EqualFold(recorded.Method, req.Method) {
+		return false
+	}
+	if m.Path && recorded.Path != req.Path {
+		return false
+	}
+	if m.Query && !(len(recorded.Query) == 0 && len(req.Query) == 0) && !reflect.DeepEqual(recorded.Query, req.Query) {
+		return false
+	}
+	if m.Body && !bodiesEqual(recorded.Body, req.Body) {
+		return false
+	}
+	return true
+}
+
+// bodiesEqual compares JSON bodies by value and other bodies byte by byte
+func bodiesEqual(a, b Body) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) == nil && json.Unmarshal(b, &vb) == nil {
+		return reflect.DeepEqual(va, vb)
+	}
+	return bytes.Equal(bytes.TrimSpace(a), bytes.TrimSpace(b))
+}
+
+// InteractionStatus is a loaded interaction with the number of times it was
+// replayed
+type InteractionStatus struct {
+	File     string          `json:"file"`
+	Request  RecordedRequest `json:"request"`
+	Status   int             `json:"status"`
+	Replayed int             `json:"replayed"`
+}
+
+// Verification lists the requests no interaction matched and the
+// interactions no request used
+type Verification struct {
+	OK        bool                `json:"ok"`
+	Unmatched []RecordedRequest   `json:"unmatched"`
+	Unused    []InteractionStatus `json:"unused"`
+}
+
+// Replayer answers requests with recorded interactions. Each request gets
+// the first matching interaction that was not replayed yet, so repeated
+// requests replay their responses in recorded order; once all of them are
+// used the last one is repeated.
+type Replayer struct {
+	rules        MatchRules
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     []int
+	unmatched    []RecordedRequest
+}
+
+// NewReplayer creates a replayer for the interactions
+func NewReplayer(interactions []Interaction, rules MatchRules) *Replayer {
+	return &Replayer{
+		rules:        rules,
+		interactions: interactions,
+		replayed:     make([]int, len(interactions)),
+	}
+}
+
+// Serve replays the interaction matching the request or answers 404
+func (r *Replayer) Serve(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req := RecordedRequest{
+		Method: c.Request.Method,
+		Path:   c.Request.URL.Path,
+		Query:  c.Request.URL.Query(),
+		Body:   body,
+	}
+	if len(req.Query) == 0 {
+		req.Query = nil
+	}
+
+	interaction, ok := r.find(req)
+	if !ok {
+		log.Printf("📼 No recorded interaction matches %s %s", req.Method, c.Request.URL.RequestURI())
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no recorded interaction matches %s %s", req.Method, c.Request.URL.RequestURI())})
+		return
+	}
+
+	for key, values := range interaction.Response.Headers {
+		for _, value := range values {
+			c.Writer.Header().Add(key, value)
+		}
+	}
+	c.Status(interaction.Response.Status)
+	c.Writer.Write(interaction.Response.Body)
+}
+
+// find picks the interaction for req and marks it replayed
+func (r *Replayer) find(req RecordedRequest) (Interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last := -1
+	for i := range r.interactions {
+		if !r.rules.Match(r.interactions[i].Request, req) {
+			continue
+		}
+		if r.replayed[i] == 0 {
+			r.replayed[i]++
+			return r.interactions[i], true
+		}
+		last = i
+	}
+	if last < 0 {
+		r.unmatched = append(r.unmatched, req)
+		return Interaction{}, false
+	}
+	r.replayed[last]++
+	return r.interactions[last], true
+}
+
+// List returns the interactions with their replay counts
+func (r *Replayer) List() []InteractionStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]InteractionStatus, len(r.interactions))
+	for i := range r.interactions {
+		list[i] = r.status(i)
+	}
+	return list
+}
+
+// Verify lists the unmatched requests and unused interactions since the
+// replayer started or was last reset
+func (r *Replayer) Verify() Verification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v := Verification{
+		Unmatched: append([]RecordedRequest{}, r.unmatched...),
+		Unused:    []InteractionStatus{},
+	}
+	for i := range r.interactions {
+		if r.replayed[i] == 0 {
+			v.Unused = append(v.Unused, r.status(i))
+		}
+	}
+	v.OK = len(v.Unmatched) == 0 && len(v.Unused) == 0
+	return v
+}
+
+// Reset clears the replay counts and unmatched requests
+func (r *Replayer) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replayed = make([]int, len(r.interactions))
+	r.unmatched = nil
+}
+
+// status describes the i-th interaction. r.mu must be held.
+func (r *Replayer) status(i int) InteractionStatus {
+	return InteractionStatus{
+		File:     r.interactions[i].File,
+		Request:  r.interactions[i].Request,
+		Status:   r.interactions[i].Response.Status,
+		Replayed: r.replayed[i],
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseMatchRules(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected MatchRules
+		wantErr  bool
+	}{
+		{"", DefaultMatchRules, false},
+		{"method,path", MatchRules{Method: true, Path: true}, false},
+		{" Path , BODY ", MatchRules{Path: true, Body: true}, false},
+		{"method,headers", MatchRules{}, true},
+	}
+
+	for _, tt := range tests {
+		rules, err := ParseMatchRules(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMatchRules(%q): expected error %v, got %v", tt.input, tt.wantErr, err)
+		}
+		if rules != tt.expected {
+			t.Errorf("ParseMatchRules(%q): expected %+v, got %+v", tt.input, tt.expected, rules)
+		}
+	}
+}
+
+func TestMatchRules_Match(t *testing.T) {
+	recorded := RecordedRequest{
+		Method: "POST",
+		Path:   "/users",
+		Query:  url.Values{"a": {"1"}, "b": {"2"}},
+		Body:   Body(`{"name": "Dave", "tags": ["x"]}`),
+	}
+
+	tests := []struct {
+		name     string
+		rules    MatchRules
+		req      RecordedRequest
+		expected bool
+	}{
+		{"Same request", MatchRules{true, true, true, true}, recorded, true},
+		{"Method case", MatchRules{Method: true}, RecordedRequest{Method: "post"}, true},
+		{"Other method", MatchRules{Method: true}, RecordedRequest{Method: "GET"}, false},
+		{"Other path", MatchRules{Path: true}, RecordedRequest{Path: "/users/1"}, false},
+		{"Query order", MatchRules{Query: true}, RecordedRequest{Query: url.Values{"b": {"2"}, "a": {"1"}}}, true},
+		{"Other query", MatchRules{Query: true}, RecordedRequest{Query: url.Values{"a": {"1"}}}, false},
+		{"No query", MatchRules{Query: true}, RecordedRequest{Query: url.Values{}}, false},
+		{"JSON body by value", MatchRules{Body: true}, RecordedRequest{Body: Body(`{"tags":["x"],"name":"Dave"}`)}, true},
+		{"Other body", MatchRules{Body: true}, RecordedRequest{Body: Body(`{"name": "Eve"}`)}, false},
+		{"Body ignored", MatchRules{Method: true, Path: true}, RecordedRequest{Method: "POST", Path: "/users"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.Match(recorded, tt.req); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReplayer(t *testing.T) {
+	interaction := func(file, path string, status int, body string) Interaction {
+		return Interaction{
+			File:     file,
+			Request:  RecordedRequest{Method: "GET", Path: path},
+			Response: RecordedResponse{Status: status, Body: Body(body)},
+		}
+	}
+	replayer := NewReplayer([]Interaction{
+		interaction("0001.json", "/users/1", http.StatusServiceUnavailable, `{"error": "down"}`),
+		interaction("0002.json", "/users/1", http.StatusOK, `{"id": 1}`),
+		interaction("0003.json", "/users/2", http.StatusOK, `{"id": 2}`),
+	}, DefaultMatchRules)
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(NewScenarioEngine(1), replayer)
+
+	// Repeated requests replay in recorded order, then repeat the last one
+	for i, status := range []int{503, 200, 200} {
+		if w := serve(router, "GET", "/users/1", ""); w.Code != status {
+			t.Errorf("Request %d: expected status %d, got %d", i+1, status, w.Code)
+		}
+	}
+	if w := serve(router, "GET", "/users/3?x=1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unmatched request, got %d", w.Code)
+	}
+	if w := serve(router, "GET", "/__admin/unknown", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected admin paths not to be replayed, got %d", w.Code)
+	}
+
+	w := serve(router, "GET", "/__admin/interactions/verify", "")
+	var v Verification
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("Invalid verification response: %s", w.Body.String())
+	}
+	if v.OK || len(v.Unmatched) != 1 || v.Unmatched[0].Path != "/users/3" || v.Unmatched[0].Query.Get("x") != "1" {
+		t.Errorf("Expected GET /users/3?x=1 to be unmatched, got %+v", v.Unmatched)
+	}
+	if len(v.Unused) != 1 || v.Unused[0].File != "0003.json" {
+		t.Errorf("Expected 0003.json to be unused, got %+v", v.Unused)
+	}
+
+	w = serve(router, "GET", "/__admin/interactions", "")
+	var list struct {
+		Interactions []InteractionStatus `json:"interactions"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Interactions) != 3 || list.Interactions[1].Replayed != 2 {
+		t.Errorf("Expected 0002.json to be replayed twice, got %+v", list.Interactions)
+	}
+
+	if w := serve(router, "POST", "/__admin/interactions/reset", ""); w.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", w.Code)
+	}
+	if v := replayer.Verify(); len(v.Unmatched) != 0 || len(v.Unused) != 3 {
+		t.Errorf("Expected reset to clear counters, got %+v", v)
+	}
+	if w := serve(router, "GET", "/users/1", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected reset to restart the replay order, got %d", w.Code)
+	}
+}
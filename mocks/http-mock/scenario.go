@@ -0,0 +1,455 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Duration is a time.Duration written as a string such as "250ms" in JSON
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string or a number of milliseconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var ms float64
+	if err := json.Unmarshal(data, &ms); err == nil {
+		*d = Duration(ms * float64(time.Millisecond))
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"250ms\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Latency is a distribution of delays added before a response
+type Latency struct {
+	// Distribution is fixed (Mean), uniform (Min..Max), normal (Mean,
+	// StdDev) or exponential (Mean). Samples are clamped to Min..Max when
+	// they are set.
+	Distribution string   `json:"distribution"`
+	Min          Duration `json:"min,omitempty"`
+	Max          Duration `json:"max,omitempty"`
+	Mean         Duration `json:"mean,omitempty"`
+	StdDev       Duration `json:"stddev,omitempty"`
+}
+
+// validate checks the distribution and its parameters
+func (l *Latency) validate() error {
+	switch l.Distribution {
+	case "fixed", "normal", "exponential":
+		if l.Mean < 0 {
+			return errors.New("latency mean must not be negative")
+		}
+	case "uniform":
+		if l.Max < l.Min {
+			return errors.New("uniform latency needs min <= max")
+		}
+	default:
+		return fmt.Errorf("unknown latency distribution %q", l.Distribution)
+	}
+	return nil
+}
+
+// sample draws a delay from the distribution
+func (l *Latency) sample(r *rand.Rand) time.Duration {
+	var d float64
+	switch l.Distribution {
+	case "fixed":
+		d = float64(l.Mean)
+	case "uniform":
+		d = float64(l.Min) + r.Float64()*float64(l.Max-l.Min)
+	case "normal":
+		d = float64(l.Mean) + r.NormFloat64()*float64(l.StdDev)
+	case "exponential":
+		d = r.ExpFloat64() * float64(l.Mean)
+	}
+
+	if l.Max > 0 {
+		d = math.Min(d, float64(l.Max))
+	}
+	return time.Duration(math.Max(d, float64(l.Min)))
+}
+
+// Fault is an injected failure. Reset closes the connection without a
+// response; Malformed sends a truncated JSON body; otherwise Status is sent
+// with Body.
+type Fault struct {
+	Status     int               `json:"status,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	RetryAfter string            `json:"retry_after,omitempty"`
+	Malformed  bool              `json:"malformed,omitempty"`
+	Reset      bool              `json:"reset,omitempty"`
+}
+
+// validate checks that the fault does something
+func (f *Fault) validate() error {
+	if f.Status == 0 && !f.Malformed && !f.Reset {
+		return errors.New("fault needs a status, malformed or reset")
+	}
+	if f.Status != 0 && (f.Status < 100 || f.Status > 599) {
+		return fmt.Errorf("invalid fault status %d", f.Status)
+	}
+	return nil
+}
+
+// Step is one stage of a stateful scenario, applied to Times consecutive
+// matching requests. A step without a fault lets the requests through.
+type Step struct {
+	Times   int      `json:"times,omitempty"`
+	Fault   *Fault   `json:"fault,omitempty"`
+	Latency *Latency `json:"latency,omitempty"`
+}
+
+// Scenario injects latency and faults into the requests matching Method and
+// Path. Path is a path.Match pattern such as "/api/v1/users/*".
+//
+// A scenario with Steps is stateful: matching requests walk through the
+// steps in order (e.g. two 503s, then success) and pass through once all
+// steps are used, unless Repeat restarts them. Otherwise Fault is injected
+// into ErrorRate of the requests (default: all) and Latency is added to
+// every request.
+type Scenario struct {
+	Name      string   `json:"name"`
+	Method    string   `json:"method,omitempty"`
+	Path      string   `json:"path"`
+	Latency   *Latency `json:"latency,omitempty"`
+	Fault     *Fault   `json:"fault,omitempty"`
+	ErrorRate *float64 `json:"error_rate,omitempty"`
+	Steps     []Step   `json:"steps,omitempty"`
+	Repeat    bool     `json:"repeat,omitempty"`
+}
+
+// validate checks the scenario and fills in defaults
+func (s *Scenario) validate() error {
+	if s.Name == "" {
+		return errors.New("scenario name is required")
+	}
+	if _, err := path.Match(s.Path, "/"); err != nil || !strings.HasPrefix(s.Path, "/") {
+		return fmt.Errorf("scenario %s: invalid path pattern %q", s.Name, s.Path)
+	}
+	s.Method = strings.ToUpper(s.Method)
+
+	if s.ErrorRate != nil && (*s.ErrorRate < 0 || *s.ErrorRate > 1) {
+		return fmt.Errorf("scenario %s: error_rate must be between 0 and 1", s.Name)
+	}
+	if s.Latency != nil {
+		if err := s.Latency.validate(); err != nil {
+			return fmt.Errorf("scenario %s: %w", s.Name, err)
+		}
+	}
+	if s.Fault != nil {
+		if err := s.Fault.validate(); err != nil {
+			return fmt.Errorf("scenario %s: %w", s.Name, err)
+		}
+	}
+	for i := range s.Steps {
+		step := &s.Steps[i]
+		if step.Times == 0 {
+			step.Times = 1
+		}
+		if step.Times < 0 {
+			return fmt.Errorf("scenario %s: step %d: times must be positive", s.Name, i+1)
+		}
+		if step.Fault != nil {
+			if err := step.Fault.validate(); err != nil {
+				return fmt.Errorf("scenario %s: step %d: %w", s.Name, i+1, err)
+			}
+		}
+		if step.Latency != nil {
+			if err := step.Latency.validate(); err != nil {
+				return fmt.Errorf("scenario %s: step %d: %w", s.Name, i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// matches reports whether the scenario applies to a request
+func (s *Scenario) matches(method, requestPath string) bool {
+	if s.Method != "" && s.Method != method {
+		return false
+	}
+	ok, _ := path.Match(s.Path, requestPath)
+	return ok
+}
+
+// ScenarioConfig is the format of the scenario file and admin API
+type ScenarioConfig struct {
+	Scenarios []Scenario `json:"scenarios"`
+}
+
+// ScenarioStatus is a scenario with its counters
+type ScenarioStatus struct {
+	Scenario
+	Hits     int `json:"hits"`
+	Injected int `json:"injected"`
+}
+
+// ScenarioEngine applies the configured scenarios to incoming requests. The
+// first matching scenario wins.
+type ScenarioEngine struct {
+	mu        sync.Mutex
+	scenarios []*ScenarioStatus
+	rand      *rand.Rand
+	sleep     func(*http.Request, time.Duration)
+}
+
+// NewScenarioEngine creates an engine without scenarios. The seed makes
+// latencies and error rates reproducible.
+func NewScenarioEngine(seed uint64) *ScenarioEngine {
+	return &ScenarioEngine{
+		rand:  rand.New(rand.NewPCG(seed, seed)),
+		sleep: sleepContext,
+	}
+}
+
+// LoadScenarioFile reads a ScenarioConfig from a JSON file
+func LoadScenarioFile(name string) (*ScenarioConfig, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var cfg ScenarioConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid scenario file %s: %w", name, err)
+	}
+	return &cfg, nil
+}
+
+// Set replaces all scenarios and resets their counters
+func (e *ScenarioEngine) Set(scenarios []Scenario) error {
+	states := make([]*ScenarioStatus, len(scenarios))
+	names := map[string]bool{}
+	for i := range scenarios {
+		if err := scenarios[i].validate(); err != nil {
+			return err
+		}
+		if names[scenarios[i].Name] {
+			return fmt.Errorf("duplicate scenario name %q", scenarios[i].Name)
+		}
+		names[scenarios[i].Name] = true
+		states[i] = &ScenarioStatus{Scenario: scenarios[i]}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scenarios = states
+	return nil
+}
+
+// Add adds a scenario, replacing one with the same name
+func (e *ScenarioEngine) Add(scenario Scenario) error {
+	if err := scenario.validate(); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, state := range e.scenarios {
+		if state.Name == scenario.Name {
+			e.scenarios[i] = &ScenarioStatus{Scenario: scenario}
+			return nil
+		}
+	}
+	e.scenarios = append(e.scenarios, &ScenarioStatus{Scenario: scenario})
+	return nil
+}
+
+// Remove removes a scenario by name and reports whether it existed
+func (e *ScenarioEngine) Remove(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, state := range e.scenarios {
+		if state.Name == name {
+			e.scenarios = append(e.scenarios[:i], e.scenarios[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Reset zeroes the counters, restarting stateful scenarios
+func (e *ScenarioEngine) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, state := range e.scenarios {
+		state.Hits, state.Injected = 0, 0
+	}
+}
+
+// List returns the scenarios with their counters
+func (e *ScenarioEngine) List() []ScenarioStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	list := make([]ScenarioStatus, len(e.scenarios))
+	for i, state := range e.scenarios {
+		list[i] = *state
+	}
+	return list
+}
+
+// decision is what the engine does with one request
+type decision struct {
+	scenario string
+	delay    time.Duration
+	fault    *Fault
+}
+
+// decide finds the scenario for a request and advances its state
+func (e *ScenarioEngine) decide(method, requestPath string) (decision, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, state := range e.scenarios {
+		if !state.matches(method, requestPath) {
+			continue
+		}
+		hit := state.Hits
+		state.Hits++
+		d := decision{scenario: state.Name}
+
+		if len(state.Steps) > 0 {
+			if step := stepAt(state.Steps, hit, state.Repeat); step != nil {
+				d.fault = step.Fault
+				if step.Latency != nil {
+					d.delay = step.Latency.sample(e.rand)
+				}
+			}
+		} else {
+			if state.Latency != nil {
+				d.delay = state.Latency.sample(e.rand)
+			}
+			if state.Fault != nil && (state.ErrorRate == nil || e.rand.Float64() < *state.ErrorRate) {
+				d.fault = state.Fault
+			}
+		}
+		if d.fault != nil {
+			state.Injected++
+		}
+		return d, true
+	}
+	return decision{}, false
+}
+
+// stepAt returns the step used by the hit-th matching request, or nil once
+// the steps are used up
+func stepAt(steps []Step, hit int, repeat bool) *Step {
+	total := 0
+	for _, step := range steps {
+		total += step.Times
+	}
+	if hit >= total {
+		if !repeat {
+			return nil
+		}
+		hit %= total
+	}
+	for i := range steps {
+		if hit < steps[i].Times {
+			return &steps[i]
+		}
+		hit -= steps[i].Times
+	}
+	return nil
+}
+
+// Middleware applies the scenarios to every request except the admin API
+func (e *ScenarioEngine) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, adminPrefix) {
+			c.Next()
+			return
+		}
+
+		d, ok := e.decide(c.Request.Method, c.Request.URL.Path)
+		if !ok {
+			c.Next()
+			return
+		}
+		if d.delay > 0 {
+			e.sleep(c.Request, d.delay)
+		}
+		if d.fault == nil {
+			c.Next()
+			return
+		}
+
+		log.Printf("💥 Scenario %s: injecting fault into %s %s", d.scenario, c.Request.Method, c.Request.URL.Path)
+		writeFault(c, d.fault)
+	}
+}
+
+// writeFault sends the injected failure and aborts the request
+func writeFault(c *gin.Context, fault *Fault) {
+	c.Abort()
+
+	if fault.Reset {
+		conn, _, err := c.Writer.Hijack()
+		if err != nil {
+			log.Printf("Failed to reset connection: %v", err)
+			return
+		}
+		// Closing with a zero linger sends a TCP RST instead of a FIN
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			tcp.SetLinger(0)
+		}
+		conn.Close()
+		return
+	}
+
+	for key, value := range fault.Headers {
+		c.Header(key, value)
+	}
+	if fault.RetryAfter != "" {
+		c.Header("Retry-After", fault.RetryAfter)
+	}
+
+	status := fault.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	body := fault.Body
+	switch {
+	case fault.Malformed:
+		body = `{"id": 1, "name": "Alice", "email": `
+	case body == "":
+		body = fmt.Sprintf(`{"error": %q}`, http.StatusText(status))
+	}
+	c.Data(status, "application/json; charset=utf-8", []byte(body))
+}
+
+// sleepContext waits for d or until the client goes away
+func sleepContext(req *http.Request, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-req.Context().Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminPrefix is the path prefix of the admin API. Scenarios never apply to
+// it.
+const adminPrefix = "/__admin"
+
+// registerScenarioAdmin adds the scenario admin API:
+//
+//	GET    /__admin/scenarios        list scenarios with their counters
+//	PUT    /__admin/scenarios        replace all scenarios ({"scenarios": [...]})
+//	POST   /__admin/scenarios        add or replace one scenario
+//	DELETE /__admin/scenarios        remove all scenarios
+//	DELETE /__admin/scenarios/:name  remove one scenario
+//	POST   /__admin/scenarios/reset  zero the counters and restart steps
+func registerScenarioAdmin(router *gin.Engine, engine *ScenarioEngine) {
+	admin := router.Group(adminPrefix + "/scenarios")
+
+	admin.GET("", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"scenarios": engine.List()})
+	})
+
+	admin.PUT("", func(c *gin.Context) {
+		var cfg ScenarioConfig
+		if err := c.ShouldBindJSON(&cfg); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := engine.Set(cfg.Scenarios); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"scenarios": engine.List()})
+	})
+
+	admin.POST("", func(c *gin.Context) {
+		var scenario Scenario
+		if err := c.ShouldBindJSON(&scenario); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := engine.Add(scenario); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, scenario)
+	})
+
+	admin.DELETE("", func(c *gin.Context) {
+		engine.Set(nil)
+		c.Status(http.StatusNoContent)
+	})
+
+	admin.DELETE("/:name", func(c *gin.Context) {
+		if !engine.Remove(c.Param("name")) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "scenario not found"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	admin.POST("/reset", func(c *gin.Context) {
+		engine.Reset()
+		c.Status(http.StatusNoContent)
+	})
+}
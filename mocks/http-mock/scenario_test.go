@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter returns the mock API with the given scenarios. Delays are
+// recorded instead of slept.
+func newTestRouter(t *testing.T, scenarios ...Scenario) (*gin.Engine, *ScenarioEngine, *[]time.Duration) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	engine := NewScenarioEngine(1)
+	var mu sync.Mutex
+	var delays []time.Duration
+	engine.sleep = func(_ *http.Request, d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		delays = append(delays, d)
+	}
+	if err := engine.Set(scenarios); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	return setupRouter(engine, nil), engine, &delays
+}
+
+func serve(router http.Handler, method, path string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestScenario_Steps(t *testing.T) {
+	tests := []struct {
+		name     string
+		repeat   bool
+		expected []int
+	}{
+		{"Fail twice then succeed", false, []int{503, 503, 200, 200, 200}},
+		{"Repeat", true, []int{503, 503, 200, 503, 503}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, engine, _ := newTestRouter(t, Scenario{
+				Name:   "flaky",
+				Method: "get",
+				Path:   "/api/v1/users/*",
+				Steps:  []Step{{Times: 2, Fault: &Fault{Status: 503}}, {}},
+				Repeat: tt.repeat,
+			})
+
+			for i, status := range tt.expected {
+				if w := serve(router, "GET", "/api/v1/users/1", ""); w.Code != status {
+					t.Errorf("Request %d: expected status %d, got %d", i+1, status, w.Code)
+				}
+			}
+			if w := serve(router, "POST", "/api/v1/users", `{"name":"x"}`); w.Code != http.StatusCreated {
+				t.Errorf("Expected unmatched request to pass, got %d", w.Code)
+			}
+
+			engine.Reset()
+			if w := serve(router, "GET", "/api/v1/users/1", ""); w.Code != 503 {
+				t.Errorf("Expected reset to restart the steps, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestScenario_Faults(t *testing.T) {
+	router, _, _ := newTestRouter(t,
+		Scenario{Name: "throttled", Path: "/api/v1/users", Fault: &Fault{Status: 429, RetryAfter: "2", Headers: map[string]string{"X-Mock": "1"}}},
+		Scenario{Name: "malformed", Path: "/api/v1/products", Fault: &Fault{Malformed: true}},
+		Scenario{Name: "custom", Path: "/api/v1/error", Fault: &Fault{Status: 502, Body: "bad gateway"}},
+	)
+
+	w := serve(router, "GET", "/api/v1/users", "")
+	if w.Code != 429 || w.Header().Get("Retry-After") != "2" || w.Header().Get("X-Mock") != "1" {
+		t.Errorf("Expected 429 with Retry-After, got %d %v", w.Code, w.Header())
+	}
+
+	w = serve(router, "GET", "/api/v1/products", "")
+	var v interface{}
+	if w.Code != 200 || json.Unmarshal(w.Body.Bytes(), &v) == nil {
+		t.Errorf("Expected malformed 200 body, got %d %s", w.Code, w.Body.String())
+	}
+
+	if w := serve(router, "GET", "/api/v1/error", ""); w.Code != 502 || w.Body.String() != "bad gateway" {
+		t.Errorf("Expected custom 502, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestScenario_ConnectionReset(t *testing.T) {
+	router, _, _ := newTestRouter(t, Scenario{Name: "reset", Path: "/api/v1/users/*", Fault: &Fault{Reset: true}})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	if resp, err := http.Get(server.URL + "/api/v1/users/1"); err == nil {
+		resp.Body.Close()
+		t.Errorf("Expected connection error, got status %d", resp.StatusCode)
+	}
+	resp, err := http.Get(server.URL + "/api/v1/users")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected other routes to work, got %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestScenario_LatencyAndErrorRate(t *testing.T) {
+	rate := 0.3
+	router, engine, delays := newTestRouter(t,
+		Scenario{Name: "fixed", Path: "/api/v1/users", Latency: &Latency{Distribution: "fixed", Mean: Duration(100 * time.Millisecond)}},
+		Scenario{
+			Name:      "uniform",
+			Path:      "/api/v1/products",
+			Latency:   &Latency{Distribution: "uniform", Min: Duration(10 * time.Millisecond), Max: Duration(20 * time.Millisecond)},
+			Fault:     &Fault{Status: 500},
+			ErrorRate: &rate,
+		},
+	)
+
+	serve(router, "GET", "/api/v1/users", "")
+	if len(*delays) != 1 || (*delays)[0] != 100*time.Millisecond {
+		t.Fatalf("Expected a fixed 100ms delay, got %v", *delays)
+	}
+
+	failures := 0
+	for i := 0; i < 200; i++ {
+		if serve(router, "GET", "/api/v1/products", "").Code == 500 {
+			failures++
+		}
+	}
+	if failures < 40 || failures > 80 {
+		t.Errorf("Expected about 60 failures at rate 0.3, got %d", failures)
+	}
+	for _, d := range (*delays)[1:] {
+		if d < 10*time.Millisecond || d > 20*time.Millisecond {
+			t.Fatalf("Expected uniform delay in 10ms..20ms, got %v", d)
+		}
+	}
+
+	status := engine.List()[1]
+	if status.Hits != 200 || status.Injected != failures {
+		t.Errorf("Expected 200 hits and %d injected, got %+v", failures, status)
+	}
+}
+
+func TestScenarioAdmin(t *testing.T) {
+	router, _, _ := newTestRouter(t)
+
+	invalid := []string{
+		`{"scenarios": [{"name": "a", "path": "api"}]}`,
+		`{"scenarios": [{"name": "a", "path": "/a", "fault": {}}]}`,
+		`{"scenarios": [{"name": "a", "path": "/a", "latency": {"distribution": "pareto"}}]}`,
+		`{"scenarios": [{"name": "a", "path": "/a", "fault": {"status": 500}, "error_rate": 2}]}`,
+		`{"scenarios": [{"name": "a", "path": "/a"}, {"name": "a", "path": "/b"}]}`,
+	}
+	for _, body := range invalid {
+		if w := serve(router, "PUT", "/__admin/scenarios", body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+
+	body := `{"name": "down", "path": "/api/v1/users", "fault": {"status": 503}, "latency": {"distribution": "fixed", "mean": "5ms"}}`
+	if w := serve(router, "POST", "/__admin/scenarios", body); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", w.Code, w.Body.String())
+	}
+	serve(router, "GET", "/api/v1/users", "")
+
+	w := serve(router, "GET", "/__admin/scenarios", "")
+	var list struct {
+		Scenarios []ScenarioStatus `json:"scenarios"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list.Scenarios) != 1 || list.Scenarios[0].Hits != 1 {
+		t.Fatalf("Expected 1 scenario with 1 hit, got %s", w.Body.String())
+	}
+	if !bytes.Contains(w.Body.Bytes(), []byte(`"mean":"5ms"`)) {
+		t.Errorf("Expected durations as strings, got %s", w.Body.String())
+	}
+
+	if w := serve(router, "DELETE", "/__admin/scenarios/down", ""); w.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", w.Code)
+	}
+	if w := serve(router, "DELETE", "/__admin/scenarios/down", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", w.Code)
+	}
+	if w := serve(router, "GET", "/api/v1/users", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected removed scenario to stop, got %d", w.Code)
+	}
+}
+
+func TestLoadScenarioFile(t *testing.T) {
+	cfg, err := LoadScenarioFile("scenarios.example.json")
+	if err != nil {
+		t.Fatalf("LoadScenarioFile failed: %v", err)
+	}
+	if err := NewScenarioEngine(1).Set(cfg.Scenarios); err != nil {
+		t.Errorf("Expected example scenarios to be valid, got %v", err)
+	}
+}
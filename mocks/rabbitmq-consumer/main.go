@@ -23,10 +23,10 @@ func main() {
 	}
 	defer ch.Close()
 
-	// キュー宣言
+	// キュー宣言（アプリ側の宣言と同じく durable）
 	q, err := ch.QueueDeclare(
 		"test_queue", // name
-		false,        // durable
+		true,         // durable
 		false,        // delete when unused
 		false,        // exclusive
 		false,        // no-wait
@@ -36,6 +36,22 @@ func main() {
 		log.Fatalf("Failed to declare a queue: %v", err)
 	}
 
+	// アルバムイベント用のキューを albums エクスチェンジにバインド
+	if err := ch.ExchangeDeclare("albums", "topic", true, false, false, false, nil); err != nil {
+		log.Fatalf("Failed to declare an exchange: %v", err)
+	}
+	events, err := ch.QueueDeclare("album_events_mock", true, false, false, false, nil)
+	if err != nil {
+		log.Fatalf("Failed to declare a queue: %v", err)
+	}
+	if err := ch.QueueBind(events.Name, "album.#", "albums", false, nil); err != nil {
+		log.Fatalf("Failed to bind a queue: %v", err)
+	}
+	eventMsgs, err := ch.Consume(events.Name, "", true, false, false, false, nil)
+	if err != nil {
+		log.Fatalf("Failed to register a consumer: %v", err)
+	}
+
 	msgs, err := ch.Consume(
 		q.Name, // queue
 		"",     // consumer
@@ -50,7 +66,7 @@ func main() {
 	}
 
 	log.Println("🚀 RabbitMQ Consumer Mock starting...")
-	log.Println("📨 Waiting for messages on queue: test_queue")
+	log.Println("📨 Waiting for messages on queue: test_queue, album_events_mock")
 
 	forever := make(chan bool)
 
@@ -62,5 +78,12 @@ func main() {
 		}
 	}()
 
+	go func() {
+		for d := range eventMsgs {
+			log.Printf("📬 Received %s event: %s", d.RoutingKey, d.Body)
+			log.Printf("   Message ID: %s", d.MessageId)
+		}
+	}()
+
 	<-forever
 }
@@ -1,24 +1,84 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"gorm.io/gorm"
 )
 
-// Album represents an album entity
+// Album represents an album entity.
+// Title and Artist are unique among albums that have not been soft-deleted,
+// so a deleted album can be re-created without purging it first.
+// ProductID links the album to a product SKU of the external ProductService.
 type Album struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	Title     string         `gorm:"size:255;not null" json:"title"`
-	Artist    string         `gorm:"size:255;not null" json:"artist"`
-	Price     float64        `gorm:"type:decimal(10,2);not null" json:"price"`
-	Tax       float32        `gorm:"type:decimal(4,2);not null;default:0.1" json:"tax"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID        uint           `gorm:"primarykey" json:"id" xml:"id"`
+	Title     string         `gorm:"size:255;not null;uniqueIndex:idx_albums_title_artist,where:deleted_at IS NULL" json:"title" xml:"title"`
+	Artist    string         `gorm:"size:255;not null;uniqueIndex:idx_albums_title_artist,where:deleted_at IS NULL" json:"artist" xml:"artist"`
+	Price     float64        `gorm:"type:decimal(10,2);not null" json:"price" xml:"price"`
+	Tax       float32        `gorm:"type:decimal(4,2);not null;default:0.1" json:"tax" xml:"tax"`
+	OwnerID   *uint          `gorm:"index" json:"owner_id,omitempty" xml:"owner_id,omitempty"`
+	ProductID *uint          `gorm:"index" json:"product_id,omitempty" xml:"product_id,omitempty"`
+	Version   uint           `gorm:"not null;default:1" json:"version" xml:"version"`
+	CreatedAt time.Time      `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at" xml:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-" xml:"-"`
 }
 
 // TableName specifies the table name for Album model
 func (Album) TableName() string {
 	return "albums"
 }
+
+// ETag returns the entity tag for the current version of the album
+func (a Album) ETag() string {
+	return fmt.Sprintf("\"%d\"", a.Version)
+}
+
+// ValidationError lists the problems found by Album.Validate
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return strings.Join(e, "; ")
+}
+
+// Validate checks the client-supplied fields of an album and returns a
+// ValidationError describing every invalid field
+func (a Album) Validate() error {
+	var errs ValidationError
+
+	if strings.TrimSpace(a.Title) == "" {
+		errs = append(errs, "title is required")
+	} else if utf8.RuneCountInString(a.Title) > 255 {
+		errs = append(errs, "title must be at most 255 characters")
+	}
+
+	if strings.TrimSpace(a.Artist) == "" {
+		errs = append(errs, "artist is required")
+	} else if utf8.RuneCountInString(a.Artist) > 255 {
+		errs = append(errs, "artist must be at most 255 characters")
+	}
+
+	if a.Price < 0 || a.Price >= 1e8 {
+		errs = append(errs, "price must be between 0 and 99999999.99")
+	}
+
+	if a.Tax < 0 || a.Tax >= 1 {
+		errs = append(errs, "tax must be a rate between 0 and 1")
+	}
+
+	if a.OwnerID != nil && *a.OwnerID == 0 {
+		errs = append(errs, "owner_id must be a user ID")
+	}
+
+	if a.ProductID != nil && *a.ProductID == 0 {
+		errs = append(errs, "product_id must be a product SKU")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
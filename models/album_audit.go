@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Audit actions recorded for album writes
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionPurge   = "purge"
+)
+
+// ErrAuditImmutable is returned when an audit entry is modified or deleted
+var ErrAuditImmutable = errors.New("album audit entries are append-only")
+
+// FieldChange holds the value of a single field before and after a write
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// AlbumAudit is an append-only record of a single album write
+type AlbumAudit struct {
+	ID      uint   `gorm:"primarykey" json:"id"`
+	AlbumID uint   `gorm:"not null;index" json:"album_id"`
+	Action  string `gorm:"size:16;not null" json:"action"`
+	Actor   string `gorm:"size:255;not null" json:"actor"`
+	// ActorVerified is false when Actor was supplied by the client (the
+	// X-Actor header or x-actor metadata) and not authenticated
+	ActorVerified bool                   `gorm:"not null;default:false" json:"actor_verified"`
+	RequestID     string                 `gorm:"size:64" json:"request_id,omitempty"`
+	Source        string                 `gorm:"size:16;not null" json:"source"`
+	Changes       map[string]FieldChange `gorm:"type:text;serializer:json" json:"changes"`
+	Snapshot      *Album                 `gorm:"type:text;serializer:json" json:"snapshot,omitempty"`
+	CreatedAt     time.Time              `gorm:"index" json:"created_at"`
+}
+
+// TableName specifies the table name for AlbumAudit model
+func (AlbumAudit) TableName() string {
+	return "album_audits"
+}
+
+// BeforeUpdate rejects updates to audit entries
+func (AlbumAudit) BeforeUpdate(*gorm.DB) error {
+	return ErrAuditImmutable
+}
+
+// BeforeDelete rejects deletes of audit entries
+func (AlbumAudit) BeforeDelete(*gorm.DB) error {
+	return ErrAuditImmutable
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// albumTitleArtistIndex is the unique index on the title and artist of live
+// albums
+const albumTitleArtistIndex = "idx_albums_title_artist"
+
+// PrepareAlbumIndexes makes an existing albums table ready for the unique
+// title and artist index, which AutoMigrate cannot create while live albums
+// share a title and artist. Without resolve the duplicates are reported as
+// an error; with resolve every duplicate except the oldest is soft-deleted,
+// so it can be renamed or purged from the trash. It does nothing on a new
+// database or once the index exists.
+func PrepareAlbumIndexes(db *gorm.DB, resolve bool) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&Album{}) || migrator.HasIndex(&Album{}, albumTitleArtistIndex) {
+		return nil
+	}
+
+	var groups []struct {
+		Title  string
+		Artist string
+		Count  int64
+	}
+	if err := db.Model(&Album{}).
+		Select("title, artist, COUNT(*) AS count").
+		Group("title, artist").
+		Having("COUNT(*) > 1").
+		Scan(&groups).Error; err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+
+	if !resolve {
+		duplicates := make([]string, len(groups))
+		for i, group := range groups {
+			duplicates[i] = fmt.Sprintf("%q by %q (%d albums)", group.Title, group.Artist, group.Count)
+		}
+		return fmt.Errorf("cannot create unique index %s, duplicate albums: %s", albumTitleArtistIndex, strings.Join(duplicates, ", "))
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, group := range groups {
+			var ids []uint
+			if err := tx.Model(&Album{}).
+				Where("title = ? AND artist = ?", group.Title, group.Artist).
+				Order("id").
+				Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&Album{}, ids[1:]).Error; err != nil {
+				return err
+			}
+			log.Printf("🗑️  Moved duplicate albums %v (%q by %q) to the trash, keeping %d", ids[1:], group.Title, group.Artist, ids[0])
+		}
+		return nil
+	})
+}
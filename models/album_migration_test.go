@@ -0,0 +1,91 @@
+package models
+
+import (
+	"golang-gin/database"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// legacyAlbum is the albums table before the unique title and artist index
+type legacyAlbum struct {
+	ID        uint `gorm:"primarykey"`
+	Title     string
+	Artist    string
+	Price     float64
+	Tax       float32
+	DeletedAt gorm.DeletedAt
+}
+
+func (legacyAlbum) TableName() string {
+	return "albums"
+}
+
+func setupLegacyDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := database.Connect(&database.Config{Driver: database.DriverSQLiteMemory})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	if err := db.AutoMigrate(&legacyAlbum{}); err != nil {
+		t.Fatalf("Failed to create legacy table: %v", err)
+	}
+	albums := []legacyAlbum{
+		{Title: "Hammerhead", Artist: "THE OFFSPRING"},
+		{Title: "Hammerhead", Artist: "THE OFFSPRING"},
+		{Title: "Shake It Off", Artist: "Taylor Swift"},
+		{Title: "Hammerhead", Artist: "THE OFFSPRING"},
+	}
+	if err := db.Create(&albums).Error; err != nil {
+		t.Fatalf("Failed to insert albums: %v", err)
+	}
+	return db
+}
+
+func TestPrepareAlbumIndexes(t *testing.T) {
+	t.Run("Report duplicates", func(t *testing.T) {
+		db := setupLegacyDB(t)
+
+		err := PrepareAlbumIndexes(db, false)
+		if err == nil || !strings.Contains(err.Error(), `"Hammerhead" by "THE OFFSPRING" (3 albums)`) {
+			t.Errorf("Expected duplicates to be reported, got %v", err)
+		}
+	})
+
+	t.Run("Resolve duplicates", func(t *testing.T) {
+		db := setupLegacyDB(t)
+
+		if err := PrepareAlbumIndexes(db, true); err != nil {
+			t.Fatalf("PrepareAlbumIndexes failed: %v", err)
+		}
+		var live []uint
+		db.Model(&Album{}).Order("id").Pluck("id", &live)
+		if len(live) != 2 || live[0] != 1 || live[1] != 3 {
+			t.Errorf("Expected albums [1 3] to stay live, got %v", live)
+		}
+
+		if err := db.AutoMigrate(&Album{}); err != nil {
+			t.Fatalf("AutoMigrate failed after resolving duplicates: %v", err)
+		}
+		if err := PrepareAlbumIndexes(db, false); err != nil {
+			t.Errorf("Expected no-op once the index exists, got %v", err)
+		}
+	})
+
+	t.Run("New database", func(t *testing.T) {
+		db, err := database.Connect(&database.Config{Driver: database.DriverSQLiteMemory})
+		if err != nil {
+			t.Fatalf("Failed to connect to database: %v", err)
+		}
+		if err := PrepareAlbumIndexes(db, false); err != nil {
+			t.Errorf("Expected no-op without albums table, got %v", err)
+		}
+	})
+}
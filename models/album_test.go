@@ -111,3 +111,31 @@ func TestAlbum_JSONTags(t *testing.T) {
 		t.Errorf("Expected Tax 0.08, got %f", album.Tax)
 	}
 }
+
+func TestAlbum_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		album   Album
+		wantErr bool
+	}{
+		{"Valid", Album{Title: "Title", Artist: "Artist", Price: 19.99, Tax: 0.1}, false},
+		{"Missing title", Album{Artist: "Artist", Price: 19.99, Tax: 0.1}, true},
+		{"Blank artist", Album{Title: "Title", Artist: "  ", Price: 19.99, Tax: 0.1}, true},
+		{"Negative price", Album{Title: "Title", Artist: "Artist", Price: -1, Tax: 0.1}, true},
+		{"Tax out of range", Album{Title: "Title", Artist: "Artist", Price: 1, Tax: 1.5}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.album.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	err := Album{Price: -1}.Validate()
+	if verr, ok := err.(ValidationError); !ok || len(verr) != 3 {
+		t.Errorf("Expected 3 validation errors, got %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// Email delivery statuses
+const (
+	// EmailStatusPending emails are waiting for their first or next attempt
+	EmailStatusPending = "pending"
+	// EmailStatusSent emails were accepted by the SMTP server
+	EmailStatusSent = "sent"
+	// EmailStatusFailed emails failed permanently or ran out of attempts
+	EmailStatusFailed = "failed"
+)
+
+// EmailMessage is an email queued for delivery by the email worker. The
+// rendered message is stored so that retries send exactly the same content.
+type EmailMessage struct {
+	ID uint `gorm:"primarykey" json:"id"`
+	// DedupKey makes enqueueing idempotent: a second email with the same key
+	// is not queued. Emails without a key are never deduplicated.
+	DedupKey *string  `gorm:"size:255;uniqueIndex" json:"dedup_key,omitempty"`
+	Template string   `gorm:"size:100" json:"template,omitempty"`
+	Locale   string   `gorm:"size:20" json:"locale,omitempty"`
+	From     string   `gorm:"column:from_address;size:320" json:"from,omitempty"`
+	To       []string `gorm:"column:to_addresses;type:text;serializer:json" json:"to"`
+	Cc       []string `gorm:"column:cc_addresses;type:text;serializer:json" json:"cc,omitempty"`
+	Bcc      []string `gorm:"column:bcc_addresses;type:text;serializer:json" json:"bcc,omitempty"`
+	Subject  string   `gorm:"size:998;not null" json:"subject"`
+	Text     string   `gorm:"type:text" json:"text,omitempty"`
+	HTML     string   `gorm:"type:text" json:"html,omitempty"`
+	// MessageID is generated when the email is queued so that retries send
+	// the same Message-ID header
+	MessageID     string     `gorm:"size:255" json:"message_id,omitempty"`
+	Status        string     `gorm:"size:20;not null;index" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"not null;index" json:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for EmailMessage model
+func (EmailMessage) TableName() string {
+	return "email_messages"
+}
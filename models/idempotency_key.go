@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyKey stores the outcome of a request made with an idempotency key
+// so that retries can be answered with the original response
+type IdempotencyKey struct {
+	Scope       string `gorm:"primaryKey;size:128"`
+	Key         string `gorm:"column:idempotency_key;primaryKey;size:255"`
+	Fingerprint string `gorm:"size:64;not null"`
+	// Owner is a random token identifying the request holding the claim
+	Owner       string `gorm:"size:32;not null;default:''"`
+	Completed   bool   `gorm:"not null;default:false"`
+	StatusCode  int    `gorm:"not null;default:0"`
+	ContentType string `gorm:"size:128"`
+	ETag        string `gorm:"column:etag;size:128"`
+	Vary        string `gorm:"size:255"`
+	// Body holds the raw response bytes, which are not necessarily text
+	// (e.g. msgpack or protobuf)
+	Body      []byte
+	CreatedAt time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+// TableName specifies the table name for IdempotencyKey model
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
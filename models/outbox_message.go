@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// OutboxMessage is an event waiting to be published to the message broker.
+// Messages are written in the same transaction as the change they describe
+// and marked published once the broker has confirmed them.
+type OutboxMessage struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	EventID       string     `gorm:"size:64;not null;uniqueIndex" json:"event_id"`
+	RoutingKey    string     `gorm:"size:255;not null" json:"routing_key"`
+	Payload       string     `gorm:"type:text;not null" json:"payload"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"not null;index" json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	PublishedAt   *time.Time `gorm:"index" json:"published_at,omitempty"`
+}
+
+// TableName specifies the table name for OutboxMessage model
+func (OutboxMessage) TableName() string {
+	return "outbox_messages"
+}
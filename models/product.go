@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Product is the local copy of a ProductService product that albums are
+// linked to. It is written when a link is validated and refreshed by the
+// product sync job, so album reads never call the ProductService.
+type Product struct {
+	ID          uint    `gorm:"primarykey;autoIncrement:false" json:"id"`
+	Name        string  `gorm:"size:255;not null" json:"name"`
+	Description string  `gorm:"type:text" json:"description"`
+	Price       float64 `gorm:"type:decimal(10,2);not null" json:"price"`
+	Stock       int32   `gorm:"not null" json:"stock"`
+	// Discontinued is set when the ProductService no longer knows the product
+	Discontinued bool      `gorm:"not null;default:false" json:"discontinued"`
+	SyncedAt     time.Time `gorm:"index" json:"synced_at"`
+}
+
+// TableName specifies the table name for Product model
+func (Product) TableName() string {
+	return "products"
+}
@@ -0,0 +1,192 @@
+package products
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	retrybackoff "golang-gin/backoff"
+	pb "golang-gin/products/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithCallTimeout sets the deadline of a single attempt (default: 2s). The
+// context passed to a call bounds all attempts together.
+func WithCallTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.callTimeout = d
+	}
+}
+
+// WithRetries sets how often a call is attempted (default: 3) and the delay
+// before the first retry, which doubles on every further retry (default:
+// 100ms). Only transient failures (Unavailable, DeadlineExceeded,
+// ResourceExhausted, Aborted) are retried; all ProductService calls are
+// reads.
+func WithRetries(maxAttempts int, backoff time.Duration) ClientOption {
+	return func(c *Client) {
+		c.maxAttempts = max(maxAttempts, 1)
+		c.backoff = backoff
+	}
+}
+
+// WithDialOptions adds options to the connection, e.g. a custom dialer in
+// tests
+func WithDialOptions(opts ...grpc.DialOption) ClientOption {
+	return func(c *Client) {
+		c.dialOpts = append(c.dialOpts, opts...)
+	}
+}
+
+// Client implements ProductService over a single gRPC connection. The
+// connection is established on the first call and re-established with
+// backoff whenever it breaks, so the client can be created before the
+// ProductService is up. It is safe for concurrent use.
+type Client struct {
+	conn        *grpc.ClientConn
+	client      pb.ProductServiceClient
+	callTimeout time.Duration
+	maxAttempts int
+	backoff     time.Duration
+	dialOpts    []grpc.DialOption
+}
+
+// NewClient creates a client of the ProductService at target, e.g.
+// "localhost:17003"
+func NewClient(target string, opts ...ClientOption) (*Client, error) {
+	c := &Client{
+		callTimeout: 2 * time.Second,
+		maxAttempts: 3,
+		backoff:     100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.Config{BaseDelay: time.Second, Multiplier: 1.6, Jitter: 0.2, MaxDelay: 30 * time.Second},
+			MinConnectTimeout: 5 * time.Second,
+		}),
+	}, c.dialOpts...)
+	conn, err := grpc.NewClient(target, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create product service client: %w", err)
+	}
+
+	c.conn = conn
+	c.client = pb.NewProductServiceClient(conn)
+	return c, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// GetProduct fetches a product by ID
+func (c *Client) GetProduct(ctx context.Context, id uint) (*Product, error) {
+	if id == 0 || id > math.MaxInt32 {
+		return nil, ErrProductNotFound
+	}
+
+	var resp *pb.Product
+	err := c.call(ctx, func(ctx context.Context) (err error) {
+		resp, err = c.client.GetProductByID(ctx, &pb.GetProductByIDRequest{Id: int32(id)})
+		return err
+	})
+	if err != nil {
+		return nil, classify(ctx, "GetProductByID", err)
+	}
+	product := fromProto(resp)
+	return &product, nil
+}
+
+// ListProducts fetches all products
+func (c *Client) ListProducts(ctx context.Context) ([]Product, error) {
+	var resp *pb.GetProductsResponse
+	err := c.call(ctx, func(ctx context.Context) (err error) {
+		resp, err = c.client.GetProducts(ctx, &pb.GetProductsRequest{})
+		return err
+	})
+	if err != nil {
+		return nil, classify(ctx, "GetProducts", err)
+	}
+
+	list := make([]Product, len(resp.Products))
+	for i, p := range resp.Products {
+		list[i] = fromProto(p)
+	}
+	return list, nil
+}
+
+// call runs fn with a per-attempt deadline, retrying transient failures
+func (c *Client) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, c.callTimeout)
+		err := fn(attemptCtx)
+		cancel()
+		if err == nil || ctx.Err() != nil || attempt >= c.maxAttempts || !retryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(c.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// maxRetryBackoff caps the delay between two attempts
+const maxRetryBackoff = 2 * time.Second
+
+// delay returns the jittered delay before the given retry, doubling from
+// the configured backoff up to maxRetryBackoff
+func (c *Client) delay(retry int) time.Duration {
+	return retrybackoff.Jitter(retrybackoff.Exponential(retry, c.backoff, maxRetryBackoff))
+}
+
+// retryable reports whether a failed call may succeed when retried
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// classify maps a call error to ErrProductNotFound or ErrUnavailable. Errors
+// caused by the caller's context are returned unchanged.
+func classify(ctx context.Context, method string, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if status.Code(err) == codes.NotFound {
+		return ErrProductNotFound
+	}
+	return fmt.Errorf("%w: %s: %v", ErrUnavailable, method, err)
+}
+
+// fromProto converts a ProductService product
+func fromProto(p *pb.Product) Product {
+	return Product{
+		ID:          uint(p.GetId()),
+		Name:        p.GetName(),
+		Description: p.GetDescription(),
+		Price:       p.GetPrice(),
+		Stock:       p.GetStock(),
+	}
+}
@@ -0,0 +1,158 @@
+package products
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	pb "golang-gin/products/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeProductServer serves Laptop (ID 1). The first failures calls fail
+// with the given code and every call waits for delay.
+type fakeProductServer struct {
+	pb.UnimplementedProductServiceServer
+	mu       sync.Mutex
+	failures int
+	code     codes.Code
+	delay    time.Duration
+	calls    int
+}
+
+func (s *fakeProductServer) fail() error {
+	s.mu.Lock()
+	s.calls++
+	delay := s.delay
+	var err error
+	if s.failures > 0 {
+		s.failures--
+		err = status.Error(s.code, "injected failure")
+	}
+	s.mu.Unlock()
+
+	time.Sleep(delay)
+	return err
+}
+
+func (s *fakeProductServer) GetProducts(ctx context.Context, req *pb.GetProductsRequest) (*pb.GetProductsResponse, error) {
+	if err := s.fail(); err != nil {
+		return nil, err
+	}
+	return &pb.GetProductsResponse{Products: []*pb.Product{{Id: 1, Name: "Laptop", Price: 1299.99, Stock: 50}}}, nil
+}
+
+func (s *fakeProductServer) GetProductByID(ctx context.Context, req *pb.GetProductByIDRequest) (*pb.Product, error) {
+	if err := s.fail(); err != nil {
+		return nil, err
+	}
+	if req.Id != 1 {
+		return nil, status.Errorf(codes.NotFound, "product %d not found", req.Id)
+	}
+	return &pb.Product{Id: 1, Name: "Laptop", Price: 1299.99, Stock: 50}, nil
+}
+
+// newTestClient serves server over an in-memory listener and returns a
+// client with fast retries
+func newTestClient(t *testing.T, server *fakeProductServer, opts ...ClientOption) *Client {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	pb.RegisterProductServiceServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	opts = append([]ClientOption{
+		WithRetries(3, time.Millisecond),
+		WithDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		})),
+	}, opts...)
+	client, err := NewClient("passthrough:///bufnet", opts...)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestClient_GetProduct(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        uint
+		failures  int
+		code      codes.Code
+		wantErr   error
+		wantCalls int
+	}{
+		{"Found", 1, 0, codes.OK, nil, 1},
+		{"Not found", 2, 0, codes.OK, ErrProductNotFound, 1},
+		{"Out of range", 1 << 40, 0, codes.OK, ErrProductNotFound, 0},
+		{"Retried", 1, 2, codes.Unavailable, nil, 3},
+		{"Retries exhausted", 1, 3, codes.Unavailable, ErrUnavailable, 3},
+		{"Not retried", 1, 1, codes.Internal, ErrUnavailable, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &fakeProductServer{failures: tt.failures, code: tt.code}
+			client := newTestClient(t, server)
+
+			product, err := client.GetProduct(context.Background(), tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && (product.Name != "Laptop" || product.Stock != 50) {
+				t.Errorf("Expected Laptop, got %+v", product)
+			}
+			if server.calls != tt.wantCalls {
+				t.Errorf("Expected %d calls, got %d", tt.wantCalls, server.calls)
+			}
+		})
+	}
+}
+
+func TestClient_Deadlines(t *testing.T) {
+	server := &fakeProductServer{delay: 50 * time.Millisecond}
+	client := newTestClient(t, server, WithCallTimeout(10*time.Millisecond), WithRetries(2, time.Millisecond))
+
+	if _, err := client.GetProduct(context.Background(), 1); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable, got %v", err)
+	}
+	server.mu.Lock()
+	if server.calls != 2 {
+		t.Errorf("Expected timed out call to be retried once, got %d calls", server.calls)
+	}
+	server.delay = 0
+	server.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.ListProducts(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	list, err := client.ListProducts(context.Background())
+	if err != nil || len(list) != 1 || list[0].ID != 1 {
+		t.Errorf("Expected Laptop in list, got %+v (%v)", list, err)
+	}
+}
+
+func TestClient_Unreachable(t *testing.T) {
+	client, err := NewClient("127.0.0.1:1", WithCallTimeout(50*time.Millisecond), WithRetries(1, 0))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.GetProduct(context.Background(), 1); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable for unreachable service, got %v", err)
+	}
+}
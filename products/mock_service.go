@@ -0,0 +1,93 @@
+package products
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// MockService is a mock implementation of ProductService for testing
+type MockService struct {
+	mu          sync.Mutex
+	products    map[uint]Product
+	unavailable bool
+	calls       atomic.Int64
+}
+
+// NewMockService creates a MockService knowing the given products
+func NewMockService(products ...Product) *MockService {
+	m := &MockService{products: map[uint]Product{}}
+	for _, product := range products {
+		m.products[product.ID] = product
+	}
+	return m
+}
+
+// SetProduct adds or replaces a product
+func (m *MockService) SetProduct(product Product) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.products[product.ID] = product
+}
+
+// DeleteProduct removes a product
+func (m *MockService) DeleteProduct(id uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.products, id)
+}
+
+// SetUnavailable makes every call fail with ErrUnavailable until reset
+func (m *MockService) SetUnavailable(unavailable bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unavailable = unavailable
+}
+
+// Calls returns the number of calls made so far
+func (m *MockService) Calls() int {
+	return int(m.calls.Load())
+}
+
+// GetProduct returns the product with the given ID
+func (m *MockService) GetProduct(ctx context.Context, id uint) (*Product, error) {
+	m.calls.Add(1)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.unavailable {
+		return nil, fmt.Errorf("%w: mock is down", ErrUnavailable)
+	}
+	product, ok := m.products[id]
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	return &product, nil
+}
+
+// ListProducts returns all products ordered by ID
+func (m *MockService) ListProducts(ctx context.Context) ([]Product, error) {
+	m.calls.Add(1)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.unavailable {
+		return nil, fmt.Errorf("%w: mock is down", ErrUnavailable)
+	}
+	list := make([]Product, 0, len(m.products))
+	for _, product := range m.products {
+		list = append(list, product)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
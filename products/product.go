@@ -0,0 +1,50 @@
+// Package products is a client of the external ProductService gRPC API
+// served by mocks/grpc-mock
+package products
+
+import (
+	"context"
+	"errors"
+	"golang-gin/models"
+	"time"
+)
+
+var (
+	// ErrProductNotFound is returned when the ProductService does not know a
+	// product
+	ErrProductNotFound = errors.New("product not found")
+	// ErrUnavailable is returned when the ProductService cannot be reached or
+	// fails after all retries
+	ErrUnavailable = errors.New("product service unavailable")
+)
+
+// Product is a product of the ProductService. Its ID is the SKU albums are
+// linked to.
+type Product struct {
+	ID          uint    `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	Stock       int32   `json:"stock"`
+}
+
+// Model converts the product into its local copy synced at the given time
+func (p *Product) Model(syncedAt time.Time) *models.Product {
+	return &models.Product{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		Stock:       p.Stock,
+		SyncedAt:    syncedAt,
+	}
+}
+
+// ProductService looks up products
+type ProductService interface {
+	// GetProduct returns the product with the given ID, ErrProductNotFound or
+	// an error wrapping ErrUnavailable
+	GetProduct(ctx context.Context, id uint) (*Product, error)
+	// ListProducts returns all products
+	ListProducts(ctx context.Context) ([]Product, error)
+}
@@ -1,59 +1,374 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"golang-gin/models"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// ErrVersionConflict is returned when an album was modified concurrently
+var ErrVersionConflict = errors.New("album version conflict")
+
+// streamBatchSize is the number of albums Stream loads per query
+const streamBatchSize = 500
+
 // AlbumRepository defines the interface for album data access
 type AlbumRepository interface {
-	FindAll() ([]models.Album, error)
-	FindByID(id uint) (*models.Album, error)
-	Create(album *models.Album) error
-	Update(album *models.Album) error
-	Delete(id uint) error
+	FindAll(ctx context.Context, opts ...FindOption) ([]models.Album, error)
+	FindByID(ctx context.Context, id uint, opts ...FindOption) (*models.Album, error)
+	Stream(ctx context.Context, fn func(album *models.Album) error) error
+	LastModified(ctx context.Context) (time.Time, error)
+	Create(ctx context.Context, album *models.Album) error
+	Update(ctx context.Context, album *models.Album) error
+	Delete(ctx context.Context, id uint, opts ...DeleteOption) error
+
+	FindDeleted(ctx context.Context) ([]models.Album, error)
+	Restore(ctx context.Context, id uint) (*models.Album, error)
+	Purge(ctx context.Context, id uint) error
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// AlbumColumns lists the album columns that can be selected with WithFields
+var AlbumColumns = []string{"id", "title", "artist", "price", "tax", "owner_id", "product_id", "version", "created_at", "updated_at"}
+
+// FindOption customizes album queries
+type FindOption func(*findOptions)
+
+// findOptions holds the settings applied by FindOption
+type findOptions struct {
+	fields []string
+}
+
+// WithFields loads only the given columns. The remaining fields of the
+// returned albums keep their zero values. Unknown columns are ignored.
+func WithFields(fields ...string) FindOption {
+	return func(o *findOptions) {
+		for _, field := range fields {
+			if isAlbumColumn(field) {
+				o.fields = append(o.fields, field)
+			}
+		}
+	}
+}
+
+// isAlbumColumn reports whether name is one of AlbumColumns
+func isAlbumColumn(name string) bool {
+	for _, column := range AlbumColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyFindOptions returns the query scoped by opts
+func applyFindOptions(db *gorm.DB, opts []FindOption) *gorm.DB {
+	var o findOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(o.fields) > 0 {
+		db = db.Select(o.fields)
+	}
+	return db
+}
+
+// DeleteOption customizes album deletes
+type DeleteOption func(*deleteOptions)
+
+// deleteOptions holds the settings applied by DeleteOption
+type deleteOptions struct {
+	version uint
+}
+
+// IfVersion deletes the album only if its stored version still matches;
+// otherwise Delete returns ErrVersionConflict
+func IfVersion(version uint) DeleteOption {
+	return func(o *deleteOptions) {
+		o.version = version
+	}
+}
+
+// newDeleteOptions applies opts
+func newDeleteOptions(opts []DeleteOption) deleteOptions {
+	var o deleteOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// AlbumChange describes a single album write
+type AlbumChange struct {
+	Action string        // one of the models.AuditAction* constants
+	Before *models.Album // nil on create
+	After  *models.Album // nil on delete and purge
 }
 
+// AlbumHook is called after every album write, inside the same transaction
+// as the write. Returning an error rolls the write back.
+type AlbumHook func(ctx context.Context, tx *gorm.DB, change AlbumChange) error
+
 // albumRepository implements AlbumRepository
 type albumRepository struct {
-	db *gorm.DB
+	db    *gorm.DB
+	hooks []AlbumHook
 }
 
 // NewAlbumRepository creates a new AlbumRepository instance
-func NewAlbumRepository(db *gorm.DB) AlbumRepository {
-	return &albumRepository{db: db}
+func NewAlbumRepository(db *gorm.DB, hooks ...AlbumHook) AlbumRepository {
+	return &albumRepository{db: db, hooks: hooks}
 }
 
 // FindAll retrieves all albums
-func (r *albumRepository) FindAll() ([]models.Album, error) {
+func (r *albumRepository) FindAll(ctx context.Context, opts ...FindOption) ([]models.Album, error) {
 	var albums []models.Album
-	if err := r.db.Find(&albums).Error; err != nil {
+	if err := applyFindOptions(r.db.WithContext(ctx), opts).Find(&albums).Error; err != nil {
 		return nil, err
 	}
 	return albums, nil
 }
 
 // FindByID retrieves an album by ID
-func (r *albumRepository) FindByID(id uint) (*models.Album, error) {
+func (r *albumRepository) FindByID(ctx context.Context, id uint, opts ...FindOption) (*models.Album, error) {
 	var album models.Album
-	if err := r.db.First(&album, id).Error; err != nil {
+	if err := applyFindOptions(r.db.WithContext(ctx), opts).First(&album, id).Error; err != nil {
 		return nil, err
 	}
 	return &album, nil
 }
 
+// Stream calls fn for every album in ID order, loading them in batches so
+// that the whole table is never held in memory. Returning an error from fn
+// stops the stream.
+func (r *albumRepository) Stream(ctx context.Context, fn func(album *models.Album) error) error {
+	var batch []models.Album
+	return r.db.WithContext(ctx).FindInBatches(&batch, streamBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+// LastModified returns the time of the most recent change to the album list,
+// including soft deletes, or the zero time if there are no albums
+func (r *albumRepository) LastModified(ctx context.Context) (time.Time, error) {
+	// Each query needs its own session, otherwise the conditions of the first
+	// one carry over to the second
+	db := func() *gorm.DB { return r.db.WithContext(ctx).Unscoped() }
+
+	var updated, deleted []models.Album
+	if err := db().Select("updated_at").Order("updated_at DESC").Limit(1).Find(&updated).Error; err != nil {
+		return time.Time{}, err
+	}
+	if err := db().Select("deleted_at").Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Limit(1).Find(&deleted).Error; err != nil {
+		return time.Time{}, err
+	}
+
+	var last time.Time
+	if len(updated) > 0 {
+		last = updated[0].UpdatedAt
+	}
+	if len(deleted) > 0 && deleted[0].DeletedAt.Time.After(last) {
+		last = deleted[0].DeletedAt.Time
+	}
+	return last, nil
+}
+
 // Create creates a new album
-func (r *albumRepository) Create(album *models.Album) error {
-	return r.db.Create(album).Error
+func (r *albumRepository) Create(ctx context.Context, album *models.Album) error {
+	return r.write(ctx, func(tx *gorm.DB) ([]AlbumChange, error) {
+		album.Version = 1
+		if err := tx.Create(album).Error; err != nil {
+			return nil, err
+		}
+
+		after := *album
+		return []AlbumChange{{Action: models.AuditActionCreate, After: &after}}, nil
+	})
 }
 
-// Update updates an existing album
-func (r *albumRepository) Update(album *models.Album) error {
-	return r.db.Save(album).Error
+// Update updates an existing album if album.Version still matches the stored
+// version, and returns ErrVersionConflict otherwise
+func (r *albumRepository) Update(ctx context.Context, album *models.Album) error {
+	return r.write(ctx, func(tx *gorm.DB) ([]AlbumChange, error) {
+		var before models.Album
+		if err := tx.First(&before, album.ID).Error; err != nil {
+			return nil, err
+		}
+
+		result := tx.Model(&models.Album{}).
+			Where("id = ? AND version = ?", album.ID, album.Version).
+			Updates(map[string]interface{}{
+				"title":      album.Title,
+				"artist":     album.Artist,
+				"price":      album.Price,
+				"tax":        album.Tax,
+				"owner_id":   album.OwnerID,
+				"product_id": album.ProductID,
+				"version":    gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, ErrVersionConflict
+		}
+
+		if err := tx.First(album, album.ID).Error; err != nil {
+			return nil, err
+		}
+
+		after := *album
+		return []AlbumChange{{Action: models.AuditActionUpdate, Before: &before, After: &after}}, nil
+	})
 }
 
 // Delete soft deletes an album by ID
-func (r *albumRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Album{}, id).Error
+func (r *albumRepository) Delete(ctx context.Context, id uint, opts ...DeleteOption) error {
+	o := newDeleteOptions(opts)
+	return r.write(ctx, func(tx *gorm.DB) ([]AlbumChange, error) {
+		var before models.Album
+		if err := tx.First(&before, id).Error; err != nil {
+			return nil, err
+		}
+
+		query := tx.Where("id = ?", id)
+		if o.version != 0 {
+			query = query.Where("version = ?", o.version)
+		}
+		result := query.Delete(&models.Album{})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, ErrVersionConflict
+		}
+
+		return []AlbumChange{{Action: models.AuditActionDelete, Before: &before}}, nil
+	})
+}
+
+// FindDeleted retrieves all soft-deleted albums, most recently deleted first
+func (r *albumRepository) FindDeleted(ctx context.Context) ([]models.Album, error) {
+	var albums []models.Album
+	if err := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Find(&albums).Error; err != nil {
+		return nil, err
+	}
+	return albums, nil
+}
+
+// Restore undeletes a soft-deleted album. It returns gorm.ErrDuplicatedKey if
+// a live album with the same title and artist has been created since.
+func (r *albumRepository) Restore(ctx context.Context, id uint) (*models.Album, error) {
+	var restored models.Album
+	err := r.write(ctx, func(tx *gorm.DB) ([]AlbumChange, error) {
+		var before models.Album
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&before, id).Error; err != nil {
+			return nil, err
+		}
+
+		if err := tx.Unscoped().Model(&models.Album{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"deleted_at": nil,
+				"version":    gorm.Expr("version + 1"),
+			}).Error; err != nil {
+			return nil, err
+		}
+
+		if err := tx.First(&restored, id).Error; err != nil {
+			return nil, err
+		}
+
+		after := restored
+		return []AlbumChange{{Action: models.AuditActionRestore, Before: &before, After: &after}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &restored, nil
+}
+
+// Purge permanently deletes a soft-deleted album
+func (r *albumRepository) Purge(ctx context.Context, id uint) error {
+	return r.write(ctx, func(tx *gorm.DB) ([]AlbumChange, error) {
+		var before models.Album
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&before, id).Error; err != nil {
+			return nil, err
+		}
+
+		if err := tx.Unscoped().Delete(&models.Album{}, id).Error; err != nil {
+			return nil, err
+		}
+
+		return []AlbumChange{{Action: models.AuditActionPurge, Before: &before}}, nil
+	})
+}
+
+// PurgeDeletedBefore permanently deletes albums soft-deleted before cutoff
+// and returns the number of purged albums
+func (r *albumRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	var purged int64
+	err := r.write(ctx, func(tx *gorm.DB) ([]AlbumChange, error) {
+		var expired []models.Album
+		if err := tx.Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Find(&expired).Error; err != nil {
+			return nil, err
+		}
+		if len(expired) == 0 {
+			return nil, nil
+		}
+
+		ids := make([]uint, 0, len(expired))
+		changes := make([]AlbumChange, 0, len(expired))
+		for i := range expired {
+			ids = append(ids, expired[i].ID)
+			changes = append(changes, AlbumChange{Action: models.AuditActionPurge, Before: &expired[i]})
+		}
+
+		result := tx.Unscoped().Delete(&models.Album{}, ids)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		purged = result.RowsAffected
+		return changes, nil
+	})
+	return purged, err
+}
+
+// write runs fn and the registered hooks for the changes it reports in a
+// single transaction. Without hooks fn runs directly on the connection.
+func (r *albumRepository) write(ctx context.Context, fn func(tx *gorm.DB) ([]AlbumChange, error)) error {
+	db := r.db.WithContext(ctx)
+	if len(r.hooks) == 0 {
+		_, err := fn(db)
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		changes, err := fn(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range changes {
+			for _, hook := range r.hooks {
+				if err := hook(ctx, tx, change); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
 }
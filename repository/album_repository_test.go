@@ -0,0 +1,327 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"golang-gin/database"
+	"golang-gin/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := database.Connect(&database.Config{Driver: database.DriverSQLiteMemory})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	if err := database.Migrate(db, &models.Album{}, &models.AlbumAudit{}, &models.OutboxMessage{}, &models.EmailMessage{}, &models.Product{}); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	if err := database.Seed(db, models.SeedAlbums); err != nil {
+		t.Fatalf("Failed to seed database: %v", err)
+	}
+
+	return db
+}
+
+func TestAlbumRepository_FindAll(t *testing.T) {
+	repo := NewAlbumRepository(setupTestDB(t))
+	ctx := context.Background()
+
+	albums, err := repo.FindAll(ctx)
+	if err != nil {
+		t.Fatalf("FindAll failed: %v", err)
+	}
+
+	if len(albums) != 3 {
+		t.Errorf("Expected 3 seeded albums, got %d", len(albums))
+	}
+}
+
+func TestAlbumRepository_FindByID(t *testing.T) {
+	repo := NewAlbumRepository(setupTestDB(t))
+	ctx := context.Background()
+
+	album, err := repo.FindByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	if album.Title != "Hammerhead" {
+		t.Errorf("Expected title 'Hammerhead', got '%s'", album.Title)
+	}
+	if album.Price != 25.05 {
+		t.Errorf("Expected price 25.05, got %f", album.Price)
+	}
+
+	if _, err := repo.FindByID(ctx, 999); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected ErrRecordNotFound, got %v", err)
+	}
+}
+
+func TestAlbumRepository_WithFields(t *testing.T) {
+	repo := NewAlbumRepository(setupTestDB(t))
+	ctx := context.Background()
+
+	albums, err := repo.FindAll(ctx, WithFields("id", "title", "bogus; DROP TABLE albums"))
+	if err != nil {
+		t.Fatalf("FindAll failed: %v", err)
+	}
+	if len(albums) != 3 {
+		t.Fatalf("Expected 3 albums, got %d", len(albums))
+	}
+	if albums[0].ID == 0 || albums[0].Title == "" {
+		t.Errorf("Expected id and title to be loaded, got %+v", albums[0])
+	}
+	if albums[0].Artist != "" || albums[0].Price != 0 {
+		t.Errorf("Expected unselected fields to be empty, got %+v", albums[0])
+	}
+
+	album, err := repo.FindByID(ctx, 2, WithFields("artist"))
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	if album.Artist != "Taylor Swift" || album.Title != "" {
+		t.Errorf("Unexpected projection %+v", album)
+	}
+}
+
+func TestAlbumRepository_LastModified(t *testing.T) {
+	repo := NewAlbumRepository(setupTestDB(t))
+	ctx := context.Background()
+
+	seeded, err := repo.LastModified(ctx)
+	if err != nil {
+		t.Fatalf("LastModified failed: %v", err)
+	}
+	if seeded.IsZero() {
+		t.Fatal("Expected last modified time of seeded albums")
+	}
+
+	// Album 1 is updated before it is deleted, so it keeps the newest
+	// updated_at while album 2 is deleted after it
+	time.Sleep(10 * time.Millisecond)
+	album, err := repo.FindByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	album.Price++
+	if err := repo.Update(ctx, album); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := repo.Delete(ctx, 1); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	afterDelete, err := repo.LastModified(ctx)
+	if err != nil {
+		t.Fatalf("LastModified failed: %v", err)
+	}
+	if !afterDelete.After(seeded) {
+		t.Errorf("Expected soft delete to advance last modified time, got %v then %v", seeded, afterDelete)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := repo.Delete(ctx, 2); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	afterSecondDelete, err := repo.LastModified(ctx)
+	if err != nil {
+		t.Fatalf("LastModified failed: %v", err)
+	}
+	if !afterSecondDelete.After(afterDelete) {
+		t.Errorf("Expected second soft delete to advance last modified time, got %v then %v", afterDelete, afterSecondDelete)
+	}
+}
+
+func TestAlbumRepository_Stream(t *testing.T) {
+	repo := NewAlbumRepository(setupTestDB(t))
+	ctx := context.Background()
+
+	if err := repo.Delete(ctx, 2); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	var ids []uint
+	err := repo.Stream(ctx, func(album *models.Album) error {
+		ids = append(ids, album.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Errorf("Expected live albums [1 3], got %v", ids)
+	}
+
+	stop := errors.New("stop")
+	err = repo.Stream(ctx, func(album *models.Album) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected callback error to stop the stream, got %v", err)
+	}
+}
+
+func TestAlbumRepository_CreateUpdateDelete(t *testing.T) {
+	repo := NewAlbumRepository(setupTestDB(t))
+	ctx := context.Background()
+
+	album := &models.Album{Title: "Test Album", Artist: "Test Artist", Price: 19.99, Tax: 0.1}
+	if err := repo.Create(ctx, album); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if album.ID == 0 {
+		t.Fatal("Expected non-zero album ID")
+	}
+
+	album.Price = 21.5
+	if err := repo.Update(ctx, album); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	updated, err := repo.FindByID(ctx, album.ID)
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	if updated.Price != 21.5 {
+		t.Errorf("Expected price 21.5, got %f", updated.Price)
+	}
+
+	if err := repo.Delete(ctx, album.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := repo.FindByID(ctx, album.ID); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected deleted album to be hidden, got %v", err)
+	}
+}
+
+func TestAlbumRepository_UpdateVersionConflict(t *testing.T) {
+	repo := NewAlbumRepository(setupTestDB(t))
+	ctx := context.Background()
+
+	first, _ := repo.FindByID(ctx, 1)
+	second, _ := repo.FindByID(ctx, 1)
+
+	first.Title = "First editor"
+	if err := repo.Update(ctx, first); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if first.Version != 2 {
+		t.Errorf("Expected version 2, got %d", first.Version)
+	}
+
+	second.Title = "Second editor"
+	if err := repo.Update(ctx, second); err != ErrVersionConflict {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+
+	missing := &models.Album{ID: 999, Version: 1}
+	if err := repo.Update(ctx, missing); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected ErrRecordNotFound, got %v", err)
+	}
+}
+
+func TestAlbumRepository_DeleteVersionConflict(t *testing.T) {
+	repo := NewAlbumRepository(setupTestDB(t))
+	ctx := context.Background()
+
+	stale, _ := repo.FindByID(ctx, 1)
+	current, _ := repo.FindByID(ctx, 1)
+	current.Title = "Concurrent edit"
+	if err := repo.Update(ctx, current); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if err := repo.Delete(ctx, 1, IfVersion(stale.Version)); err != ErrVersionConflict {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+	if _, err := repo.FindByID(ctx, 1); err != nil {
+		t.Errorf("Expected album to survive a stale delete, got %v", err)
+	}
+
+	if err := repo.Delete(ctx, 1, IfVersion(current.Version)); err != nil {
+		t.Errorf("Delete with current version failed: %v", err)
+	}
+}
+
+func TestAlbumRepository_SoftDeleteLifecycle(t *testing.T) {
+	repo := NewAlbumRepository(setupTestDB(t))
+	ctx := context.Background()
+
+	if err := repo.Delete(ctx, 1); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	deleted, err := repo.FindDeleted(ctx)
+	if err != nil {
+		t.Fatalf("FindDeleted failed: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0].ID != 1 {
+		t.Fatalf("Expected album 1 in trash, got %+v", deleted)
+	}
+
+	// The partial unique index ignores soft-deleted rows
+	duplicate := &models.Album{Title: deleted[0].Title, Artist: deleted[0].Artist, Price: 1, Tax: 0.1}
+	if err := repo.Create(ctx, duplicate); err != nil {
+		t.Fatalf("Expected re-creating a deleted album to succeed, got %v", err)
+	}
+	if _, err := repo.Restore(ctx, 1); err != gorm.ErrDuplicatedKey {
+		t.Errorf("Expected ErrDuplicatedKey when restoring over a live duplicate, got %v", err)
+	}
+	if err := repo.Create(ctx, &models.Album{Title: duplicate.Title, Artist: duplicate.Artist, Price: 1, Tax: 0.1}); err != gorm.ErrDuplicatedKey {
+		t.Errorf("Expected ErrDuplicatedKey for live duplicate, got %v", err)
+	}
+
+	if err := repo.Delete(ctx, duplicate.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := repo.Purge(ctx, duplicate.ID); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+
+	restored, err := repo.Restore(ctx, 1)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if restored.Version != 2 {
+		t.Errorf("Expected version 2 after restore, got %d", restored.Version)
+	}
+
+	if err := repo.Purge(ctx, 1); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected ErrRecordNotFound when purging a live album, got %v", err)
+	}
+}
+
+func TestAlbumRepository_PurgeDeletedBefore(t *testing.T) {
+	repo := NewAlbumRepository(setupTestDB(t))
+	ctx := context.Background()
+
+	repo.Delete(ctx, 1)
+	repo.Delete(ctx, 2)
+
+	purged, err := repo.PurgeDeletedBefore(ctx, time.Now().UTC().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeDeletedBefore failed: %v", err)
+	}
+	if purged != 0 {
+		t.Errorf("Expected 0 purged albums, got %d", purged)
+	}
+
+	purged, err = repo.PurgeDeletedBefore(ctx, time.Now().UTC().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeDeletedBefore failed: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("Expected 2 purged albums, got %d", purged)
+	}
+}
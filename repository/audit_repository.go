@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"golang-gin/audit"
+	"golang-gin/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditRepository defines the interface for the append-only album audit trail
+type AuditRepository interface {
+	Record(ctx context.Context, entry *models.AlbumAudit) error
+	FindByAlbumID(ctx context.Context, albumID uint) ([]models.AlbumAudit, error)
+	FindAlbumAsOf(ctx context.Context, albumID uint, at time.Time) (*models.Album, error)
+	FindByAction(ctx context.Context, action string, albumIDs []uint) ([]models.AlbumAudit, error)
+}
+
+// auditRepository implements AuditRepository
+type auditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new AuditRepository instance
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// Record appends an entry to the audit trail
+func (r *auditRepository) Record(ctx context.Context, entry *models.AlbumAudit) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// FindByAlbumID retrieves the audit trail of an album, oldest first
+func (r *auditRepository) FindByAlbumID(ctx context.Context, albumID uint) ([]models.AlbumAudit, error) {
+	var entries []models.AlbumAudit
+	if err := r.db.WithContext(ctx).
+		Where("album_id = ?", albumID).
+		Order("created_at ASC, id ASC").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// FindAlbumAsOf reconstructs an album as it was at the given time. It returns
+// gorm.ErrRecordNotFound if the album did not exist or was deleted at that time.
+func (r *auditRepository) FindAlbumAsOf(ctx context.Context, albumID uint, at time.Time) (*models.Album, error) {
+	var entry models.AlbumAudit
+	if err := r.db.WithContext(ctx).
+		Where("album_id = ? AND created_at <= ?", albumID, at).
+		Order("created_at DESC, id DESC").
+		First(&entry).Error; err != nil {
+		return nil, err
+	}
+	return albumFromAudit(&entry)
+}
+
+// FindByAction retrieves the entries with the given action for several
+// albums, oldest first
+func (r *auditRepository) FindByAction(ctx context.Context, action string, albumIDs []uint) ([]models.AlbumAudit, error) {
+	entries := []models.AlbumAudit{}
+	if len(albumIDs) == 0 {
+		return entries, nil
+	}
+	if err := r.db.WithContext(ctx).
+		Where("action = ? AND album_id IN ?", action, albumIDs).
+		Order("created_at ASC, id ASC").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// albumFromAudit returns the album state recorded by an audit entry
+func albumFromAudit(entry *models.AlbumAudit) (*models.Album, error) {
+	if entry.Snapshot == nil || entry.Action == models.AuditActionDelete || entry.Action == models.AuditActionPurge {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return entry.Snapshot, nil
+}
+
+// AuditHook returns an AlbumHook that records every album write in the audit
+// trail, using the actor, request ID and source carried by the context
+func AuditHook() AlbumHook {
+	return func(ctx context.Context, tx *gorm.DB, change AlbumChange) error {
+		md := audit.FromContext(ctx)
+
+		entry := &models.AlbumAudit{
+			Action:        change.Action,
+			Actor:         md.Actor,
+			ActorVerified: md.ActorVerified,
+			RequestID:     md.RequestID,
+			Source:        string(md.Source),
+			Changes:       audit.Diff(change.Before, change.After),
+		}
+
+		if change.After != nil {
+			entry.AlbumID = change.After.ID
+			entry.Snapshot = change.After
+		} else {
+			entry.AlbumID = change.Before.ID
+			entry.Snapshot = change.Before
+		}
+
+		return NewAuditRepository(tx).Record(ctx, entry)
+	}
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"golang-gin/audit"
+	"golang-gin/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestAuditHook_RecordsAlbumWrites(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewAlbumRepository(db, AuditHook())
+	audits := NewAuditRepository(db)
+	ctx := audit.WithMetadata(context.Background(), audit.Metadata{
+		Actor:     "alice",
+		RequestID: "req-1",
+		Source:    audit.SourceREST,
+	})
+
+	album := &models.Album{Title: "Audited", Artist: "Artist", Price: 10, Tax: 0.1}
+	if err := repo.Create(ctx, album); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	album.Price = 12.5
+	if err := repo.Update(ctx, album); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := repo.Delete(ctx, album.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	entries, err := audits.FindByAlbumID(ctx, album.ID)
+	if err != nil {
+		t.Fatalf("FindByAlbumID failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 audit entries, got %d", len(entries))
+	}
+
+	actions := []string{models.AuditActionCreate, models.AuditActionUpdate, models.AuditActionDelete}
+	for i, entry := range entries {
+		if entry.Action != actions[i] {
+			t.Errorf("Entry %d: expected action %s, got %s", i, actions[i], entry.Action)
+		}
+		if entry.Actor != "alice" || entry.ActorVerified || entry.RequestID != "req-1" || entry.Source != "rest" {
+			t.Errorf("Entry %d: unexpected metadata %+v", i, entry)
+		}
+	}
+
+	created, err := audits.FindByAction(ctx, models.AuditActionCreate, []uint{album.ID, 999})
+	if err != nil {
+		t.Fatalf("FindByAction failed: %v", err)
+	}
+	if len(created) != 1 || created[0].Actor != "alice" {
+		t.Errorf("Expected the create entry, got %+v", created)
+	}
+
+	update := entries[1]
+	if len(update.Changes) != 1 {
+		t.Fatalf("Expected only price to change, got %+v", update.Changes)
+	}
+	if change := update.Changes["price"]; change.From != 10.0 || change.To != 12.5 {
+		t.Errorf("Unexpected price change: %+v", change)
+	}
+}
+
+func TestAuditHook_RollsBackWithWrite(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewAlbumRepository(db, AuditHook())
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, &models.Album{Title: "Hammerhead", Artist: "THE OFFSPRING", Price: 1, Tax: 0.1}); err == nil {
+		t.Fatal("Expected duplicate create to fail")
+	}
+
+	var count int64
+	db.Model(&models.AlbumAudit{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected no audit entries for a failed write, got %d", count)
+	}
+}
+
+func TestAuditRepository_FindAlbumAsOf(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewAlbumRepository(db, AuditHook())
+	audits := NewAuditRepository(db)
+	ctx := context.Background()
+
+	album := &models.Album{Title: "Time Travel", Artist: "Artist", Price: 10, Tax: 0.1}
+	if err := repo.Create(ctx, album); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	afterCreate := time.Now().UTC()
+	time.Sleep(10 * time.Millisecond)
+
+	album.Title = "Time Travel (Deluxe)"
+	if err := repo.Update(ctx, album); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	afterUpdate := time.Now().UTC()
+	time.Sleep(10 * time.Millisecond)
+
+	if err := repo.Delete(ctx, album.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if _, err := audits.FindAlbumAsOf(ctx, album.ID, afterCreate.Add(-time.Hour)); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected ErrRecordNotFound before creation, got %v", err)
+	}
+
+	old, err := audits.FindAlbumAsOf(ctx, album.ID, afterCreate)
+	if err != nil {
+		t.Fatalf("FindAlbumAsOf failed: %v", err)
+	}
+	if old.Title != "Time Travel" {
+		t.Errorf("Expected original title, got %s", old.Title)
+	}
+
+	updated, err := audits.FindAlbumAsOf(ctx, album.ID, afterUpdate)
+	if err != nil {
+		t.Fatalf("FindAlbumAsOf failed: %v", err)
+	}
+	if updated.Title != "Time Travel (Deluxe)" {
+		t.Errorf("Expected updated title, got %s", updated.Title)
+	}
+
+	if _, err := audits.FindAlbumAsOf(ctx, album.ID, time.Now().UTC()); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected ErrRecordNotFound after deletion, got %v", err)
+	}
+}
+
+func TestAuditRepository_AppendOnly(t *testing.T) {
+	db := setupTestDB(t)
+	audits := NewAuditRepository(db)
+	ctx := context.Background()
+
+	entry := &models.AlbumAudit{AlbumID: 1, Action: models.AuditActionUpdate, Actor: "bob", Source: "cli"}
+	if err := audits.Record(ctx, entry); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	if err := db.Model(entry).Update("actor", "mallory").Error; err != models.ErrAuditImmutable {
+		t.Errorf("Expected ErrAuditImmutable on update, got %v", err)
+	}
+	if err := db.Delete(entry).Error; err != models.ErrAuditImmutable {
+		t.Errorf("Expected ErrAuditImmutable on delete, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"golang-gin/cache"
+	"golang-gin/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CacheStats reports how album lookups were served
+type CacheStats = cache.Stats
+
+// CacheOption configures a CachedAlbumRepository
+type CacheOption func(*CachedAlbumRepository)
+
+// WithCacheTTL sets how long found albums are cached
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(r *CachedAlbumRepository) {
+		r.opts = append(r.opts, cache.WithTTL(ttl))
+	}
+}
+
+// WithNegativeCacheTTL sets how long unknown album IDs are cached. Zero
+// disables negative caching.
+func WithNegativeCacheTTL(ttl time.Duration) CacheOption {
+	return func(r *CachedAlbumRepository) {
+		r.opts = append(r.opts, cache.WithNegativeTTL(ttl))
+	}
+}
+
+// CachedAlbumRepository is a read-through cache in front of an
+// AlbumRepository. FindByID is served from the store, concurrent misses for
+// the same album share one load, and writes invalidate the written album.
+// Lookups with FindOptions and all other reads go to the wrapped repository.
+type CachedAlbumRepository struct {
+	AlbumRepository
+
+	opts   []cache.LoaderOption
+	albums *cache.Loader[models.Album]
+}
+
+// NewCachedAlbumRepository wraps repo with a cache backed by store
+func NewCachedAlbumRepository(repo AlbumRepository, store cache.Store, opts ...CacheOption) *CachedAlbumRepository {
+	r := &CachedAlbumRepository{AlbumRepository: repo}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.albums = cache.NewLoader[models.Album](store, "Album", gorm.ErrRecordNotFound, r.opts...)
+	return r
+}
+
+// albumCacheKey returns the store key of an album. The version segment lets
+// a shared store hold entries of different formats during a rollout.
+func albumCacheKey(id uint) string {
+	return fmt.Sprintf("albums:v2:%d", id)
+}
+
+// FindByID retrieves an album by ID, from the cache when possible
+func (r *CachedAlbumRepository) FindByID(ctx context.Context, id uint, opts ...FindOption) (*models.Album, error) {
+	if len(opts) > 0 {
+		return r.AlbumRepository.FindByID(ctx, id, opts...)
+	}
+	return r.albums.Get(ctx, albumCacheKey(id), func(ctx context.Context) (*models.Album, error) {
+		return r.AlbumRepository.FindByID(ctx, id)
+	})
+}
+
+// Invalidate removes the given albums from the cache
+func (r *CachedAlbumRepository) Invalidate(ctx context.Context, ids ...uint) {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = albumCacheKey(id)
+	}
+	r.albums.Invalidate(ctx, keys...)
+}
+
+// Stats returns the cache counters
+func (r *CachedAlbumRepository) Stats() CacheStats {
+	return r.albums.Stats()
+}
+
+// Create creates a new album and drops a cached not-found entry for its ID
+func (r *CachedAlbumRepository) Create(ctx context.Context, album *models.Album) error {
+	if err := r.AlbumRepository.Create(ctx, album); err != nil {
+		return err
+	}
+	r.Invalidate(ctx, album.ID)
+	return nil
+}
+
+// Update updates an album and invalidates its cache entry
+func (r *CachedAlbumRepository) Update(ctx context.Context, album *models.Album) error {
+	defer r.Invalidate(ctx, album.ID)
+	return r.AlbumRepository.Update(ctx, album)
+}
+
+// Delete soft deletes an album and invalidates its cache entry
+func (r *CachedAlbumRepository) Delete(ctx context.Context, id uint, opts ...DeleteOption) error {
+	defer r.Invalidate(ctx, id)
+	return r.AlbumRepository.Delete(ctx, id, opts...)
+}
+
+// Restore restores a soft-deleted album and invalidates its cache entry
+func (r *CachedAlbumRepository) Restore(ctx context.Context, id uint) (*models.Album, error) {
+	defer r.Invalidate(ctx, id)
+	return r.AlbumRepository.Restore(ctx, id)
+}
+
+// Purge permanently deletes an album and invalidates its cache entry
+func (r *CachedAlbumRepository) Purge(ctx context.Context, id uint) error {
+	defer r.Invalidate(ctx, id)
+	return r.AlbumRepository.Purge(ctx, id)
+}
+
+// WrapUnitOfWork returns a UnitOfWork that invalidates every album written
+// inside a transaction once the transaction has finished
+func (r *CachedAlbumRepository) WrapUnitOfWork(uow UnitOfWork) UnitOfWork {
+	return &cachedUnitOfWork{uow: uow, cache: r}
+}
+
+// cachedUnitOfWork invalidates the albums written by its transactions
+type cachedUnitOfWork struct {
+	uow   UnitOfWork
+	cache *CachedAlbumRepository
+}
+
+// WithTx runs fn and invalidates the written albums after commit or rollback
+func (u *cachedUnitOfWork) WithTx(ctx context.Context, fn func(repos Repositories) error) error {
+	written := &writtenAlbums{}
+	defer func() {
+		u.cache.Invalidate(ctx, written.ids...)
+	}()
+
+	return u.uow.WithTx(ctx, func(repos Repositories) error {
+		repos.Albums = &recordingAlbumRepository{AlbumRepository: repos.Albums, written: written}
+		return fn(repos)
+	})
+}
+
+// writtenAlbums collects the IDs of albums written in a transaction
+type writtenAlbums struct {
+	ids []uint
+}
+
+// recordingAlbumRepository records the IDs of the albums it writes
+type recordingAlbumRepository struct {
+	AlbumRepository
+	written *writtenAlbums
+}
+
+// Create creates a new album and records its ID
+func (r *recordingAlbumRepository) Create(ctx context.Context, album *models.Album) error {
+	err := r.AlbumRepository.Create(ctx, album)
+	if album.ID != 0 {
+		r.written.ids = append(r.written.ids, album.ID)
+	}
+	return err
+}
+
+// Update updates an album and records its ID
+func (r *recordingAlbumRepository) Update(ctx context.Context, album *models.Album) error {
+	r.written.ids = append(r.written.ids, album.ID)
+	return r.AlbumRepository.Update(ctx, album)
+}
+
+// Delete soft deletes an album and records its ID
+func (r *recordingAlbumRepository) Delete(ctx context.Context, id uint, opts ...DeleteOption) error {
+	r.written.ids = append(r.written.ids, id)
+	return r.AlbumRepository.Delete(ctx, id, opts...)
+}
+
+// Restore restores an album and records its ID
+func (r *recordingAlbumRepository) Restore(ctx context.Context, id uint) (*models.Album, error) {
+	r.written.ids = append(r.written.ids, id)
+	return r.AlbumRepository.Restore(ctx, id)
+}
+
+// Purge permanently deletes an album and records its ID
+func (r *recordingAlbumRepository) Purge(ctx context.Context, id uint) error {
+	r.written.ids = append(r.written.ids, id)
+	return r.AlbumRepository.Purge(ctx, id)
+}
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"golang-gin/cache"
+	"golang-gin/models"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// countingAlbumRepository counts FindByID calls and can hold them until
+// release is closed
+type countingAlbumRepository struct {
+	AlbumRepository
+	calls   atomic.Int64
+	release chan struct{}
+}
+
+func (r *countingAlbumRepository) FindByID(ctx context.Context, id uint, opts ...FindOption) (*models.Album, error) {
+	r.calls.Add(1)
+	if r.release != nil {
+		<-r.release
+	}
+	return r.AlbumRepository.FindByID(ctx, id, opts...)
+}
+
+func newCachedTestRepository() (*CachedAlbumRepository, *countingAlbumRepository) {
+	counting := &countingAlbumRepository{AlbumRepository: NewMockAlbumRepository()}
+	return NewCachedAlbumRepository(counting, cache.NewLRU(100)), counting
+}
+
+func TestCachedAlbumRepository_FindByID(t *testing.T) {
+	repo, counting := newCachedTestRepository()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		album, err := repo.FindByID(ctx, 1)
+		if err != nil {
+			t.Fatalf("FindByID failed: %v", err)
+		}
+		if album.Title != "Hammerhead" {
+			t.Errorf("Expected title Hammerhead, got %s", album.Title)
+		}
+		// Callers may modify the album without affecting the cached copy
+		album.Title = "changed"
+	}
+
+	if counting.calls.Load() != 1 {
+		t.Errorf("Expected 1 repository call, got %d", counting.calls.Load())
+	}
+
+	stats := repo.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, got %+v", stats)
+	}
+}
+
+func TestCachedAlbumRepository_NegativeCaching(t *testing.T) {
+	repo, counting := newCachedTestRepository()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := repo.FindByID(ctx, 999); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("Expected ErrRecordNotFound, got %v", err)
+		}
+	}
+	if counting.calls.Load() != 1 {
+		t.Errorf("Expected 1 repository call, got %d", counting.calls.Load())
+	}
+	if repo.Stats().NegativeHits != 1 {
+		t.Errorf("Expected 1 negative hit, got %+v", repo.Stats())
+	}
+
+	disabled := NewCachedAlbumRepository(counting, cache.NewLRU(100), WithNegativeCacheTTL(0))
+	disabled.FindByID(ctx, 999)
+	disabled.FindByID(ctx, 999)
+	if counting.calls.Load() != 3 {
+		t.Errorf("Expected negative caching to be disabled, got %d calls", counting.calls.Load())
+	}
+}
+
+func TestCachedAlbumRepository_Invalidation(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		write func(repo *CachedAlbumRepository) error
+		found bool
+	}{
+		{"Update", func(repo *CachedAlbumRepository) error {
+			album, _ := repo.FindByID(ctx, 1)
+			album.Title = "Updated"
+			return repo.Update(ctx, album)
+		}, true},
+		{"Delete", func(repo *CachedAlbumRepository) error {
+			return repo.Delete(ctx, 1)
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, _ := newCachedTestRepository()
+			if _, err := repo.FindByID(ctx, 1); err != nil {
+				t.Fatalf("FindByID failed: %v", err)
+			}
+
+			if err := tt.write(repo); err != nil {
+				t.Fatalf("%s failed: %v", tt.name, err)
+			}
+
+			album, err := repo.FindByID(ctx, 1)
+			if tt.found {
+				if err != nil || album.Title != "Updated" {
+					t.Errorf("Expected updated album, got %+v, %v", album, err)
+				}
+				return
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				t.Errorf("Expected ErrRecordNotFound after delete, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCachedAlbumRepository_CreateClearsNegativeEntry(t *testing.T) {
+	repo, _ := newCachedTestRepository()
+	ctx := context.Background()
+
+	album := &models.Album{Title: "New", Artist: "Artist", Price: 10}
+	nextID := uint(4)
+	if _, err := repo.FindByID(ctx, nextID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected ErrRecordNotFound, got %v", err)
+	}
+
+	if err := repo.Create(ctx, album); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if album.ID != nextID {
+		t.Fatalf("Expected album ID %d, got %d", nextID, album.ID)
+	}
+
+	if _, err := repo.FindByID(ctx, nextID); err != nil {
+		t.Errorf("Expected created album, got %v", err)
+	}
+}
+
+func TestCachedAlbumRepository_Singleflight(t *testing.T) {
+	repo, counting := newCachedTestRepository()
+	counting.release = make(chan struct{})
+
+	const callers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.FindByID(context.Background(), 1); err != nil {
+				t.Errorf("FindByID failed: %v", err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(counting.release)
+	wg.Wait()
+
+	if counting.calls.Load() != 1 {
+		t.Errorf("Expected 1 repository call for %d concurrent callers, got %d", callers, counting.calls.Load())
+	}
+	if stats := repo.Stats(); stats.Misses != callers || stats.SharedLoads != callers {
+		t.Errorf("Expected %d shared misses, got %+v", callers, stats)
+	}
+}
+
+func TestCachedAlbumRepository_CallerCancel(t *testing.T) {
+	repo, counting := newCachedTestRepository()
+	counting.release = make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := repo.FindByID(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	// The shared load keeps running for other callers
+	close(counting.release)
+	if _, err := repo.FindByID(context.Background(), 1); err != nil {
+		t.Errorf("FindByID failed: %v", err)
+	}
+}
+
+func TestCachedAlbumRepository_UnitOfWork(t *testing.T) {
+	mock := NewMockAlbumRepository()
+	repo := NewCachedAlbumRepository(mock, cache.NewLRU(100))
+	uow := repo.WrapUnitOfWork(NewMockUnitOfWork(mock))
+	ctx := context.Background()
+
+	if _, err := repo.FindByID(ctx, 1); err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+
+	err := uow.WithTx(ctx, func(repos Repositories) error {
+		album, err := repos.Albums.FindByID(ctx, 1)
+		if err != nil {
+			return err
+		}
+		album.Title = "In transaction"
+		return repos.Albums.Update(ctx, album)
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+
+	album, err := repo.FindByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	if album.Title != "In transaction" {
+		t.Errorf("Expected cache to be invalidated after commit, got title %s", album.Title)
+	}
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"golang-gin/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrEmailNotFailed is returned when requeueing an email that has not failed
+var ErrEmailNotFailed = errors.New("email has not failed")
+
+// EmailFilter selects emails returned by EmailRepository.List
+type EmailFilter struct {
+	Status string
+	// Recipient matches emails with the address in To, Cc or Bcc
+	Recipient string
+	Limit     int
+	Offset    int
+}
+
+// EmailRepository defines the interface for the email queue
+type EmailRepository interface {
+	// Enqueue inserts msg as a pending email. If an email with the same
+	// dedup key already exists, msg is replaced by it and created is false.
+	Enqueue(ctx context.Context, msg *models.EmailMessage) (created bool, err error)
+	FindByID(ctx context.Context, id uint) (*models.EmailMessage, error)
+	// List returns the emails matching filter, newest first, and the total
+	// number of matching emails
+	List(ctx context.Context, filter EmailFilter) ([]models.EmailMessage, int64, error)
+	// Claim returns up to limit pending emails that are due at now, oldest
+	// first, and hides them from other workers until now+lease
+	Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.EmailMessage, error)
+	MarkSent(ctx context.Context, id uint, sentAt time.Time) error
+	// MarkRetry records a failed attempt and schedules the next one
+	MarkRetry(ctx context.Context, id uint, nextAttemptAt time.Time, lastError string) error
+	// MarkFailed records a failed attempt and gives up on the email
+	MarkFailed(ctx context.Context, id uint, lastError string) error
+	// Requeue makes a failed email pending again with a fresh attempt count
+	Requeue(ctx context.Context, id uint, now time.Time) error
+	DeleteSentBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// emailRepository implements EmailRepository
+type emailRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailRepository creates a new EmailRepository instance
+func NewEmailRepository(db *gorm.DB) EmailRepository {
+	return &emailRepository{db: db}
+}
+
+// Enqueue inserts a pending email unless its dedup key is already taken
+func (r *emailRepository) Enqueue(ctx context.Context, msg *models.EmailMessage) (bool, error) {
+	db := r.db.WithContext(ctx)
+
+	msg.Status = models.EmailStatusPending
+	if msg.NextAttemptAt.IsZero() {
+		msg.NextAttemptAt = time.Now().UTC()
+	}
+
+	err := db.Create(msg).Error
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, gorm.ErrDuplicatedKey) || msg.DedupKey == nil {
+		return false, err
+	}
+
+	var existing models.EmailMessage
+	if err := db.Where("dedup_key = ?", *msg.DedupKey).First(&existing).Error; err != nil {
+		return false, err
+	}
+	*msg = existing
+	return false, nil
+}
+
+// FindByID retrieves an email by its ID
+func (r *emailRepository) FindByID(ctx context.Context, id uint) (*models.EmailMessage, error) {
+	var msg models.EmailMessage
+	if err := r.db.WithContext(ctx).First(&msg, id).Error; err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// List returns the emails matching filter
+func (r *emailRepository) List(ctx context.Context, filter EmailFilter) ([]models.EmailMessage, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.EmailMessage{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Recipient != "" {
+		// Addresses are stored as JSON arrays, so a substring match is enough
+		pattern := "%" + escapeLike(strings.ToLower(filter.Recipient)) + "%"
+		query = query.Where(
+			"LOWER(to_addresses) LIKE ? ESCAPE '\\' OR LOWER(cc_addresses) LIKE ? ESCAPE '\\' OR LOWER(bcc_addresses) LIKE ? ESCAPE '\\'",
+			pattern, pattern, pattern,
+		)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var messages []models.EmailMessage
+	query = query.Order("id DESC").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&messages).Error; err != nil {
+		return nil, 0, err
+	}
+	return messages, total, nil
+}
+
+// Claim returns due emails. Like the outbox, each email is claimed by moving
+// its next_attempt_at forward only if no other worker has done so first.
+func (r *emailRepository) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.EmailMessage, error) {
+	db := r.db.WithContext(ctx)
+
+	var due []models.EmailMessage
+	if err := db.
+		Where("status = ? AND next_attempt_at <= ?", models.EmailStatusPending, now).
+		Order("id").
+		Limit(limit).
+		Find(&due).Error; err != nil {
+		return nil, err
+	}
+
+	claimed := due[:0]
+	for _, msg := range due {
+		result := db.Model(&models.EmailMessage{}).
+			Where("id = ? AND next_attempt_at = ? AND status = ?", msg.ID, msg.NextAttemptAt, models.EmailStatusPending).
+			Update("next_attempt_at", now.Add(lease))
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			claimed = append(claimed, msg)
+		}
+	}
+	return claimed, nil
+}
+
+// MarkSent records that the SMTP server accepted an email
+func (r *emailRepository) MarkSent(ctx context.Context, id uint, sentAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.EmailMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     models.EmailStatusSent,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"sent_at":    sentAt,
+			"last_error": "",
+		}).Error
+}
+
+// MarkRetry records a transient delivery failure
+func (r *emailRepository) MarkRetry(ctx context.Context, id uint, nextAttemptAt time.Time, lastError string) error {
+	return r.db.WithContext(ctx).Model(&models.EmailMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastError,
+		}).Error
+}
+
+// MarkFailed records a permanent delivery failure
+func (r *emailRepository) MarkFailed(ctx context.Context, id uint, lastError string) error {
+	return r.db.WithContext(ctx).Model(&models.EmailMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     models.EmailStatusFailed,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"last_error": lastError,
+		}).Error
+}
+
+// Requeue makes a failed email pending again. It returns
+// gorm.ErrRecordNotFound if the email does not exist and ErrEmailNotFailed if
+// it is still pending or was sent.
+func (r *emailRepository) Requeue(ctx context.Context, id uint, now time.Time) error {
+	db := r.db.WithContext(ctx)
+
+	result := db.Model(&models.EmailMessage{}).
+		Where("id = ? AND status = ?", id, models.EmailStatusFailed).
+		Updates(map[string]interface{}{
+			"status":          models.EmailStatusPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 1 {
+		return nil
+	}
+
+	if _, err := r.FindByID(ctx, id); err != nil {
+		return err
+	}
+	return ErrEmailNotFailed
+}
+
+// DeleteSentBefore deletes emails sent before cutoff
+func (r *emailRepository) DeleteSentBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status = ? AND sent_at < ?", models.EmailStatusSent, cutoff).
+		Delete(&models.EmailMessage{})
+	return result.RowsAffected, result.Error
+}
+
+// escapeLike escapes the LIKE wildcards in s
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
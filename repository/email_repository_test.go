@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"golang-gin/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestEmailRepository_Enqueue(t *testing.T) {
+	emails := NewEmailRepository(setupTestDB(t))
+	ctx := context.Background()
+
+	key := "album.created:e1"
+	first := &models.EmailMessage{DedupKey: &key, To: []string{"a@example.com"}, Subject: "first"}
+	created, err := emails.Enqueue(ctx, first)
+	if err != nil || !created {
+		t.Fatalf("Expected email to be created, got %v (%v)", created, err)
+	}
+	if first.Status != models.EmailStatusPending || first.NextAttemptAt.IsZero() {
+		t.Errorf("Expected pending email due now, got %+v", first)
+	}
+
+	duplicate := &models.EmailMessage{DedupKey: &key, To: []string{"b@example.com"}, Subject: "second"}
+	created, err = emails.Enqueue(ctx, duplicate)
+	if err != nil || created {
+		t.Fatalf("Expected duplicate not to be created, got %v (%v)", created, err)
+	}
+	if duplicate.ID != first.ID || duplicate.Subject != "first" || duplicate.To[0] != "a@example.com" {
+		t.Errorf("Expected the existing email, got %+v", duplicate)
+	}
+
+	// Emails without a dedup key are never deduplicated
+	for i := 0; i < 2; i++ {
+		if created, err := emails.Enqueue(ctx, &models.EmailMessage{To: []string{"c@example.com"}, Subject: "no key"}); err != nil || !created {
+			t.Errorf("Expected email without key to be created, got %v (%v)", created, err)
+		}
+	}
+}
+
+func TestEmailRepository_Lifecycle(t *testing.T) {
+	db := setupTestDB(t)
+	emails := NewEmailRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	msg := &models.EmailMessage{To: []string{"a@example.com"}, Subject: "hello", NextAttemptAt: now}
+	if _, err := emails.Enqueue(ctx, msg); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	claimed, err := emails.Claim(ctx, now, 10, time.Minute)
+	if err != nil || len(claimed) != 1 || claimed[0].To[0] != "a@example.com" {
+		t.Fatalf("Expected 1 claimed email, got %+v (%v)", claimed, err)
+	}
+	if again, _ := emails.Claim(ctx, now, 10, time.Minute); len(again) != 0 {
+		t.Errorf("Expected claimed email to be hidden, got %d", len(again))
+	}
+
+	if err := emails.MarkRetry(ctx, msg.ID, now.Add(time.Second), "421 try again"); err != nil {
+		t.Fatalf("MarkRetry failed: %v", err)
+	}
+	claimed, _ = emails.Claim(ctx, now.Add(time.Second), 10, time.Minute)
+	if len(claimed) != 1 || claimed[0].Attempts != 1 || claimed[0].LastError != "421 try again" {
+		t.Fatalf("Expected retried email with 1 attempt, got %+v", claimed)
+	}
+
+	if err := emails.MarkFailed(ctx, msg.ID, "550 no such user"); err != nil {
+		t.Fatalf("MarkFailed failed: %v", err)
+	}
+	if again, _ := emails.Claim(ctx, now.Add(time.Hour), 10, time.Minute); len(again) != 0 {
+		t.Errorf("Expected failed email not to be claimed, got %d", len(again))
+	}
+
+	if err := emails.Requeue(ctx, msg.ID, now.Add(time.Hour)); err != nil {
+		t.Fatalf("Requeue failed: %v", err)
+	}
+	claimed, _ = emails.Claim(ctx, now.Add(time.Hour), 10, time.Minute)
+	if len(claimed) != 1 || claimed[0].Attempts != 0 || claimed[0].Status != models.EmailStatusPending {
+		t.Fatalf("Expected requeued email, got %+v", claimed)
+	}
+
+	if err := emails.MarkSent(ctx, msg.ID, now); err != nil {
+		t.Fatalf("MarkSent failed: %v", err)
+	}
+	sent, _ := emails.FindByID(ctx, msg.ID)
+	if sent.Status != models.EmailStatusSent || sent.SentAt == nil || sent.LastError != "" {
+		t.Errorf("Expected sent email, got %+v", sent)
+	}
+	if err := emails.Requeue(ctx, msg.ID, now); !errors.Is(err, ErrEmailNotFailed) {
+		t.Errorf("Expected ErrEmailNotFailed, got %v", err)
+	}
+	if err := emails.Requeue(ctx, 999, now); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected ErrRecordNotFound, got %v", err)
+	}
+
+	deleted, err := emails.DeleteSentBefore(ctx, now.Add(time.Second))
+	if err != nil || deleted != 1 {
+		t.Errorf("Expected 1 deleted email, got %d (%v)", deleted, err)
+	}
+}
+
+func TestEmailRepository_List(t *testing.T) {
+	ctx := context.Background()
+	repos := map[string]EmailRepository{
+		"gorm": NewEmailRepository(setupTestDB(t)),
+		"mock": NewMockEmailRepository(),
+	}
+
+	for name, emails := range repos {
+		t.Run(name, func(t *testing.T) {
+			for _, msg := range []*models.EmailMessage{
+				{To: []string{"山田 太郎 <Yamada@example.com>"}, Subject: "1"},
+				{To: []string{"suzuki@example.com"}, Cc: []string{"yamada@example.com"}, Subject: "2"},
+				{To: []string{"100%_sure@example.com"}, Subject: "3"},
+			} {
+				if _, err := emails.Enqueue(ctx, msg); err != nil {
+					t.Fatalf("Enqueue failed: %v", err)
+				}
+			}
+			if err := emails.MarkFailed(ctx, 1, "550"); err != nil {
+				t.Fatalf("MarkFailed failed: %v", err)
+			}
+
+			tests := []struct {
+				name     string
+				filter   EmailFilter
+				expected []string
+				total    int64
+			}{
+				{"All", EmailFilter{}, []string{"3", "2", "1"}, 3},
+				{"Status", EmailFilter{Status: models.EmailStatusPending}, []string{"3", "2"}, 2},
+				{"Recipient", EmailFilter{Recipient: "yamada@example.com"}, []string{"2", "1"}, 2},
+				{"Wildcards are literal", EmailFilter{Recipient: "%"}, []string{"3"}, 1},
+				{"Paged", EmailFilter{Limit: 1, Offset: 1}, []string{"2"}, 3},
+			}
+
+			for _, tt := range tests {
+				messages, total, err := emails.List(ctx, tt.filter)
+				if err != nil {
+					t.Fatalf("%s: List failed: %v", tt.name, err)
+				}
+				var subjects []string
+				for _, msg := range messages {
+					subjects = append(subjects, msg.Subject)
+				}
+				if total != tt.total || len(subjects) != len(tt.expected) {
+					t.Errorf("%s: Expected %v (total %d), got %v (total %d)", tt.name, tt.expected, tt.total, subjects, total)
+					continue
+				}
+				for i := range subjects {
+					if subjects[i] != tt.expected[i] {
+						t.Errorf("%s: Expected %v, got %v", tt.name, tt.expected, subjects)
+						break
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"golang-gin/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrClaimLost is returned when an idempotency key is completed by a request
+// that no longer holds it, e.g. because its claim went stale and was taken
+// over by a retry
+var ErrClaimLost = errors.New("idempotency key is no longer held by this request")
+
+// IdempotencyRepository defines the interface for idempotency key storage
+type IdempotencyRepository interface {
+	// Claim inserts record unless the key is already taken. It returns nil when
+	// the key was claimed, or the existing record otherwise. Expired records
+	// and in-progress records created before staleBefore are replaced.
+	Claim(ctx context.Context, record *models.IdempotencyKey, staleBefore time.Time) (*models.IdempotencyKey, error)
+	// Complete stores the response of a key claimed by record.Owner, and
+	// returns ErrClaimLost if the key is held by someone else
+	Complete(ctx context.Context, record *models.IdempotencyKey) error
+	// Release deletes a key still in progress and held by owner
+	Release(ctx context.Context, scope, key, owner string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// idempotencyRepository implements IdempotencyRepository
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyRepository creates a new IdempotencyRepository instance
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Claim inserts record unless the key is already taken
+func (r *idempotencyRepository) Claim(ctx context.Context, record *models.IdempotencyKey, staleBefore time.Time) (*models.IdempotencyKey, error) {
+	db := r.db.WithContext(ctx)
+
+	if err := db.
+		Where("scope = ? AND idempotency_key = ?", record.Scope, record.Key).
+		Where("expires_at < ? OR (completed = ? AND created_at < ?)", record.CreatedAt, false, staleBefore).
+		Delete(&models.IdempotencyKey{}).Error; err != nil {
+		return nil, err
+	}
+
+	err := db.Create(record).Error
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, err
+	}
+
+	var existing models.IdempotencyKey
+	if err := db.Where("scope = ? AND idempotency_key = ?", record.Scope, record.Key).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// Complete stores the response of a claimed key
+func (r *idempotencyRepository) Complete(ctx context.Context, record *models.IdempotencyKey) error {
+	result := r.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where("scope = ? AND idempotency_key = ? AND owner = ? AND completed = ?", record.Scope, record.Key, record.Owner, false).
+		Updates(map[string]interface{}{
+			"completed":    true,
+			"status_code":  record.StatusCode,
+			"content_type": record.ContentType,
+			"etag":         record.ETag,
+			"vary":         record.Vary,
+			"body":         record.Body,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrClaimLost
+	}
+	return nil
+}
+
+// Release deletes an in-progress key so the request can be retried
+func (r *idempotencyRepository) Release(ctx context.Context, scope, key, owner string) error {
+	return r.db.WithContext(ctx).
+		Where("scope = ? AND idempotency_key = ? AND owner = ? AND completed = ?", scope, key, owner, false).
+		Delete(&models.IdempotencyKey{}).Error
+}
+
+// DeleteExpired deletes keys that expired before now
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at < ?", now).
+		Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
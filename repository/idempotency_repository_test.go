@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"golang-gin/models"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyRepository_ClaimCompleteRelease(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&models.IdempotencyKey{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	repo := NewIdempotencyRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	record := func() *models.IdempotencyKey {
+		return &models.IdempotencyKey{Scope: "s", Key: "k", Fingerprint: "f", Owner: "first", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	}
+
+	if existing, err := repo.Claim(ctx, record(), now.Add(-time.Minute)); err != nil || existing != nil {
+		t.Fatalf("Expected first claim to succeed, got %+v, %v", existing, err)
+	}
+
+	existing, err := repo.Claim(ctx, record(), now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+	if existing == nil || existing.Completed {
+		t.Fatalf("Expected in-progress record, got %+v", existing)
+	}
+
+	if err := repo.Complete(ctx, &models.IdempotencyKey{Scope: "s", Key: "k", Owner: "other", StatusCode: 500}); err != ErrClaimLost {
+		t.Fatalf("Expected ErrClaimLost for another owner, got %v", err)
+	}
+	if err := repo.Release(ctx, "s", "k", "other"); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if existing, _ := repo.Claim(ctx, record(), now.Add(-time.Minute)); existing == nil {
+		t.Fatal("Expected the claim to survive a release by another owner")
+	}
+
+	if err := repo.Complete(ctx, &models.IdempotencyKey{Scope: "s", Key: "k", Owner: "first", StatusCode: 201, ETag: `"1"`, Body: []byte("\x81\xa2id\x01\x00\xff")}); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	existing, _ = repo.Claim(ctx, record(), now.Add(-time.Minute))
+	if existing == nil || !existing.Completed || existing.StatusCode != 201 || existing.ETag != `"1"` ||
+		string(existing.Body) != "\x81\xa2id\x01\x00\xff" {
+		t.Fatalf("Expected completed record, got %+v", existing)
+	}
+
+	// Completed records are not released
+	repo.Release(ctx, "s", "k", "first")
+	if existing, _ := repo.Claim(ctx, record(), now.Add(-time.Minute)); existing == nil {
+		t.Error("Expected completed record to survive Release")
+	}
+
+	// Expired records are replaced
+	later := record()
+	later.CreatedAt = now.Add(2 * time.Hour)
+	later.ExpiresAt = now.Add(3 * time.Hour)
+	if existing, err := repo.Claim(ctx, later, now); err != nil || existing != nil {
+		t.Errorf("Expected expired record to be replaced, got %+v, %v", existing, err)
+	}
+
+	deleted, err := repo.DeleteExpired(ctx, now.Add(4*time.Hour))
+	if err != nil || deleted != 1 {
+		t.Errorf("Expected 1 expired record deleted, got %d, %v", deleted, err)
+	}
+}
@@ -1,13 +1,18 @@
 package repository
 
 import (
+	"context"
 	"golang-gin/models"
+	"sort"
+	"sync"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 // MockAlbumRepository is a mock implementation of AlbumRepository for testing
 type MockAlbumRepository struct {
+	mu     sync.RWMutex
 	albums []models.Album
 }
 
@@ -15,53 +20,325 @@ type MockAlbumRepository struct {
 func NewMockAlbumRepository() *MockAlbumRepository {
 	return &MockAlbumRepository{
 		albums: []models.Album{
-			{ID: 1, Title: "Hammerhead", Artist: "THE OFFSPRING", Price: 25.05, Tax: 0.1},
-			{ID: 2, Title: "Shake It Off", Artist: "Taylor Swift", Price: 23.14, Tax: 0.1},
-			{ID: 3, Title: "mysterious love", Artist: "Miho Komatsu", Price: 18.88, Tax: 0.1},
+			{ID: 1, Title: "Hammerhead", Artist: "THE OFFSPRING", Price: 25.05, Tax: 0.1, Version: 1},
+			{ID: 2, Title: "Shake It Off", Artist: "Taylor Swift", Price: 23.14, Tax: 0.1, Version: 1},
+			{ID: 3, Title: "mysterious love", Artist: "Miho Komatsu", Price: 18.88, Tax: 0.1, Version: 1},
 		},
 	}
 }
 
 // FindAll retrieves all albums
-func (m *MockAlbumRepository) FindAll() ([]models.Album, error) {
-	return m.albums, nil
+func (m *MockAlbumRepository) FindAll(ctx context.Context, opts ...FindOption) ([]models.Album, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	albums := []models.Album{}
+	for _, album := range m.albums {
+		if !album.DeletedAt.Valid {
+			albums = append(albums, project(album, opts))
+		}
+	}
+	return albums, nil
 }
 
 // FindByID retrieves an album by ID
-func (m *MockAlbumRepository) FindByID(id uint) (*models.Album, error) {
+func (m *MockAlbumRepository) FindByID(ctx context.Context, id uint, opts ...FindOption) (*models.Album, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if i := m.indexOf(id, false); i >= 0 {
+		album := project(m.albums[i], opts)
+		return &album, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// project copies the fields selected by opts, like a SQL SELECT list would
+func project(album models.Album, opts []FindOption) models.Album {
+	var o findOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(o.fields) == 0 {
+		return album
+	}
+
+	projected := models.Album{DeletedAt: album.DeletedAt}
+	for _, field := range o.fields {
+		switch field {
+		case "id":
+			projected.ID = album.ID
+		case "title":
+			projected.Title = album.Title
+		case "artist":
+			projected.Artist = album.Artist
+		case "price":
+			projected.Price = album.Price
+		case "tax":
+			projected.Tax = album.Tax
+		case "owner_id":
+			projected.OwnerID = album.OwnerID
+		case "product_id":
+			projected.ProductID = album.ProductID
+		case "version":
+			projected.Version = album.Version
+		case "created_at":
+			projected.CreatedAt = album.CreatedAt
+		case "updated_at":
+			projected.UpdatedAt = album.UpdatedAt
+		}
+	}
+	return projected
+}
+
+// Stream calls fn for every album in ID order
+func (m *MockAlbumRepository) Stream(ctx context.Context, fn func(album *models.Album) error) error {
+	albums, err := m.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(albums, func(i, j int) bool { return albums[i].ID < albums[j].ID })
+
+	for i := range albums {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&albums[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LastModified returns the time of the most recent change to the album list
+func (m *MockAlbumRepository) LastModified(ctx context.Context) (time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return time.Time{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var last time.Time
 	for _, album := range m.albums {
-		if album.ID == id {
-			return &album, nil
+		if album.UpdatedAt.After(last) {
+			last = album.UpdatedAt
+		}
+		if album.DeletedAt.Valid && album.DeletedAt.Time.After(last) {
+			last = album.DeletedAt.Time
 		}
 	}
-	return nil, gorm.ErrRecordNotFound
+	return last, nil
 }
 
 // Create creates a new album
-func (m *MockAlbumRepository) Create(album *models.Album) error {
-	album.ID = uint(len(m.albums) + 1)
+func (m *MockAlbumRepository) Create(ctx context.Context, album *models.Album) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.isDuplicate(album) {
+		return gorm.ErrDuplicatedKey
+	}
+
+	now := time.Now().UTC()
+	album.ID = m.nextID()
+	album.Version = 1
+	album.CreatedAt = now
+	album.UpdatedAt = now
 	m.albums = append(m.albums, *album)
 	return nil
 }
 
 // Update updates an existing album
-func (m *MockAlbumRepository) Update(album *models.Album) error {
-	for i, a := range m.albums {
-		if a.ID == album.ID {
-			m.albums[i] = *album
-			return nil
-		}
+func (m *MockAlbumRepository) Update(ctx context.Context, album *models.Album) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.indexOf(album.ID, false)
+	if i < 0 {
+		return gorm.ErrRecordNotFound
+	}
+	if m.albums[i].Version != album.Version {
+		return ErrVersionConflict
+	}
+	if m.isDuplicate(album) {
+		return gorm.ErrDuplicatedKey
 	}
-	return gorm.ErrRecordNotFound
+
+	album.Version++
+	album.CreatedAt = m.albums[i].CreatedAt
+	album.UpdatedAt = time.Now().UTC()
+	m.albums[i] = *album
+	return nil
 }
 
 // Delete soft deletes an album by ID
-func (m *MockAlbumRepository) Delete(id uint) error {
+func (m *MockAlbumRepository) Delete(ctx context.Context, id uint, opts ...DeleteOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	o := newDeleteOptions(opts)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.indexOf(id, false)
+	if i < 0 {
+		return gorm.ErrRecordNotFound
+	}
+	if o.version != 0 && m.albums[i].Version != o.version {
+		return ErrVersionConflict
+	}
+	m.albums[i].DeletedAt = gorm.DeletedAt{Time: time.Now().UTC(), Valid: true}
+	return nil
+}
+
+// FindDeleted retrieves all soft-deleted albums, most recently deleted first
+func (m *MockAlbumRepository) FindDeleted(ctx context.Context) ([]models.Album, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	albums := []models.Album{}
+	for _, album := range m.albums {
+		if album.DeletedAt.Valid {
+			albums = append(albums, album)
+		}
+	}
+	sort.SliceStable(albums, func(i, j int) bool {
+		return albums[i].DeletedAt.Time.After(albums[j].DeletedAt.Time)
+	})
+	return albums, nil
+}
+
+// Restore undeletes a soft-deleted album
+func (m *MockAlbumRepository) Restore(ctx context.Context, id uint) (*models.Album, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.indexOf(id, true)
+	if i < 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if m.isDuplicate(&m.albums[i]) {
+		return nil, gorm.ErrDuplicatedKey
+	}
+
+	m.albums[i].DeletedAt = gorm.DeletedAt{}
+	m.albums[i].Version++
+	album := m.albums[i]
+	return &album, nil
+}
+
+// Purge permanently deletes a soft-deleted album
+func (m *MockAlbumRepository) Purge(ctx context.Context, id uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.indexOf(id, true)
+	if i < 0 {
+		return gorm.ErrRecordNotFound
+	}
+	m.albums = append(m.albums[:i], m.albums[i+1:]...)
+	return nil
+}
+
+// PurgeDeletedBefore permanently deletes albums soft-deleted before cutoff
+func (m *MockAlbumRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged int64
+	kept := m.albums[:0]
+	for _, album := range m.albums {
+		if album.DeletedAt.Valid && album.DeletedAt.Time.Before(cutoff) {
+			purged++
+			continue
+		}
+		kept = append(kept, album)
+	}
+	m.albums = kept
+	return purged, nil
+}
+
+// indexOf returns the index of the album with the given ID among live or
+// soft-deleted albums, or -1; callers must hold the lock
+func (m *MockAlbumRepository) indexOf(id uint, deleted bool) int {
 	for i, album := range m.albums {
-		if album.ID == id {
-			m.albums = append(m.albums[:i], m.albums[i+1:]...)
-			return nil
+		if album.ID == id && album.DeletedAt.Valid == deleted {
+			return i
 		}
 	}
-	return gorm.ErrRecordNotFound
+	return -1
+}
+
+// isDuplicate reports whether another live album has the same title and
+// artist, mirroring the partial unique index; callers must hold the lock
+func (m *MockAlbumRepository) isDuplicate(album *models.Album) bool {
+	for _, a := range m.albums {
+		if a.ID != album.ID && !a.DeletedAt.Valid && a.Title == album.Title && a.Artist == album.Artist {
+			return true
+		}
+	}
+	return false
+}
+
+// nextID returns the next free album ID; callers must hold the write lock
+func (m *MockAlbumRepository) nextID() uint {
+	var max uint
+	for _, album := range m.albums {
+		if album.ID > max {
+			max = album.ID
+		}
+	}
+	return max + 1
+}
+
+// snapshot returns a copy of the current albums
+func (m *MockAlbumRepository) snapshot() []models.Album {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	albums := make([]models.Album, len(m.albums))
+	copy(albums, m.albums)
+	return albums
+}
+
+// rollback replaces the current albums with a snapshot
+func (m *MockAlbumRepository) rollback(albums []models.Album) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.albums = albums
 }
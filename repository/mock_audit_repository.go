@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"golang-gin/models"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MockAuditRepository is a mock implementation of AuditRepository for testing
+type MockAuditRepository struct {
+	mu      sync.RWMutex
+	entries []models.AlbumAudit
+}
+
+// NewMockAuditRepository creates a new empty MockAuditRepository
+func NewMockAuditRepository() *MockAuditRepository {
+	return &MockAuditRepository{}
+}
+
+// Record appends an entry to the audit trail
+func (m *MockAuditRepository) Record(ctx context.Context, entry *models.AlbumAudit) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry.ID = uint(len(m.entries) + 1)
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+	m.entries = append(m.entries, *entry)
+	return nil
+}
+
+// FindByAlbumID retrieves the audit trail of an album, oldest first
+func (m *MockAuditRepository) FindByAlbumID(ctx context.Context, albumID uint) ([]models.AlbumAudit, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := []models.AlbumAudit{}
+	for _, entry := range m.entries {
+		if entry.AlbumID == albumID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// FindAlbumAsOf reconstructs an album as it was at the given time
+func (m *MockAuditRepository) FindAlbumAsOf(ctx context.Context, albumID uint, at time.Time) (*models.Album, error) {
+	entries, err := m.FindByAlbumID(ctx, albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !entries[i].CreatedAt.After(at) {
+			return albumFromAudit(&entries[i])
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// FindByAction retrieves the entries with the given action for several albums
+func (m *MockAuditRepository) FindByAction(ctx context.Context, action string, albumIDs []uint) ([]models.AlbumAudit, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := []models.AlbumAudit{}
+	for _, entry := range m.entries {
+		if entry.Action != action {
+			continue
+		}
+		for _, id := range albumIDs {
+			if entry.AlbumID == id {
+				entries = append(entries, entry)
+				break
+			}
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"golang-gin/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MockEmailRepository is a mock implementation of EmailRepository for testing
+type MockEmailRepository struct {
+	mu       sync.Mutex
+	messages map[uint]*models.EmailMessage
+	nextID   uint
+}
+
+// NewMockEmailRepository creates a new empty MockEmailRepository
+func NewMockEmailRepository() *MockEmailRepository {
+	return &MockEmailRepository{messages: map[uint]*models.EmailMessage{}, nextID: 1}
+}
+
+// Enqueue inserts a pending email unless its dedup key is already taken
+func (m *MockEmailRepository) Enqueue(ctx context.Context, msg *models.EmailMessage) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if msg.DedupKey != nil {
+		for _, existing := range m.messages {
+			if existing.DedupKey != nil && *existing.DedupKey == *msg.DedupKey {
+				*msg = *existing
+				return false, nil
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	msg.ID = m.nextID
+	m.nextID++
+	msg.Status = models.EmailStatusPending
+	if msg.NextAttemptAt.IsZero() {
+		msg.NextAttemptAt = now
+	}
+	msg.CreatedAt = now
+	msg.UpdatedAt = now
+	stored := *msg
+	m.messages[msg.ID] = &stored
+	return true, nil
+}
+
+// FindByID retrieves an email by its ID
+func (m *MockEmailRepository) FindByID(ctx context.Context, id uint) (*models.EmailMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg, ok := m.messages[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *msg
+	return &found, nil
+}
+
+// List returns the emails matching filter, newest first
+func (m *MockEmailRepository) List(ctx context.Context, filter EmailFilter) ([]models.EmailMessage, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var matched []models.EmailMessage
+	for _, msg := range m.messages {
+		if filter.Status != "" && msg.Status != filter.Status {
+			continue
+		}
+		if filter.Recipient != "" && !hasRecipient(msg, filter.Recipient) {
+			continue
+		}
+		matched = append(matched, *msg)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID > matched[j].ID })
+
+	total := int64(len(matched))
+	if filter.Offset >= len(matched) {
+		return []models.EmailMessage{}, total, nil
+	}
+	matched = matched[filter.Offset:]
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched, total, nil
+}
+
+// Claim returns due emails and hides them until now+lease
+func (m *MockEmailRepository) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.EmailMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var claimed []models.EmailMessage
+	for _, msg := range m.messages {
+		if msg.Status == models.EmailStatusPending && !msg.NextAttemptAt.After(now) {
+			claimed = append(claimed, *msg)
+		}
+	}
+	sort.Slice(claimed, func(i, j int) bool { return claimed[i].ID < claimed[j].ID })
+	if len(claimed) > limit {
+		claimed = claimed[:limit]
+	}
+
+	for _, msg := range claimed {
+		m.messages[msg.ID].NextAttemptAt = now.Add(lease)
+	}
+	return claimed, nil
+}
+
+// MarkSent records that the SMTP server accepted an email
+func (m *MockEmailRepository) MarkSent(ctx context.Context, id uint, sentAt time.Time) error {
+	return m.update(ctx, id, func(msg *models.EmailMessage) {
+		msg.Status = models.EmailStatusSent
+		msg.Attempts++
+		msg.SentAt = &sentAt
+		msg.LastError = ""
+	})
+}
+
+// MarkRetry records a transient delivery failure
+func (m *MockEmailRepository) MarkRetry(ctx context.Context, id uint, nextAttemptAt time.Time, lastError string) error {
+	return m.update(ctx, id, func(msg *models.EmailMessage) {
+		msg.Attempts++
+		msg.NextAttemptAt = nextAttemptAt
+		msg.LastError = lastError
+	})
+}
+
+// MarkFailed records a permanent delivery failure
+func (m *MockEmailRepository) MarkFailed(ctx context.Context, id uint, lastError string) error {
+	return m.update(ctx, id, func(msg *models.EmailMessage) {
+		msg.Status = models.EmailStatusFailed
+		msg.Attempts++
+		msg.LastError = lastError
+	})
+}
+
+// Requeue makes a failed email pending again
+func (m *MockEmailRepository) Requeue(ctx context.Context, id uint, now time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg, ok := m.messages[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if msg.Status != models.EmailStatusFailed {
+		return ErrEmailNotFailed
+	}
+	msg.Status = models.EmailStatusPending
+	msg.Attempts = 0
+	msg.NextAttemptAt = now
+	msg.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// DeleteSentBefore deletes emails sent before cutoff
+func (m *MockEmailRepository) DeleteSentBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for id, msg := range m.messages {
+		if msg.Status == models.EmailStatusSent && msg.SentAt != nil && msg.SentAt.Before(cutoff) {
+			delete(m.messages, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Messages returns a copy of all emails ordered by ID
+func (m *MockEmailRepository) Messages() []models.EmailMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	messages := make([]models.EmailMessage, 0, len(m.messages))
+	for _, msg := range m.messages {
+		messages = append(messages, *msg)
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages
+}
+
+// update applies fn to the stored email with the given ID, if any
+func (m *MockEmailRepository) update(ctx context.Context, id uint, fn func(*models.EmailMessage)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if msg, ok := m.messages[id]; ok {
+		fn(msg)
+		msg.UpdatedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// hasRecipient reports whether the email is addressed to recipient
+func hasRecipient(msg *models.EmailMessage, recipient string) bool {
+	recipient = strings.ToLower(recipient)
+	for _, list := range [][]string{msg.To, msg.Cc, msg.Bcc} {
+		for _, address := range list {
+			if strings.Contains(strings.ToLower(address), recipient) {
+				return true
+			}
+		}
+	}
+	return false
+}
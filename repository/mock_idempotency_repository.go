@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"golang-gin/models"
+	"sync"
+	"time"
+)
+
+// MockIdempotencyRepository is a mock implementation of IdempotencyRepository for testing
+type MockIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]models.IdempotencyKey
+}
+
+// NewMockIdempotencyRepository creates a new empty MockIdempotencyRepository
+func NewMockIdempotencyRepository() *MockIdempotencyRepository {
+	return &MockIdempotencyRepository{records: map[string]models.IdempotencyKey{}}
+}
+
+// Claim inserts record unless the key is already taken
+func (m *MockIdempotencyRepository) Claim(ctx context.Context, record *models.IdempotencyKey, staleBefore time.Time) (*models.IdempotencyKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := record.Scope + "\x00" + record.Key
+	if existing, ok := m.records[id]; ok {
+		expired := existing.ExpiresAt.Before(record.CreatedAt)
+		stale := !existing.Completed && existing.CreatedAt.Before(staleBefore)
+		if !expired && !stale {
+			return &existing, nil
+		}
+	}
+
+	m.records[id] = *record
+	return nil, nil
+}
+
+// Complete stores the response of a claimed key
+func (m *MockIdempotencyRepository) Complete(ctx context.Context, record *models.IdempotencyKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := record.Scope + "\x00" + record.Key
+	stored, ok := m.records[id]
+	if !ok || stored.Owner != record.Owner || stored.Completed {
+		return ErrClaimLost
+	}
+	stored.Completed = true
+	stored.StatusCode = record.StatusCode
+	stored.ContentType = record.ContentType
+	stored.ETag = record.ETag
+	stored.Vary = record.Vary
+	stored.Body = record.Body
+	m.records[id] = stored
+	return nil
+}
+
+// Release deletes an in-progress key so the request can be retried
+func (m *MockIdempotencyRepository) Release(ctx context.Context, scope, key, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := scope + "\x00" + key
+	if stored, ok := m.records[id]; ok && stored.Owner == owner && !stored.Completed {
+		delete(m.records, id)
+	}
+	return nil
+}
+
+// DeleteExpired deletes keys that expired before now
+func (m *MockIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for id, record := range m.records {
+		if record.ExpiresAt.Before(now) {
+			delete(m.records, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
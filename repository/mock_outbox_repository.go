@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"golang-gin/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MockOutboxRepository is a mock implementation of OutboxRepository for testing
+type MockOutboxRepository struct {
+	mu       sync.Mutex
+	messages map[uint]*models.OutboxMessage
+	nextID   uint
+}
+
+// NewMockOutboxRepository creates a new empty MockOutboxRepository
+func NewMockOutboxRepository() *MockOutboxRepository {
+	return &MockOutboxRepository{messages: map[uint]*models.OutboxMessage{}, nextID: 1}
+}
+
+// Add inserts a message into the outbox
+func (m *MockOutboxRepository) Add(ctx context.Context, msg *models.OutboxMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg.ID = m.nextID
+	m.nextID++
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now().UTC()
+	}
+	stored := *msg
+	m.messages[msg.ID] = &stored
+	return nil
+}
+
+// Claim returns due messages and hides them until now+lease
+func (m *MockOutboxRepository) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.OutboxMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var claimed []models.OutboxMessage
+	for _, msg := range m.messages {
+		if msg.PublishedAt == nil && !msg.NextAttemptAt.After(now) {
+			claimed = append(claimed, *msg)
+		}
+	}
+	sort.Slice(claimed, func(i, j int) bool { return claimed[i].ID < claimed[j].ID })
+	if len(claimed) > limit {
+		claimed = claimed[:limit]
+	}
+
+	for _, msg := range claimed {
+		m.messages[msg.ID].NextAttemptAt = now.Add(lease)
+	}
+	return claimed, nil
+}
+
+// MarkPublished records that the broker confirmed a message
+func (m *MockOutboxRepository) MarkPublished(ctx context.Context, id uint, publishedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if msg, ok := m.messages[id]; ok {
+		msg.PublishedAt = &publishedAt
+		msg.LastError = ""
+	}
+	return nil
+}
+
+// MarkFailed records a failed publish attempt
+func (m *MockOutboxRepository) MarkFailed(ctx context.Context, id uint, nextAttemptAt time.Time, lastError string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if msg, ok := m.messages[id]; ok {
+		msg.Attempts++
+		msg.NextAttemptAt = nextAttemptAt
+		msg.LastError = lastError
+	}
+	return nil
+}
+
+// DeletePublishedBefore deletes messages published before cutoff
+func (m *MockOutboxRepository) DeletePublishedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for id, msg := range m.messages {
+		if msg.PublishedAt != nil && msg.PublishedAt.Before(cutoff) {
+			delete(m.messages, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Messages returns a copy of all messages ordered by ID
+func (m *MockOutboxRepository) Messages() []models.OutboxMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	messages := make([]models.OutboxMessage, 0, len(m.messages))
+	for _, msg := range m.messages {
+		messages = append(messages, *msg)
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages
+}
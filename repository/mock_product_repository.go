@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"golang-gin/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MockProductRepository is a mock implementation of ProductRepository for
+// testing
+type MockProductRepository struct {
+	mu       sync.Mutex
+	products map[uint]models.Product
+}
+
+// NewMockProductRepository creates a new empty MockProductRepository
+func NewMockProductRepository() *MockProductRepository {
+	return &MockProductRepository{products: map[uint]models.Product{}}
+}
+
+// Upsert inserts or replaces a product
+func (m *MockProductRepository) Upsert(ctx context.Context, product *models.Product) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	product.Discontinued = false
+	m.products[product.ID] = *product
+	return nil
+}
+
+// FindByIDs retrieves the products with the given IDs
+func (m *MockProductRepository) FindByIDs(ctx context.Context, ids []uint) ([]models.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var products []models.Product
+	for _, id := range ids {
+		if product, ok := m.products[id]; ok {
+			products = append(products, product)
+		}
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+	return products, nil
+}
+
+// FindSyncedBefore retrieves a page of products due for a refresh
+func (m *MockProductRepository) FindSyncedBefore(ctx context.Context, before time.Time, afterID uint, limit int) ([]models.Product, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var products []models.Product
+	for _, product := range m.products {
+		if product.SyncedAt.Before(before) && product.ID > afterID {
+			products = append(products, product)
+		}
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+	if len(products) > limit {
+		products = products[:limit]
+	}
+	return products, nil
+}
+
+// MarkDiscontinued flags a product as discontinued
+func (m *MockProductRepository) MarkDiscontinued(ctx context.Context, id uint, syncedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if product, ok := m.products[id]; ok {
+		product.Discontinued = true
+		product.SyncedAt = syncedAt
+		m.products[id] = product
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"sync"
+)
+
+// MockUnitOfWork is a mock implementation of UnitOfWork for testing.
+// It serializes transactions and restores the mock data when fn fails.
+type MockUnitOfWork struct {
+	mu     sync.Mutex
+	Albums *MockAlbumRepository
+	Audits *MockAuditRepository
+}
+
+// NewMockUnitOfWork creates a new MockUnitOfWork around the given mock repositories
+func NewMockUnitOfWork(albums *MockAlbumRepository) *MockUnitOfWork {
+	return &MockUnitOfWork{Albums: albums, Audits: NewMockAuditRepository()}
+}
+
+// WithTx runs fn and rolls back the mock data when it returns an error or panics
+func (u *MockUnitOfWork) WithTx(ctx context.Context, fn func(repos Repositories) error) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	albums := u.Albums.snapshot()
+	defer func() {
+		if r := recover(); r != nil {
+			u.Albums.rollback(albums)
+			panic(r)
+		}
+		if err != nil {
+			u.Albums.rollback(albums)
+		}
+	}()
+
+	return fn(Repositories{Albums: u.Albums, Audits: u.Audits})
+}
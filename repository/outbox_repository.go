@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"golang-gin/audit"
+	"golang-gin/events"
+	"golang-gin/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OutboxRepository defines the interface for the transactional outbox
+type OutboxRepository interface {
+	Add(ctx context.Context, msg *models.OutboxMessage) error
+	// Claim returns up to limit unpublished messages that are due at now,
+	// oldest first, and hides them from other relays until now+lease
+	Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.OutboxMessage, error)
+	MarkPublished(ctx context.Context, id uint, publishedAt time.Time) error
+	// MarkFailed records a failed attempt and schedules the next one
+	MarkFailed(ctx context.Context, id uint, nextAttemptAt time.Time, lastError string) error
+	DeletePublishedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// outboxRepository implements OutboxRepository
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates a new OutboxRepository instance
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// Add inserts a message into the outbox
+func (r *outboxRepository) Add(ctx context.Context, msg *models.OutboxMessage) error {
+	return r.db.WithContext(ctx).Create(msg).Error
+}
+
+// Claim returns due messages. Each message is claimed by moving its
+// next_attempt_at forward only if no other relay has done so first, which
+// works without row locks on every supported database.
+func (r *outboxRepository) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.OutboxMessage, error) {
+	db := r.db.WithContext(ctx)
+
+	var due []models.OutboxMessage
+	if err := db.
+		Where("published_at IS NULL AND next_attempt_at <= ?", now).
+		Order("id").
+		Limit(limit).
+		Find(&due).Error; err != nil {
+		return nil, err
+	}
+
+	claimed := due[:0]
+	for _, msg := range due {
+		result := db.Model(&models.OutboxMessage{}).
+			Where("id = ? AND next_attempt_at = ? AND published_at IS NULL", msg.ID, msg.NextAttemptAt).
+			Update("next_attempt_at", now.Add(lease))
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			claimed = append(claimed, msg)
+		}
+	}
+	return claimed, nil
+}
+
+// MarkPublished records that the broker confirmed a message
+func (r *outboxRepository) MarkPublished(ctx context.Context, id uint, publishedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"published_at": publishedAt,
+			"last_error":   "",
+		}).Error
+}
+
+// MarkFailed records a failed publish attempt
+func (r *outboxRepository) MarkFailed(ctx context.Context, id uint, nextAttemptAt time.Time, lastError string) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastError,
+		}).Error
+}
+
+// DeletePublishedBefore deletes messages published before cutoff
+func (r *outboxRepository) DeletePublishedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("published_at IS NOT NULL AND published_at < ?", cutoff).
+		Delete(&models.OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
+
+// OutboxHook returns an AlbumHook that writes an album event to the outbox
+// for every published album write, in the same transaction as the write
+func OutboxHook() AlbumHook {
+	return func(ctx context.Context, tx *gorm.DB, change AlbumChange) error {
+		eventType, ok := events.AlbumEventType(change.Action)
+		if !ok {
+			return nil
+		}
+
+		album := change.After
+		if album == nil {
+			album = change.Before
+		}
+
+		now := time.Now().UTC()
+		event := events.NewAlbumEvent(eventType, album, audit.FromContext(ctx), now)
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		return NewOutboxRepository(tx).Add(ctx, &models.OutboxMessage{
+			EventID:       event.ID,
+			RoutingKey:    eventType,
+			Payload:       string(payload),
+			NextAttemptAt: now,
+		})
+	}
+}
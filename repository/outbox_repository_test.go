@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"golang-gin/events"
+	"golang-gin/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestOutboxHook(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewAlbumRepository(db, OutboxHook())
+	outbox := NewOutboxRepository(db)
+	ctx := context.Background()
+
+	album := &models.Album{Title: "Outbox", Artist: "Artist", Price: 10, Tax: 0.1}
+	if err := repo.Create(ctx, album); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	album.Price = 12
+	if err := repo.Update(ctx, album); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := repo.Delete(ctx, album.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := repo.Purge(ctx, album.ID); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+
+	messages, err := outbox.Claim(ctx, time.Now().UTC(), 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+
+	expected := []string{events.AlbumCreated, events.AlbumUpdated, events.AlbumDeleted}
+	if len(messages) != len(expected) {
+		t.Fatalf("Expected %d outbox messages, got %d", len(expected), len(messages))
+	}
+	for i, msg := range messages {
+		if msg.RoutingKey != expected[i] {
+			t.Errorf("Expected routing key %s, got %s", expected[i], msg.RoutingKey)
+		}
+
+		var event events.AlbumEvent
+		if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+			t.Fatalf("Invalid payload: %v", err)
+		}
+		if event.ID != msg.EventID || event.Album.ID != album.ID || event.SchemaVersion != events.SchemaVersion {
+			t.Errorf("Unexpected event %+v for message %+v", event, msg)
+		}
+	}
+}
+
+func TestOutboxHook_RolledBackWithWrite(t *testing.T) {
+	db := setupTestDB(t)
+	uow := NewUnitOfWork(db, OutboxHook())
+	ctx := context.Background()
+
+	errAbort := errors.New("abort")
+	err := uow.WithTx(ctx, func(repos Repositories) error {
+		if err := repos.Albums.Create(ctx, &models.Album{Title: "Rolled back", Artist: "Artist", Price: 1}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("Expected abort error, got %v", err)
+	}
+
+	var count int64
+	db.Model(&models.OutboxMessage{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected no outbox messages after rollback, got %d", count)
+	}
+}
+
+func TestOutboxRepository_Lifecycle(t *testing.T) {
+	db := setupTestDB(t)
+	outbox := NewOutboxRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	msg := &models.OutboxMessage{EventID: "e1", RoutingKey: events.AlbumCreated, Payload: "{}", NextAttemptAt: now}
+	if err := outbox.Add(ctx, msg); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	claimed, err := outbox.Claim(ctx, now, 10, time.Minute)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("Expected 1 claimed message, got %d (%v)", len(claimed), err)
+	}
+
+	// A claimed message is hidden until its lease expires
+	if again, _ := outbox.Claim(ctx, now, 10, time.Minute); len(again) != 0 {
+		t.Errorf("Expected claimed message to be hidden, got %d", len(again))
+	}
+
+	if err := outbox.MarkFailed(ctx, msg.ID, now.Add(time.Second), "broker down"); err != nil {
+		t.Fatalf("MarkFailed failed: %v", err)
+	}
+	claimed, _ = outbox.Claim(ctx, now.Add(time.Second), 10, time.Minute)
+	if len(claimed) != 1 || claimed[0].Attempts != 1 || claimed[0].LastError != "broker down" {
+		t.Fatalf("Expected retried message with 1 attempt, got %+v", claimed)
+	}
+
+	if err := outbox.MarkPublished(ctx, msg.ID, now); err != nil {
+		t.Fatalf("MarkPublished failed: %v", err)
+	}
+	if again, _ := outbox.Claim(ctx, now.Add(time.Hour), 10, time.Minute); len(again) != 0 {
+		t.Errorf("Expected published message not to be claimed, got %d", len(again))
+	}
+
+	deleted, err := outbox.DeletePublishedBefore(ctx, now.Add(time.Second))
+	if err != nil || deleted != 1 {
+		t.Errorf("Expected 1 deleted message, got %d (%v)", deleted, err)
+	}
+	if err := db.First(&models.OutboxMessage{}, msg.ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected message to be deleted, got %v", err)
+	}
+}
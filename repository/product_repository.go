@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"golang-gin/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProductRepository defines the interface for the local copies of the
+// products albums are linked to
+type ProductRepository interface {
+	// Upsert stores the current state of a product that the ProductService
+	// knows, clearing its discontinued flag
+	Upsert(ctx context.Context, product *models.Product) error
+	FindByIDs(ctx context.Context, ids []uint) ([]models.Product, error)
+	// FindSyncedBefore returns up to limit products with an ID above afterID
+	// that were last synced before the given time, in ID order
+	FindSyncedBefore(ctx context.Context, before time.Time, afterID uint, limit int) ([]models.Product, error)
+	// MarkDiscontinued records that the ProductService no longer knows a
+	// product
+	MarkDiscontinued(ctx context.Context, id uint, syncedAt time.Time) error
+}
+
+// productRepository implements ProductRepository
+type productRepository struct {
+	db *gorm.DB
+}
+
+// NewProductRepository creates a new ProductRepository instance
+func NewProductRepository(db *gorm.DB) ProductRepository {
+	return &productRepository{db: db}
+}
+
+// Upsert inserts or replaces a product
+func (r *productRepository) Upsert(ctx context.Context, product *models.Product) error {
+	product.Discontinued = false
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "description", "price", "stock", "discontinued", "synced_at"}),
+	}).Create(product).Error
+}
+
+// FindByIDs retrieves the products with the given IDs. Unknown IDs are
+// skipped.
+func (r *productRepository) FindByIDs(ctx context.Context, ids []uint) ([]models.Product, error) {
+	var products []models.Product
+	if len(ids) == 0 {
+		return products, nil
+	}
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Order("id ASC").Find(&products).Error; err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// FindSyncedBefore retrieves a page of products due for a refresh
+func (r *productRepository) FindSyncedBefore(ctx context.Context, before time.Time, afterID uint, limit int) ([]models.Product, error) {
+	var products []models.Product
+	if err := r.db.WithContext(ctx).
+		Where("synced_at < ? AND id > ?", before, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&products).Error; err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// MarkDiscontinued flags a product as discontinued
+func (r *productRepository) MarkDiscontinued(ctx context.Context, id uint, syncedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Product{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"discontinued": true, "synced_at": syncedAt}).Error
+}
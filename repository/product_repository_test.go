@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"golang-gin/models"
+	"testing"
+	"time"
+)
+
+func TestProductRepository(t *testing.T) {
+	repos := map[string]ProductRepository{
+		"gorm": NewProductRepository(setupTestDB(t)),
+		"mock": NewMockProductRepository(),
+	}
+
+	for name, products := range repos {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			synced := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+			for id := uint(1); id <= 3; id++ {
+				if err := products.Upsert(ctx, &models.Product{ID: id, Name: "Laptop", Price: 1299.99, Stock: 50, SyncedAt: synced}); err != nil {
+					t.Fatalf("Upsert failed: %v", err)
+				}
+			}
+			if err := products.MarkDiscontinued(ctx, 2, synced); err != nil {
+				t.Fatalf("MarkDiscontinued failed: %v", err)
+			}
+			if err := products.Upsert(ctx, &models.Product{ID: 3, Name: "Laptop", Price: 999, Stock: 0, SyncedAt: synced.Add(2 * time.Hour)}); err != nil {
+				t.Fatalf("Upsert failed: %v", err)
+			}
+
+			found, err := products.FindByIDs(ctx, []uint{3, 2, 9})
+			if err != nil || len(found) != 2 {
+				t.Fatalf("Expected 2 products, got %+v (%v)", found, err)
+			}
+			if !found[0].Discontinued || found[1].Price != 999 || found[1].Stock != 0 {
+				t.Errorf("Unexpected products: %+v", found)
+			}
+
+			due, err := products.FindSyncedBefore(ctx, synced.Add(time.Minute), 0, 1)
+			if err != nil || len(due) != 1 || due[0].ID != 1 {
+				t.Fatalf("Expected product 1 to be due, got %+v (%v)", due, err)
+			}
+			due, _ = products.FindSyncedBefore(ctx, synced.Add(time.Minute), due[0].ID, 10)
+			if len(due) != 1 || due[0].ID != 2 {
+				t.Errorf("Expected product 2 on the next page, got %+v", due)
+			}
+
+			// Relinking a discontinued product clears the flag
+			products.Upsert(ctx, &models.Product{ID: 2, Name: "Mouse", SyncedAt: synced})
+			if found, _ := products.FindByIDs(ctx, []uint{2}); len(found) != 1 || found[0].Discontinued {
+				t.Errorf("Expected discontinued flag to be cleared, got %+v", found)
+			}
+		})
+	}
+}
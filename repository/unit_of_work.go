@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Repositories groups the repositories that share a single transaction
+type Repositories struct {
+	Albums AlbumRepository
+	Audits AuditRepository
+}
+
+// UnitOfWork runs multi-step operations atomically
+type UnitOfWork interface {
+	// WithTx runs fn inside a transaction. The transaction is committed when
+	// fn returns nil and rolled back when it returns an error or panics.
+	WithTx(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+// unitOfWork implements UnitOfWork on top of GORM transactions
+type unitOfWork struct {
+	db         *gorm.DB
+	albumHooks []AlbumHook
+}
+
+// NewUnitOfWork creates a new UnitOfWork instance. The album hooks are
+// applied to the album repository handed to every transaction.
+func NewUnitOfWork(db *gorm.DB, albumHooks ...AlbumHook) UnitOfWork {
+	return &unitOfWork{db: db, albumHooks: albumHooks}
+}
+
+// WithTx runs fn with repositories bound to a new transaction
+func (u *unitOfWork) WithTx(ctx context.Context, fn func(repos Repositories) error) error {
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(Repositories{
+			Albums: NewAlbumRepository(tx, u.albumHooks...),
+			Audits: NewAuditRepository(tx),
+		})
+	})
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"golang-gin/models"
+	"testing"
+)
+
+func TestUnitOfWork_WithTx_Commit(t *testing.T) {
+	db := setupTestDB(t)
+	uow := NewUnitOfWork(db)
+	ctx := context.Background()
+
+	err := uow.WithTx(ctx, func(repos Repositories) error {
+		if err := repos.Albums.Create(ctx, &models.Album{Title: "A", Artist: "X", Price: 10, Tax: 0.1}); err != nil {
+			return err
+		}
+		return repos.Albums.Create(ctx, &models.Album{Title: "B", Artist: "X", Price: 20, Tax: 0.1})
+	})
+	if err != nil {
+		t.Fatalf("WithTx failed: %v", err)
+	}
+
+	albums, err := NewAlbumRepository(db).FindAll(ctx)
+	if err != nil {
+		t.Fatalf("FindAll failed: %v", err)
+	}
+	if len(albums) != 5 {
+		t.Errorf("Expected 5 albums after commit, got %d", len(albums))
+	}
+}
+
+func TestUnitOfWork_WithTx_Rollback(t *testing.T) {
+	db := setupTestDB(t)
+	uow := NewUnitOfWork(db)
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+
+	err := uow.WithTx(ctx, func(repos Repositories) error {
+		if err := repos.Albums.Create(ctx, &models.Album{Title: "A", Artist: "X", Price: 10, Tax: 0.1}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("Expected errAbort, got %v", err)
+	}
+
+	albums, err := NewAlbumRepository(db).FindAll(ctx)
+	if err != nil {
+		t.Fatalf("FindAll failed: %v", err)
+	}
+	if len(albums) != 3 {
+		t.Errorf("Expected 3 albums after rollback, got %d", len(albums))
+	}
+}
+
+func TestUnitOfWork_WithTx_CanceledContext(t *testing.T) {
+	uow := NewUnitOfWork(setupTestDB(t))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	err := uow.WithTx(ctx, func(repos Repositories) error {
+		called = true
+		return nil
+	})
+	if err == nil {
+		t.Error("Expected error for canceled context")
+	}
+	if called {
+		t.Error("Expected fn not to be called for canceled context")
+	}
+}
+
+func TestMockUnitOfWork_WithTx_Rollback(t *testing.T) {
+	albums := NewMockAlbumRepository()
+	uow := NewMockUnitOfWork(albums)
+	ctx := context.Background()
+
+	err := uow.WithTx(ctx, func(repos Repositories) error {
+		if err := repos.Albums.Create(ctx, &models.Album{Title: "A", Artist: "X", Price: 10, Tax: 0.1}); err != nil {
+			return err
+		}
+		return errors.New("abort")
+	})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+
+	all, _ := albums.FindAll(ctx)
+	if len(all) != 3 {
+		t.Errorf("Expected 3 albums after rollback, got %d", len(all))
+	}
+}
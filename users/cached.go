@@ -0,0 +1,83 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-gin/cache"
+	"time"
+)
+
+// CacheOption configures a CachedService
+type CacheOption func(*CachedService)
+
+// WithTTL sets how long a user is served from the cache before it is fetched
+// again
+func WithTTL(ttl time.Duration) CacheOption {
+	return func(s *CachedService) {
+		s.ttl = ttl
+	}
+}
+
+// WithStaleTTL sets how long an expired user is still served while the users
+// API is unavailable. Zero disables stale reads.
+func WithStaleTTL(ttl time.Duration) CacheOption {
+	return func(s *CachedService) {
+		s.staleTTL = ttl
+	}
+}
+
+// WithNegativeTTL sets how long unknown user IDs are cached. Zero disables
+// negative caching.
+func WithNegativeTTL(ttl time.Duration) CacheOption {
+	return func(s *CachedService) {
+		s.negativeTTL = ttl
+	}
+}
+
+// CachedService is a read-through cache in front of a UserService. GetUser is
+// served from the store, concurrent misses for the same user share one
+// request, and an expired user is served for up to the stale TTL when the
+// users API is unavailable. ListUsers is not cached.
+type CachedService struct {
+	UserService
+
+	ttl         time.Duration
+	staleTTL    time.Duration
+	negativeTTL time.Duration
+	users       *cache.Loader[User]
+	now         func() time.Time
+}
+
+// NewCachedService wraps svc with a cache backed by store
+func NewCachedService(svc UserService, store cache.Store, opts ...CacheOption) *CachedService {
+	s := &CachedService{
+		UserService: svc,
+		ttl:         5 * time.Minute,
+		staleTTL:    time.Hour,
+		negativeTTL: 30 * time.Second,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.users = cache.NewLoader[User](store, "User", ErrUserNotFound,
+		cache.WithTTL(s.ttl),
+		cache.WithStaleTTL(s.staleTTL, func(err error) bool { return errors.Is(err, ErrUnavailable) }),
+		cache.WithNegativeTTL(s.negativeTTL),
+		cache.WithClock(func() time.Time { return s.now() }),
+	)
+	return s
+}
+
+// userCacheKey returns the store key of a user
+func userCacheKey(id uint) string {
+	return fmt.Sprintf("users:v2:%d", id)
+}
+
+// GetUser returns a user, from the cache when possible
+func (s *CachedService) GetUser(ctx context.Context, id uint) (*User, error) {
+	return s.users.Get(ctx, userCacheKey(id), func(ctx context.Context) (*User, error) {
+		return s.UserService.GetUser(ctx, id)
+	})
+}
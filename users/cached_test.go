@@ -0,0 +1,94 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"golang-gin/cache"
+	"testing"
+	"time"
+)
+
+// newTestCachedService returns a cached mock service with a controllable
+// clock
+func newTestCachedService(opts ...CacheOption) (*CachedService, *MockService, *time.Time) {
+	mock := NewMockService(User{ID: 1, Name: "Alice"})
+	svc := NewCachedService(mock, cache.NewLRU(10), opts...)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+	return svc, mock, &now
+}
+
+func TestCachedService_GetUser(t *testing.T) {
+	svc, mock, now := newTestCachedService(WithTTL(time.Minute))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if user, err := svc.GetUser(ctx, 1); err != nil || user.Name != "Alice" {
+			t.Fatalf("Expected Alice, got %+v (%v)", user, err)
+		}
+	}
+	if mock.Calls() != 1 {
+		t.Errorf("Expected 1 call, got %d", mock.Calls())
+	}
+
+	*now = now.Add(2 * time.Minute)
+	svc.GetUser(ctx, 1)
+	if mock.Calls() != 2 {
+		t.Errorf("Expected expired user to be fetched again, got %d calls", mock.Calls())
+	}
+}
+
+func TestCachedService_NotFound(t *testing.T) {
+	svc, mock, _ := newTestCachedService()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.GetUser(ctx, 2); !errors.Is(err, ErrUserNotFound) {
+			t.Fatalf("Expected ErrUserNotFound, got %v", err)
+		}
+	}
+	if mock.Calls() != 1 {
+		t.Errorf("Expected unknown user to be cached, got %d calls", mock.Calls())
+	}
+
+	svc, mock, _ = newTestCachedService(WithNegativeTTL(0))
+	svc.GetUser(ctx, 2)
+	svc.GetUser(ctx, 2)
+	if mock.Calls() != 2 {
+		t.Errorf("Expected no negative caching, got %d calls", mock.Calls())
+	}
+}
+
+func TestCachedService_Unavailable(t *testing.T) {
+	tests := []struct {
+		name      string
+		staleTTL  time.Duration
+		wantStale bool
+	}{
+		{"Serves stale user", time.Hour, true},
+		{"Stale reads disabled", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, mock, now := newTestCachedService(WithTTL(time.Minute), WithStaleTTL(tt.staleTTL))
+			ctx := context.Background()
+
+			if _, err := svc.GetUser(ctx, 1); err != nil {
+				t.Fatalf("GetUser failed: %v", err)
+			}
+			mock.SetUnavailable(true)
+			*now = now.Add(2 * time.Minute)
+
+			user, err := svc.GetUser(ctx, 1)
+			if tt.wantStale && (err != nil || user.Name != "Alice") {
+				t.Errorf("Expected stale Alice, got %+v (%v)", user, err)
+			}
+			if !tt.wantStale && !errors.Is(err, ErrUnavailable) {
+				t.Errorf("Expected ErrUnavailable, got %v", err)
+			}
+			if _, err := svc.GetUser(ctx, 3); !errors.Is(err, ErrUnavailable) {
+				t.Errorf("Expected ErrUnavailable for uncached user, got %v", err)
+			}
+		})
+	}
+}
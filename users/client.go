@@ -0,0 +1,53 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"golang-gin/clients"
+	"net/http"
+)
+
+// client implements UserService on top of the users API
+type client struct {
+	http *clients.HTTPClient
+}
+
+// NewClient creates a UserService calling the users API through httpClient,
+// which provides the timeout, retry and circuit breaker settings
+func NewClient(httpClient *clients.HTTPClient) UserService {
+	return &client{http: httpClient}
+}
+
+// GetUser fetches a user by ID
+func (c *client) GetUser(ctx context.Context, id uint) (*User, error) {
+	user, err := clients.GetJSON[*User](ctx, c.http, fmt.Sprintf("/api/v1/users/%d", id))
+	if err != nil {
+		return nil, classify(ctx, err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("%w: empty response for user %d", ErrUnavailable, id)
+	}
+	return user, nil
+}
+
+// ListUsers fetches all users
+func (c *client) ListUsers(ctx context.Context) ([]User, error) {
+	list, err := clients.GetJSON[[]User](ctx, c.http, "/api/v1/users")
+	if err != nil {
+		return nil, classify(ctx, err)
+	}
+	return list, nil
+}
+
+// classify maps a client error to ErrUserNotFound for 404 responses and to
+// ErrUnavailable otherwise. Errors caused by the caller's context are
+// returned unchanged.
+func classify(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	if clients.HTTPStatus(err) == http.StatusNotFound {
+		return ErrUserNotFound
+	}
+	return fmt.Errorf("%w: %v", ErrUnavailable, err)
+}
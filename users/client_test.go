@@ -0,0 +1,83 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"golang-gin/clients"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newUsersAPI starts a users API knowing Alice (ID 1) that fails every
+// request to /api/v1/users/500
+func newUsersAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":1,"name":"Alice","email":"alice@example.com","user_type":"admin"}]`))
+	})
+	mux.HandleFunc("GET /api/v1/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("id") {
+		case "1":
+			w.Write([]byte(`{"id":1,"name":"Alice","email":"alice@example.com","user_type":"admin"}`))
+		case "500":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			http.Error(w, `{"error":"user not found"}`, http.StatusNotFound)
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_GetUser(t *testing.T) {
+	server := newUsersAPI(t)
+	svc := NewClient(clients.NewHTTPClient(server.URL, clients.WithRetryPolicy(clients.NoRetry)))
+
+	tests := []struct {
+		name    string
+		id      uint
+		want    string
+		wantErr error
+	}{
+		{"Found", 1, "Alice", nil},
+		{"Not found", 2, "", ErrUserNotFound},
+		{"Server error", 500, "", ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := svc.GetUser(context.Background(), tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && (user.Name != tt.want || user.UserType != "admin") {
+				t.Errorf("Expected %s, got %+v", tt.want, user)
+			}
+		})
+	}
+
+	list, err := svc.ListUsers(context.Background())
+	if err != nil || len(list) != 1 || list[0].Email != "alice@example.com" {
+		t.Errorf("Expected Alice in list, got %+v (%v)", list, err)
+	}
+}
+
+func TestClient_Unreachable(t *testing.T) {
+	server := newUsersAPI(t)
+	server.Close()
+	svc := NewClient(clients.NewHTTPClient(server.URL, clients.WithRetryPolicy(clients.NoRetry)))
+
+	if _, err := svc.GetUser(context.Background(), 1); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	if _, err := svc.GetUser(ctx, 1); errors.Is(err, ErrUnavailable) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
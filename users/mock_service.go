@@ -0,0 +1,79 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// MockService is a mock implementation of UserService for testing
+type MockService struct {
+	mu          sync.Mutex
+	users       map[uint]User
+	unavailable bool
+	calls       atomic.Int64
+}
+
+// NewMockService creates a MockService knowing the given users
+func NewMockService(users ...User) *MockService {
+	m := &MockService{users: map[uint]User{}}
+	for _, user := range users {
+		m.users[user.ID] = user
+	}
+	return m
+}
+
+// SetUnavailable makes every call fail with ErrUnavailable until reset
+func (m *MockService) SetUnavailable(unavailable bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unavailable = unavailable
+}
+
+// Calls returns the number of calls made so far
+func (m *MockService) Calls() int {
+	return int(m.calls.Load())
+}
+
+// GetUser returns the user with the given ID
+func (m *MockService) GetUser(ctx context.Context, id uint) (*User, error) {
+	m.calls.Add(1)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.unavailable {
+		return nil, fmt.Errorf("%w: mock is down", ErrUnavailable)
+	}
+	user, ok := m.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+// ListUsers returns all users ordered by ID
+func (m *MockService) ListUsers(ctx context.Context) ([]User, error) {
+	m.calls.Add(1)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.unavailable {
+		return nil, fmt.Errorf("%w: mock is down", ErrUnavailable)
+	}
+	list := make([]User, 0, len(m.users))
+	for _, user := range m.users {
+		list = append(list, user)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
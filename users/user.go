@@ -0,0 +1,32 @@
+// Package users integrates the users API served by mocks/http-mock
+package users
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrUserNotFound is returned when the users API does not know a user
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUnavailable is returned when the users API cannot be reached or
+	// fails. Callers should degrade instead of failing the request.
+	ErrUnavailable = errors.New("users service unavailable")
+)
+
+// User is a user of the users API
+type User struct {
+	ID       uint   `json:"id" xml:"id"`
+	Name     string `json:"name" xml:"name"`
+	Email    string `json:"email" xml:"email"`
+	UserType string `json:"user_type" xml:"user_type"`
+}
+
+// UserService looks up users
+type UserService interface {
+	// GetUser returns the user with the given ID, ErrUserNotFound or an
+	// error wrapping ErrUnavailable
+	GetUser(ctx context.Context, id uint) (*User, error)
+	// ListUsers returns all users
+	ListUsers(ctx context.Context) ([]User, error)
+}